@@ -0,0 +1,61 @@
+// Fallbacks for the parts of the whisper.cpp API which are newer than some
+// releases of whisper.cpp, so that the bindings build against either. Each
+// part of the API is detected with the macro which whisper.h defines for it.
+
+#pragma once
+
+#include <stdint.h>
+
+// Stage timings are zero when whisper.cpp does not provide them
+#ifndef WHISPER_HAS_TIMINGS
+struct whisper_timings {
+    int64_t mel_us;
+    int64_t sample_us;
+    int64_t encode_us;
+    int64_t decode_us;
+    int64_t batchd_us;
+    int64_t prompt_us;
+};
+
+static inline struct whisper_timings whisper_get_timings(struct whisper_context * ctx) {
+    struct whisper_timings timings = {0};
+    return timings;
+}
+
+static inline struct whisper_timings whisper_get_timings_from_state(struct whisper_state * state) {
+    struct whisper_timings timings = {0};
+    return timings;
+}
+#endif
+
+// The log mel spectrogram cannot be read when whisper.cpp does not provide it
+#ifdef WHISPER_HAS_GET_MEL
+#define WHISPER_GO_HAS_GET_MEL 1
+#else
+#define WHISPER_GO_HAS_GET_MEL 0
+
+static inline const float * whisper_get_mel(struct whisper_context * ctx, int * n_len, int * n_len_org, int * n_mel) {
+    *n_len = *n_len_org = *n_mel = 0;
+    return NULL;
+}
+
+static inline const float * whisper_get_mel_from_state(struct whisper_state * state, int * n_len, int * n_len_org, int * n_mel) {
+    *n_len = *n_len_org = *n_mel = 0;
+    return NULL;
+}
+#endif
+
+// The output of the encoder cannot be read when whisper.cpp does not provide it
+#ifdef WHISPER_HAS_ENCODER_OUTPUT
+#define WHISPER_GO_HAS_ENCODER_OUTPUT 1
+#else
+#define WHISPER_GO_HAS_ENCODER_OUTPUT 0
+
+static inline int whisper_get_encoder_output(struct whisper_context * ctx, float * data, int n_data) {
+    return -1;
+}
+
+static inline int whisper_get_encoder_output_from_state(struct whisper_state * state, float * data, int n_data) {
+    return -1;
+}
+#endif
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"errors"
+
+	// Package imports
+	api "github.com/ggerganov/whisper.cpp/bindings/go/pkg/server"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// NewAdmission returns the admission control set by the -max-sessions,
+// -session-queue and -session-queue-timeout flags, or nil when the number of
+// sessions is not limited
+func NewAdmission(flags *Flags) *api.Admission {
+	return api.NewAdmission(int(flags.GetMaxSessions()), int(flags.GetSessionQueue()), flags.GetSessionQueueTimeout())
+}
+
+// LimitStreams returns a gRPC interceptor which admits each stream, and
+// rejects those which are not admitted with the RESOURCE_EXHAUSTED code
+func LimitStreams(admission *api.Admission) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := admission.Acquire(stream.Context()); errors.Is(err, api.ErrTooManySessions) {
+			return status.Error(codes.ResourceExhausted, err.Error())
+		} else if err != nil {
+			return status.FromContextError(err).Err()
+		}
+		defer admission.Release()
+		return handler(srv, stream)
+	}
+}
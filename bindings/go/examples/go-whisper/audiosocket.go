@@ -0,0 +1,284 @@
+package main
+
+import (
+	gocontext "context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	// Package imports
+	audiosocket "github.com/ggerganov/whisper.cpp/bindings/go/pkg/audiosocket"
+	postprocess "github.com/ggerganov/whisper.cpp/bindings/go/pkg/postprocess"
+	record "github.com/ggerganov/whisper.cpp/bindings/go/pkg/record"
+	resample "github.com/ggerganov/whisper.cpp/bindings/go/pkg/resample"
+	api "github.com/ggerganov/whisper.cpp/bindings/go/pkg/server"
+	vad "github.com/ggerganov/whisper.cpp/bindings/go/pkg/vad"
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// audioSocketServer transcribes calls streamed by Asterisk over AudioSocket
+// connections, each with a context from the pool
+type audioSocketServer struct {
+	sync.Mutex
+	flags     *Flags
+	pool      whisper.Pool
+	admission *api.Admission
+	filter    postprocess.Filter
+	observe   observers
+	conns     map[net.Conn]struct{}
+	wg        sync.WaitGroup
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// ServeAudioSocket accepts AudioSocket connections on the -listen-audiosocket
+// address, and prints the segments of each call labelled with the call UUID,
+// until the go context is cancelled. On cancellation, in-flight calls are
+// transcribed for up to the -shutdown-timeout duration. The model is reloaded
+// on SIGHUP.
+func ServeAudioSocket(ctx gocontext.Context, flags *Flags) error {
+	pool, err := newPool(flags, flags.GetModelName(), flags.GetModel(), whisper.PoolWait)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+	ReloadOnHangup(ctx, flags, map[string]whisper.Pool{flags.GetModelName(): pool})
+	filter, err := flags.GetPostprocess()
+	if err != nil {
+		return err
+	}
+	observers, err := newObservers(flags)
+	if err != nil {
+		return err
+	}
+	defer observers.Close()
+	server := &audioSocketServer{
+		flags:     flags,
+		pool:      pool,
+		admission: NewAdmission(flags),
+		filter:    filter,
+		observe:   observers,
+		conns:     make(map[net.Conn]struct{}),
+	}
+
+	listener, err := net.Listen("tcp", flags.GetListenAudioSocket())
+	if err != nil {
+		return err
+	}
+	flags.Logger().Info("serving AudioSocket", "addr", listener.Addr().String(), "model", flags.GetModelName())
+
+	// Stop accepting connections on cancellation
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	// Accept connections until the listener is closed
+	for {
+		conn, err := listener.Accept()
+		if ctx.Err() != nil {
+			break
+		} else if err != nil {
+			return err
+		}
+		server.Lock()
+		server.conns[conn] = struct{}{}
+		server.wg.Add(1)
+		server.Unlock()
+		go server.serve(conn)
+	}
+
+	// Wait for in-flight calls, then close their connections
+	flags.Logger().Info("shutting down", "timeout", flags.GetShutdownTimeout())
+	done := make(chan struct{})
+	go func() {
+		server.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(flags.GetShutdownTimeout()):
+		server.Lock()
+		for conn := range server.conns {
+			conn.Close()
+		}
+		server.Unlock()
+		<-done
+	}
+
+	// Return success
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Transcribe a call until hangup or the connection is closed
+func (server *audioSocketServer) serve(conn net.Conn) {
+	defer server.wg.Done()
+	defer func() {
+		server.Lock()
+		delete(server.conns, conn)
+		server.Unlock()
+		conn.Close()
+	}()
+	logger := server.flags.Logger().With("remote", conn.RemoteAddr().String())
+	if err := server.call(conn, logger); err != nil {
+		logger.Error("call failed", "error", err)
+	}
+}
+
+func (server *audioSocketServer) call(conn net.Conn, logger *slog.Logger) error {
+	flags := server.flags
+
+	// The first message is the call UUID
+	server.setDeadline(conn, time.Now())
+	message, err := audiosocket.Read(conn)
+	if err != nil {
+		return err
+	}
+	uuid, err := message.UUID()
+	if err != nil {
+		return err
+	}
+	logger = logger.With("session", uuid)
+	logger.Info("call started")
+
+	// Admit the call, and obtain a context from the pool
+	if err := server.admission.Acquire(gocontext.Background()); err != nil {
+		return err
+	}
+	defer server.admission.Release()
+	context, err := server.pool.Get(gocontext.Background())
+	if err != nil {
+		return err
+	}
+	defer server.pool.Put(context)
+	if err := flags.SetParams(context); err != nil {
+		return err
+	}
+
+	// Print each segment labelled with the call
+	stream, err := whisper.NewStreamingContext(context, func(segment whisper.Segment) {
+		for _, segment := range server.filter([]whisper.Segment{segment}) {
+			server.observe.Segment("audiosocket", uuid, context, segment)
+			segment.Text = fmt.Sprintf("[%s] %s", uuid, segment.Text)
+			server.Lock()
+			Output(os.Stdout, context, []whisper.Segment{segment}, flags.IsColorize())
+			server.Unlock()
+		}
+	})
+	if err != nil {
+		return err
+	}
+	stream.SetSlidingWindow(flags.GetSlidingWindow())
+	if flags.IsVAD() {
+		stream.SetVAD(vad.New(whisper.SampleRate, flags.GetVADThreshold(), flags.GetVADHangover()), nil)
+		stream.SetMaxUtterance(flags.GetMaxUtterance())
+	}
+
+	// Record the call
+	var recorder *record.Recorder
+	if dir := flags.GetRecordDir(); dir != "" {
+		if recorder, err = record.Create(dir, "audiosocket-"+uuid); err != nil {
+			return err
+		}
+		defer recorder.Close()
+	}
+
+	// Feed audio until hangup, or a limit set by -max-audio,
+	// -max-session-time or -max-bytes is reached
+	start := time.Now()
+	var samples, bytes int
+	var limit string
+	for limit == "" {
+		server.setDeadline(conn, start)
+		message, err := audiosocket.Read(conn)
+		if errors.Is(err, io.EOF) {
+			break
+		} else if errors.Is(err, os.ErrDeadlineExceeded) {
+			if maxTime := flags.GetMaxSessionTime(); maxTime > 0 && time.Since(start) >= maxTime {
+				limit = "max-session-time"
+			} else {
+				logger.Info("call idle", "timeout", flags.GetIdleTimeout())
+			}
+			break
+		} else if err != nil {
+			return err
+		}
+		switch message.Kind {
+		case audiosocket.KindAudio:
+			if bytes += len(message.Payload); flags.GetMaxBytes() > 0 && uint64(bytes) > flags.GetMaxBytes() {
+				limit = "max-bytes"
+				continue
+			}
+			data, err := message.Samples()
+			if err != nil {
+				return err
+			}
+			data = resample.Linear(data, audiosocket.SampleRate, whisper.SampleRate)
+			if maxSamples := int(flags.GetMaxAudio().Seconds() * whisper.SampleRate); maxSamples > 0 && samples+len(data) > maxSamples {
+				data = data[:maxSamples-samples]
+				limit = "max-audio"
+			}
+			if recorder != nil {
+				if err := recorder.Write(data); err != nil {
+					return err
+				}
+			}
+			if err := stream.Feed(data); err != nil {
+				return err
+			}
+			samples += len(data)
+		case audiosocket.KindDTMF:
+			logger.Debug("dtmf", "digit", string(message.Payload))
+		case audiosocket.KindError:
+			logger.Warn("call error", "payload", fmt.Sprintf("%x", message.Payload))
+		}
+		if message.Kind == audiosocket.KindHangup || message.Kind == audiosocket.KindError {
+			break
+		}
+	}
+
+	// Hang up when a limit is reached
+	if limit != "" {
+		logger.Warn("call limit reached", "limit", limit, "audio", time.Duration(samples)*time.Second/whisper.SampleRate, "bytes", bytes, "elapsed", time.Since(start).Truncate(time.Millisecond))
+		if err := audiosocket.Write(conn, &audiosocket.Message{Kind: audiosocket.KindHangup}); err != nil {
+			logger.Warn("unable to hang up", "error", err)
+		}
+	}
+
+	// Transcribe the remaining audio
+	if err := stream.Flush(); err != nil {
+		return err
+	}
+	server.observe.End("audiosocket", uuid, context, samples)
+	logger.Info("call ended", "audio", time.Duration(samples)*time.Second/whisper.SampleRate)
+
+	// Return success
+	return nil
+}
+
+// Set the read deadline for the next message, when -idle-timeout is set,
+// and no later than -max-session-time from the start of the call
+func (server *audioSocketServer) setDeadline(conn net.Conn, start time.Time) {
+	var deadline time.Time
+	if timeout := server.flags.GetIdleTimeout(); timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	if maxTime := server.flags.GetMaxSessionTime(); maxTime > 0 && (deadline.IsZero() || start.Add(maxTime).Before(deadline)) {
+		deadline = start.Add(maxTime)
+	}
+	if !deadline.IsZero() {
+		conn.SetReadDeadline(deadline)
+	}
+}
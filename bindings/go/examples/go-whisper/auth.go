@@ -0,0 +1,56 @@
+package main
+
+import (
+	gocontext "context"
+	"strings"
+
+	// Package imports
+	api "github.com/ggerganov/whisper.cpp/bindings/go/pkg/server"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	metadata "google.golang.org/grpc/metadata"
+	status "google.golang.org/grpc/status"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// Environment variable with comma-separated API tokens, used in addition
+	// to the -auth-token flag
+	envAuthToken = "WHISPER_AUTH_TOKEN"
+
+	headerAPIKey = "X-API-Key"
+	bearerPrefix = "Bearer "
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// AuthenticateStream returns a gRPC interceptor which rejects streams
+// without a valid API token in the "authorization" (as a bearer token) or
+// "x-api-key" metadata. When no tokens are configured, all streams are
+// accepted.
+func AuthenticateStream(tokens []string) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if len(tokens) > 0 && !api.IsAuthorized(tokens, grpcToken(stream.Context())) {
+			return status.Error(codes.Unauthenticated, api.ErrUnauthorized.Error())
+		}
+		return handler(srv, stream)
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Return the token from the gRPC metadata
+func grpcToken(ctx gocontext.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if key := md.Get(strings.ToLower(headerAPIKey)); len(key) > 0 {
+		return key[0]
+	}
+	if auth := md.Get("authorization"); len(auth) > 0 && strings.HasPrefix(auth[0], bearerPrefix) {
+		return strings.TrimPrefix(auth[0], bearerPrefix)
+	}
+	return ""
+}
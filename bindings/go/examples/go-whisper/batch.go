@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	gocontext "context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	// Package imports
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// The result of processing a file, which is held until the results of the
+// preceding files have been written
+type batchResult struct {
+	buf           bytes.Buffer
+	transcription *transcription // The transcription, with -concat-output
+	err           error
+	done          chan struct{}
+}
+
+// A model for a worker, which processes with decoding states of its own
+// against the weights of the shared model. The contexts are reused for each
+// file, so that the states are allocated once.
+type workerModel struct {
+	whisper.Model
+	contexts []whisper.StateContext
+	next     int // The next context to return for the current file
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// ProcessFiles transcribes the inputs with up to -parallel workers. Results
+// which are not written to a file are written to w in the order the inputs
+// were given. The model is loaded once: the first worker decodes with its
+// default state, and each other worker with decoding states of its own.
+// Files which fail are logged and skipped, and processing stops when the go
+// context is cancelled. With -concat-output, the inputs are written as one
+// continuous recording instead, and a file which fails stops processing.
+func ProcessFiles(ctx gocontext.Context, model whisper.Model, inputs []Input, w io.Writer, flags *Flags) error {
+	logger := flags.Logger()
+
+	// Share the model with each worker. On return, stop queueing files and
+	// wait for the workers before their decoding states are released.
+	ctx, cancel := gocontext.WithCancel(ctx)
+	workers := min(int(flags.GetParallel()), len(inputs))
+	models := []whisper.Model{model}
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+		for _, model := range models[1:] {
+			model.Close()
+		}
+	}()
+	for len(models) < workers {
+		models = append(models, &workerModel{Model: model})
+	}
+	if workers > 1 {
+		logger.Info("processing in parallel", "workers", workers, "files", len(inputs))
+	}
+
+	// Start the workers
+	results := make([]*batchResult, len(inputs))
+	for i := range results {
+		results[i] = &batchResult{done: make(chan struct{})}
+	}
+	jobs := make(chan int)
+	for _, model := range models {
+		wg.Add(1)
+		go func(model whisper.Model) {
+			defer wg.Done()
+			for i := range jobs {
+				if worker, ok := model.(*workerModel); ok {
+					worker.next = 0
+				}
+				if flags.IsConcatOutput() {
+					results[i].transcription, results[i].err = transcribe(ctx, model, inputs[i], flags)
+				} else {
+					results[i].err = Process(ctx, model, inputs[i], &results[i].buf, flags)
+				}
+				close(results[i].done)
+			}
+		}(model)
+	}
+
+	// Queue the files, and mark those which were not started on cancellation
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(jobs)
+		for i := range inputs {
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				for _, result := range results[i:] {
+					result.err = ctx.Err()
+					close(result.done)
+				}
+				return
+			}
+		}
+	}()
+
+	// Write the results in order
+	var transcriptions []*transcription
+	for i, result := range results {
+		<-result.done
+		if errors.Is(result.err, gocontext.Canceled) {
+			logger.Warn("interrupted", "path", inputs[i].Path)
+			if flags.IsConcatOutput() {
+				return result.err
+			}
+			break
+		} else if result.err != nil && flags.IsConcatOutput() {
+			return fmt.Errorf("%s: %w", inputs[i].Path, result.err)
+		} else if result.err != nil {
+			logger.Error("unable to process", "path", inputs[i].Path, "error", result.err)
+			continue
+		}
+		if result.transcription != nil {
+			transcriptions = append(transcriptions, result.transcription)
+		} else if _, err := io.Copy(w, &result.buf); err != nil {
+			return err
+		}
+	}
+
+	// Write the inputs as one recording, as the results of the first input
+	if len(transcriptions) > 0 {
+		return concatTranscriptions(transcriptions).write(inputs[0], w, flags)
+	}
+
+	// Return success
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Return a context with its own decoding state, reusing the contexts of the
+// previous file in the order they were returned
+func (model *workerModel) NewContext() (whisper.Context, error) {
+	if model.next < len(model.contexts) {
+		context := model.contexts[model.next]
+		context.Reset()
+		model.next++
+		return context, nil
+	}
+	context, err := model.NewState()
+	if err != nil {
+		return nil, err
+	}
+	model.contexts = append(model.contexts, context)
+	model.next++
+
+	// Return success
+	return context, nil
+}
+
+// Release the decoding states of the worker, but not the shared model
+func (model *workerModel) Close() error {
+	var result error
+	for _, context := range model.contexts {
+		if err := context.Close(); err != nil {
+			result = err
+		}
+	}
+	model.contexts = nil
+	return result
+}
+
+// Return the transcriptions as one continuous recording, in order, with the
+// times of each offset by the duration of the audio before it
+func concatTranscriptions(transcriptions []*transcription) *transcription {
+	first := transcriptions[0]
+	result := &transcription{
+		context:  first.context,
+		detected: first.detected,
+		language: first.language,
+	}
+	var offset time.Duration
+	for _, transcription := range transcriptions {
+		for i, segment := range transcription.segments {
+			segment = offsetSegment(segment, offset)
+			segment.Num = len(result.segments)
+			result.segments = append(result.segments, segment)
+			result.labels = append(result.labels, transcription.labels[i])
+		}
+		offset += toDuration(transcription.samples)
+		result.samples += transcription.samples
+		result.decode += transcription.decode
+		result.process += transcription.process
+	}
+	return result
+}
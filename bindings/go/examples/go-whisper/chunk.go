@@ -0,0 +1,169 @@
+package main
+
+import (
+	gocontext "context"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	// Package imports
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// Shortest chunk, as whisper decodes audio in 30 second windows
+	minChunkSize = 30 * time.Second
+
+	// Each chunk is cut at the quietest point in this much audio before its
+	// end, so that words are not split between chunks
+	chunkSearch = 5 * time.Second
+
+	// Duration of the frames compared when searching for the quietest point
+	chunkFrame = 20 * time.Millisecond
+
+	// Number of words at the end of a chunk passed as the prompt of the next
+	chunkPromptWords = 100
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Decode and process the audio a chunk at a time, as set by -chunk-size, so
+// that only a chunk of a long recording is held in memory. The text at the
+// end of each chunk is passed to the next as the prompt, after any -prompt,
+// so that the transcript reads on across the cut. Returns the segments, with
+// times from the start of the file, and the number of samples of the file
+// at the whisper sample rate. The progress is updated with the audio
+// processed.
+func processChunks(ctx gocontext.Context, context whisper.Context, r io.ReadSeeker, cb whisper.SegmentCallback, progress *progress, flags *Flags) ([]whisper.Segment, int, error) {
+	blocks, rate, err := DecodeBlocks(r, flags)
+	if err != nil {
+		return nil, 0, err
+	}
+	resample := func(data []float32) []float32 { return data }
+	if rate != whisper.SampleRate {
+		resampler, err := flags.GetResampler()
+		if err != nil {
+			return nil, 0, err
+		}
+		flags.Logger().Debug("resampling", "from", rate, "to", whisper.SampleRate)
+		resample = func(data []float32) []float32 {
+			return resampler(data, rate, whisper.SampleRate)
+		}
+	}
+
+	// The section set by -offset and -duration, in samples of the file
+	offset, duration := flags.GetOffset(), flags.GetDuration()
+	if offset < 0 || duration < 0 {
+		return nil, 0, fmt.Errorf("-offset and -duration cannot be negative")
+	}
+	skip, limit := int(offset.Seconds()*float64(rate)), -1
+	if duration > 0 {
+		limit = int(duration.Seconds() * float64(rate))
+	}
+
+	// Process each chunk, and move its segments to the start of the chunk
+	var segments []whisper.Segment
+	first, start, total := skip, skip, 0
+	prompt := flags.GetPrompt()
+	transcribe := func(data []float32) error {
+		at := time.Duration(start) * time.Second / time.Duration(rate)
+		flags.Logger().Debug("processing chunk", "start", at, "duration", time.Duration(len(data))*time.Second/time.Duration(rate))
+		var chunkcb whisper.SegmentCallback
+		if cb != nil {
+			chunkcb = func(segment whisper.Segment) {
+				cb(offsetSegment(segment, at))
+			}
+		}
+		base := time.Duration(start-first) * time.Second / time.Duration(rate)
+		length := time.Duration(len(data)) * time.Second / time.Duration(rate)
+		result, err := process(ctx, context, resample(data), 0, 0, chunkcb, func(done float64) {
+			progress.Update(base + time.Duration(done*float64(length)))
+		}, flags)
+		if err != nil {
+			return err
+		}
+		text := make([]string, 0, len(result))
+		for _, segment := range result {
+			segment = offsetSegment(segment, at)
+			segment.Num = len(segments)
+			segments = append(segments, segment)
+			text = append(text, segment.Text)
+		}
+		if words := strings.Fields(strings.Join(text, " ")); len(words) > 0 {
+			words = words[max(0, len(words)-chunkPromptWords):]
+			context.SetInitialPrompt(strings.TrimSpace(prompt + " " + strings.Join(words, " ")))
+		}
+		start += len(data)
+		return nil
+	}
+
+	// Decode the blocks of the section, and process a chunk whenever one has
+	// been decoded
+	size := int(flags.GetChunkSize().Seconds() * float64(rate))
+	var buf []float32
+	for limit != 0 {
+		block, err := blocks()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, 0, err
+		}
+		data, err := mixChannels(block, flags.GetChannel())
+		if err != nil {
+			return nil, 0, err
+		}
+		total += len(data)
+		if skip > 0 {
+			n := min(skip, len(data))
+			data, skip = data[n:], skip-n
+		}
+		if limit > 0 {
+			data = data[:min(len(data), limit)]
+			limit -= len(data)
+		}
+		buf = append(buf, data...)
+		for len(buf) >= size {
+			n := quietest(buf[:size], int(chunkSearch.Seconds()*float64(rate)), int(chunkFrame.Seconds()*float64(rate)))
+			if err := transcribe(buf[:n]); err != nil {
+				return nil, 0, err
+			}
+			buf = append(buf[:0], buf[n:]...)
+		}
+	}
+	if skip > 0 {
+		return nil, 0, fmt.Errorf("-offset %v is beyond the end of the audio at %v", offset, time.Duration(total)*time.Second/time.Duration(rate))
+	}
+	if len(buf) > 0 {
+		if err := transcribe(buf); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	// Return success
+	return segments, int(int64(total) * whisper.SampleRate / int64(rate)), nil
+}
+
+// Return the index of the middle of the quietest frame in the last search
+// samples of the data, or the length of the data when it is too short
+func quietest(data []float32, search, frame int) int {
+	result, energy := len(data), math.Inf(1)
+	if frame <= 0 || search > len(data)/2 {
+		return result
+	}
+	for i := len(data) - search; i+frame <= len(data); i += frame {
+		var e float64
+		for _, v := range data[i : i+frame] {
+			e += float64(v) * float64(v)
+		}
+		if e < energy {
+			result, energy = i+frame/2, e
+		}
+	}
+	return result
+}
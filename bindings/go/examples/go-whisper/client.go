@@ -0,0 +1,506 @@
+package main
+
+import (
+	"bytes"
+	gocontext "context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	// Package imports
+	api "github.com/ggerganov/whisper.cpp/bindings/go/pkg/server"
+	speech "github.com/ggerganov/whisper.cpp/bindings/go/pkg/server/grpc"
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	grpc "google.golang.org/grpc"
+	credentials "google.golang.org/grpc/credentials"
+	insecure "google.golang.org/grpc/credentials/insecure"
+	metadata "google.golang.org/grpc/metadata"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// client sends an audio file to a remote server in one or more sessions,
+// for the client and loadtest subcommands
+type client struct {
+	sync.Mutex
+	url      *url.URL
+	file     string
+	token    string
+	language string
+	model    string
+	interim  bool
+	realtime bool
+	chunk    time.Duration
+	insecure bool
+	sessions uint
+	prefix   bool // Results are prefixed with the number of the session
+	quiet    bool // Results are not printed
+
+	// The audio sent, which is 16-bit PCM for a gRPC server and the file for
+	// an HTTP server, and the connection to a gRPC server
+	data   []byte
+	conn   *grpc.ClientConn
+	speech speech.SpeechClient
+}
+
+// The outcome of a client session
+type clientSession struct {
+	id      string        // Session identifier returned by the server
+	audio   time.Duration // Audio sent
+	elapsed time.Duration // Time from the start of the session to the last result
+	first   time.Duration // Time to the first final result
+	finals  int
+
+	// Time from sending the end of the audio of each final result to
+	// receiving it
+	latencies []time.Duration
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// The subcommand which runs the client
+	clientCommand = "client"
+
+	// Amount of audio in each chunk streamed to a gRPC server
+	defaultClientChunk = 100 * time.Millisecond
+)
+
+var (
+	errClientURL  = errors.New("-url is required")
+	errClientFile = errors.New("-file is required")
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// RunClient runs the client subcommand, which streams an audio file to a
+// remote server and prints the final results as they arrive, for verifying
+// a deployment. A grpc:// or grpcs:// URL streams the audio to the
+// StreamingRecognize RPC, and an http:// or https:// URL uploads the file to
+// the /transcribe endpoint. With -sessions, the file is sent in several
+// concurrent sessions, and the timings of each session are logged.
+func RunClient(ctx gocontext.Context, name string, args []string) error {
+	var interim bool
+	client, flags, err := newClient(name, clientCommand, args, 1, false, func(fs *flag.FlagSet) {
+		fs.BoolVar(&interim, "interim", false, "Print interim results of gRPC streams to standard error")
+	})
+	if err != nil {
+		return err
+	}
+	client.interim = interim
+	if err := client.open(ctx, flags); err != nil {
+		return err
+	}
+	defer client.Close()
+
+	// Run the sessions, and log the timings of each
+	logger := flags.Logger()
+	var failed int
+	var audio, elapsed, first time.Duration
+	start := time.Now()
+	client.run(ctx, 0, func(n int, session clientSession, err error) {
+		if err != nil {
+			failed++
+			logger.Error("session failed", "n", n, "session", session.id, "error", err)
+			return
+		}
+		audio += session.audio
+		elapsed = max(elapsed, session.elapsed)
+		first = max(first, session.first)
+		logger.Info("session", "n", n, "session", session.id, "audio", session.audio, "elapsed", session.elapsed, "first_result", session.first, "finals", session.finals, "rtf", math.Round(rtf(session.elapsed, session.audio)*1000)/1000)
+	})
+	if client.sessions > 1 {
+		logger.Info("sessions", "count", client.sessions, "failed", failed, "audio", audio, "elapsed", time.Since(start), "max_elapsed", elapsed, "max_first_result", first)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d sessions failed", failed, client.sessions)
+	}
+
+	// Return success
+	return nil
+}
+
+// Close the connection to the server
+func (client *client) Close() error {
+	if client.conn != nil {
+		return client.conn.Close()
+	}
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Return a client from the flags of a subcommand, which are those shared by
+// the client and loadtest subcommands and those added by register, with the
+// default number of sessions and pacing of the subcommand. The other flags
+// have their defaults, and are set from the environment, to decode the file
+// and to log.
+func newClient(name, command string, args []string, sessions uint, realtime bool, register func(fs *flag.FlagSet)) (*client, *Flags, error) {
+	client := &client{}
+	fs := flag.NewFlagSet(name+" "+command, flag.ContinueOnError)
+	rawurl := fs.String("url", "", "Server URL: grpc://host:port, grpcs://host:port, or http(s)://host:port[/transcribe]")
+	fs.StringVar(&client.file, "file", "", "Audio file to send")
+	fs.StringVar(&client.token, "token", strings.TrimSpace(strings.Split(os.Getenv(envAuthToken), ",")[0]), "API token sent to the server, when it requires one")
+	fs.StringVar(&client.language, "language", "", "Spoken language, or empty for the server default")
+	fs.StringVar(&client.model, "model", "", "Name of the model on the server, or empty for the server default")
+	fs.BoolVar(&client.realtime, "realtime", realtime, "Stream the audio at real time, rather than as fast as possible")
+	fs.DurationVar(&client.chunk, "chunk", defaultClientChunk, "Amount of audio in each chunk streamed to a gRPC server")
+	fs.BoolVar(&client.insecure, "insecure", false, "Skip verification of the server certificate")
+	fs.UintVar(&client.sessions, "sessions", sessions, "Number of concurrent sessions which send the file")
+	if register != nil {
+		register(fs)
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	} else if *rawurl == "" {
+		return nil, nil, errClientURL
+	} else if client.file == "" {
+		return nil, nil, errClientFile
+	} else if client.chunk <= 0 {
+		return nil, nil, fmt.Errorf("-chunk must be positive: %v", client.chunk)
+	}
+	if u, err := url.Parse(*rawurl); err != nil {
+		return nil, nil, err
+	} else {
+		client.url = u
+	}
+	client.sessions = max(client.sessions, 1)
+	client.prefix = client.sessions > 1
+	flags, err := NewFlags(name, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Return success
+	return client, flags, nil
+}
+
+// Read the audio, decoding it for a gRPC server, and connect to the server
+func (client *client) open(ctx gocontext.Context, flags *Flags) error {
+	switch client.url.Scheme {
+	case "grpc", "grpcs":
+		data, err := client.decode(flags)
+		if err != nil {
+			return err
+		}
+		conn, err := client.dial(ctx)
+		if err != nil {
+			return err
+		}
+		client.data, client.conn, client.speech = data, conn, speech.NewSpeechClient(conn)
+	case "http", "https":
+		data, err := os.ReadFile(client.file)
+		if err != nil {
+			return err
+		}
+		client.data = data
+	default:
+		return fmt.Errorf("unsupported URL scheme %q: use grpc, grpcs, http or https", client.url.Scheme)
+	}
+
+	// Return success
+	return nil
+}
+
+// Run the sessions concurrently, with their starts spread evenly over the
+// ramp, and call done with the outcome of each as it ends. Calls to done
+// are serialized.
+func (client *client) run(ctx gocontext.Context, ramp time.Duration, done func(n int, session clientSession, err error)) {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	for n := 1; n <= int(client.sessions); n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			var session clientSession
+			var err error
+			if sleep(ctx, ramp*time.Duration(n-1)/time.Duration(client.sessions)) {
+				session, err = client.session(ctx, n)
+			} else {
+				err = ctx.Err()
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			done(n, session, err)
+		}(n)
+	}
+	wg.Wait()
+}
+
+// Run a session with the protocol of the URL
+func (client *client) session(ctx gocontext.Context, n int) (clientSession, error) {
+	if client.speech != nil {
+		return client.stream(ctx, n)
+	}
+	return client.upload(ctx, n)
+}
+
+// Decode the file to 16-bit PCM at the whisper sample rate
+func (client *client) decode(flags *Flags) ([]byte, error) {
+	fh, err := os.Open(client.file)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	samples, err := Decode(fh, flags)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, len(samples)*2)
+	for i, v := range samples {
+		binary.LittleEndian.PutUint16(data[i*2:], uint16(int16(max(min(v, 1), -1)*math.MaxInt16)))
+	}
+	return data, nil
+}
+
+// Connect to a gRPC server, over TLS for grpcs://
+func (client *client) dial(ctx gocontext.Context) (*grpc.ClientConn, error) {
+	addr := client.url.Host
+	if addr == "" {
+		return nil, fmt.Errorf("missing host in URL: %q", client.url)
+	}
+	creds := insecure.NewCredentials()
+	if client.url.Scheme == "grpcs" {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(addr, "443")
+		}
+		creds = credentials.NewTLS(&tls.Config{InsecureSkipVerify: client.insecure})
+	}
+	return grpc.DialContext(ctx, addr, grpc.WithTransportCredentials(creds))
+}
+
+// Stream the audio to the StreamingRecognize RPC, and print the results
+func (client *client) stream(ctx gocontext.Context, n int) (clientSession, error) {
+	var session clientSession
+	data := client.data
+	if client.token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", bearerPrefix+client.token)
+	}
+	ctx, cancel := gocontext.WithCancel(ctx)
+	defer cancel()
+	start := time.Now()
+	stream, err := client.speech.StreamingRecognize(ctx)
+	if err != nil {
+		return session, err
+	}
+	if err := stream.Send(&speech.StreamingRecognizeRequest{
+		StreamingRequest: &speech.StreamingRecognizeRequest_StreamingConfig{
+			StreamingConfig: &speech.StreamingRecognitionConfig{
+				Config: &speech.RecognitionConfig{
+					Encoding:        speech.RecognitionConfig_LINEAR16,
+					SampleRateHertz: whisper.SampleRate,
+					LanguageCode:    client.language,
+					Model:           client.model,
+				},
+				InterimResults: client.interim,
+			},
+		},
+	}); err != nil {
+		return session, err
+	}
+
+	// Send the audio in chunks, at real time when -realtime is set, and
+	// record when each chunk is sent
+	size := max(int(client.chunk.Seconds()*whisper.SampleRate)*2, 2)
+	var mu sync.Mutex
+	var times []time.Time
+	sent := make(chan error, 1)
+	go func() {
+		defer close(sent)
+		for i := 0; i < len(data); i += size {
+			chunk := data[i:min(i+size, len(data))]
+			if client.realtime {
+				if !sleep(ctx, start.Add(toDuration(i/2)).Sub(time.Now())) {
+					return
+				}
+			}
+			if err := stream.Send(&speech.StreamingRecognizeRequest{
+				StreamingRequest: &speech.StreamingRecognizeRequest_AudioContent{AudioContent: chunk},
+			}); err != nil {
+				// The error is returned by Recv
+				return
+			}
+			mu.Lock()
+			times = append(times, time.Now())
+			mu.Unlock()
+		}
+		sent <- stream.CloseSend()
+	}()
+	session.audio = toDuration(len(data) / 2)
+
+	// The session identifier is returned in the header
+	if header, err := stream.Header(); err == nil {
+		if id := header.Get("x-request-id"); len(id) > 0 {
+			session.id = id[0]
+		}
+	}
+
+	// Print results until the server ends the stream, with the latency of
+	// each final result from the chunk which contained the end of its audio
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return session, err
+		}
+		for _, result := range resp.GetResults() {
+			alternatives := result.GetAlternatives()
+			if len(alternatives) == 0 {
+				continue
+			}
+			text := strings.TrimSpace(alternatives[0].GetTranscript())
+			if !result.GetIsFinal() {
+				if client.interim {
+					client.print(os.Stderr, n, "... "+text)
+				}
+				continue
+			}
+			now := time.Now()
+			if session.finals == 0 {
+				session.first = now.Sub(start)
+			}
+			session.finals++
+			chunk := int(result.GetEndTime().AsDuration().Seconds()*whisper.SampleRate) * 2 / size
+			mu.Lock()
+			if len(times) > 0 {
+				session.latencies = append(session.latencies, now.Sub(times[min(max(chunk, 0), len(times)-1)]))
+			}
+			mu.Unlock()
+			client.print(os.Stdout, n, clientLine(result.GetStartTime().AsDuration(), result.GetEndTime().AsDuration(), text))
+		}
+	}
+	session.elapsed = time.Since(start)
+	if err := <-sent; err != nil {
+		return session, err
+	}
+
+	// Return success
+	return session, nil
+}
+
+// Upload the file to the /transcribe endpoint, and print the segments of the
+// transcript
+func (client *client) upload(ctx gocontext.Context, n int) (clientSession, error) {
+	var session clientSession
+	data := client.data
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+	if client.language != "" {
+		mw.WriteField("language", client.language)
+	}
+	if part, err := mw.CreateFormFile("file", filepath.Base(client.file)); err != nil {
+		return session, err
+	} else if _, err := part.Write(data); err != nil {
+		return session, err
+	}
+	if err := mw.Close(); err != nil {
+		return session, err
+	}
+
+	// The model is selected by the path
+	u := *client.url
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/transcribe"
+	}
+	if client.model != "" {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + url.PathEscape(client.model)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), body)
+	if err != nil {
+		return session, err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	if client.token != "" {
+		req.Header.Set("Authorization", bearerPrefix+client.token)
+	}
+	httpclient := http.DefaultClient
+	if client.insecure {
+		httpclient = &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	}
+
+	// Send the request and decode the transcript
+	start := time.Now()
+	resp, err := httpclient.Do(req)
+	if err != nil {
+		return session, err
+	}
+	defer resp.Body.Close()
+	session.id = resp.Header.Get("X-Request-Id")
+	if resp.StatusCode != http.StatusOK {
+		var response api.ResponseError
+		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil || response.Error == "" {
+			return session, fmt.Errorf("%s: %s", u.String(), resp.Status)
+		}
+		return session, fmt.Errorf("%s: %s", resp.Status, response.Error)
+	}
+	var transcript api.Transcript
+	if err := json.NewDecoder(resp.Body).Decode(&transcript); err != nil {
+		return session, err
+	}
+	session.elapsed = time.Since(start)
+	if len(transcript.Segments) > 0 {
+		session.first = session.elapsed
+		session.latencies = []time.Duration{session.elapsed}
+	}
+	session.audio = time.Duration(transcript.Timings.Audio) * time.Millisecond
+	session.finals = len(transcript.Segments)
+	for _, segment := range transcript.Segments {
+		client.print(os.Stdout, n, clientLine(time.Duration(segment.Start)*time.Millisecond, time.Duration(segment.End)*time.Millisecond, segment.Text))
+	}
+
+	// Return success
+	return session, nil
+}
+
+// Print a result, prefixed by the number of the session when there are
+// several, unless results are not printed
+func (client *client) print(w io.Writer, n int, line string) {
+	client.Lock()
+	defer client.Unlock()
+	if client.quiet {
+		return
+	} else if client.prefix {
+		fmt.Fprintf(w, "%d: %s\n", n, line)
+	} else {
+		fmt.Fprintln(w, line)
+	}
+}
+
+// Return the line printed for a final result
+func clientLine(start, end time.Duration, text string) string {
+	return fmt.Sprintf("[%s --> %s] %s", vttTimestamp(start), vttTimestamp(end), strings.TrimSpace(text))
+}
+
+// Sleep for a duration, and return false if the context is cancelled first
+func sleep(ctx gocontext.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -9,6 +9,7 @@ const (
 	Reset     = "\033[0m"
 	RGBPrefix = "\033[38;5;" // followed by RGB values in decimal format separated by colons
 	RGBSuffix = "m"
+	ClearLine = "\r\033[K" // return to the start of the line and clear it
 )
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strings"
+
+	// Package imports
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Mark the words of each segment with a probability below min by wrapping
+// them in brackets, or remove them when drop is true. The segment text is
+// rebuilt from the text tokens, and word timestamps are marked in the same way.
+func markConfidence(context whisper.Context, segments []whisper.Segment, min float32, drop bool) []whisper.Segment {
+	for i, segment := range segments {
+		words := textWords(context, segment.Tokens)
+		if len(words) == 0 {
+			continue
+		}
+		segments[i].Text = strings.Join(markWords(words, min, drop), " ")
+		if len(segment.Words) > 0 {
+			result := make([]whisper.Word, 0, len(segment.Words))
+			for _, word := range segment.Words {
+				if word.P < min {
+					if drop {
+						continue
+					}
+					word.Text = "[" + word.Text + "]"
+				}
+				result = append(result, word)
+			}
+			segments[i].Words = result
+		}
+	}
+	return segments
+}
+
+// Return the text of each word, with words below min wrapped in brackets or
+// removed when drop is true
+func markWords(words []whisper.Word, min float32, drop bool) []string {
+	result := make([]string, 0, len(words))
+	for _, word := range words {
+		switch {
+		case word.P >= min:
+			result = append(result, word.Text)
+		case !drop:
+			result = append(result, "["+word.Text+"]")
+		}
+	}
+	return result
+}
+
+// Group the text tokens into words, where a token with a leading space
+// starts a new word. The probability of a word is the mean probability of
+// its tokens.
+func textWords(context whisper.Context, tokens []whisper.Token) []whisper.Word {
+	var result []whisper.Word
+	var n int
+	for _, token := range tokens {
+		if !context.IsText(token) || token.Text == "" {
+			continue
+		}
+		if len(result) == 0 || strings.HasPrefix(token.Text, " ") {
+			if n > 0 {
+				result[len(result)-1].P /= float32(n)
+			}
+			result = append(result, whisper.Word{Start: token.Start})
+			n = 0
+		}
+		word := &result[len(result)-1]
+		word.Text += token.Text
+		word.P += token.P
+		word.End = token.End
+		n++
+	}
+	if n > 0 {
+		result[len(result)-1].P /= float32(n)
+	}
+	for i := range result {
+		result[i].Text = strings.TrimSpace(result[i].Text)
+	}
+	return result
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	// Package imports
+	yaml "gopkg.in/yaml.v3"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// Prefix of the environment variables which set flags, followed by the
+	// flag name in upper case with dashes replaced by underscores
+	envPrefix = "WHISPER_"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Set the flags which are not on the command line from the environment, and
+// then those which are still not set from the -config file, so that the
+// command line overrides the environment, which overrides the file
+func (flags *Flags) configure() error {
+	set := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	if err := flags.setFromEnv(set); err != nil {
+		return err
+	}
+	if path := flags.Lookup("config").Value.String(); path != "" {
+		return flags.setFromConfig(path, set)
+	}
+
+	// Return success
+	return nil
+}
+
+// Set the flags which are not set from their environment variables. The
+// values of repeated flags are separated by commas.
+func (flags *Flags) setFromEnv(set map[string]bool) error {
+	var result error
+	flags.VisitAll(func(f *flag.Flag) {
+		// The auth tokens are read from the environment with the flag
+		if set[f.Name] || f.Name == "auth-token" || result != nil {
+			return
+		}
+		name := envName(f.Name)
+		value, exists := os.LookupEnv(name)
+		if !exists {
+			return
+		}
+		values := []string{value}
+		if _, ok := f.Value.(*stringList); ok {
+			values = strings.Split(value, ",")
+		}
+		for _, value := range values {
+			if err := f.Value.Set(strings.TrimSpace(value)); err != nil {
+				result = fmt.Errorf("%s: %w", name, err)
+				return
+			}
+		}
+		set[f.Name] = true
+	})
+	return result
+}
+
+// Set the flags which are not set from a YAML or JSON file, whose keys are
+// the flag names. Repeated flags are set from lists.
+func (flags *Flags) setFromConfig(path string, set map[string]bool) error {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml", ".json":
+	default:
+		return fmt.Errorf("%s: unsupported config format %q, use YAML or JSON", path, ext)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var config map[string]any
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	// Set the flags in name order, so that errors are reported consistently
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		f := flags.Lookup(key)
+		if f == nil || key == "config" {
+			return fmt.Errorf("%s: unknown flag %q", path, key)
+		} else if set[key] {
+			continue
+		}
+		var values []any
+		switch value := config[key].(type) {
+		case []any:
+			if _, ok := f.Value.(*stringList); !ok {
+				return fmt.Errorf("%s: %s: flag cannot be repeated", path, key)
+			}
+			values = value
+		case map[string]any:
+			return fmt.Errorf("%s: %s: unexpected mapping", path, key)
+		case nil:
+			values = []any{""}
+		default:
+			values = []any{value}
+		}
+		for _, value := range values {
+			if err := f.Value.Set(fmt.Sprint(value)); err != nil {
+				return fmt.Errorf("%s: %s: %w", path, key, err)
+			}
+		}
+	}
+
+	// Return success
+	return nil
+}
+
+// Return the environment variable for a flag
+func envName(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	// Package imports
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+// Header row of CSV and TSV output
+var csvHeader = []string{"start_ms", "end_ms", "speaker", "text", "avg_logprob", "language"}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Output segments as comma or tab separated values, with a header row. The
+// speaker column holds the labels of each segment, and avg_logprob is the
+// mean log probability of the text tokens. The language column holds the
+// language each segment was decoded as.
+func OutputCSV(w io.Writer, context whisper.Context, segments []whisper.Segment, labels [][]string, comma rune) error {
+	enc := csv.NewWriter(w)
+	enc.Comma = comma
+	if err := enc.Write(csvHeader); err != nil {
+		return err
+	}
+	for i, segment := range segments {
+		var speaker string
+		if i < len(labels) {
+			speaker = strings.Join(labels[i], " ")
+		}
+		if err := enc.Write([]string{
+			fmt.Sprint(segment.Start.Milliseconds()),
+			fmt.Sprint(segment.End.Milliseconds()),
+			speaker,
+			segment.Text,
+			fmt.Sprintf("%.4f", avgLogprob(context, segment)),
+			segment.Language,
+		}); err != nil {
+			return err
+		}
+	}
+	enc.Flush()
+	return enc.Error()
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Return the mean log probability of the text tokens of a segment, or zero
+// if there are none
+func avgLogprob(context whisper.Context, segment whisper.Segment) float32 {
+	var sum float32
+	var n int
+	for _, token := range segment.Tokens {
+		if context.IsText(token) {
+			sum += token.Plog
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float32(n)
+}
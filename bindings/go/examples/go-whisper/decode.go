@@ -0,0 +1,405 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	// Package imports
+	g711 "github.com/ggerganov/whisper.cpp/bindings/go/pkg/g711"
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	audio "github.com/go-audio/audio"
+	wav "github.com/go-audio/wav"
+	mp3 "github.com/hajimehoshi/go-mp3"
+	flac "github.com/mewkiz/flac"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// BlockReader returns the next block of decoded samples of each channel, at
+// the sample rate of the file, or io.EOF after the last block
+type BlockReader func() ([][]float32, error)
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+var (
+	magicWAV  = []byte("RIFF")
+	magicFLAC = []byte("fLaC")
+	magicID3  = []byte("ID3")
+)
+
+var (
+	errUnsupportedFormat = errors.New("unsupported audio format")
+)
+
+const (
+	// Number of samples of each channel decoded at once by a BlockReader
+	blockSamples = 64 * 1024
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Decode a WAV, FLAC or MP3 file to mono samples at the whisper sample
+// rate. Multi-channel audio is averaged, unless the -channel flag selects
+// a single channel.
+func Decode(r io.ReadSeeker, flags *Flags) ([]float32, error) {
+	channels, err := DecodeChannels(r, flags)
+	if err != nil {
+		return nil, err
+	}
+	return mixChannels(channels, flags.GetChannel())
+}
+
+// DecodeChannels decodes a WAV, FLAC or MP3 file - load the full buffer, and
+// resample each channel to the whisper sample rate if necessary. The format
+// is detected from the start of the file, unless the -raw flag declares raw
+// audio. Other formats are transcoded with ffmpeg when the -use-ffmpeg flag
+// is set.
+func DecodeChannels(r io.ReadSeeker, flags *Flags) ([][]float32, error) {
+	format, err := inputFormat(r, flags)
+	if err != nil {
+		return nil, err
+	}
+	var channels [][]float32
+	var rate int
+	switch format {
+	case "ffmpeg":
+		channels, err = decodeFFmpeg(r, flags)
+		rate = whisper.SampleRate
+	case "raw":
+		channels, err = decodeRaw(r, flags.GetRawEncoding(), flags.GetRawChannels())
+		rate = flags.GetRawRate()
+	case "flac":
+		channels, rate, err = decodeFLAC(r)
+	case "mp3":
+		channels, rate, err = decodeMP3(r)
+	default:
+		channels, rate, err = decodeWAV(r)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Resample to the whisper sample rate
+	if rate != whisper.SampleRate {
+		resampler, err := flags.GetResampler()
+		if err != nil {
+			return nil, err
+		}
+		flags.Logger().Debug("resampling", "from", rate, "to", whisper.SampleRate)
+		for i := range channels {
+			channels[i] = resampler(channels[i], rate, whisper.SampleRate)
+		}
+	}
+
+	// Return success
+	return channels, nil
+}
+
+// DecodeBlocks decodes a WAV, FLAC or MP3 file, or raw audio, a block at a
+// time rather than loading the full buffer, so that long recordings are
+// decoded in bounded memory. Returns the block reader and the sample rate of
+// the file. The samples are not resampled.
+func DecodeBlocks(r io.ReadSeeker, flags *Flags) (BlockReader, int, error) {
+	format, err := inputFormat(r, flags)
+	if err != nil {
+		return nil, 0, err
+	}
+	switch format {
+	case "ffmpeg":
+		return decodeFFmpegBlocks(r, flags)
+	case "raw":
+		blocks, err := decodeRawBlocks(r, flags.GetRawEncoding(), flags.GetRawChannels())
+		return blocks, flags.GetRawRate(), err
+	case "flac":
+		return decodeFLACBlocks(r)
+	case "mp3":
+		return decodeMP3Blocks(r)
+	default:
+		return decodeWAVBlocks(r)
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Return the format of the input: raw when the -raw flag declares raw audio,
+// otherwise the format detected from the start of the file, or ffmpeg when
+// the format is not supported and the -use-ffmpeg flag is set
+func inputFormat(r io.ReadSeeker, flags *Flags) (string, error) {
+	if flags.GetRawEncoding() != "" {
+		return "raw", nil
+	}
+	format, err := detectFormat(r)
+	if errors.Is(err, errUnsupportedFormat) {
+		if flags.IsUseFFmpeg() {
+			return "ffmpeg", nil
+		}
+		return "", fmt.Errorf("%w, use -use-ffmpeg to transcode it with ffmpeg", err)
+	}
+	return format, err
+}
+
+// Return the format of the file from its first bytes, and rewind
+func detectFormat(r io.ReadSeeker) (string, error) {
+	header := make([]byte, 4)
+	n, err := io.ReadFull(r, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	header = header[:n]
+	switch {
+	case bytes.HasPrefix(header, magicWAV):
+		return "wav", nil
+	case bytes.HasPrefix(header, magicFLAC):
+		return "flac", nil
+	case bytes.HasPrefix(header, magicID3):
+		return "mp3", nil
+	case len(header) >= 2 && header[0] == 0xFF && header[1]&0xE0 == 0xE0:
+		// MPEG audio frame sync
+		return "mp3", nil
+	default:
+		return "", errUnsupportedFormat
+	}
+}
+
+// Decode raw interleaved samples
+func decodeRaw(r io.Reader, encoding string, channels int) ([][]float32, error) {
+	if channels < 1 {
+		return nil, fmt.Errorf("invalid number of channels: %d", channels)
+	}
+	buf, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var data []float32
+	switch encoding {
+	case "s16le":
+		data = make([]float32, len(buf)/2)
+		for i := range data {
+			data[i] = float32(int16(binary.LittleEndian.Uint16(buf[i*2:]))) / math.MaxInt16
+		}
+	case "f32le":
+		data = make([]float32, len(buf)/4)
+		for i := range data {
+			data[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[i*4:]))
+		}
+	case "mulaw":
+		data = g711.PCMUToFloat32(buf)
+	case "alaw":
+		data = g711.PCMAToFloat32(buf)
+	default:
+		return nil, fmt.Errorf("unsupported raw encoding: %q", encoding)
+	}
+
+	// Return success
+	return deinterleave(data, channels), nil
+}
+
+// Return a block reader for raw interleaved samples
+func decodeRawBlocks(r io.Reader, encoding string, channels int) (BlockReader, error) {
+	var size int
+	switch encoding {
+	case "s16le":
+		size = 2
+	case "f32le":
+		size = 4
+	case "mulaw", "alaw":
+		size = 1
+	default:
+		return nil, fmt.Errorf("unsupported raw encoding: %q", encoding)
+	}
+	if channels < 1 {
+		return nil, fmt.Errorf("invalid number of channels: %d", channels)
+	}
+	buf := make([]byte, blockSamples*channels*size)
+	return func() ([][]float32, error) {
+		n, err := io.ReadFull(r, buf)
+		if n -= n % (channels * size); n == 0 {
+			if err == nil || err == io.ErrUnexpectedEOF {
+				err = io.EOF
+			}
+			return nil, err
+		} else if err != nil && err != io.ErrUnexpectedEOF {
+			return nil, err
+		}
+		return decodeRaw(bytes.NewReader(buf[:n]), encoding, channels)
+	}, nil
+}
+
+func decodeWAV(r io.ReadSeeker) ([][]float32, int, error) {
+	dec := wav.NewDecoder(r)
+	if buf, err := dec.FullPCMBuffer(); err != nil {
+		return nil, 0, err
+	} else if dec.NumChans < 1 {
+		return nil, 0, fmt.Errorf("invalid number of channels: %d", dec.NumChans)
+	} else {
+		return deinterleave(buf.AsFloat32Buffer().Data, int(dec.NumChans)), int(dec.SampleRate), nil
+	}
+}
+
+// Return a block reader for a WAV file
+func decodeWAVBlocks(r io.ReadSeeker) (BlockReader, int, error) {
+	dec := wav.NewDecoder(r)
+	if err := dec.FwdToPCM(); err != nil {
+		return nil, 0, err
+	} else if err := dec.Err(); err != nil {
+		return nil, 0, err
+	} else if dec.NumChans < 1 {
+		return nil, 0, fmt.Errorf("invalid number of channels: %d", dec.NumChans)
+	}
+	channels := int(dec.NumChans)
+	buf := &audio.IntBuffer{Data: make([]int, blockSamples*channels)}
+	return func() ([][]float32, error) {
+		buf.Data = buf.Data[:cap(buf.Data)]
+		n, err := dec.PCMBuffer(buf)
+		if n -= n % channels; n == 0 {
+			if err == nil {
+				err = io.EOF
+			}
+			return nil, err
+		} else if err != nil {
+			return nil, err
+		}
+		buf.Data = buf.Data[:n]
+		return deinterleave(buf.AsFloat32Buffer().Data, channels), nil
+	}, int(dec.SampleRate), nil
+}
+
+func decodeFLAC(r io.Reader) ([][]float32, int, error) {
+	stream, err := flac.New(bufio.NewReader(r))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer stream.Close()
+
+	// Scale samples to [-1, 1)
+	scale := float32(int64(1) << (stream.Info.BitsPerSample - 1))
+	channels := make([][]float32, stream.Info.NChannels)
+	for i := range channels {
+		channels[i] = make([]float32, 0, stream.Info.NSamples)
+	}
+	for {
+		frame, err := stream.ParseNext()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, 0, err
+		}
+		for i, subframe := range frame.Subframes {
+			for _, v := range subframe.Samples {
+				channels[i] = append(channels[i], float32(v)/scale)
+			}
+		}
+	}
+
+	// Return success
+	return channels, int(stream.Info.SampleRate), nil
+}
+
+// Return a block reader for a FLAC file, which returns a block per frame
+func decodeFLACBlocks(r io.Reader) (BlockReader, int, error) {
+	stream, err := flac.New(bufio.NewReader(r))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Scale samples to [-1, 1)
+	scale := float32(int64(1) << (stream.Info.BitsPerSample - 1))
+	return func() ([][]float32, error) {
+		frame, err := stream.ParseNext()
+		if err != nil {
+			return nil, err
+		}
+		channels := make([][]float32, len(frame.Subframes))
+		for i, subframe := range frame.Subframes {
+			channels[i] = make([]float32, len(subframe.Samples))
+			for j, v := range subframe.Samples {
+				channels[i][j] = float32(v) / scale
+			}
+		}
+		return channels, nil
+	}, int(stream.Info.SampleRate), nil
+}
+
+// Return a block reader for an MP3 file, which is decoded to 16-bit stereo
+// samples as with decodeMP3
+func decodeMP3Blocks(r io.Reader) (BlockReader, int, error) {
+	dec, err := mp3.NewDecoder(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	blocks, err := decodeRawBlocks(dec, "s16le", 2)
+	if err != nil {
+		return nil, 0, err
+	}
+	return blocks, dec.SampleRate(), nil
+}
+
+// Decode an MP3 file. The decoder always returns 16-bit stereo samples, with
+// mono files duplicated into both channels.
+func decodeMP3(r io.Reader) ([][]float32, int, error) {
+	dec, err := mp3.NewDecoder(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	buf, err := io.ReadAll(dec)
+	if err != nil {
+		return nil, 0, err
+	}
+	channels, err := decodeRaw(bytes.NewReader(buf), "s16le", 2)
+	if err != nil {
+		return nil, 0, err
+	}
+	return channels, dec.SampleRate(), nil
+}
+
+// Split interleaved samples into channels
+func deinterleave(data []float32, channels int) [][]float32 {
+	result := make([][]float32, channels)
+	if channels == 1 {
+		result[0] = data
+		return result
+	}
+	n := len(data) / channels
+	for ch := range result {
+		result[ch] = make([]float32, n)
+		for i := range result[ch] {
+			result[ch][i] = data[i*channels+ch]
+		}
+	}
+	return result
+}
+
+// Return a single channel, or the average of all channels when channel is
+// negative
+func mixChannels(channels [][]float32, channel int) ([]float32, error) {
+	switch {
+	case channel >= len(channels):
+		return nil, fmt.Errorf("channel %d out of range, audio has %d channels", channel, len(channels))
+	case channel >= 0:
+		return channels[channel], nil
+	case len(channels) == 1:
+		return channels[0], nil
+	}
+	result := make([]float32, len(channels[0]))
+	for _, data := range channels {
+		for i, v := range data[:len(result)] {
+			result[i] += v
+		}
+	}
+	for i := range result {
+		result[i] /= float32(len(channels))
+	}
+	return result, nil
+}
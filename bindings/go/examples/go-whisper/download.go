@@ -0,0 +1,41 @@
+package main
+
+import (
+	gocontext "context"
+	"os"
+
+	// Package imports
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// DownloadModels downloads each model given with the -download-model flag to
+// the -model-dir directory, logging the progress every ten percent
+func DownloadModels(ctx gocontext.Context, flags *Flags) error {
+	dir := flags.GetModelDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, name := range flags.GetDownloadModels() {
+		flags.Logger().Info("downloading model", "model", name, "dir", dir)
+		var pct int64
+		path, err := whisper.DownloadWithProgress(ctx, name, dir, func(count, total int64) {
+			if total <= 0 {
+				return
+			}
+			if n := count * 100 / total; n >= pct+10 {
+				pct = n - n%10
+				flags.Logger().Info("downloading model", "model", name, "progress", pct, "mb", count>>20)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		flags.Logger().Info("downloaded model", "model", name, "path", path)
+	}
+
+	// Return success
+	return nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	// Package imports
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Transcode a file which is not WAV, FLAC or MP3 with ffmpeg, to samples of
+// each channel at the whisper sample rate
+func decodeFFmpeg(r io.ReadSeeker, flags *Flags) ([][]float32, error) {
+	cmd, stderr := ffmpegCommand(r, flags)
+	data, err := cmd.Output()
+	if err != nil {
+		return nil, ffmpegError(err, stderr)
+	}
+	pcm := bytes.NewReader(data)
+	channels, err := ffmpegChannels(pcm)
+	if err != nil {
+		return nil, err
+	}
+	return decodeRaw(pcm, "f32le", channels)
+}
+
+// Return a block reader for a file transcoded with ffmpeg, which returns
+// blocks at the whisper sample rate as they are transcoded
+func decodeFFmpegBlocks(r io.ReadSeeker, flags *Flags) (BlockReader, int, error) {
+	cmd, stderr := ffmpegCommand(r, flags)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, 0, ffmpegError(err, stderr)
+	}
+
+	// Stop ffmpeg when the transcoded audio cannot be read, and return the
+	// error of ffmpeg when it failed
+	stop := func(err error) error {
+		cmd.Process.Kill()
+		if exit := cmd.Wait(); exit != nil && stderr.Len() > 0 {
+			return ffmpegError(exit, stderr)
+		}
+		return err
+	}
+	pcm := bufio.NewReader(stdout)
+	channels, err := ffmpegChannels(pcm)
+	if err != nil {
+		return nil, 0, stop(err)
+	}
+	blocks, err := decodeRawBlocks(pcm, "f32le", channels)
+	if err != nil {
+		return nil, 0, stop(err)
+	}
+
+	// Wait for ffmpeg to exit after the last block
+	return func() ([][]float32, error) {
+		block, err := blocks()
+		if err == io.EOF {
+			if err := cmd.Wait(); err != nil {
+				return nil, ffmpegError(err, stderr)
+			}
+		} else if err != nil {
+			return nil, stop(err)
+		}
+		return block, err
+	}, whisper.SampleRate, nil
+}
+
+// Return the ffmpeg command which transcodes the first audio stream of the
+// file to 32-bit float WAV at the whisper sample rate on standard output.
+// Regular files are opened by ffmpeg, so that formats which cannot be read
+// from a pipe, such as MP4 with the index at the end, are transcoded. Other
+// inputs are piped.
+func ffmpegCommand(r io.ReadSeeker, flags *Flags) (*exec.Cmd, *bytes.Buffer) {
+	input := "pipe:0"
+	if fh, ok := r.(*os.File); ok {
+		if info, err := fh.Stat(); err == nil && info.Mode().IsRegular() {
+			input = fh.Name()
+		}
+	}
+	cmd := exec.Command(flags.GetFFmpeg(),
+		"-nostdin", "-hide_banner", "-loglevel", "error",
+		"-i", input, "-map", "0:a:0", "-map_metadata", "-1",
+		"-c:a", "pcm_f32le", "-ar", strconv.Itoa(whisper.SampleRate), "-f", "wav", "pipe:1",
+	)
+	if input == "pipe:0" {
+		cmd.Stdin = r
+	}
+	stderr := new(bytes.Buffer)
+	cmd.Stderr = stderr
+	flags.Logger().Debug("transcoding", "command", cmd.String())
+	return cmd, stderr
+}
+
+// Read the WAV header written by ffmpeg up to the start of the samples, and
+// return the number of channels. The sizes in the header are not used, as
+// they are not known when ffmpeg writes to a pipe.
+func ffmpegChannels(r io.Reader) (int, error) {
+	var header [12]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, fmt.Errorf("ffmpeg: no audio: %w", err)
+	} else if !bytes.HasPrefix(header[:], magicWAV) || string(header[8:]) != "WAVE" {
+		return 0, fmt.Errorf("ffmpeg: unexpected output")
+	}
+	channels := 0
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			return 0, fmt.Errorf("ffmpeg: %w", err)
+		}
+		size := int64(binary.LittleEndian.Uint32(chunk[4:]))
+		switch string(chunk[:4]) {
+		case "fmt ":
+			format := make([]byte, size+size&1)
+			if _, err := io.ReadFull(r, format); err != nil {
+				return 0, fmt.Errorf("ffmpeg: %w", err)
+			} else if len(format) < 4 {
+				return 0, fmt.Errorf("ffmpeg: unexpected output")
+			}
+			channels = int(binary.LittleEndian.Uint16(format[2:]))
+		case "data":
+			if channels == 0 {
+				return 0, fmt.Errorf("ffmpeg: unexpected output")
+			}
+			return channels, nil
+		default:
+			if _, err := io.CopyN(io.Discard, r, size+size&1); err != nil {
+				return 0, fmt.Errorf("ffmpeg: %w", err)
+			}
+		}
+	}
+}
+
+// Return the error of the ffmpeg command, with its last line of output
+func ffmpegError(err error, stderr *bytes.Buffer) error {
+	lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+	if line := strings.TrimSpace(lines[len(lines)-1]); line != "" {
+		return fmt.Errorf("ffmpeg: %w: %s", err, line)
+	}
+	return fmt.Errorf("ffmpeg: %w", err)
+}
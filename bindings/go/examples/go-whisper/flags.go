@@ -3,10 +3,20 @@ package main
 import (
 	"flag"
 	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
 	"strings"
+	"text/template"
 	"time"
 
 	// Packages
+	postprocess "github.com/ggerganov/whisper.cpp/bindings/go/pkg/postprocess"
+	resample "github.com/ggerganov/whisper.cpp/bindings/go/pkg/resample"
+	speech "github.com/ggerganov/whisper.cpp/bindings/go/pkg/server/grpc"
+	vad "github.com/ggerganov/whisper.cpp/bindings/go/pkg/vad"
 	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
 )
 
@@ -15,8 +25,12 @@ import (
 
 type Flags struct {
 	*flag.FlagSet
+	logger *slog.Logger
 }
 
+// stringList is a flag which can be repeated
+type stringList []string
+
 ///////////////////////////////////////////////////////////////////////////////
 // LIFECYCLE
 
@@ -33,6 +47,18 @@ func NewFlags(name string, args []string) (*Flags, error) {
 		return nil, err
 	}
 
+	// Set the other flags from the environment and the -config file
+	if err := flags.configure(); err != nil {
+		return nil, err
+	}
+
+	// Create the logger
+	if logger, err := NewLogger(flags.Output(), flags.Lookup("log-level").Value.String(), flags.Lookup("log-json").Value.String() == "true"); err != nil {
+		return nil, err
+	} else {
+		flags.logger = logger
+	}
+
 	// Return success
 	return flags, nil
 }
@@ -40,8 +66,61 @@ func NewFlags(name string, args []string) (*Flags, error) {
 ///////////////////////////////////////////////////////////////////////////////
 // PUBLIC METHODS
 
+// Logger returns the structured logger
+func (flags *Flags) Logger() *slog.Logger {
+	return flags.logger
+}
+
+// SetLogger replaces the structured logger
+func (flags *Flags) SetLogger(logger *slog.Logger) {
+	flags.logger = logger
+}
+
+// GetModel returns the first model path
 func (flags *Flags) GetModel() string {
-	return flags.Lookup("model").Value.String()
+	if models := flags.GetModels(); len(models) > 0 {
+		return models[0]
+	}
+	return ""
+}
+
+// GetModels returns all the model paths
+func (flags *Flags) GetModels() []string {
+	var result []string
+	for _, str := range *flags.Lookup("model").Value.(*stringList) {
+		_, path := splitModel(str)
+		result = append(result, path)
+	}
+	return result
+}
+
+// GetModelName returns the name of the first model
+func (flags *Flags) GetModelName() string {
+	if names := flags.GetModelNames(); len(names) > 0 {
+		return names[0]
+	}
+	return ""
+}
+
+// GetModelNames returns the name of each model, which is set as name=path,
+// or is otherwise derived from the path
+func (flags *Flags) GetModelNames() []string {
+	var result []string
+	for _, str := range *flags.Lookup("model").Value.(*stringList) {
+		name, _ := splitModel(str)
+		result = append(result, name)
+	}
+	return result
+}
+
+// GetDownloadModels returns the names of the models to download
+func (flags *Flags) GetDownloadModels() []string {
+	return *flags.Lookup("download-model").Value.(*stringList)
+}
+
+// GetModelDir returns the directory models are downloaded to
+func (flags *Flags) GetModelDir() string {
+	return flags.Lookup("model-dir").Value.String()
 }
 
 func (flags *Flags) GetLanguage() string {
@@ -52,14 +131,30 @@ func (flags *Flags) IsTranslate() bool {
 	return flags.Lookup("translate").Value.(flag.Getter).Get().(bool)
 }
 
+// IsProgress returns true if the progress of each file is logged, rather
+// than drawn as a bar on a terminal
+func (flags *Flags) IsProgress() bool {
+	return flags.Lookup("progress").Value.String() == "true"
+}
+
+// GetChunkSize returns the duration of the chunks each file is decoded and
+// transcribed in, or zero to decode the whole file first
+func (flags *Flags) GetChunkSize() time.Duration {
+	return flags.Lookup("chunk-size").Value.(flag.Getter).Get().(time.Duration)
+}
+
+// GetOffset returns the offset into each file at which transcription starts
 func (flags *Flags) GetOffset() time.Duration {
 	return flags.Lookup("offset").Value.(flag.Getter).Get().(time.Duration)
 }
 
+// GetDuration returns the duration of each file transcribed from the offset,
+// or zero for the rest of the file
 func (flags *Flags) GetDuration() time.Duration {
 	return flags.Lookup("duration").Value.(flag.Getter).Get().(time.Duration)
 }
 
+// GetThreads returns the number of threads used by each transcription
 func (flags *Flags) GetThreads() uint {
 	return flags.Lookup("threads").Value.(flag.Getter).Get().(uint)
 }
@@ -76,10 +171,22 @@ func (flags *Flags) IsTokens() bool {
 	return flags.Lookup("tokens").Value.String() == "true"
 }
 
+func (flags *Flags) IsWordTimestamps() bool {
+	return flags.Lookup("word-timestamps").Value.String() == "true"
+}
+
+func (flags *Flags) IsDiarize() bool {
+	return flags.Lookup("diarize").Value.String() == "true"
+}
+
 func (flags *Flags) IsColorize() bool {
 	return flags.Lookup("colorize").Value.String() == "true"
 }
 
+func (flags *Flags) IsSplitOnWord() bool {
+	return flags.Lookup("split-on-word").Value.String() == "true"
+}
+
 func (flags *Flags) GetMaxLen() uint {
 	return flags.Lookup("max-len").Value.(flag.Getter).Get().(uint)
 }
@@ -92,43 +199,548 @@ func (flags *Flags) GetWordThreshold() float32 {
 	return float32(flags.Lookup("word-thold").Value.(flag.Getter).Get().(float64))
 }
 
+func (flags *Flags) GetListen() string {
+	return flags.Lookup("listen").Value.String()
+}
+
+func (flags *Flags) GetGRPC() string {
+	return flags.Lookup("grpc").Value.String()
+}
+
+func (flags *Flags) GetListenRTP() string {
+	return flags.Lookup("listen-rtp").Value.String()
+}
+
+// IsMic returns true when audio is captured from an input device, with
+// -mic, -mic-device or -loopback
+func (flags *Flags) IsMic() bool {
+	return flags.Lookup("mic").Value.String() == "true" || flags.GetMicDevice() != "" || flags.IsLoopback()
+}
+
+// IsTUI returns true when the transcript of the microphone is shown in a
+// terminal UI
+func (flags *Flags) IsTUI() bool {
+	return flags.Lookup("tui").Value.String() == "true"
+}
+
+func (flags *Flags) GetMicDevice() string {
+	return flags.Lookup("mic-device").Value.String()
+}
+
+func (flags *Flags) IsLoopback() bool {
+	return flags.Lookup("loopback").Value.String() == "true"
+}
+
+func (flags *Flags) IsListDevices() bool {
+	return flags.Lookup("list-devices").Value.String() == "true"
+}
+
+func (flags *Flags) GetListenAudioSocket() string {
+	return flags.Lookup("listen-audiosocket").Value.String()
+}
+
+func (flags *Flags) GetListenMRCP() string {
+	return flags.Lookup("listen-mrcp").Value.String()
+}
+
+func (flags *Flags) GetSinks() []string {
+	return *flags.Lookup("sink").Value.(*stringList)
+}
+
+func (flags *Flags) GetWebhookURL() string {
+	return flags.Lookup("webhook-url").Value.String()
+}
+
+func (flags *Flags) IsWebhookSegments() bool {
+	return flags.Lookup("webhook-segments").Value.String() == "true"
+}
+
+func (flags *Flags) GetWebhookRetries() uint {
+	return flags.Lookup("webhook-retries").Value.(flag.Getter).Get().(uint)
+}
+
+// GetRTPJitter returns the amount of audio held in the jitter buffer while
+// waiting for a missing packet
+func (flags *Flags) GetRTPJitter() time.Duration {
+	return flags.Lookup("rtp-jitter").Value.(flag.Getter).Get().(time.Duration)
+}
+
+func (flags *Flags) GetRTPOpusPayloadType() uint {
+	return flags.Lookup("rtp-opus-pt").Value.(flag.Getter).Get().(uint)
+}
+
+func (flags *Flags) GetTLSCert() string {
+	return flags.Lookup("tls-cert").Value.String()
+}
+
+func (flags *Flags) GetTLSKey() string {
+	return flags.Lookup("tls-key").Value.String()
+}
+
+func (flags *Flags) IsTLSSelfSigned() bool {
+	return flags.Lookup("tls-self-signed").Value.String() == "true"
+}
+
+// GetPostprocess returns the filters applied to the segments before they
+// are output
+func (flags *Flags) GetPostprocess() (postprocess.Filter, error) {
+	filters := []postprocess.Filter{postprocess.TrimSpace}
+	if minGap := flags.Lookup("merge-short").Value.(flag.Getter).Get().(time.Duration); minGap > 0 {
+		filters = append(filters, postprocess.MergeShort(minGap))
+	}
+	if flags.Lookup("profanity").Value.String() == "true" {
+		filters = append(filters, postprocess.Profanity())
+	}
+	if flags.Lookup("redact").Value.String() == "true" {
+		filters = append(filters, postprocess.PII())
+	}
+	for _, expr := range *flags.Lookup("redact-regex").Value.(*stringList) {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("-redact-regex: %w", err)
+		}
+		filters = append(filters, postprocess.Redact(re, ""))
+	}
+	return postprocess.Chain(filters...), nil
+}
+
+// GetRecordDir returns the directory the audio of each streaming session is
+// recorded to, or an empty string if audio is not recorded
+func (flags *Flags) GetRecordDir() string {
+	return flags.Lookup("record-dir").Value.String()
+}
+
+// GetKeepalive returns the interval at which idle gRPC connections are
+// pinged, or zero for the gRPC default
+func (flags *Flags) GetKeepalive() time.Duration {
+	return flags.Lookup("keepalive").Value.(flag.Getter).Get().(time.Duration)
+}
+
+// GetIdleTimeout returns the time without audio after which a gRPC or RTP
+// stream is ended, or zero for no timeout
+func (flags *Flags) GetIdleTimeout() time.Duration {
+	return flags.Lookup("idle-timeout").Value.(flag.Getter).Get().(time.Duration)
+}
+
+// GetAuthTokens returns the API tokens accepted by the servers, from the
+// -auth-token flag and the WHISPER_AUTH_TOKEN environment variable
+func (flags *Flags) GetAuthTokens() []string {
+	result := append([]string(nil), *flags.Lookup("auth-token").Value.(*stringList)...)
+	for _, token := range strings.Split(os.Getenv(envAuthToken), ",") {
+		if token = strings.TrimSpace(token); token != "" {
+			result = append(result, token)
+		}
+	}
+	return result
+}
+
+// GetCORSOrigins returns the origins of the browser apps which can call the
+// HTTP endpoints
+func (flags *Flags) GetCORSOrigins() []string {
+	return *flags.Lookup("cors-origin").Value.(*stringList)
+}
+
+func (flags *Flags) GetShutdownTimeout() time.Duration {
+	return flags.Lookup("shutdown-timeout").Value.(flag.Getter).Get().(time.Duration)
+}
+
+func (flags *Flags) GetPoolSize() uint {
+	return flags.Lookup("pool-size").Value.(flag.Getter).Get().(uint)
+}
+
+func (flags *Flags) GetMaxAudio() time.Duration {
+	return flags.Lookup("max-audio").Value.(flag.Getter).Get().(time.Duration)
+}
+
+func (flags *Flags) GetMaxSessionTime() time.Duration {
+	return flags.Lookup("max-session-time").Value.(flag.Getter).Get().(time.Duration)
+}
+
+func (flags *Flags) GetMaxBytes() uint64 {
+	return flags.Lookup("max-bytes").Value.(flag.Getter).Get().(uint64)
+}
+
+func (flags *Flags) GetMaxSessions() uint {
+	return flags.Lookup("max-sessions").Value.(flag.Getter).Get().(uint)
+}
+
+func (flags *Flags) GetSessionQueue() uint {
+	return flags.Lookup("session-queue").Value.(flag.Getter).Get().(uint)
+}
+
+func (flags *Flags) GetSessionQueueTimeout() time.Duration {
+	return flags.Lookup("session-queue-timeout").Value.(flag.Getter).Get().(time.Duration)
+}
+
+// IsSharedModel returns true if the transcriptions of a pool share one copy
+// of the model
+func (flags *Flags) IsSharedModel() bool {
+	return flags.Lookup("shared-model").Value.String() == "true"
+}
+
+func (flags *Flags) IsRecursive() bool {
+	return flags.Lookup("recursive").Value.String() == "true"
+}
+
+// IsConcatOutput returns true when the inputs are transcribed as one
+// continuous recording, with one output
+func (flags *Flags) IsConcatOutput() bool {
+	return flags.Lookup("concat-output").Value.String() == "true"
+}
+
+// GetOutputDir returns the directory results are written to, or an empty
+// string to write the results to standard output
+func (flags *Flags) GetOutputDir() string {
+	return flags.Lookup("output-dir").Value.String()
+}
+
+// GetOutputTemplate returns the template for the names of output files
+func (flags *Flags) GetOutputTemplate() (*template.Template, error) {
+	return template.New("output-name-template").Parse(flags.Lookup("output-name-template").Value.String())
+}
+
+// GetParallel returns the number of files processed concurrently
+func (flags *Flags) GetParallel() uint {
+	return max(flags.Lookup("parallel").Value.(flag.Getter).Get().(uint), 1)
+}
+
+// GetContextParams returns the parameters for loading the models
+func (flags *Flags) GetContextParams() whisper.ContextParams {
+	params := whisper.DefaultContextParams()
+	params.UseGPU = flags.Lookup("gpu").Value.String() == "true"
+	params.OpenVINODevice = flags.Lookup("openvino-device").Value.String()
+	return params
+}
+
+func (flags *Flags) GetResampler() (resample.Resampler, error) {
+	switch method := strings.ToLower(flags.Lookup("resample").Value.String()); method {
+	case "sinc":
+		return resample.Sinc, nil
+	case "linear":
+		return resample.Linear, nil
+	default:
+		return nil, fmt.Errorf("unsupported resampling method: %q", method)
+	}
+}
+
+func (flags *Flags) GetLagPolicy() (speech.LagPolicy, error) {
+	switch policy := strings.ToLower(flags.Lookup("lag-policy").Value.String()); policy {
+	case "block":
+		return speech.LagBlock, nil
+	case "drop":
+		return speech.LagDrop, nil
+	case "skip":
+		return speech.LagSkip, nil
+	case "notify":
+		return speech.LagNotify, nil
+	default:
+		return 0, fmt.Errorf("unsupported lag policy: %q", policy)
+	}
+}
+
+func (flags *Flags) GetMaxLag() time.Duration {
+	return flags.Lookup("max-lag").Value.(flag.Getter).Get().(time.Duration)
+}
+
+func (flags *Flags) GetTemperature() float32 {
+	return float32(flags.Lookup("temperature").Value.(flag.Getter).Get().(float64))
+}
+
+// GetTemperatureFallback returns the temperature increment on decoding
+// failure, or a negative value to use the default
+func (flags *Flags) GetTemperatureFallback() float32 {
+	return float32(flags.Lookup("temperature-inc").Value.(flag.Getter).Get().(float64))
+}
+
+func (flags *Flags) GetEntropyThreshold() float32 {
+	return float32(flags.Lookup("entropy-thold").Value.(flag.Getter).Get().(float64))
+}
+
+func (flags *Flags) GetLogprobThreshold() float32 {
+	return float32(flags.Lookup("logprob-thold").Value.(flag.Getter).Get().(float64))
+}
+
+func (flags *Flags) GetNoSpeechThreshold() float32 {
+	return float32(flags.Lookup("no-speech-thold").Value.(flag.Getter).Get().(float64))
+}
+
+func (flags *Flags) GetBeamSize() uint {
+	return flags.Lookup("beam-size").Value.(flag.Getter).Get().(uint)
+}
+
+func (flags *Flags) GetBestOf() uint {
+	return flags.Lookup("best-of").Value.(flag.Getter).Get().(uint)
+}
+
+func (flags *Flags) IsSuppressBlank() bool {
+	return flags.Lookup("suppress-blank").Value.String() == "true"
+}
+
+func (flags *Flags) IsSuppressNonSpeech() bool {
+	return flags.Lookup("suppress-nst").Value.String() == "true"
+}
+
+func (flags *Flags) IsNoContext() bool {
+	return flags.Lookup("no-context").Value.String() == "true"
+}
+
+// GetPrompt returns the initial prompt
+func (flags *Flags) GetPrompt() string {
+	return flags.Lookup("prompt").Value.String()
+}
+
+// GetHotwords returns the comma-separated hotwords, and the boost added to
+// the logits of their tokens
+func (flags *Flags) GetHotwords() ([]string, float32) {
+	var words []string
+	for _, word := range strings.Split(flags.Lookup("hotwords").Value.String(), ",") {
+		if word = strings.TrimSpace(word); word != "" {
+			words = append(words, word)
+		}
+	}
+	return words, float32(flags.Lookup("hotword-boost").Value.(flag.Getter).Get().(float64))
+}
+
+// GetAlternatives returns the number of hypotheses of each segment in JSON
+// output, or zero if alternatives are not decoded
+func (flags *Flags) GetAlternatives() uint {
+	return flags.Lookup("alternatives").Value.(flag.Getter).Get().(uint)
+}
+
+// GetConfidenceMin returns the probability below which words are marked as
+// low confidence, or zero if words are not marked
+func (flags *Flags) GetConfidenceMin() float32 {
+	return float32(flags.Lookup("confidence-min").Value.(flag.Getter).Get().(float64))
+}
+
+func (flags *Flags) IsConfidenceDrop() bool {
+	return flags.Lookup("confidence-drop").Value.String() == "true"
+}
+
+// GetRawEncoding returns the encoding of raw input audio, or an empty
+// string if the input is a WAV, FLAC or MP3 file
+func (flags *Flags) GetRawEncoding() string {
+	return strings.ToLower(flags.Lookup("raw").Value.String())
+}
+
+// IsUseFFmpeg returns true when files in other formats than WAV, FLAC and
+// MP3 are transcoded with ffmpeg
+func (flags *Flags) IsUseFFmpeg() bool {
+	return flags.Lookup("use-ffmpeg").Value.String() == "true"
+}
+
+// GetFFmpeg returns the name or path of the ffmpeg command
+func (flags *Flags) GetFFmpeg() string {
+	return flags.Lookup("ffmpeg").Value.String()
+}
+
+// IsUseYtDlp returns true when URLs are downloaded with yt-dlp
+func (flags *Flags) IsUseYtDlp() bool {
+	return flags.Lookup("use-yt-dlp").Value.String() == "true"
+}
+
+// GetYtDlp returns the name or path of the yt-dlp command
+func (flags *Flags) GetYtDlp() string {
+	return flags.Lookup("yt-dlp").Value.String()
+}
+
+// GetURLCache returns the directory audio downloaded from URLs is cached in,
+// which by default is in the user cache directory
+func (flags *Flags) GetURLCache() (string, error) {
+	if dir := flags.Lookup("url-cache").Value.String(); dir != "" {
+		return dir, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("-url-cache: %w", err)
+	}
+	return filepath.Join(dir, "go-whisper", "audio"), nil
+}
+
+func (flags *Flags) GetRawRate() int {
+	return int(flags.Lookup("raw-rate").Value.(flag.Getter).Get().(uint))
+}
+
+func (flags *Flags) GetRawChannels() int {
+	return int(flags.Lookup("raw-channels").Value.(flag.Getter).Get().(uint))
+}
+
+// GetChannel returns the channel to transcribe, or -1 to average all
+// channels
+func (flags *Flags) GetChannel() int {
+	return flags.Lookup("channel").Value.(flag.Getter).Get().(int)
+}
+
+// IsEachChannel returns true if each channel is transcribed separately
+func (flags *Flags) IsEachChannel() bool {
+	return flags.Lookup("each-channel").Value.String() == "true"
+}
+
+// IsSplitChannels returns true if the two channels of a call recording are
+// transcribed in separate contexts and interleaved by time
+func (flags *Flags) IsSplitChannels() bool {
+	return flags.Lookup("split-channels").Value.String() == "true"
+}
+
+func (flags *Flags) IsVAD() bool {
+	return flags.Lookup("vad").Value.String() == "true" || flags.getSilence() != 0
+}
+
+func (flags *Flags) GetVADThreshold() float32 {
+	return float32(flags.Lookup("vad-thold").Value.(flag.Getter).Get().(float64))
+}
+
+// GetVADHangover returns the silence which ends a speech region, which is
+// the -silence-ms when set
+func (flags *Flags) GetVADHangover() time.Duration {
+	if silence := flags.getSilence(); silence != 0 {
+		return silence
+	}
+	return flags.Lookup("vad-hangover").Value.(flag.Getter).Get().(time.Duration)
+}
+
+// GetMaxUtterance returns the maximum length of an utterance when speech
+// regions are transcribed once they end, or zero when they are transcribed
+// each window
+func (flags *Flags) GetMaxUtterance() time.Duration {
+	if flags.getSilence() == 0 {
+		return 0
+	}
+	return time.Duration(flags.Lookup("max-utterance-sec").Value.(flag.Getter).Get().(uint)) * time.Second
+}
+
+func (flags *Flags) getSilence() time.Duration {
+	return time.Duration(flags.Lookup("silence-ms").Value.(flag.Getter).Get().(uint)) * time.Millisecond
+}
+
+func (flags *Flags) GetPartial() time.Duration {
+	return flags.Lookup("partial").Value.(flag.Getter).Get().(time.Duration)
+}
+
+// GetSlidingWindow returns the step, length and keep of the sliding decode
+// window, with a zero step when the sliding window is not used
+func (flags *Flags) GetSlidingWindow() (time.Duration, time.Duration, time.Duration) {
+	ms := func(name string) time.Duration {
+		return time.Duration(flags.Lookup(name).Value.(flag.Getter).Get().(uint)) * time.Millisecond
+	}
+	return ms("step"), ms("length"), ms("keep")
+}
+
+// IsPartial returns true if interim hypotheses are displayed while streaming
+func (flags *Flags) IsPartial() bool {
+	step, _, _ := flags.GetSlidingWindow()
+	return flags.GetPartial() != 0 || step != 0
+}
+
+// IsStream returns true if the audio is fed through a streaming context
+func (flags *Flags) IsStream() bool {
+	return flags.IsVAD() || flags.IsPartial() || flags.GetSimulateStream() != 0
+}
+
+// GetSimulateStream returns the frame size in which audio files are fed
+// through the streaming context in real time, or zero when they are not
+func (flags *Flags) GetSimulateStream() time.Duration {
+	return flags.Lookup("simulate-stream").Value.(flag.Getter).Get().(time.Duration)
+}
+
 func (flags *Flags) SetParams(context whisper.Context) error {
+	logger := flags.Logger()
 	if lang := flags.GetLanguage(); lang != "" && lang != "auto" {
-		fmt.Fprintf(flags.Output(), "Setting language to %q\n", lang)
+		logger.Debug("setting parameter", "name", "language", "value", lang)
 		if err := context.SetLanguage(lang); err != nil {
 			return err
 		}
 	}
 	if flags.IsTranslate() && context.IsMultilingual() {
-		fmt.Fprintf(flags.Output(), "Setting translate to true\n")
+		logger.Debug("setting parameter", "name", "translate", "value", true)
 		context.SetTranslate(true)
-	}
-	if offset := flags.GetOffset(); offset != 0 {
-		fmt.Fprintf(flags.Output(), "Setting offset to %v\n", offset)
-		context.SetOffset(offset)
-	}
-	if duration := flags.GetDuration(); duration != 0 {
-		fmt.Fprintf(flags.Output(), "Setting duration to %v\n", duration)
-		context.SetDuration(duration)
+	} else if flags.IsTranslate() {
+		logger.Warn("ignoring translate, model is not multilingual")
 	}
 	if flags.IsSpeedup() {
-		fmt.Fprintf(flags.Output(), "Setting speedup to true\n")
+		logger.Debug("setting parameter", "name", "speedup", "value", true)
 		context.SetSpeedup(true)
 	}
 	if threads := flags.GetThreads(); threads != 0 {
-		fmt.Fprintf(flags.Output(), "Setting threads to %d\n", threads)
+		logger.Debug("setting parameter", "name", "threads", "value", threads)
 		context.SetThreads(threads)
 	}
 	if max_len := flags.GetMaxLen(); max_len != 0 {
-		fmt.Fprintf(flags.Output(), "Setting max_segment_length to %d\n", max_len)
+		logger.Debug("setting parameter", "name", "max_segment_length", "value", max_len)
 		context.SetMaxSegmentLength(max_len)
 	}
+	if flags.IsSplitOnWord() {
+		logger.Debug("setting parameter", "name", "split_on_word", "value", true)
+		context.SetSplitOnWord(true)
+	}
 	if max_tokens := flags.GetMaxTokens(); max_tokens != 0 {
-		fmt.Fprintf(flags.Output(), "Setting max_tokens to %d\n", max_tokens)
+		logger.Debug("setting parameter", "name", "max_tokens", "value", max_tokens)
 		context.SetMaxTokensPerSegment(max_tokens)
 	}
+	if flags.IsWordTimestamps() {
+		logger.Debug("setting parameter", "name", "word_timestamps", "value", true)
+		context.SetWordTimestamps(true)
+	}
+	if flags.IsDiarize() {
+		logger.Debug("setting parameter", "name", "diarize", "value", true)
+		context.SetDiarize(true)
+	}
+	if temperature := flags.GetTemperature(); temperature != 0 {
+		logger.Debug("setting parameter", "name", "temperature", "value", temperature)
+		context.SetTemperature(temperature)
+	}
+	if temperature_inc := flags.GetTemperatureFallback(); temperature_inc >= 0 {
+		logger.Debug("setting parameter", "name", "temperature_inc", "value", temperature_inc)
+		context.SetTemperatureFallback(temperature_inc)
+	}
+	if entropy_thold := flags.GetEntropyThreshold(); entropy_thold != 0 {
+		logger.Debug("setting parameter", "name", "entropy_thold", "value", entropy_thold)
+		context.SetEntropyThreshold(entropy_thold)
+	}
+	if logprob_thold := flags.GetLogprobThreshold(); logprob_thold != 0 {
+		logger.Debug("setting parameter", "name", "logprob_thold", "value", logprob_thold)
+		context.SetLogprobThreshold(logprob_thold)
+	}
+	if no_speech_thold := flags.GetNoSpeechThreshold(); no_speech_thold != 0 {
+		logger.Debug("setting parameter", "name", "no_speech_thold", "value", no_speech_thold)
+		context.SetNoSpeechThreshold(no_speech_thold)
+	}
+	if beam_size := flags.GetBeamSize(); beam_size != 0 {
+		logger.Debug("setting parameter", "name", "beam_size", "value", beam_size)
+		context.SetBeamSize(beam_size)
+	}
+	if best_of := flags.GetBestOf(); best_of != 0 {
+		logger.Debug("setting parameter", "name", "best_of", "value", best_of)
+		context.SetBestOf(best_of)
+	}
+	if !flags.IsSuppressBlank() {
+		logger.Debug("setting parameter", "name", "suppress_blank", "value", false)
+		context.SetSuppressBlank(false)
+	}
+	if !flags.IsNoContext() {
+		logger.Debug("setting parameter", "name", "no_context", "value", false)
+		context.SetNoContext(false)
+	}
+	if flags.IsSuppressNonSpeech() {
+		logger.Debug("setting parameter", "name", "suppress_non_speech_tokens", "value", true)
+		context.SetSuppressNonSpeech(true)
+	}
+	if prompt := flags.GetPrompt(); prompt != "" {
+		logger.Debug("setting parameter", "name", "initial_prompt", "value", prompt)
+		context.SetInitialPrompt(prompt)
+	}
+	if hotwords, boost := flags.GetHotwords(); len(hotwords) > 0 {
+		logger.Debug("setting parameter", "name", "hotwords", "value", hotwords, "boost", boost)
+		if err := context.SetHotwords(hotwords, boost); err != nil {
+			return err
+		}
+	}
+	if alternatives := flags.GetAlternatives(); alternatives > 1 {
+		logger.Debug("setting parameter", "name", "alternatives", "value", alternatives)
+		context.SetAlternatives(alternatives)
+	}
 	if word_threshold := flags.GetWordThreshold(); word_threshold != 0 {
-		fmt.Fprintf(flags.Output(), "Setting word_threshold to %f\n", word_threshold)
+		logger.Debug("setting parameter", "name", "word_threshold", "value", word_threshold)
 		context.SetTokenThreshold(word_threshold)
 	}
 
@@ -139,18 +751,125 @@ func (flags *Flags) SetParams(context whisper.Context) error {
 ///////////////////////////////////////////////////////////////////////////////
 // PRIVATE METHODS
 
+func (v *stringList) String() string {
+	return strings.Join(*v, ",")
+}
+
+func (v *stringList) Set(value string) error {
+	*v = append(*v, value)
+	return nil
+}
+
 func registerFlags(flag *Flags) {
-	flag.String("model", "", "Path to the model file")
+	flag.String("config", "", "Read the flags from this YAML or JSON file, keyed by flag name, with the command line and "+envPrefix+"* environment variables taking precedence")
+	flag.Var(new(stringList), "model", "Path to the model file, or name=path to name the model (can be repeated to serve several models)")
 	flag.String("language", "", "Spoken language")
 	flag.Bool("translate", false, "Translate from source language to english")
-	flag.Duration("offset", 0, "Time offset")
-	flag.Duration("duration", 0, "Duration of audio to process")
-	flag.Uint("threads", 0, "Number of threads to use")
+	flag.Duration("offset", 0, "Start transcribing each file at this offset into the audio")
+	flag.Duration("duration", 0, "Transcribe this duration of each file from the -offset, or 0 for the rest of the file")
+	flag.Bool("progress", false, "Log the progress of each file, rather than drawing a progress bar when the output is a terminal")
+	flag.Duration("chunk-size", 0, "Decode and transcribe each file in chunks of this duration, to bound memory for long recordings, or 0 to decode the whole file first")
+	flag.Uint("threads", uint(runtime.NumCPU()), "Number of threads used by each transcription, or 0 to keep the default of the number of CPUs")
 	flag.Bool("speedup", false, "Enable speedup")
-	flag.Uint("max-len", 0, "Maximum segment length in characters")
+	flag.Uint("max-len", 0, "Maximum segment length in characters, to keep subtitle lines readable")
+	flag.Bool("split-on-word", false, "Split segments on word boundaries rather than tokens when -max-len is set")
+	flag.Uint("max-line-chars", 0, "Wrap SRT and VTT cues on word boundaries into lines of at most this many characters")
+	flag.Uint("max-lines", defaultMaxLines, "Maximum number of lines in an SRT or VTT cue when -max-line-chars is set, or 0 for no limit")
+	flag.Duration("offset-timestamps", 0, "Add this offset, which can be negative, to the times of SRT and VTT cues")
+	flag.Float64("timestamp-scale", 1, "Multiply the times of SRT and VTT cues by this factor before the offset, to correct drift")
 	flag.Uint("max-tokens", 0, "Maximum tokens per segment")
 	flag.Float64("word-thold", 0, "Maximum segment score")
+	flag.Bool("word-timestamps", false, "Output a subtitle for each word, using token timestamps")
+	flag.Bool("diarize", false, "Label speaker turns (requires a tinydiarize model)")
+	flag.Float64("temperature", 0, "Initial decoding temperature")
+	flag.Float64("temperature-inc", -1, "Temperature increment when decoding fails, 0 disables the fallback, or -1 for the model default of 0.2")
+	flag.Float64("entropy-thold", 0, "Entropy threshold below which decoding falls back to a higher temperature, or 0 for the model default of 2.4")
+	flag.Float64("logprob-thold", 0, "Average log probability threshold below which decoding falls back to a higher temperature, or 0 for the model default of -1.0")
+	flag.Float64("no-speech-thold", 0, "No speech probability threshold, or 0 for the model default of 0.6")
+	flag.Uint("beam-size", 0, "Beam size for beam search, or 0 or 1 for greedy decoding")
+	flag.Uint("best-of", 0, "Number of candidates when sampling with non-zero temperature, or 0 for the model default of 5")
+	flag.Bool("suppress-blank", true, "Suppress blank outputs at the start of a segment")
+	flag.Bool("suppress-nst", false, "Suppress non-speech tokens such as [Music] or (applause)")
+	flag.Bool("no-context", true, "Decode without the text decoded before as context, as conditioning on previous text can cause repetition loops")
+	flag.String("prompt", "", "Initial prompt, to bias the decoder towards vocabulary such as names and jargon")
+	flag.String("hotwords", "", "Comma-separated names and domain terms to bias the decoder towards, through the initial prompt")
+	flag.Float64("hotword-boost", 0, "Also add this to the logits of the tokens of each of the -hotwords as it is decoded")
+	flag.Uint("alternatives", 0, "Number of hypotheses of each segment in JSON output, sampling the audio of the segment again for each")
+	flag.Float64("confidence-min", 0, "Mark words with a probability below this threshold, in brackets in text output and tagged in JSON output")
+	flag.Bool("confidence-drop", false, "Drop words below the -confidence-min threshold rather than marking them")
+	flag.Duration("merge-short", 0, "Merge segments shorter than this duration into the following segment")
+	flag.Bool("profanity", false, "Mask profanity in the transcript")
+	flag.Bool("redact", false, "Redact card numbers, social security numbers and phone numbers in the transcript")
+	flag.Var(new(stringList), "redact-regex", "Mask text matching this regular expression (can be repeated)")
 	flag.Bool("tokens", false, "Display tokens")
 	flag.Bool("colorize", false, "Colorize tokens")
-	flag.String("out", "", "Output format (srt, none or leave as empty string)")
+	flag.String("resample", "sinc", "Resampling method for audio not at 16 kHz (sinc or linear)")
+	flag.String("raw", "", "Encoding of raw input audio without a header (s16le, f32le, mulaw or alaw)")
+	flag.Uint("raw-rate", whisper.SampleRate, "Sample rate of raw input audio")
+	flag.Uint("raw-channels", 1, "Number of interleaved channels of raw input audio")
+	flag.Bool("use-ffmpeg", false, "Transcode files in other formats than WAV, FLAC and MP3, including video, with ffmpeg")
+	flag.String("ffmpeg", "ffmpeg", "Name or path of the ffmpeg command used with -use-ffmpeg")
+	flag.String("url-cache", "", "Directory in which audio downloaded from URLs is cached (default in the user cache directory)")
+	flag.Bool("use-yt-dlp", false, "Download the audio of pages given as URLs, such as videos, with yt-dlp")
+	flag.String("yt-dlp", "yt-dlp", "Name or path of the yt-dlp command used with -use-yt-dlp")
+	flag.Int("channel", -1, "Channel of multi-channel audio to transcribe, or -1 to average all channels")
+	flag.Bool("each-channel", false, "Transcribe each channel of multi-channel audio separately")
+	flag.Bool("split-channels", false, "Transcribe the two legs of a stereo call recording separately, labelled Caller and Callee")
+	flag.Bool("vad", false, "Only transcribe speech regions, using voice activity detection")
+	flag.Float64("vad-thold", vad.DefaultThreshold, "Voice activity detection energy threshold")
+	flag.Duration("vad-hangover", vad.DefaultHangover, "Silence duration which ends a speech region")
+	flag.Uint("silence-ms", 0, "Buffer each utterance until this many milliseconds of silence and then transcribe it, using voice activity detection")
+	flag.Uint("max-utterance-sec", 30, "Transcribe an utterance once it reaches this many seconds, without waiting for -silence-ms")
+	flag.Duration("partial", 0, "Display interim hypotheses at this interval while streaming")
+	flag.Uint("step", 0, "Decode a sliding window every step milliseconds of audio while streaming, or zero for the default window")
+	flag.Uint("length", 10000, "Length of the sliding window in milliseconds, after which its segments are final")
+	flag.Uint("keep", 200, "Audio in milliseconds kept from each final sliding window for the next one")
+	flag.Var(new(stringList), "download-model", "Download the model with this name, such as base.en, to the -model-dir and exit (can be repeated)")
+	flag.String("model-dir", "models", "Directory models are downloaded to")
+	flag.Duration("simulate-stream", 0, "Feed audio files through the streaming path in frames of this duration, paced in real time")
+	flag.String("out", "", "Output format (srt, vtt, lrc, json, csv, tsv, html, none or leave as empty string)")
+	flag.String("listen", "", "Serve the HTTP transcription endpoint on this address, instead of processing files")
+	flag.String("grpc", "", "Serve the gRPC streaming recognition service on this address, instead of processing files")
+	flag.Bool("mic", false, "Transcribe audio from the default input device in real time, instead of processing files (requires the portaudio build tag)")
+	flag.String("mic-device", "", "Transcribe audio from the input device whose name contains this, as listed by -list-devices, instead of the default input device")
+	flag.Bool("loopback", false, "Transcribe the audio output of the system in real time, such as a meeting or video, from a PulseAudio or PipeWire monitor or a WASAPI loopback device")
+	flag.Bool("tui", false, "Show the live transcript, audio level, real-time factor and statistics of -mic in a terminal UI, with keys to pause, resume and quit")
+	flag.Bool("list-devices", false, "List the input devices and exit")
+	flag.String("listen-rtp", "", "Transcribe RTP packets (PCMU, PCMA or Opus) received on this UDP address, instead of processing files")
+	flag.String("listen-audiosocket", "", "Transcribe calls streamed by Asterisk AudioSocket connections on this TCP address, instead of processing files")
+	flag.Var(new(stringList), "sink", "Publish the segments of gRPC, RTP, AudioSocket and MRCP sessions to this kafka://, nats:// or redis:// URL (can be repeated)")
+	flag.String("webhook-url", "", "POST the JSON transcript of each gRPC, RTP, AudioSocket and MRCP session to this URL when it ends")
+	flag.Bool("webhook-segments", false, "POST each final segment to the -webhook-url, rather than the transcript when the session ends")
+	flag.Uint("webhook-retries", 5, "Number of times a failed -webhook-url delivery is retried, with exponential backoff")
+	flag.String("listen-mrcp", "", "Serve an MRCPv2 speech recognizer, with SIP on this UDP address and control connections on this TCP address, instead of processing files")
+	flag.Duration("rtp-jitter", 100*time.Millisecond, "Audio held in the RTP jitter buffer while waiting for a missing packet")
+	flag.Uint("rtp-opus-pt", 111, "RTP payload type of Opus packets")
+	flag.Duration("keepalive", 0, "Ping idle gRPC connections at this interval, closing those which do not respond")
+	flag.String("lag-policy", "block", "When gRPC stream audio waits for longer than -max-lag to be decoded, block the client, drop the oldest audio, skip to the latest audio, or notify the client and block (block, drop, skip or notify)")
+	flag.Duration("max-lag", speech.DefaultMaxLag, "Audio which can wait to be decoded before the -lag-policy is applied")
+	flag.Duration("idle-timeout", 0, "End gRPC and RTP streams when no audio is received for this duration")
+	flag.String("record-dir", "", "Record the audio of each gRPC and RTP stream to a WAV file in this directory")
+	flag.String("tls-cert", "", "Path to the TLS certificate, to serve over TLS")
+	flag.String("tls-key", "", "Path to the TLS private key, to serve over TLS")
+	flag.Bool("tls-self-signed", false, "Serve over TLS with a generated self-signed certificate, for testing")
+	flag.Var(new(stringList), "auth-token", "API token required by the servers (can be repeated, or set "+envAuthToken+")")
+	flag.Var(new(stringList), "cors-origin", "Origin of a browser app which can call the HTTP endpoints, such as https://app.example.com, or * for any origin (can be repeated)")
+	flag.Duration("shutdown-timeout", 30*time.Second, "Time to wait for in-flight requests to complete when shutting down the servers")
+	flag.String("log-level", "info", "Log level ("+logLevels()+")")
+	flag.Bool("log-json", false, "Log as JSON instead of text")
+	flag.Bool("gpu", true, "Use the GPU, when whisper.cpp is built with GPU support")
+	flag.String("openvino-device", "", "Run the encoder with OpenVINO on this device, such as CPU or GPU (requires the openvino build tag)")
+	flag.String("output-dir", "", "Write the results for each file to this directory, instead of standard output")
+	flag.String("output-name-template", defaultOutputName, "Template for the names of output files, with fields .Base, .Ext, .Lang and .Model")
+	flag.Bool("recursive", false, "Transcribe audio files in subdirectories of directory arguments")
+	flag.Uint("parallel", 1, "Number of files processed concurrently, each worker decoding with its own state against one copy of the model")
+	flag.Bool("concat-output", false, "Transcribe the inputs in order as one continuous recording, with one output")
+	flag.Uint("pool-size", 1, "Maximum number of concurrent transcriptions when serving")
+	flag.Duration("max-audio", 0, "End each request, stream or call when it exceeds this duration of audio, or 0 for no limit")
+	flag.Duration("max-session-time", 0, "End each request, stream or call when it has run for this duration, or 0 for no limit")
+	flag.Uint64("max-bytes", 0, "End each request, stream or call when it has sent this many bytes of audio, or 0 for no limit")
+	flag.Uint("max-sessions", 0, "Maximum number of concurrent requests, streams and calls when serving, rejecting others, or 0 for no limit")
+	flag.Uint("session-queue", 0, "Number of sessions which wait for one to end when -max-sessions is reached, before others are rejected")
+	flag.Duration("session-queue-timeout", 30*time.Second, "Reject sessions which wait in the -session-queue for longer than this duration, or 0 to wait until the client gives up")
+	flag.Bool("shared-model", true, "Load each model once when serving, with a decoding state for each of the -pool-size transcriptions, unless -openvino-device is set; set to false to load a copy for each")
 }
@@ -0,0 +1,140 @@
+package main
+
+import (
+	gocontext "context"
+	"fmt"
+	"net"
+	"time"
+
+	// Packages
+	speech "github.com/ggerganov/whisper.cpp/bindings/go/pkg/server/grpc"
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	grpc "google.golang.org/grpc"
+	credentials "google.golang.org/grpc/credentials"
+	keepalive "google.golang.org/grpc/keepalive"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// grpcObserver passes the results of gRPC streams to the observers
+type grpcObserver struct {
+	observers observers
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Serve the gRPC streaming recognition service on the -grpc address until
+// the go context is cancelled. Streams select a model by name in their
+// configuration, and the first model given by the -model flag is the
+// default. On cancellation, in-flight streams are drained for up to the
+// -shutdown-timeout duration. The model is reloaded on SIGHUP.
+func ServeGRPC(ctx gocontext.Context, flags *Flags) error {
+	pools := make(map[string]whisper.Pool)
+	defer func() {
+		for _, pool := range pools {
+			pool.Close()
+		}
+	}()
+	names := flags.GetModelNames()
+	for i, path := range flags.GetModels() {
+		if _, exists := pools[names[i]]; exists {
+			return fmt.Errorf("duplicate model name: %q", names[i])
+		}
+		pool, err := newPool(flags, names[i], path, whisper.PoolWait)
+		if err != nil {
+			return err
+		}
+		pools[names[i]] = pool
+	}
+	ReloadOnHangup(ctx, flags, pools)
+	filter, err := flags.GetPostprocess()
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", flags.GetGRPC())
+	if err != nil {
+		return err
+	}
+	opts := []grpc.ServerOption{
+		grpc.ChainStreamInterceptor(LogStreams(flags.Logger()), AuthenticateStream(flags.GetAuthTokens()), LimitStreams(NewAdmission(flags))),
+	}
+	if interval := flags.GetKeepalive(); interval > 0 {
+		opts = append(opts, grpc.KeepaliveParams(keepalive.ServerParameters{
+			Time:    interval,
+			Timeout: interval,
+		}))
+	}
+	if config, err := TLSConfig(flags); err != nil {
+		return err
+	} else if config != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(config)))
+	}
+	speechserver := speech.NewServer(pools[flags.GetModelName()], nil)
+	for name, pool := range pools {
+		speechserver.SetModel(name, pool)
+	}
+	speechserver.SetFilter(filter)
+	speechserver.SetRecordDir(flags.GetRecordDir())
+	speechserver.SetIdleTimeout(flags.GetIdleTimeout())
+	speechserver.SetLimits(flags.GetMaxAudio(), flags.GetMaxSessionTime(), int64(flags.GetMaxBytes()))
+	if policy, err := flags.GetLagPolicy(); err != nil {
+		return err
+	} else {
+		speechserver.SetLag(policy, flags.GetMaxLag())
+	}
+	speechserver.SetVAD(flags.GetVADThreshold(), flags.GetVADHangover())
+	observers, err := newObservers(flags)
+	if err != nil {
+		return err
+	}
+	defer observers.Close()
+	if len(observers) > 0 {
+		speechserver.SetObserver(grpcObserver{observers})
+	}
+	server := grpc.NewServer(opts...)
+	speech.RegisterSpeechServer(server, speechserver)
+	flags.Logger().Info("serving gRPC", "addr", listener.Addr().String(), "model", flags.GetModelName())
+
+	// Serve streams in the background
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.Serve(listener)
+	}()
+
+	// Wait for an error or cancellation
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	// Drain in-flight streams, then stop
+	flags.Logger().Info("shutting down", "timeout", flags.GetShutdownTimeout())
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(flags.GetShutdownTimeout()):
+		server.Stop()
+	}
+
+	// Return success
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func (observer grpcObserver) Segment(session string, context whisper.Context, segment whisper.Segment) {
+	observer.observers.Segment("grpc", session, context, segment)
+}
+
+func (observer grpcObserver) End(session string, context whisper.Context, samples int) {
+	observer.observers.End("grpc", session, context, samples)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+
+	// Package imports
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// A segment of HTML output
+type htmlSegment struct {
+	Start  string
+	Labels []string
+	Words  []htmlWord
+}
+
+// A word of HTML output, colored by its probability
+type htmlWord struct {
+	Text  string
+	Title string
+	Color template.CSS
+	Low   bool
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+// A self-contained transcript, in which each word is colored from red to
+// green by its probability, with its times and probability in a tooltip
+var htmlTemplate = template.Must(template.New("html").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{ .Title }}</title>
+<style>
+body { font-family: sans-serif; line-height: 1.8; max-width: 50em; margin: 2em auto; padding: 0 1em; }
+p { margin: 0 0 0.5em; }
+.time { color: #888; font-family: monospace; margin-right: 0.5em; }
+.label { background: #eee; border-radius: 3px; font-size: 0.8em; margin-right: 0.5em; padding: 0 0.3em; }
+.word { border-radius: 3px; padding: 0.1em 0.05em; }
+.low { text-decoration: underline dotted; }
+.legend { color: #888; font-size: 0.8em; margin-bottom: 1.5em; }
+</style>
+</head>
+<body>
+<div class="legend">Word probability:
+{{- range .Legend }} <span class="word" style="background: {{ .Color }}">{{ .Text }}</span>{{ end }}
+</div>
+{{- range .Segments }}
+<p><span class="time">{{ .Start }}</span>
+{{- range .Labels }}<span class="label">{{ . }}</span>{{ end }}
+{{- range .Words }} <span class="word{{ if .Low }} low{{ end }}" style="background: {{ .Color }}" title="{{ .Title }}">{{ .Text }}</span>{{ end }}</p>
+{{- end }}
+</body>
+</html>
+`))
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Output segments as a self-contained HTML page, with each word colored by its
+// probability and a tooltip with its times and probability. Words below the
+// confidence threshold, when not zero, are underlined. The labels of each
+// segment are shown before its text.
+func OutputHTML(w io.Writer, context whisper.Context, segments []whisper.Segment, labels [][]string, title string, confidence float32) error {
+	var data struct {
+		Title    string
+		Legend   []htmlWord
+		Segments []htmlSegment
+	}
+	data.Title = title
+	for _, p := range []float32{0, 0.25, 0.5, 0.75, 1} {
+		data.Legend = append(data.Legend, htmlWord{Text: fmt.Sprintf("%.0f%%", p*100), Color: heatColor(p)})
+	}
+	for i, segment := range segments {
+		result := htmlSegment{Start: vttTimestamp(segment.Start)}
+		if i < len(labels) {
+			result.Labels = labels[i]
+		}
+		for _, word := range htmlWords(context, segment) {
+			start, end := word.Start, word.End
+			if end <= start {
+				start, end = segment.Start, segment.End
+			}
+			result.Words = append(result.Words, htmlWord{
+				Text:  strings.TrimSpace(word.Text),
+				Title: fmt.Sprintf("%s --> %s, p=%.2f", vttTimestamp(start), vttTimestamp(end), word.P),
+				Color: heatColor(word.P),
+				Low:   confidence > 0 && word.P < confidence,
+			})
+		}
+		data.Segments = append(data.Segments, result)
+	}
+	return htmlTemplate.Execute(w, data)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Return the words of a segment with their probabilities, from the word
+// timestamps, or the text tokens grouped into words. When the text has been
+// changed by post-processing, such as redaction, so that it is no longer
+// that of the tokens, the text is a single word with the mean probability.
+func htmlWords(context whisper.Context, segment whisper.Segment) []whisper.Word {
+	if len(segment.Words) > 0 {
+		return segment.Words
+	}
+	words := textWords(context, segment.Tokens)
+	var text []string
+	var p float32
+	for _, word := range words {
+		text = append(text, strings.TrimSpace(word.Text))
+		p += word.P
+	}
+	if strings.Join(text, " ") == segment.Text {
+		return words
+	} else if segment.Text == "" {
+		return nil
+	} else if len(words) > 0 {
+		p /= float32(len(words))
+	} else {
+		p = 1
+	}
+	return []whisper.Word{{Text: segment.Text, Start: segment.Start, End: segment.End, P: p}}
+}
+
+// Return the background color for a probability, from red at zero through
+// yellow to green at one
+func heatColor(p float32) template.CSS {
+	p = min(max(p, 0), 1)
+	return template.CSS(fmt.Sprintf("hsl(%d, 80%%, 80%%)", int(p*120)))
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Input is a file or URL to transcribe. When Dir is not empty, the results are
+// written to a file in that directory rather than to standard output.
+type Input struct {
+	Path string
+	Dir  string
+}
+
+// OutputName holds the fields of the -output-name-template
+type OutputName struct {
+	Base  string // Filename of the input or URL without its extension, or "stdin"
+	Ext   string // Extension of the -out format, such as ".srt"
+	Lang  string // Language of the transcript
+	Model string // Name of the model
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+// File extensions of the audio files found in directories and glob patterns
+var audioExts = []string{".wav", ".flac", ".mp3"}
+
+// File extensions of the audio and video files also found with -use-ffmpeg
+var ffmpegExts = []string{".m4a", ".aac", ".ogg", ".opus", ".wma", ".webm", ".mp4", ".mkv", ".mov", ".avi"}
+
+// The default -output-name-template
+const defaultOutputName = "{{.Base}}{{.Ext}}"
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Inputs returns the files to transcribe for the command line arguments.
+// Each directory and glob pattern is expanded to the audio files it
+// contains, descending into subdirectories when -recursive is specified.
+// The results for each file are written to the -output-dir directory when
+// set. Otherwise, the results for files found in directories and glob
+// patterns are written next to each file, and the results for files given
+// directly, and "-" for standard input, are written to standard output.
+func Inputs(args []string, flags *Flags) ([]Input, error) {
+	if _, err := flags.GetOutputTemplate(); err != nil {
+		return nil, err
+	}
+	var result []Input
+	for _, arg := range args {
+		// Files and URLs are passed through
+		info, err := os.Stat(arg)
+		if arg == "-" || isURL(arg) || (err == nil && !info.IsDir()) {
+			result = append(result, Input{Path: arg, Dir: flags.GetOutputDir()})
+			continue
+		}
+
+		// Expand directories and glob patterns
+		var matches []string
+		if err == nil {
+			matches = []string{arg}
+		} else if strings.ContainsAny(arg, "*?[") {
+			if matches, err = filepath.Glob(arg); err != nil {
+				return nil, err
+			}
+		} else {
+			return nil, err
+		}
+		exts := audioExts
+		if flags.IsUseFFmpeg() {
+			exts = append(slices.Clone(audioExts), ffmpegExts...)
+		}
+		var paths []string
+		for _, match := range matches {
+			found, err := findAudio(match, exts, flags.IsRecursive())
+			if err != nil {
+				return nil, err
+			}
+			paths = append(paths, found...)
+		}
+		if len(paths) == 0 {
+			return nil, fmt.Errorf("no audio files found: %q", arg)
+		}
+		for _, path := range paths {
+			dir := flags.GetOutputDir()
+			if dir == "" {
+				dir = filepath.Dir(path)
+			}
+			result = append(result, Input{Path: path, Dir: dir})
+		}
+	}
+
+	// Return success
+	return result, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Return the files at path with one of the extensions, where path is a file
+// or a directory. Files in subdirectories are included when recursive is
+// true.
+func findAudio(path string, exts []string, recursive bool) ([]string, error) {
+	var result []string
+	err := filepath.WalkDir(path, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if name != path && !recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if slices.Contains(exts, strings.ToLower(filepath.Ext(name))) {
+			result = append(result, name)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// Return the path the results for an input are written to, which is named
+// with the -output-name-template, or an empty string when the results are
+// written to standard output
+func outputPath(input Input, lang, model string, flags *Flags) (string, error) {
+	if input.Dir == "" {
+		return "", nil
+	}
+	tmpl, err := flags.GetOutputTemplate()
+	if err != nil {
+		return "", err
+	}
+	name := OutputName{
+		Base:  inputBase(input),
+		Ext:   outputExt(flags.GetOut()),
+		Lang:  lang,
+		Model: model,
+	}
+	var str strings.Builder
+	if err := tmpl.Execute(&str, name); err != nil {
+		return "", err
+	}
+	return filepath.Join(input.Dir, str.String()), nil
+}
+
+// Return the filename of an input or URL without its extension, or "stdin"
+func inputBase(input Input) string {
+	if input.Path == "-" {
+		return "stdin"
+	} else if isURL(input.Path) {
+		return urlBase(input.Path)
+	}
+	return strings.TrimSuffix(filepath.Base(input.Path), filepath.Ext(input.Path))
+}
+
+// Return the file extension for an output format
+func outputExt(format string) string {
+	switch format {
+	case "srt", "vtt", "lrc", "json", "csv", "tsv", "html":
+		return "." + format
+	default:
+		return ".txt"
+	}
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+
+	// Package imports
+	api "github.com/ggerganov/whisper.cpp/bindings/go/pkg/server"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Output transcript as JSON
+func OutputJSON(w io.Writer, transcript *api.Transcript) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(transcript)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	gocontext "context"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	// Package imports
+	status "google.golang.org/grpc/status"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// The outcome of the sessions of a load test
+type loadReport struct {
+	sessions  int
+	failed    int
+	audio     time.Duration
+	elapsed   time.Duration
+	first     []time.Duration // Time to the first final result of each session
+	latencies []time.Duration // Latency of each final result
+	durations []time.Duration // Time each successful session took
+	errors    map[string]int  // Number of sessions which failed with each error
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// The subcommand which runs a load test
+	loadtestCommand = "loadtest"
+
+	// Number of sessions of a load test
+	defaultLoadTestSessions = 50
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// RunLoadTest runs the loadtest subcommand, which sends an audio file to a
+// remote server in concurrent sessions, streamed at real time by default,
+// and reports the error rate and the percentiles of the time to the first
+// result, the latency of each final result and the duration of the
+// sessions. The starts of the sessions are spread over -ramp.
+func RunLoadTest(ctx gocontext.Context, name string, args []string) error {
+	var ramp time.Duration
+	client, flags, err := newClient(name, loadtestCommand, args, defaultLoadTestSessions, true, func(fs *flag.FlagSet) {
+		fs.DurationVar(&ramp, "ramp", 0, "Time over which the starts of the sessions are spread")
+	})
+	if err != nil {
+		return err
+	}
+	client.quiet = true
+	if err := client.open(ctx, flags); err != nil {
+		return err
+	}
+	defer client.Close()
+
+	// Run the sessions
+	logger := flags.Logger()
+	logger.Info("load test", "url", client.url.String(), "sessions", client.sessions, "realtime", client.realtime, "ramp", ramp)
+	report := &loadReport{errors: make(map[string]int)}
+	start := time.Now()
+	client.run(ctx, ramp, func(n int, session clientSession, err error) {
+		logger.Debug("session", "n", n, "session", session.id, "elapsed", session.elapsed, "first_result", session.first, "finals", session.finals, "error", err)
+		report.add(session, err)
+	})
+	report.elapsed = time.Since(start)
+
+	// Report the outcome of the sessions
+	return report.write(os.Stdout)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Add the outcome of a session to the report. Sessions which fail are
+// counted by the error, or by the status message of a gRPC error.
+func (report *loadReport) add(session clientSession, err error) {
+	report.sessions++
+	if err != nil {
+		report.failed++
+		if s, ok := status.FromError(err); ok {
+			report.errors[s.Code().String()+": "+s.Message()]++
+		} else {
+			report.errors[err.Error()]++
+		}
+		return
+	}
+	report.audio += session.audio
+	report.durations = append(report.durations, session.elapsed)
+	if session.finals > 0 {
+		report.first = append(report.first, session.first)
+	}
+	report.latencies = append(report.latencies, session.latencies...)
+}
+
+// Write the report, with the errors in descending order of count
+func (report *loadReport) write(w io.Writer) error {
+	var str strings.Builder
+	rate := 0.0
+	if report.sessions > 0 {
+		rate = 100 * float64(report.failed) / float64(report.sessions)
+	}
+	fmt.Fprintf(&str, "sessions        %d, %d failed (%.1f%%)\n", report.sessions, report.failed, rate)
+	fmt.Fprintf(&str, "audio           %v in %v\n", report.audio.Round(time.Millisecond), report.elapsed.Round(time.Millisecond))
+	for _, row := range []struct {
+		name   string
+		values []time.Duration
+	}{
+		{"first result", report.first},
+		{"result latency", report.latencies},
+		{"session time", report.durations},
+	} {
+		fmt.Fprintf(&str, "%-16s%s\n", row.name, percentiles(row.values))
+	}
+	if len(report.errors) > 0 {
+		errs := make([]string, 0, len(report.errors))
+		for err := range report.errors {
+			errs = append(errs, err)
+		}
+		slices.SortFunc(errs, func(a, b string) int {
+			if n := report.errors[b] - report.errors[a]; n != 0 {
+				return n
+			}
+			return strings.Compare(a, b)
+		})
+		str.WriteString("errors\n")
+		for _, err := range errs {
+			fmt.Fprintf(&str, "  %6d  %s\n", report.errors[err], err)
+		}
+	}
+	_, err := io.WriteString(w, str.String())
+	return err
+}
+
+// Return the 50th, 90th and 99th percentiles and the maximum of durations,
+// by the nearest rank
+func percentiles(values []time.Duration) string {
+	if len(values) == 0 {
+		return "-"
+	}
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	rank := func(p float64) time.Duration {
+		i := int(math.Ceil(p*float64(len(sorted)))) - 1
+		return sorted[min(max(i, 0), len(sorted)-1)].Round(time.Millisecond)
+	}
+	return fmt.Sprintf("p50 %v  p90 %v  p99 %v  max %v  (n=%d)", rank(0.5), rank(0.9), rank(0.99), sorted[len(sorted)-1].Round(time.Millisecond), len(sorted))
+}
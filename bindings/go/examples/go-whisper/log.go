@@ -0,0 +1,73 @@
+package main
+
+import (
+	gocontext "context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	// Package imports
+	api "github.com/ggerganov/whisper.cpp/bindings/go/pkg/server"
+	grpc "google.golang.org/grpc"
+	status "google.golang.org/grpc/status"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// A gRPC stream with a go context carrying its session identifier
+type sessionStream struct {
+	grpc.ServerStream
+	ctx gocontext.Context
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// NewLogger returns a structured logger which writes to w, at the level
+// given by -log-level, as text or as JSON when -log-json is specified
+func NewLogger(w io.Writer, level string, json bool) (*slog.Logger, error) {
+	var v slog.Level
+	if err := v.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level: %q", level)
+	}
+	opts := &slog.HandlerOptions{Level: v}
+	if json {
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	}
+	return slog.New(slog.NewTextHandler(w, opts)), nil
+}
+
+// LogStreams returns a gRPC interceptor which assigns each stream a session
+// identifier, carried by the go context of the stream, and logs the stream
+// once it has completed
+func LogStreams(logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		id := api.NewSessionId()
+		logger := logger.With("session", id)
+		err := handler(srv, &sessionStream{stream, api.WithSessionId(stream.Context(), id)})
+		logger.Info("stream", "method", info.FullMethod, "status", status.Code(err).String(), "duration", time.Since(start))
+		return err
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Return the go context of the stream
+func (stream *sessionStream) Context() gocontext.Context {
+	return stream.ctx
+}
+
+// Return the log level names, for the flag usage
+func logLevels() string {
+	levels := []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError}
+	names := make([]string, len(levels))
+	for i, level := range levels {
+		names[i] = strings.ToLower(level.String())
+	}
+	return strings.Join(names, ", ")
+}
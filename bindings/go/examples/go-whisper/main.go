@@ -1,43 +1,134 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 
 	// Packages
 	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
 )
 
 func main() {
-	flags, err := NewFlags(filepath.Base(os.Args[0]), os.Args[1:])
+	// Run the client and loadtest subcommands
+	name := filepath.Base(os.Args[0])
+	if len(os.Args) > 1 && (os.Args[1] == clientCommand || os.Args[1] == loadtestCommand) {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		run := RunClient
+		if os.Args[1] == loadtestCommand {
+			run = RunLoadTest
+		}
+		err := run(ctx, name, os.Args[2:])
+		stop()
+		if err == flag.ErrHelp {
+			os.Exit(0)
+		} else if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	flags, err := NewFlags(name, os.Args[1:])
 	if err == flag.ErrHelp {
 		os.Exit(0)
 	} else if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
-	} else if flags.GetModel() == "" {
+	} else if flags.IsListDevices() {
+		if err := ListDevices(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	} else if flags.GetModel() == "" && len(flags.GetDownloadModels()) == 0 {
 		fmt.Fprintln(os.Stderr, "Use -model flag to specify which model file to use")
 		os.Exit(1)
-	} else if flags.NArg() == 0 {
+	}
+
+	// Stop processing, and shut down the servers, on interrupt
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Download models
+	logger := flags.Logger()
+	if len(flags.GetDownloadModels()) > 0 {
+		if err := DownloadModels(ctx, flags); err != nil {
+			logger.Error("unable to download model", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Run the servers until interrupted
+	if flags.GetListen() != "" {
+		if err := Serve(ctx, flags); err != nil {
+			logger.Error("server failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	} else if flags.GetGRPC() != "" {
+		if err := ServeGRPC(ctx, flags); err != nil {
+			logger.Error("server failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	} else if flags.GetListenAudioSocket() != "" {
+		if err := ServeAudioSocket(ctx, flags); err != nil {
+			logger.Error("server failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	} else if flags.GetListenMRCP() != "" {
+		if err := ServeMRCP(ctx, flags); err != nil {
+			logger.Error("server failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	} else if flags.NArg() == 0 && flags.GetListenRTP() == "" && !flags.IsMic() {
 		fmt.Fprintln(os.Stderr, "No input files specified")
 		os.Exit(1)
 	}
 
 	// Load model
-	model, err := whisper.New(flags.GetModel())
+	model, err := whisper.NewWithParams(flags.GetModel(), flags.GetContextParams())
 	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
+		logger.Error("unable to load model", "path", flags.GetModel(), "error", err)
 		os.Exit(1)
 	}
 	defer model.Close()
+	logger.Debug("loaded model", append([]any{"model", flags.GetModelName(), "path", flags.GetModel()}, modelInfo(model.Info())...)...)
 
-	// Process files
-	for _, filename := range flags.Args() {
-		if err := Process(model, filename, flags); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			continue
+	// Transcribe RTP packets until interrupted
+	if flags.GetListenRTP() != "" {
+		if err := ServeRTP(ctx, model, flags); err != nil {
+			logger.Error("RTP receiver failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Transcribe the microphone until interrupted
+	if flags.IsMic() {
+		if err := ServeMic(ctx, model, flags); err != nil {
+			logger.Error("microphone capture failed", "error", err)
+			os.Exit(1)
 		}
+		return
+	}
+
+	// Process files
+	inputs, err := Inputs(flags.Args(), flags)
+	if err != nil {
+		logger.Error("invalid input", "error", err)
+		os.Exit(1)
+	}
+	if err := ProcessFiles(ctx, model, inputs, os.Stdout, flags); err != nil {
+		logger.Error("unable to process", "error", err)
+		os.Exit(1)
 	}
 }
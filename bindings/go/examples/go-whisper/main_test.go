@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	// Packages
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	assert "github.com/stretchr/testify/assert"
+)
+
+// Return a duration of seconds
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// Return a segment with the text, from start to end seconds
+func segment(start, end float64, text string) whisper.Segment {
+	return whisper.Segment{Start: seconds(start), End: seconds(end), Text: text}
+}
+
+// Return a word with the text, from start to end seconds
+func word(start, end float64, text string) whisper.Word {
+	return whisper.Word{Start: seconds(start), End: seconds(end), Text: text}
+}
+
+func Test_Main_000(t *testing.T) {
+	assert := assert.New(t)
+
+	// Timestamps of each subtitle format
+	tests := []struct {
+		t             time.Duration
+		srt, vtt, lrc string
+	}{
+		{0, "00:00:00,000", "00:00:00.000", "00:00.00"},
+		{seconds(1.5), "00:00:01,500", "00:00:01.500", "00:01.50"},
+		{seconds(59.999), "00:00:59,999", "00:00:59.999", "00:59.99"},
+		{time.Hour + 2*time.Minute + seconds(3.456), "01:02:03,456", "01:02:03.456", "62:03.45"},
+	}
+	for _, test := range tests {
+		assert.Equal(test.srt, srtTimestamp(test.t), test.t)
+		assert.Equal(test.vtt, vttTimestamp(test.t), test.t)
+		assert.Equal(test.lrc, lrcTimestamp(test.t), test.t)
+	}
+}
+
+func Test_Main_001(t *testing.T) {
+	assert := assert.New(t)
+
+	// Cue times are scaled, then offset, and no earlier than zero
+	tests := []struct {
+		opts SubtitleOptions
+		t    time.Duration
+		want time.Duration
+	}{
+		{SubtitleOptions{}, time.Second, time.Second},
+		{SubtitleOptions{Scale: 1}, time.Second, time.Second},
+		{SubtitleOptions{Scale: 2}, time.Second, 2 * time.Second},
+		{SubtitleOptions{Offset: time.Second}, time.Second, 2 * time.Second},
+		{SubtitleOptions{Offset: -2 * time.Second}, time.Second, 0},
+		{SubtitleOptions{Scale: 0.5, Offset: time.Second}, 3 * time.Second, seconds(2.5)},
+	}
+	for _, test := range tests {
+		assert.Equal(test.want, test.opts.cueTime(test.t), test.opts)
+	}
+}
+
+func Test_Main_002(t *testing.T) {
+	assert := assert.New(t)
+
+	// Lines are wrapped at spaces where possible, and otherwise cut
+	tests := []struct {
+		line  string
+		width int
+		want  []string
+	}{
+		{"short", 10, []string{"short"}},
+		{"hello world", 5, []string{"hello", "world"}},
+		{"a bc def", 4, []string{"a", "bc", "def"}},
+		{"abcdefgh", 3, []string{"abc", "def", "gh"}},
+		{"héllo wörld", 5, []string{"héllo", "wörld"}},
+	}
+	for _, test := range tests {
+		assert.Equal(test.want, wrap(test.line, test.width), test.line)
+	}
+}
+
+func Test_Main_003(t *testing.T) {
+	assert := assert.New(t)
+
+	// Without word timestamps, the words of "one two three four" are
+	// interpolated a second for each character
+	text := segment(0, 18, "one two three four")
+	words := segment(0, 2, "hi there")
+	words.Words = []whisper.Word{word(0, 0.5, " hi"), word(0.5, 2, " there")}
+
+	tests := []struct {
+		name     string
+		segments []whisper.Segment
+		opts     SubtitleOptions
+		want     []whisper.Word
+	}{
+		{"segment", []whisper.Segment{text}, SubtitleOptions{}, []whisper.Word{word(0, 18, "one two three four")}},
+		{"words", []whisper.Segment{words}, SubtitleOptions{}, words.Words},
+		{"one line", []whisper.Segment{text}, SubtitleOptions{MaxLineChars: 7, MaxLines: 1}, []whisper.Word{
+			word(0, 7, "one two"), word(8, 13, "three"), word(14, 18, "four"),
+		}},
+		{"two lines", []whisper.Segment{text}, SubtitleOptions{MaxLineChars: 7, MaxLines: 2}, []whisper.Word{
+			word(0, 13, "one two\nthree"), word(14, 18, "four"),
+		}},
+		{"unlimited lines", []whisper.Segment{text}, SubtitleOptions{MaxLineChars: 7}, []whisper.Word{
+			word(0, 18, "one two\nthree\nfour"),
+		}},
+		{"trimmed words", []whisper.Segment{words}, SubtitleOptions{MaxLineChars: 20}, []whisper.Word{
+			word(0, 2, "hi there"),
+		}},
+		{"scale", []whisper.Segment{segment(1, 2, "a")}, SubtitleOptions{Scale: 2}, []whisper.Word{word(2, 4, "a")}},
+		{"offset", []whisper.Segment{segment(0, 5, "a"), segment(8, 12, "b")}, SubtitleOptions{Offset: -10 * time.Second}, []whisper.Word{
+			word(0, 2, "b"),
+		}},
+	}
+	for _, test := range tests {
+		assert.Equal(test.want, toCues(test.segments, test.opts), test.name)
+	}
+}
+
+func Test_Main_004(t *testing.T) {
+	assert := assert.New(t)
+	segments := []whisper.Segment{segment(0, 1.5, "hello"), segment(1.5, 3, "world")}
+
+	tests := []struct {
+		name   string
+		output func(*bytes.Buffer) error
+		want   string
+	}{
+		{"srt", func(w *bytes.Buffer) error { return OutputSRT(w, segments, SubtitleOptions{}) },
+			"1\n00:00:00,000 --> 00:00:01,500\nhello\n\n" +
+				"2\n00:00:01,500 --> 00:00:03,000\nworld\n\n"},
+		{"vtt", func(w *bytes.Buffer) error { return OutputVTT(w, segments, SubtitleOptions{}) },
+			"WEBVTT\n\n" +
+				"00:00:00.000 --> 00:00:01.500\nhello\n\n" +
+				"00:00:01.500 --> 00:00:03.000\nworld\n\n"},
+		{"lrc", func(w *bytes.Buffer) error { return OutputLRC(w, segments) },
+			"[00:00.00]hello\n[00:01.50]world\n"},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		assert.NoError(test.output(&buf), test.name)
+		assert.Equal(test.want, buf.String(), test.name)
+	}
+
+	// Enhanced LRC has a timestamp before each word
+	words := segment(0, 2, "hi there")
+	words.Words = []whisper.Word{word(0, 0.5, "hi"), word(0.5, 2, "there")}
+	var buf bytes.Buffer
+	assert.NoError(OutputLRC(&buf, []whisper.Segment{words}))
+	assert.Equal("[00:00.00] <00:00.00> hi <00:00.50> there <00:02.00>\n", buf.String())
+}
+
+func Test_Main_005(t *testing.T) {
+	assert := assert.New(t)
+
+	// Segments, with their tokens and words, are offset
+	tests := []struct {
+		offset time.Duration
+		want   time.Duration
+	}{
+		{0, time.Second},
+		{time.Second, 2 * time.Second},
+		{-time.Second, 0},
+	}
+	for _, test := range tests {
+		s := segment(1, 2, "a")
+		s.Tokens = []whisper.Token{{Start: seconds(1), End: seconds(2)}}
+		s.Words = []whisper.Word{word(1, 2, "a")}
+		s = offsetSegment(s, test.offset)
+		assert.Equal(test.want, s.Start, test.offset)
+		assert.Equal(test.want+time.Second, s.End, test.offset)
+		assert.Equal(test.want, s.Tokens[0].Start, test.offset)
+		assert.Equal(test.want+time.Second, s.Tokens[0].End, test.offset)
+		assert.Equal(test.want, s.Words[0].Start, test.offset)
+		assert.Equal(test.want+time.Second, s.Words[0].End, test.offset)
+	}
+
+	// Durations and samples at the whisper sample rate
+	for _, samples := range []int{0, 1600, whisper.SampleRate, 90 * whisper.SampleRate} {
+		assert.Equal(samples, toSamples(toDuration(samples)))
+	}
+	assert.Equal(100*time.Millisecond, toDuration(1600))
+}
+
+func Test_Main_006(t *testing.T) {
+	assert := assert.New(t)
+
+	// Transcriptions are joined with the times of each offset by the audio
+	// before it, and the segments numbered on
+	first := &transcription{
+		segments: []whisper.Segment{segment(0, 1, "one"), segment(1, 2, "two")},
+		labels:   [][]string{{"SPEAKER 1"}, {"SPEAKER 2"}},
+		samples:  3 * whisper.SampleRate,
+		detected: "en",
+		language: "en",
+		decode:   time.Second,
+		process:  2 * time.Second,
+	}
+	second := &transcription{
+		segments: []whisper.Segment{segment(0.5, 1, "three")},
+		labels:   [][]string{nil},
+		samples:  whisper.SampleRate,
+		detected: "de",
+		language: "de",
+		decode:   time.Second,
+		process:  time.Second,
+	}
+	result := concatTranscriptions([]*transcription{first, second})
+	assert.Equal("en", result.detected)
+	assert.Equal("en", result.language)
+	assert.Equal(4*whisper.SampleRate, result.samples)
+	assert.Equal(2*time.Second, result.decode)
+	assert.Equal(3*time.Second, result.process)
+	assert.Equal([][]string{{"SPEAKER 1"}, {"SPEAKER 2"}, nil}, result.labels)
+
+	tests := []struct {
+		text       string
+		start, end time.Duration
+	}{
+		{"one", 0, seconds(1)},
+		{"two", seconds(1), seconds(2)},
+		{"three", seconds(3.5), seconds(4)},
+	}
+	if assert.Len(result.segments, len(tests)) {
+		for i, test := range tests {
+			assert.Equal(i, result.segments[i].Num, test.text)
+			assert.Equal(test.text, result.segments[i].Text)
+			assert.Equal(test.start, result.segments[i].Start, test.text)
+			assert.Equal(test.end, result.segments[i].End, test.text)
+		}
+	}
+}
+
+func Test_Main_007(t *testing.T) {
+	assert := assert.New(t)
+
+	// Chunks are cut at the middle of the quietest frame in the search
+	// samples before their end
+	loud := func(n int) []float32 {
+		data := make([]float32, n)
+		for i := range data {
+			data[i] = 1
+		}
+		return data
+	}
+	quiet := loud(100)
+	for i := 70; i < 80; i++ {
+		quiet[i] = 0.1
+	}
+	quieter := loud(100)
+	quieter[65] = 0
+
+	tests := []struct {
+		name                string
+		data                []float32
+		search, frame, want int
+	}{
+		{"quiet frame", quiet, 40, 10, 75},
+		{"quieter frame", quieter, 40, 10, 65},
+		{"first of equal frames", loud(100), 40, 10, 65},
+		{"outside the search", quiet, 20, 10, 85},
+		{"no frame", quiet, 40, 0, 100},
+		{"too short", loud(50), 40, 10, 50},
+	}
+	for _, test := range tests {
+		assert.Equal(test.want, quietest(test.data, test.search, test.frame), test.name)
+	}
+}
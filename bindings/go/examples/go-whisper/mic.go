@@ -0,0 +1,191 @@
+package main
+
+import (
+	gocontext "context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	// Package imports
+	mic "github.com/ggerganov/whisper.cpp/bindings/go/pkg/mic"
+	resample "github.com/ggerganov/whisper.cpp/bindings/go/pkg/resample"
+	vad "github.com/ggerganov/whisper.cpp/bindings/go/pkg/vad"
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// Duration of the audio read from the device at once
+	micBuffer = 100 * time.Millisecond
+
+	// The sliding window step when -step is not set, as with the stream
+	// example of whisper.cpp
+	micStep = 3 * time.Second
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// ServeMic transcribes audio from the default input device in real time, or
+// from the device selected with -mic-device or -loopback, and prints
+// segments as they are transcribed, until the go context is cancelled.
+// Unless -vad or -step is set, the audio is decoded in a sliding window
+// every three seconds, with interim hypotheses. With -tui, the transcript is
+// shown in a terminal UI.
+func ServeMic(ctx gocontext.Context, model whisper.Model, flags *Flags) error {
+	context, err := model.NewContext()
+	if err != nil {
+		return err
+	}
+	if err := flags.SetParams(context); err != nil {
+		return err
+	}
+	filter, err := flags.GetPostprocess()
+	if err != nil {
+		return err
+	}
+	step, length, keep := flags.GetSlidingWindow()
+	if step == 0 && !flags.IsVAD() {
+		step = micStep
+	}
+	partial := flags.IsPartial() || step > 0
+	ui, err := newTUI(flags)
+	if err != nil {
+		return err
+	}
+	defer ui.Close()
+	stream, err := whisper.NewStreamingContext(context, func(segment whisper.Segment) {
+		segments := filter([]whisper.Segment{segment})
+		if ui != nil {
+			for _, segment := range segments {
+				var text strings.Builder
+				Output(&text, context, []whisper.Segment{segment}, false)
+				ui.Segment(text.String(), len(strings.Fields(segment.Text)))
+			}
+			return
+		}
+		if partial {
+			fmt.Fprint(flags.Output(), ClearLine)
+		}
+		Output(os.Stdout, context, segments, flags.IsColorize())
+	})
+	if err != nil {
+		return err
+	}
+	if flags.IsVAD() {
+		stream.SetVAD(vad.New(whisper.SampleRate, flags.GetVADThreshold(), flags.GetVADHangover()), nil)
+		stream.SetMaxUtterance(flags.GetMaxUtterance())
+	}
+	stream.SetSlidingWindow(step, length, keep)
+	if partial {
+		stream.SetPartialCallback(func(segment whisper.Segment) {
+			for _, segment := range filter([]whisper.Segment{segment}) {
+				if ui != nil {
+					ui.Partial(segment.Text)
+				} else {
+					fmt.Fprintf(flags.Output(), "%s  ...%s", ClearLine, segment.Text)
+				}
+			}
+		}, flags.GetPartial())
+	}
+
+	// Capture audio until cancelled, or until quit with -tui. While paused,
+	// the audio is read and skipped, and the audio before the pause is
+	// transcribed.
+	capture, err := openCapture(flags)
+	if err != nil {
+		return err
+	}
+	defer capture.Close()
+	flags.Logger().Info("capturing audio", "rate", capture.Rate(), "model", flags.GetModelName())
+	buf := make([]float32, int(micBuffer.Seconds()*float64(capture.Rate())))
+	var skipped time.Duration
+	for ctx.Err() == nil {
+		if err := capture.Read(buf); errors.Is(err, mic.ErrOverflow) {
+			if ui != nil {
+				ui.Overflow()
+			} else {
+				flags.Logger().Warn("transcription is falling behind the audio", "error", err)
+			}
+		} else if err != nil {
+			return err
+		}
+		data := buf
+		if capture.Rate() != whisper.SampleRate {
+			data = resample.Linear(buf, capture.Rate(), whisper.SampleRate)
+		}
+		ui.Audio(data)
+		switch ui.Poll() {
+		case tuiQuit:
+			return stream.Flush()
+		case tuiPause:
+			if err := stream.Flush(); err != nil {
+				return err
+			}
+		case tuiResume:
+			stream.Skip(skipped)
+			skipped = 0
+		}
+		if ui.Paused() {
+			skipped += toDuration(len(data))
+			continue
+		}
+		start := time.Now()
+		if err := stream.Feed(data); err != nil {
+			return err
+		}
+		ui.Fed(toDuration(len(data)), time.Since(start))
+	}
+
+	// Transcribe the remaining audio
+	return stream.Flush()
+}
+
+// ListDevices writes the input devices, with the loopback devices which
+// capture the audio output of the system marked
+func ListDevices(w io.Writer) error {
+	devices, err := mic.Devices()
+	if err != nil {
+		return err
+	}
+	for _, device := range devices {
+		fmt.Fprintf(w, "%3d: %v, %d channels, %v Hz\n", device.Index, device, device.Channels, device.Rate)
+	}
+
+	// Return success
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Open the input device whose name contains -mic-device, or the first
+// loopback device with -loopback, or otherwise the default input device
+func openCapture(flags *Flags) (*mic.Capture, error) {
+	name := flags.GetMicDevice()
+	if name == "" && !flags.IsLoopback() {
+		return mic.Open(whisper.SampleRate, int(micBuffer.Seconds()*whisper.SampleRate))
+	}
+	devices, err := mic.Devices()
+	if err != nil {
+		return nil, err
+	}
+	var device mic.Device
+	if name != "" {
+		device, err = mic.Find(devices, name)
+	} else {
+		device, err = mic.FindLoopback(devices)
+	}
+	if errors.Is(err, mic.ErrNoDevice) {
+		return nil, fmt.Errorf("%w: %q", err, name)
+	} else if err != nil {
+		return nil, err
+	}
+	flags.Logger().Info("opening device", "device", device.String(), "channels", device.Channels, "rate", device.Rate)
+	return mic.OpenDevice(device, int(micBuffer.Seconds()*device.Rate))
+}
@@ -0,0 +1,622 @@
+package main
+
+import (
+	"bufio"
+	gocontext "context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	// Package imports
+	mrcp "github.com/ggerganov/whisper.cpp/bindings/go/pkg/mrcp"
+	postprocess "github.com/ggerganov/whisper.cpp/bindings/go/pkg/postprocess"
+	rtp "github.com/ggerganov/whisper.cpp/bindings/go/pkg/rtp"
+	api "github.com/ggerganov/whisper.cpp/bindings/go/pkg/server"
+	vad "github.com/ggerganov/whisper.cpp/bindings/go/pkg/vad"
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// mrcpServer serves the speechrecog resource to MRCPv2 clients. Sessions are
+// established with SIP over UDP, and each has a context from the pool, an RTP
+// port for the audio and a channel on a control connection.
+type mrcpServer struct {
+	sync.Mutex
+	flags     *Flags
+	pool      whisper.Pool
+	admission *api.Admission
+	filter    postprocess.Filter
+	observe   observers
+	host      string                  // Host the RTP ports are opened on
+	sipPort   int                     // Port of the SIP listener
+	port      int                     // Port of the control listener
+	sessions  map[string]*mrcpSession // Sessions by SIP call identifier
+	channels  map[string]*mrcpSession // Sessions by channel identifier
+	conns     map[*mrcpConn]struct{}
+	wg        sync.WaitGroup
+}
+
+// mrcpConn is a control connection, which carries the messages of one or
+// more channels
+type mrcpConn struct {
+	sync.Mutex
+	net.Conn
+}
+
+// mrcpSession is a recognizer session. At most one RECOGNIZE request is in
+// progress at once, and audio is only transcribed while it is.
+type mrcpSession struct {
+	sync.Mutex
+	server   *mrcpServer
+	logger   *slog.Logger
+	callID   string
+	channel  string
+	answer   *mrcp.SIPMessage // Response to the INVITE, resent on retransmission
+	context  whisper.Context
+	receiver *rtpReceiver
+	rtp      net.PacketConn
+	control  *mrcpConn     // Control connection, once a request has been received
+	request  *mrcp.Message // RECOGNIZE request in progress
+	started  bool          // True once speech has started
+	noInput  time.Duration // No-input timeout of the request
+	timer    *time.Timer
+	text     []string
+	p        float32 // Sum of the probabilities of the text tokens
+	n        int     // Number of text tokens
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// ServeMRCP serves a speech recognizer resource on the -listen-mrcp address,
+// with SIP on the UDP port and MRCPv2 control connections on the TCP port,
+// until the go context is cancelled. Each session uses a context from the
+// pool, and INVITEs are rejected as busy when none is available. The model is
+// reloaded on SIGHUP.
+func ServeMRCP(ctx gocontext.Context, flags *Flags) error {
+	pool, err := newPool(flags, flags.GetModelName(), flags.GetModel(), whisper.PoolReject)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+	ReloadOnHangup(ctx, flags, map[string]whisper.Pool{flags.GetModelName(): pool})
+	filter, err := flags.GetPostprocess()
+	if err != nil {
+		return err
+	}
+
+	observers, err := newObservers(flags)
+	if err != nil {
+		return err
+	}
+	defer observers.Close()
+
+	// Listen for SIP and control connections
+	sip, err := net.ListenPacket("udp", flags.GetListenMRCP())
+	if err != nil {
+		return err
+	}
+	defer sip.Close()
+	listener, err := net.Listen("tcp", flags.GetListenMRCP())
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+	host, _, err := net.SplitHostPort(sip.LocalAddr().String())
+	if err != nil {
+		return err
+	}
+	server := &mrcpServer{
+		flags:     flags,
+		pool:      pool,
+		admission: NewAdmission(flags),
+		filter:    filter,
+		observe:   observers,
+		host:      host,
+		sipPort:   sip.LocalAddr().(*net.UDPAddr).Port,
+		port:      listener.Addr().(*net.TCPAddr).Port,
+		sessions:  make(map[string]*mrcpSession),
+		channels:  make(map[string]*mrcpSession),
+		conns:     make(map[*mrcpConn]struct{}),
+	}
+	flags.Logger().Info("serving MRCP", "sip", sip.LocalAddr().String(), "control", listener.Addr().String(), "model", flags.GetModelName())
+
+	// Stop on cancellation
+	go func() {
+		<-ctx.Done()
+		sip.Close()
+		listener.Close()
+	}()
+
+	// Accept control connections
+	server.wg.Add(1)
+	go func() {
+		defer server.wg.Done()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			server.wg.Add(1)
+			go server.serveControl(&mrcpConn{Conn: conn})
+		}
+	}()
+
+	// Handle SIP requests until cancelled
+	buf := make([]byte, 1<<16)
+	for {
+		n, from, err := sip.ReadFrom(buf)
+		if ctx.Err() != nil {
+			break
+		} else if err != nil {
+			return err
+		}
+		request, err := mrcp.ParseSIP(buf[:n])
+		if err != nil || request.Method == "" {
+			continue
+		}
+		if response := server.handleSIP(request, from); response != nil {
+			if _, err := sip.WriteTo(response.Bytes(), from); err != nil {
+				flags.Logger().Warn("unable to send SIP response", "to", from.String(), "error", err)
+			}
+		}
+	}
+
+	// End the sessions and close the control connections
+	flags.Logger().Info("shutting down")
+	server.Lock()
+	for _, session := range server.sessions {
+		session.Close()
+	}
+	for conn := range server.conns {
+		conn.Close()
+	}
+	server.Unlock()
+	server.wg.Wait()
+
+	// Return success
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS - SERVER
+
+// Return the response to a SIP request, or nil if there is none
+func (server *mrcpServer) handleSIP(request *mrcp.SIPMessage, from net.Addr) *mrcp.SIPMessage {
+	switch request.Method {
+	case mrcp.SIPInvite:
+		return server.invite(request, from)
+	case mrcp.SIPAck:
+		return nil
+	case mrcp.SIPBye:
+		server.Lock()
+		session := server.sessions[request.CallID()]
+		server.Unlock()
+		if session == nil {
+			return mrcp.NewSIPResponse(request, 481, "Call/Transaction Does Not Exist")
+		}
+		session.Close()
+		return mrcp.NewSIPResponse(request, 200, "OK")
+	case mrcp.SIPOptions, mrcp.SIPCancel:
+		// INVITEs are answered at once, so there is nothing to cancel
+		return mrcp.NewSIPResponse(request, 200, "OK")
+	default:
+		return mrcp.NewSIPResponse(request, 501, "Not Implemented")
+	}
+}
+
+// Establish a session for an INVITE, and return the response with the SDP
+// answer
+func (server *mrcpServer) invite(request *mrcp.SIPMessage, from net.Addr) *mrcp.SIPMessage {
+	logger := server.flags.Logger().With("call_id", request.CallID())
+
+	// A retransmitted INVITE is answered again
+	server.Lock()
+	session := server.sessions[request.CallID()]
+	server.Unlock()
+	if session != nil {
+		return session.answer
+	}
+
+	// Accept offers of the recognizer resource with a supported encoding
+	offer, err := mrcp.ParseSDP(request.Body)
+	if err != nil || offer.Resource != mrcp.Resource {
+		logger.Warn("unsupported offer", "resource", resourceName(offer))
+		return mrcp.NewSIPResponse(request, 488, "Not Acceptable Here")
+	}
+	offer.Payloads = slices.DeleteFunc(offer.Payloads, func(pt uint8) bool {
+		return pt != rtp.PayloadPCMU && pt != rtp.PayloadPCMA && pt != uint8(server.flags.GetRTPOpusPayloadType())
+	})
+	if len(offer.Payloads) == 0 {
+		logger.Warn("no supported encoding")
+		return mrcp.NewSIPResponse(request, 488, "Not Acceptable Here")
+	}
+
+	// Admit the session, and obtain a context from the pool. INVITEs are
+	// answered without waiting, so sessions are not queued.
+	if !server.admission.TryAcquire() {
+		logger.Warn("rejected", "error", api.ErrTooManySessions)
+		return mrcp.NewSIPResponse(request, 486, "Busy Here")
+	}
+	context, err := server.pool.Get(gocontext.Background())
+	if errors.Is(err, whisper.ErrPoolExhausted) {
+		server.admission.Release()
+		logger.Warn("no context available")
+		return mrcp.NewSIPResponse(request, 486, "Busy Here")
+	} else if err != nil {
+		server.admission.Release()
+		logger.Error("unable to obtain context", "error", err)
+		return mrcp.NewSIPResponse(request, 500, "Server Internal Error")
+	}
+	session, err = server.newSession(context, request.CallID(), logger)
+	if err != nil {
+		server.pool.Put(context)
+		server.admission.Release()
+		logger.Error("unable to create session", "error", err)
+		return mrcp.NewSIPResponse(request, 500, "Server Internal Error")
+	}
+
+	// Answer with the control port, the channel and the RTP port
+	addr := server.localAddr(from)
+	offer.Addr = addr
+	offer.ControlPort = server.port
+	offer.Channel = session.channel
+	offer.AudioPort = session.rtp.LocalAddr().(*net.UDPAddr).Port
+	response := mrcp.NewSIPResponse(request, 200, "OK")
+	if to := response.Header.Get("To"); !strings.Contains(to, ";tag=") {
+		response.Header.Set("To", to+";tag="+api.NewSessionId())
+	}
+	response.Header.Set("Contact", fmt.Sprintf("<sip:%s>", net.JoinHostPort(addr, strconv.Itoa(server.sipPort))))
+	response.Header.Set("Content-Type", "application/sdp")
+	response.Body = offer.Bytes()
+	session.answer = response
+
+	// Start receiving audio
+	server.Lock()
+	server.sessions[session.callID] = session
+	server.channels[session.channel] = session
+	server.wg.Add(1)
+	server.Unlock()
+	go session.run()
+	logger.Info("session started", "channel", session.channel, "rtp", session.rtp.LocalAddr().String())
+
+	// Return success
+	return response
+}
+
+// Return a session with an RTP port and a streaming context
+func (server *mrcpServer) newSession(context whisper.Context, callID string, logger *slog.Logger) (*mrcpSession, error) {
+	if err := server.flags.SetParams(context); err != nil {
+		return nil, err
+	}
+	session := &mrcpSession{
+		server:  server,
+		callID:  callID,
+		channel: api.NewSessionId(),
+		context: context,
+	}
+	session.logger = logger.With("session", session.channel)
+	stream, err := whisper.NewStreamingContext(context, session.segment)
+	if err != nil {
+		return nil, err
+	}
+	stream.SetSlidingWindow(server.flags.GetSlidingWindow())
+	session.receiver = newRTPReceiver(stream, server.flags)
+	if session.rtp, err = net.ListenPacket("udp", net.JoinHostPort(server.host, "0")); err != nil {
+		return nil, err
+	}
+
+	// Return success
+	return session, nil
+}
+
+// Return the address answered to a client, which is the listening host or,
+// when listening on all interfaces, the local address used to reach it
+func (server *mrcpServer) localAddr(remote net.Addr) string {
+	if ip := net.ParseIP(server.host); ip != nil && !ip.IsUnspecified() {
+		return server.host
+	}
+	conn, err := net.Dial("udp", remote.String())
+	if err != nil {
+		return server.host
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP.String()
+}
+
+// Read requests from a control connection, and pass them to the session of
+// their channel
+func (server *mrcpServer) serveControl(conn *mrcpConn) {
+	defer server.wg.Done()
+	server.Lock()
+	server.conns[conn] = struct{}{}
+	server.Unlock()
+	defer func() {
+		server.Lock()
+		delete(server.conns, conn)
+		server.Unlock()
+		conn.Close()
+	}()
+
+	reader := bufio.NewReader(conn)
+	for {
+		message, err := mrcp.Read(reader)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) && !errors.Is(err, io.EOF) {
+				server.flags.Logger().Warn("control connection failed", "remote", conn.RemoteAddr().String(), "error", err)
+			}
+			return
+		} else if message.Kind != mrcp.KindRequest {
+			continue
+		}
+		server.Lock()
+		session := server.channels[message.Channel()]
+		server.Unlock()
+		if session == nil {
+			conn.send(mrcp.NewResponse(message, mrcp.StatusResourceNotAllocated, mrcp.StateComplete))
+			continue
+		}
+		session.handle(conn, message)
+	}
+}
+
+// Remove a session once it has ended
+func (server *mrcpServer) remove(session *mrcpSession) {
+	server.Lock()
+	defer server.Unlock()
+	delete(server.sessions, session.callID)
+	delete(server.channels, session.channel)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS - SESSION
+
+// End the session. The RTP port is closed, and the session is removed once
+// the remaining audio has been handled.
+func (session *mrcpSession) Close() error {
+	session.Lock()
+	defer session.Unlock()
+	session.stopTimer()
+	session.request = nil
+	return session.rtp.Close()
+}
+
+// Receive RTP packets until the session ends, and feed them into the stream
+// while a RECOGNIZE request is in progress
+func (session *mrcpSession) run() {
+	server := session.server
+	defer server.wg.Done()
+	defer func() {
+		session.receiver.Close()
+		server.pool.Put(session.context)
+		server.admission.Release()
+		server.remove(session)
+		session.logger.Info("session ended")
+	}()
+
+	buf := make([]byte, 1<<16)
+	for {
+		if timeout := server.flags.GetIdleTimeout(); timeout > 0 {
+			session.rtp.SetReadDeadline(time.Now().Add(timeout))
+		}
+		n, _, err := session.rtp.ReadFrom(buf)
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			session.logger.Info("session idle", "timeout", server.flags.GetIdleTimeout())
+			session.Close()
+			return
+		} else if err != nil {
+			return
+		}
+		packet, err := rtp.Parse(append([]byte(nil), buf[:n]...))
+		if err != nil {
+			continue
+		}
+
+		session.Lock()
+		if session.request != nil {
+			err = session.receiver.Push(packet)
+		}
+		if err == nil && session.request == nil {
+			err = session.receiver.Flush()
+		}
+		if err != nil {
+			session.logger.Error("recognition failed", "error", err)
+			session.complete(mrcp.CauseError)
+		}
+		session.Unlock()
+	}
+}
+
+// Handle a request on the channel of the session
+func (session *mrcpSession) handle(conn *mrcpConn, request *mrcp.Message) {
+	session.Lock()
+	defer session.Unlock()
+	session.control = conn
+	session.logger.Debug("request", "request", request.String())
+
+	// Set the language of the context
+	if lang := request.Header.Get(mrcp.HeaderSpeechLanguage); lang != "" {
+		lang, _, _ = strings.Cut(lang, "-")
+		if err := session.context.SetLanguage(strings.ToLower(lang)); err != nil {
+			session.send(mrcp.NewResponse(request, mrcp.StatusUnsupportedValue, mrcp.StateComplete))
+			return
+		}
+	}
+
+	switch request.Name {
+	case mrcp.MethodRecognize:
+		if session.request != nil {
+			session.send(mrcp.NewResponse(request, mrcp.StatusMethodNotValid, mrcp.StateComplete))
+			return
+		}
+		session.request = request
+		session.started = false
+		session.text, session.p, session.n = nil, 0, 0
+		session.noInput = 0
+		if ms, err := strconv.ParseUint(request.Header.Get(mrcp.HeaderNoInputTimeout), 10, 32); err == nil {
+			session.noInput = time.Duration(ms) * time.Millisecond
+		}
+		session.receiver.stream.SetVAD(vad.New(whisper.SampleRate, session.server.flags.GetVADThreshold(), session.server.flags.GetVADHangover()), session.vadEvent)
+		session.receiver.stream.SetMaxUtterance(session.server.flags.GetMaxUtterance())
+		session.send(mrcp.NewResponse(request, mrcp.StatusSuccess, mrcp.StateInProgress))
+		if request.Header.Get(mrcp.HeaderStartInputTimers) != "false" {
+			session.startTimer()
+		}
+	case mrcp.MethodStartInputTimers:
+		session.startTimer()
+		session.send(mrcp.NewResponse(request, mrcp.StatusSuccess, mrcp.StateComplete))
+	case mrcp.MethodStop:
+		response := mrcp.NewResponse(request, mrcp.StatusSuccess, mrcp.StateComplete)
+		if session.request != nil {
+			response.Header.Set(mrcp.HeaderActiveRequestIdList, fmt.Sprint(session.request.RequestID))
+			session.stopTimer()
+			session.request = nil
+			if err := session.receiver.Flush(); err != nil {
+				session.logger.Error("recognition failed", "error", err)
+			}
+		}
+		session.send(response)
+	case mrcp.MethodSetParams, mrcp.MethodGetParams, mrcp.MethodDefineGrammar:
+		// Grammars are accepted, but recognition is always dictation
+		session.send(mrcp.NewResponse(request, mrcp.StatusSuccess, mrcp.StateComplete))
+	default:
+		session.send(mrcp.NewResponse(request, mrcp.StatusMethodNotAllowed, mrcp.StateComplete))
+	}
+}
+
+// Collect the segments of the request in progress
+func (session *mrcpSession) segment(segment whisper.Segment) {
+	if session.request == nil {
+		return
+	}
+	for _, segment := range session.server.filter([]whisper.Segment{segment}) {
+		session.server.observe.Segment("mrcp", session.name(), session.context, segment)
+		session.text = append(session.text, segment.Text)
+		for _, token := range segment.Tokens {
+			if session.context.IsText(token) {
+				session.p += token.P
+				session.n++
+			}
+		}
+	}
+}
+
+// Signal the start of input, and complete the request at the end of speech
+func (session *mrcpSession) vadEvent(evt vad.Event) {
+	if session.request == nil {
+		return
+	}
+	switch evt.Type {
+	case vad.SpeechStart:
+		if !session.started {
+			session.started = true
+			session.stopTimer()
+			event := mrcp.NewEvent(session.request, mrcp.EventStartOfInput, mrcp.StateInProgress)
+			event.Header.Set(mrcp.HeaderInputType, "speech")
+			session.send(event)
+		}
+	case vad.SpeechEnd:
+		if strings.TrimSpace(strings.Join(session.text, "")) == "" {
+			session.complete(mrcp.CauseNoMatch)
+		} else {
+			session.complete(mrcp.CauseSuccess)
+		}
+	}
+}
+
+// Complete the request in progress with a RECOGNITION-COMPLETE event, which
+// carries the result when successful
+func (session *mrcpSession) complete(cause string) {
+	if session.request == nil {
+		return
+	}
+	event := mrcp.NewEvent(session.request, mrcp.EventRecognitionComplete, mrcp.StateComplete)
+	event.Header.Set(mrcp.HeaderCompletionCause, cause)
+	if cause == mrcp.CauseSuccess {
+		text := strings.TrimSpace(strings.Join(session.text, ""))
+		var confidence float32
+		if session.n > 0 {
+			confidence = session.p / float32(session.n)
+		}
+		body, err := mrcp.NLSML("", text, confidence)
+		if err != nil {
+			session.logger.Error("unable to encode result", "error", err)
+			event.Header.Set(mrcp.HeaderCompletionCause, mrcp.CauseError)
+		} else {
+			event.Header.Set(mrcp.HeaderContentType, mrcp.ContentTypeNLSML)
+			event.Body = body
+		}
+		fmt.Fprintf(os.Stdout, "[%s] %s\n", session.channel, text)
+	}
+	session.server.observe.End("mrcp", session.name(), session.context, session.receiver.samples)
+	session.logger.Info("recognition complete", "request_id", session.request.RequestID, "cause", event.Header.Get(mrcp.HeaderCompletionCause))
+	session.stopTimer()
+	session.request = nil
+	session.send(event)
+}
+
+// Return the name of the request in progress
+func (session *mrcpSession) name() string {
+	return fmt.Sprintf("%s-%d", session.channel, session.request.RequestID)
+}
+
+// Start the no-input timer of the request in progress, when it has a
+// timeout and speech has not started
+func (session *mrcpSession) startTimer() {
+	if session.request == nil || session.started || session.noInput == 0 || session.timer != nil {
+		return
+	}
+	request := session.request
+	session.timer = time.AfterFunc(session.noInput, func() {
+		session.Lock()
+		defer session.Unlock()
+		if session.request == request && !session.started {
+			session.complete(mrcp.CauseNoInputTimeout)
+		}
+	})
+}
+
+func (session *mrcpSession) stopTimer() {
+	if session.timer != nil {
+		session.timer.Stop()
+		session.timer = nil
+	}
+}
+
+// Send a message on the control connection of the session
+func (session *mrcpSession) send(message *mrcp.Message) {
+	if session.control == nil {
+		return
+	}
+	if err := session.control.send(message); err != nil {
+		session.logger.Warn("unable to send", "message", message.String(), "error", err)
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS - CONNECTION
+
+// Send a message, serialised with the messages of other channels
+func (conn *mrcpConn) send(message *mrcp.Message) error {
+	conn.Lock()
+	defer conn.Unlock()
+	return mrcp.Write(conn.Conn, message)
+}
+
+// Return the resource of an offer, for logging
+func resourceName(offer *mrcp.SDP) string {
+	if offer == nil {
+		return ""
+	}
+	return offer.Resource
+}
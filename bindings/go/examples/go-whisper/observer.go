@@ -0,0 +1,154 @@
+package main
+
+import (
+	gocontext "context"
+	"errors"
+	"io"
+	"net/url"
+	"time"
+
+	// Package imports
+	sink "github.com/ggerganov/whisper.cpp/bindings/go/pkg/sink"
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// observer receives the final segments of streaming sessions, filtered for
+// output, and is notified when each session ends
+type observer interface {
+	io.Closer
+
+	// Segment is called with each final segment of a session
+	Segment(source, id string, context whisper.Context, segment whisper.Segment)
+
+	// End is called when a session ends, with the number of samples received
+	End(source, id string, context whisper.Context, samples int)
+}
+
+// observers notifies each of the observers set by the flags
+type observers []observer
+
+// sinkObserver publishes segments to a sink in the background, so that a
+// slow message bus does not hold up transcription
+type sinkObserver struct {
+	flags    *Flags
+	name     string // The sink URL, without any password
+	sink     sink.Sink
+	messages chan *sink.Message
+	done     chan struct{}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// Number of messages held for a sink before they are dropped
+	sinkQueueSize = 1024
+
+	// Timeout for publishing each message
+	sinkTimeout = 10 * time.Second
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Return the observers for the -webhook-url and each -sink
+func newObservers(flags *Flags) (observers, error) {
+	var result observers
+	if hook := newWebhook(flags); hook != nil {
+		result = append(result, hook)
+	}
+	for _, str := range flags.GetSinks() {
+		bus, err := sink.Open(str)
+		if err != nil {
+			result.Close()
+			return nil, err
+		}
+		name := str
+		if u, err := url.Parse(str); err == nil {
+			name = u.Redacted()
+		}
+		result = append(result, newSinkObserver(flags, name, bus))
+	}
+
+	// Return success
+	return result, nil
+}
+
+func newSinkObserver(flags *Flags, name string, bus sink.Sink) *sinkObserver {
+	observer := &sinkObserver{
+		flags:    flags,
+		name:     name,
+		sink:     bus,
+		messages: make(chan *sink.Message, sinkQueueSize),
+		done:     make(chan struct{}),
+	}
+	go observer.run()
+	return observer
+}
+
+// Close each observer, waiting for pending deliveries
+func (observers observers) Close() error {
+	var result error
+	for _, observer := range observers {
+		result = errors.Join(result, observer.Close())
+	}
+	return result
+}
+
+// Close publishes the queued messages, and then closes the sink
+func (observer *sinkObserver) Close() error {
+	close(observer.messages)
+	<-observer.done
+	return observer.sink.Close()
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+func (observers observers) Segment(source, id string, context whisper.Context, segment whisper.Segment) {
+	for _, observer := range observers {
+		observer.Segment(source, id, context, segment)
+	}
+}
+
+func (observers observers) End(source, id string, context whisper.Context, samples int) {
+	for _, observer := range observers {
+		observer.End(source, id, context, samples)
+	}
+}
+
+// Segment queues a segment to be published, or drops it when the queue is
+// full
+func (observer *sinkObserver) Segment(source, id string, context whisper.Context, segment whisper.Segment) {
+	language := segment.Language
+	if language == "" {
+		language = context.DetectedLanguage()
+	}
+	select {
+	case observer.messages <- sink.NewMessage(source, id, language, segment):
+	default:
+		observer.flags.Logger().Warn("sink queue full, dropping segment", "sink", observer.name, "session", id)
+	}
+}
+
+// End does nothing, as each segment has been published
+func (observer *sinkObserver) End(string, string, whisper.Context, int) {
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Publish the queued messages in order until the queue is closed
+func (observer *sinkObserver) run() {
+	defer close(observer.done)
+	for message := range observer.messages {
+		ctx, cancel := gocontext.WithTimeout(gocontext.Background(), sinkTimeout)
+		if err := observer.sink.Publish(ctx, message); err != nil {
+			observer.flags.Logger().Error("unable to publish", "sink", observer.name, "session", message.Session, "error", err)
+		}
+		cancel()
+	}
+}
@@ -1,50 +1,116 @@
 package main
 
 import (
+	"bytes"
+	gocontext "context"
 	"fmt"
 	"io"
+	"math"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	// Package imports
+	api "github.com/ggerganov/whisper.cpp/bindings/go/pkg/server"
+	vad "github.com/ggerganov/whisper.cpp/bindings/go/pkg/vad"
 	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
-	wav "github.com/go-audio/wav"
 )
 
-func Process(model whisper.Model, path string, flags *Flags) error {
-	var data []float32
+// The transcription of an input, which is held until it is written
+type transcription struct {
+	context  whisper.Context
+	segments []whisper.Segment
+	labels   [][]string // Speaker and channel labels of each segment
+	samples  int        // Number of samples of each channel
+	detected string     // The language detected, or empty when it was set
+	language string     // The language detected, or the language set
+	decode   time.Duration
+	process  time.Duration
+}
+
+// Labels for the channels of a call recording, when -split-channels is
+// specified
+var callLegs = []string{"Caller", "Callee"}
+
+// Process the audio file for the input, the audio downloaded when the path is
+// a URL, or standard input when the path is "-", and write the results to the
+// output file for the input, or to w
+func Process(ctx gocontext.Context, model whisper.Model, input Input, w io.Writer, flags *Flags) error {
+	transcription, err := transcribe(ctx, model, input, flags)
+	if err != nil {
+		return err
+	}
+	return transcription.write(input, w, flags)
+}
+
+// Transcribe the audio file for the input, as with Process, and return the
+// transcription
+func transcribe(ctx gocontext.Context, model whisper.Model, input Input, flags *Flags) (*transcription, error) {
+	path := input.Path
 
 	// Create processing context
 	context, err := model.NewContext()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Set the parameters
 	if err := flags.SetParams(context); err != nil {
-		return err
+		return nil, err
 	}
 
-	fmt.Printf("\n%s\n", context.SystemInfo())
+	logger := flags.Logger()
+	logger.Debug("system info", "info", context.SystemInfo())
 
-	// Open the file
-	fmt.Fprintf(flags.Output(), "Loading %q\n", path)
-	fh, err := os.Open(path)
-	if err != nil {
-		return err
+	// Open the file, or read standard input when the path is "-"
+	var r io.ReadSeeker
+	if path == "-" {
+		logger.Info("loading", "path", path)
+		buf, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(buf)
+	} else {
+		if isURL(path) {
+			if path, err = Fetch(ctx, path, flags); err != nil {
+				return nil, err
+			}
+		}
+		logger.Info("loading", "path", path)
+		fh, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer fh.Close()
+		r = fh
 	}
-	defer fh.Close()
 
-	// Decode the WAV file - load the full buffer
-	dec := wav.NewDecoder(fh)
-	if buf, err := dec.FullPCMBuffer(); err != nil {
-		return err
-	} else if dec.SampleRate != whisper.SampleRate {
-		return fmt.Errorf("unsupported sample rate: %d", dec.SampleRate)
-	} else if dec.NumChans != 1 {
-		return fmt.Errorf("unsupported number of channels: %d", dec.NumChans)
-	} else {
-		data = buf.AsFloat32Buffer().Data
+	// Decode the audio, keeping the channels apart when -each-channel is
+	// specified. When -chunk-size is specified, the audio is decoded as it
+	// is processed instead.
+	t0 := time.Now()
+	var channels [][]float32
+	var samples int
+	if chunk := flags.GetChunkSize(); chunk > 0 {
+		if chunk < minChunkSize {
+			return nil, fmt.Errorf("-chunk-size must be at least %v", minChunkSize)
+		} else if flags.IsEachChannel() || flags.IsSplitChannels() {
+			return nil, fmt.Errorf("-chunk-size cannot be combined with -each-channel or -split-channels")
+		}
+	} else if channels, err = DecodeChannels(r, flags); err != nil {
+		return nil, err
+	} else if !flags.IsEachChannel() && !flags.IsSplitChannels() {
+		data, err := mixChannels(channels, flags.GetChannel())
+		if err != nil {
+			return nil, err
+		}
+		channels = [][]float32{data}
+	}
+	if len(channels) > 0 {
+		samples = len(channels[0])
 	}
 
 	// Segment callback when -tokens is specified
@@ -64,53 +130,454 @@ func Process(model whisper.Model, path string, flags *Flags) error {
 		}
 	}
 
+	// Each leg of a call is transcribed in its own context when
+	// -split-channels is specified
+	contexts := []whisper.Context{context}
+	if flags.IsSplitChannels() {
+		if len(channels) != len(callLegs) {
+			return nil, fmt.Errorf("-split-channels requires %d channels, audio has %d", len(callLegs), len(channels))
+		}
+		callee, err := model.NewContext()
+		if err != nil {
+			return nil, err
+		}
+		if err := flags.SetParams(callee); err != nil {
+			return nil, err
+		}
+		contexts = append(contexts, callee)
+	}
+
+	// Post-process the segments when they are output
+	filter, err := flags.GetPostprocess()
+	if err != nil {
+		return nil, err
+	}
+
 	// Process the data
-	fmt.Fprintf(flags.Output(), "  ...processing %q\n", path)
-	context.ResetTimings()
-	if err := context.Process(data, cb, nil); err != nil {
-		return err
+	var segments []whisper.Segment
+	var labels [][]string // Speaker and channel labels of each segment
+	for _, context := range contexts {
+		context.ResetTimings()
+	}
+	// Report the progress through the section of each channel set by -offset
+	// and -duration, whose length is not known until the audio is decoded
+	// when it is decoded in chunks
+	section := flags.GetDuration()
+	if samples > 0 {
+		if rest := max(toDuration(samples)-flags.GetOffset(), 0); section == 0 || section > rest {
+			section = rest
+		}
+	}
+	progress := newProgress(flags, path, section*time.Duration(max(len(channels), 1)))
+	defer progress.Done()
+
+	t1 := time.Now()
+	for ch := 0; ch < max(len(channels), 1); ch++ {
+		context := contexts[ch%len(contexts)]
+		if len(channels) > 1 {
+			logger.Info("processing", "path", path, "channel", ch)
+		} else {
+			logger.Info("processing", "path", path)
+		}
+		var result []whisper.Segment
+		if channels == nil {
+			result, samples, err = processChunks(ctx, context, r, cb, progress, flags)
+		} else {
+			base := section * time.Duration(ch)
+			result, err = process(ctx, context, channels[ch], flags.GetOffset(), flags.GetDuration(), cb, func(done float64) {
+				progress.Update(base + time.Duration(done*float64(section)))
+			}, flags)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// Mark low confidence words when -confidence-min is specified. JSON
+		// output tags the words instead, unless they are dropped.
+		if minConfidence := flags.GetConfidenceMin(); minConfidence > 0 && (flags.GetOut() != "json" && flags.GetOut() != "html" || flags.IsConfidenceDrop()) {
+			result = markConfidence(context, result, minConfidence, flags.IsConfidenceDrop())
+		}
+
+		result = filter(result)
+
+		// Label speakers when -diarize is specified, and channels when each
+		// channel is transcribed separately
+		var turns []int
+		if flags.IsDiarize() {
+			turns = speakers(result)
+		}
+		for i := range result {
+			var label []string
+			if flags.IsSplitChannels() {
+				label = append(label, callLegs[ch])
+			} else if len(channels) > 1 {
+				label = append(label, fmt.Sprintf("CHANNEL %d", ch))
+			}
+			if turns != nil {
+				label = append(label, fmt.Sprintf("SPEAKER %d", turns[i]))
+			}
+			labels = append(labels, label)
+		}
+		segments = append(segments, result...)
+	}
+	t2 := time.Now()
+	progress.Done()
+
+	for _, context := range contexts {
+		context.PrintTimings()
+	}
+
+	// Interleave the call legs by time
+	if flags.IsSplitChannels() {
+		order := make([]int, len(segments))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(i, j int) bool {
+			return segments[order[i]].Start < segments[order[j]].Start
+		})
+		sorted, sortedLabels := make([]whisper.Segment, len(segments)), make([][]string, len(segments))
+		for i, j := range order {
+			sorted[i], sortedLabels[i] = segments[j], labels[j]
+			sorted[i].Num = i
+		}
+		segments, labels = sorted, sortedLabels
 	}
 
-	context.PrintTimings()
+	// Return success
+	result := &transcription{
+		context:  context,
+		segments: segments,
+		labels:   labels,
+		samples:  samples,
+		detected: context.DetectedLanguage(),
+		language: context.DetectedLanguage(),
+		decode:   t1.Sub(t0),
+		process:  t2.Sub(t1),
+	}
+	if result.language == "" {
+		result.language = context.Language()
+	}
+	return result, nil
+}
+
+// Write the transcription of the input to the output file for the input, or
+// to w
+func (transcription *transcription) write(input Input, w io.Writer, flags *Flags) error {
+	logger := flags.Logger()
+	context, segments, labels := transcription.context, transcription.segments, transcription.labels
+
+	// Prefix the text with the labels, except for CSV and TSV output which
+	// have a speaker column, and HTML output which shows them apart. When the
+	// language is detected, the language of each segment is labelled too,
+	// except for JSON output which has a language field.
+	if format := flags.GetOut(); format != "csv" && format != "tsv" {
+		for i, label := range labels {
+			if flags.GetLanguage() == "auto" && format != "json" && segments[i].Language != "" {
+				label = append(label, segments[i].Language)
+			}
+			if format == "html" {
+				labels[i] = label
+			} else if len(label) > 0 {
+				segments[i].Text = "[" + strings.Join(label, "] [") + "] " + segments[i].Text
+			}
+		}
+	}
 
 	// Print out the results
-	switch {
-	case flags.GetOut() == "srt":
-		return OutputSRT(os.Stdout, context)
-	case flags.GetOut() == "none":
+	subtitles, err := flags.GetSubtitleOptions()
+	if err != nil {
+		return err
+	}
+	write := func(w io.Writer) error {
+		switch {
+		case flags.GetOut() == "srt":
+			return OutputSRT(w, segments, subtitles)
+		case flags.GetOut() == "vtt":
+			return OutputVTT(w, segments, subtitles)
+		case flags.GetOut() == "lrc":
+			return OutputLRC(w, segments)
+		case flags.GetOut() == "csv":
+			return OutputCSV(w, context, segments, labels, ',')
+		case flags.GetOut() == "tsv":
+			return OutputCSV(w, context, segments, labels, '\t')
+		case flags.GetOut() == "html":
+			return OutputHTML(w, context, segments, labels, inputBase(input), flags.GetConfidenceMin())
+		case flags.GetOut() == "json":
+			transcript := api.NewTranscript(context, segments, transcription.samples, transcription.decode, transcription.process, flags.GetConfidenceMin())
+			transcript.Language = transcription.detected
+			return OutputJSON(w, transcript)
+		default:
+			return Output(w, context, segments, flags.IsColorize())
+		}
+	}
+	if flags.GetOut() == "none" {
 		return nil
-	default:
-		return Output(os.Stdout, context, flags.IsColorize())
 	}
+
+	// Write the results to a file when the input has an output directory,
+	// otherwise to w
+	out, err := outputPath(input, transcription.language, flags.GetModelName(), flags)
+	if err != nil {
+		return err
+	} else if out == "" {
+		return write(w)
+	}
+	if err := os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+		return err
+	}
+	fh, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	if err := write(fh); err != nil {
+		fh.Close()
+		return err
+	}
+	if err := fh.Close(); err != nil {
+		return err
+	}
+	logger.Info("wrote", "path", out)
+
+	// Return success
+	return nil
 }
 
-// Output text as SRT file
-func OutputSRT(w io.Writer, context whisper.Context) error {
-	n := 1
-	for {
-		segment, err := context.NextSegment()
-		if err == io.EOF {
-			return nil
-		} else if err != nil {
-			return err
+// Process the section of the data from the offset for the duration, or to
+// the end when the duration is zero, and return the segments. When voice
+// activity detection or interim hypotheses are enabled, the data is fed
+// through a streaming context in chunks. When report is not nil, it is
+// called with the fraction of the section processed. Processing stops when
+// the go context is cancelled.
+func process(ctx gocontext.Context, context whisper.Context, data []float32, offset, duration time.Duration, cb whisper.SegmentCallback, report func(float64), flags *Flags) ([]whisper.Segment, error) {
+	var segments []whisper.Segment
+
+	// Check the section of the audio
+	if offset < 0 || duration < 0 {
+		return nil, fmt.Errorf("-offset and -duration cannot be negative")
+	} else if offset > 0 && toSamples(offset) >= len(data) {
+		return nil, fmt.Errorf("-offset %v is beyond the end of the audio at %v", offset, toDuration(len(data)))
+	}
+
+	if !flags.IsStream() {
+		if offset > 0 {
+			flags.Logger().Debug("setting parameter", "name", "offset", "value", offset)
+			context.SetOffset(offset)
+		}
+		if duration > 0 {
+			flags.Logger().Debug("setting parameter", "name", "duration", "value", duration)
+			context.SetDuration(duration)
+		}
+		var callProgress whisper.ProgressCallback
+		if report != nil {
+			callProgress = func(percent int) {
+				report(float64(percent) / 100)
+			}
+		}
+		if err := context.ProcessContext(ctx, data, cb, callProgress); err != nil {
+			return nil, err
+		}
+		for {
+			segment, err := context.NextSegment()
+			if err == io.EOF {
+				return segments, nil
+			} else if err != nil {
+				return nil, err
+			}
+			segments = append(segments, segment)
+		}
+	}
+
+	// The streaming context processes windows of the audio, so the section
+	// is cut from the audio instead, and the segments moved to the offset
+	data = data[toSamples(offset):]
+	if duration > 0 && toSamples(duration) < len(data) {
+		data = data[:toSamples(duration)]
+	}
+
+	filter, err := flags.GetPostprocess()
+	if err != nil {
+		return nil, err
+	}
+	partial := flags.IsPartial()
+	stream, err := whisper.NewStreamingContext(context, func(segment whisper.Segment) {
+		if partial {
+			fmt.Fprint(flags.Output(), ClearLine)
 		}
-		fmt.Fprintln(w, n)
-		fmt.Fprintln(w, srtTimestamp(segment.Start), " --> ", srtTimestamp(segment.End))
-		fmt.Fprintln(w, segment.Text)
+		segment = offsetSegment(segment, offset)
+		if cb != nil {
+			cb(segment)
+		}
+		segments = append(segments, segment)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if flags.IsVAD() {
+		stream.SetVAD(vad.New(whisper.SampleRate, flags.GetVADThreshold(), flags.GetVADHangover()), func(evt vad.Event) {
+			flags.Logger().Debug("voice activity", "event", evt.Type.String(), "time", evt.Time.Truncate(time.Millisecond))
+		})
+		stream.SetMaxUtterance(flags.GetMaxUtterance())
+	}
+	stream.SetSlidingWindow(flags.GetSlidingWindow())
+	if partial {
+		stream.SetPartialCallback(func(segment whisper.Segment) {
+			for _, segment := range filter([]whisper.Segment{segment}) {
+				fmt.Fprintf(flags.Output(), "%s  ...%s", ClearLine, segment.Text)
+			}
+		}, flags.GetPartial())
+	}
+
+	// Feed the data in chunks, as it would arrive from a live source. When
+	// -simulate-stream is specified, the chunks are the frame size and are
+	// paced in real time.
+	chunk := int(vad.FrameDuration.Seconds()*whisper.SampleRate) * 10
+	frame := flags.GetSimulateStream()
+	if frame > 0 {
+		chunk = max(toSamples(frame), 1)
+	}
+	start := time.Now()
+	for i := 0; i < len(data); i += chunk {
+		j := i + chunk
+		if j > len(data) {
+			j = len(data)
+		}
+		if frame > 0 {
+			select {
+			case <-time.After(time.Until(start.Add(toDuration(i)))):
+			case <-ctx.Done():
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := stream.Feed(data[i:j]); err != nil {
+			return nil, err
+		}
+		if report != nil {
+			report(float64(j) / float64(len(data)))
+		}
+	}
+	if frame > 0 {
+		flags.Logger().Debug("simulated stream", "audio", toDuration(len(data)), "elapsed", time.Since(start).Truncate(time.Millisecond))
+	}
+	if err := stream.Flush(); err != nil {
+		return nil, err
+	}
+	if partial {
+		fmt.Fprint(flags.Output(), ClearLine)
+	}
+
+	// Return success
+	return segments, nil
+}
+
+// Move the times of a segment, its tokens and words by an offset
+func offsetSegment(segment whisper.Segment, offset time.Duration) whisper.Segment {
+	if offset == 0 {
+		return segment
+	}
+	segment.Start += offset
+	segment.End += offset
+	for i := range segment.Tokens {
+		segment.Tokens[i].Start += offset
+		segment.Tokens[i].End += offset
+	}
+	for i := range segment.Words {
+		segment.Words[i].Start += offset
+		segment.Words[i].End += offset
+	}
+	return segment
+}
+
+// Return the number of samples for a duration at the whisper sample rate
+func toSamples(t time.Duration) int {
+	return int(t.Seconds() * whisper.SampleRate)
+}
+
+// Return the duration of a number of samples at the whisper sample rate
+func toDuration(samples int) time.Duration {
+	return time.Duration(samples) * time.Second / whisper.SampleRate
+}
+
+// Output text as SRT file, with the cues shaped by the options
+func OutputSRT(w io.Writer, segments []whisper.Segment, opts SubtitleOptions) error {
+	for n, cue := range toCues(segments, opts) {
+		fmt.Fprintln(w, n+1)
+		fmt.Fprintf(w, "%s --> %s\n", srtTimestamp(cue.Start), srtTimestamp(cue.End))
+		fmt.Fprintln(w, cue.Text)
 		fmt.Fprintln(w, "")
-		n++
 	}
+	return nil
 }
 
-// Output text to terminal
-func Output(w io.Writer, context whisper.Context, colorize bool) error {
-	for {
-		segment, err := context.NextSegment()
-		if err == io.EOF {
-			return nil
-		} else if err != nil {
-			return err
+// Output text as WebVTT file, with the cues shaped by the options
+func OutputVTT(w io.Writer, segments []whisper.Segment, opts SubtitleOptions) error {
+	fmt.Fprintln(w, "WEBVTT")
+	fmt.Fprintln(w, "")
+	for _, cue := range toCues(segments, opts) {
+		fmt.Fprintln(w, vttTimestamp(cue.Start), "-->", vttTimestamp(cue.End))
+		fmt.Fprintln(w, cue.Text)
+		fmt.Fprintln(w, "")
+	}
+	return nil
+}
+
+// Output text as LRC lyrics, with a line per segment. When word timestamps
+// are available, each word is preceded by an inline timestamp, as in
+// enhanced LRC files.
+func OutputLRC(w io.Writer, segments []whisper.Segment) error {
+	for _, segment := range segments {
+		if len(segment.Words) == 0 {
+			fmt.Fprintf(w, "[%s]%s\n", lrcTimestamp(segment.Start), segment.Text)
+			continue
+		}
+		fmt.Fprintf(w, "[%s]", lrcTimestamp(segment.Start))
+		for _, word := range segment.Words {
+			fmt.Fprintf(w, " <%s> %s", lrcTimestamp(word.Start), word.Text)
 		}
+		fmt.Fprintf(w, " <%s>\n", lrcTimestamp(segment.End))
+	}
+	return nil
+}
+
+// Return the subtitle cues for segments, one per word when word
+// timestamps are available. When the maximum characters of a line are set,
+// the words of each segment are wrapped into cues of up to the maximum
+// lines instead. The times of the cues are scaled and offset.
+func toCues(segments []whisper.Segment, opts SubtitleOptions) []whisper.Word {
+	var result []whisper.Word
+	maxLines := opts.MaxLines
+	if maxLines <= 0 {
+		maxLines = math.MaxInt
+	}
+	for _, segment := range segments {
+		if opts.MaxLineChars > 0 {
+			result = append(result, shapeCues(segment, opts.MaxLineChars, maxLines)...)
+		} else if len(segment.Words) > 0 {
+			result = append(result, segment.Words...)
+		} else {
+			result = append(result, whisper.Word{Text: segment.Text, Start: segment.Start, End: segment.End})
+		}
+	}
+
+	// Scale and offset the times, dropping cues which end before the start
+	if opts.Offset == 0 && (opts.Scale == 0 || opts.Scale == 1) {
+		return result
+	}
+	cues := result[:0]
+	for _, cue := range result {
+		cue.Start, cue.End = opts.cueTime(cue.Start), opts.cueTime(cue.End)
+		if cue.End > 0 {
+			cues = append(cues, cue)
+		}
+	}
+	return cues
+}
+
+// Output text to terminal
+func Output(w io.Writer, context whisper.Context, segments []whisper.Segment, colorize bool) error {
+	for _, segment := range segments {
 		fmt.Fprintf(w, "[%6s->%6s]", segment.Start.Truncate(time.Millisecond), segment.End.Truncate(time.Millisecond))
 		if colorize {
 			for _, token := range segment.Tokens {
@@ -124,6 +591,31 @@ func Output(w io.Writer, context whisper.Context, colorize bool) error {
 			fmt.Fprintln(w, " ", segment.Text)
 		}
 	}
+	return nil
+}
+
+// Return the speaker number for each segment, starting with speaker 1 and
+// alternating between speakers 1 and 2 on each speaker turn
+func speakers(segments []whisper.Segment) []int {
+	result := make([]int, len(segments))
+	speaker := 1
+	for i, segment := range segments {
+		result[i] = speaker
+		if segment.SpeakerTurnNext {
+			speaker = 3 - speaker
+		}
+	}
+	return result
+}
+
+// Return lrcTimestamp, in minutes, seconds and hundredths of a second
+func lrcTimestamp(t time.Duration) string {
+	return fmt.Sprintf("%02d:%02d.%02d", t/time.Minute, (t%time.Minute)/time.Second, (t%time.Second)/(10*time.Millisecond))
+}
+
+// Return vttTimestamp
+func vttTimestamp(t time.Duration) string {
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", t/time.Hour, (t%time.Hour)/time.Minute, (t%time.Minute)/time.Second, (t%time.Second)/time.Millisecond)
 }
 
 // Return srtTimestamp
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"time"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// progress reports how much of a file has been transcribed, as a bar when
+// the output is a terminal, or as log lines with -progress
+type progress struct {
+	flags   *Flags
+	path    string
+	audio   time.Duration // Duration of the audio, or zero when not known
+	start   time.Time
+	bar     bool // Draw a bar, rather than logging lines
+	percent int  // Last percent reported
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// Width of the progress bar, in characters
+	progressWidth = 30
+
+	// Shortest interval between log lines of the progress of a file whose
+	// duration is not known
+	progressInterval = 10 * time.Second
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Return the progress of transcribing the audio of a file, or nil when it
+// is not reported. The bar is drawn when the output is a terminal, unless
+// other output is written while processing, or files are processed in
+// parallel.
+func newProgress(flags *Flags, path string, audio time.Duration) *progress {
+	progress := &progress{flags: flags, path: path, audio: audio, start: time.Now(), percent: -1}
+	switch {
+	case flags.IsProgress():
+		return progress
+	case !isTerminal(flags.Output()), flags.IsTokens(), flags.IsPartial(), flags.GetParallel() > 1:
+		return nil
+	default:
+		progress.bar = true
+		return progress
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Update reports the duration of audio transcribed so far
+func (progress *progress) Update(done time.Duration) {
+	if progress == nil || done <= 0 {
+		return
+	}
+	elapsed := time.Since(progress.start)
+	percent := -1
+	if progress.audio > 0 {
+		done = min(done, progress.audio)
+		percent = int(done * 100 / progress.audio)
+	}
+
+	// Log a line each percent, or at an interval when the duration is not
+	// known
+	if !progress.bar {
+		if percent < 0 {
+			if elapsed < time.Duration(progress.percent+1)*progressInterval {
+				return
+			}
+			progress.percent = int(elapsed / progressInterval)
+		} else if percent <= progress.percent {
+			return
+		} else {
+			progress.percent = percent
+		}
+		args := []any{"path", progress.path, "audio", done.Truncate(time.Millisecond), "elapsed", elapsed.Truncate(time.Millisecond), "rtf", rtf(elapsed, done)}
+		if percent >= 0 {
+			args = append(args, "percent", percent, "eta", eta(elapsed, done, progress.audio).Truncate(time.Second))
+		}
+		progress.flags.Logger().Info("progress", args...)
+		return
+	}
+
+	// Draw the bar
+	var str strings.Builder
+	str.WriteString(ClearLine)
+	if percent >= 0 {
+		n := percent * progressWidth / 100
+		fmt.Fprintf(&str, "[%s%s] %3d%%  ", strings.Repeat("#", n), strings.Repeat("-", progressWidth-n), percent)
+	} else {
+		fmt.Fprintf(&str, "%s  ", clock(done))
+	}
+	fmt.Fprintf(&str, "elapsed %s", clock(elapsed))
+	if percent >= 0 {
+		fmt.Fprintf(&str, "  eta %s", clock(eta(elapsed, done, progress.audio)))
+	}
+	fmt.Fprintf(&str, "  %.2fx real time", rtf(elapsed, done))
+	fmt.Fprint(progress.flags.Output(), str.String())
+}
+
+// Done clears the bar
+func (progress *progress) Done() {
+	if progress != nil && progress.bar {
+		fmt.Fprint(progress.flags.Output(), ClearLine)
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Return true if the writer is a terminal
+func isTerminal(w io.Writer) bool {
+	if fh, ok := w.(*os.File); !ok {
+		return false
+	} else if info, err := fh.Stat(); err != nil {
+		return false
+	} else {
+		return info.Mode()&os.ModeCharDevice != 0
+	}
+}
+
+// Return the real-time factor, the processing time for each second of audio
+func rtf(elapsed, done time.Duration) float64 {
+	return math.Round(elapsed.Seconds()/done.Seconds()*100) / 100
+}
+
+// Return the estimated time to transcribe the rest of the audio
+func eta(elapsed, done, audio time.Duration) time.Duration {
+	return time.Duration(float64(elapsed) * float64(audio-done) / float64(done))
+}
+
+// Format a duration as minutes and seconds, or hours, minutes and seconds
+func clock(t time.Duration) string {
+	t = t.Round(time.Second)
+	if h := t / time.Hour; h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, t/time.Minute%60, t/time.Second%60)
+	}
+	return fmt.Sprintf("%02d:%02d", t/time.Minute, t/time.Second%60)
+}
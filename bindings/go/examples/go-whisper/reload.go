@@ -0,0 +1,54 @@
+package main
+
+import (
+	gocontext "context"
+	"maps"
+	"os"
+	"os/signal"
+	"syscall"
+
+	// Package imports
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// ReloadOnHangup reloads the model of each pool from its path when the
+// process receives SIGHUP, until the go context is cancelled, so that a
+// model file replaced on disk is used for new sessions without a restart.
+// Sessions in progress keep the previous model until they end.
+func ReloadOnHangup(ctx gocontext.Context, flags *Flags, pools map[string]whisper.Pool) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	pools = maps.Clone(pools)
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-signals:
+				for name, pool := range pools {
+					reload(flags, name, pool, pool.Path())
+				}
+			}
+		}
+	}()
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Reload the model of a pool from a path, and log the result
+func reload(flags *Flags, name string, pool whisper.Pool, path string) error {
+	flags.Logger().Info("reloading model", "model", name, "path", path)
+	if err := pool.Reload(path); err != nil {
+		flags.Logger().Error("unable to reload model", "model", name, "path", path, "error", err)
+		return err
+	}
+	flags.Logger().Info("reloaded model", append([]any{"model", name, "path", path}, modelInfo(pool.Info())...)...)
+
+	// Return success
+	return nil
+}
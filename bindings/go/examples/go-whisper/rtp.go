@@ -0,0 +1,303 @@
+package main
+
+import (
+	gocontext "context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	// Package imports
+	g711 "github.com/ggerganov/whisper.cpp/bindings/go/pkg/g711"
+	opus "github.com/ggerganov/whisper.cpp/bindings/go/pkg/opus"
+	record "github.com/ggerganov/whisper.cpp/bindings/go/pkg/record"
+	resample "github.com/ggerganov/whisper.cpp/bindings/go/pkg/resample"
+	rtp "github.com/ggerganov/whisper.cpp/bindings/go/pkg/rtp"
+	vad "github.com/ggerganov/whisper.cpp/bindings/go/pkg/vad"
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// rtpReceiver decodes the payloads of a single RTP stream and feeds them
+// into a streaming context
+type rtpReceiver struct {
+	flags   *Flags
+	stream  whisper.StreamingContext
+	jitter  *rtp.JitterBuffer
+	opus    *opus.Decoder
+	record  *record.Recorder // Recording of the stream, when -record-dir is set
+	observe observers        // Observers notified of segments and when the stream ends
+	ssrc    uint32
+	samples int       // Number of samples fed into the current stream
+	next    uint32    // Timestamp expected for the next packet
+	timed   bool      // True once the next timestamp is known
+	active  bool      // True once a packet of the stream has been received
+	last    time.Time // Time the last packet was received
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// Packets are assumed to carry this duration of audio when sizing the
+	// jitter buffer
+	rtpPacketTime = 20 * time.Millisecond
+
+	// Longer gaps in the timestamps, for example after a pause or a clock
+	// jump, are not filled with silence
+	rtpMaxGap = time.Second
+
+	// Interval at which the receiver checks for cancellation
+	rtpReadTimeout = 250 * time.Millisecond
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// ServeRTP receives RTP packets on the -listen-rtp address, and prints
+// segments as they are transcribed. A single stream is transcribed at once,
+// and packets from a new source end the current stream. Receiving stops
+// when the go context is cancelled.
+func ServeRTP(ctx gocontext.Context, model whisper.Model, flags *Flags) error {
+	context, err := model.NewContext()
+	if err != nil {
+		return err
+	}
+	if err := flags.SetParams(context); err != nil {
+		return err
+	}
+	filter, err := flags.GetPostprocess()
+	if err != nil {
+		return err
+	}
+	observers, err := newObservers(flags)
+	if err != nil {
+		return err
+	}
+	defer observers.Close()
+	var receiver *rtpReceiver
+	stream, err := whisper.NewStreamingContext(context, func(segment whisper.Segment) {
+		if flags.IsPartial() {
+			fmt.Fprint(flags.Output(), ClearLine)
+		}
+		segments := filter([]whisper.Segment{segment})
+		for _, segment := range segments {
+			observers.Segment("rtp", receiver.name(), context, segment)
+		}
+		Output(os.Stdout, context, segments, flags.IsColorize())
+	})
+	if err != nil {
+		return err
+	}
+	if flags.IsVAD() {
+		stream.SetVAD(vad.New(whisper.SampleRate, flags.GetVADThreshold(), flags.GetVADHangover()), nil)
+		stream.SetMaxUtterance(flags.GetMaxUtterance())
+	}
+	stream.SetSlidingWindow(flags.GetSlidingWindow())
+	if flags.IsPartial() {
+		stream.SetPartialCallback(func(segment whisper.Segment) {
+			for _, segment := range filter([]whisper.Segment{segment}) {
+				fmt.Fprintf(flags.Output(), "%s  ...%s", ClearLine, segment.Text)
+			}
+		}, flags.GetPartial())
+	}
+	receiver = newRTPReceiver(stream, flags)
+	receiver.observe = observers
+	defer receiver.Close()
+
+	conn, err := net.ListenPacket("udp", flags.GetListenRTP())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	flags.Logger().Info("receiving RTP", "addr", conn.LocalAddr().String(), "model", flags.GetModelName())
+
+	buf := make([]byte, 1<<16)
+	for ctx.Err() == nil {
+		conn.SetReadDeadline(time.Now().Add(rtpReadTimeout))
+		n, _, err := conn.ReadFrom(buf)
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			if timeout := flags.GetIdleTimeout(); timeout > 0 && receiver.Idle() > timeout {
+				flags.Logger().Info("stream idle", "ssrc", fmt.Sprintf("0x%08x", receiver.ssrc), "timeout", timeout)
+				if err := receiver.Flush(); err != nil {
+					return err
+				}
+			}
+			continue
+		} else if err != nil {
+			return err
+		}
+		packet, err := rtp.Parse(append([]byte(nil), buf[:n]...))
+		if err != nil {
+			continue
+		}
+		if err := receiver.Push(packet); err != nil {
+			return err
+		}
+	}
+
+	// Transcribe the remaining audio
+	return receiver.Flush()
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Return a receiver which feeds the decoded audio into a streaming context
+func newRTPReceiver(stream whisper.StreamingContext, flags *Flags) *rtpReceiver {
+	return &rtpReceiver{
+		flags:  flags,
+		stream: stream,
+		jitter: rtp.NewJitterBuffer(int(flags.GetRTPJitter() / rtpPacketTime)),
+	}
+}
+
+func (receiver *rtpReceiver) Close() error {
+	if receiver.record != nil {
+		receiver.record.Close()
+		receiver.record = nil
+	}
+	if receiver.opus != nil {
+		return receiver.opus.Close()
+	}
+	return nil
+}
+
+// Push a packet through the jitter buffer, and feed the packets which are
+// ready. A packet from a new source flushes the current stream first.
+func (receiver *rtpReceiver) Push(packet *rtp.Packet) error {
+	if receiver.active && packet.SSRC != receiver.ssrc {
+		if err := receiver.Flush(); err != nil {
+			return err
+		}
+	}
+	if !receiver.active {
+		receiver.flags.Logger().Info("stream started", "ssrc", fmt.Sprintf("0x%08x", packet.SSRC))
+		receiver.ssrc = packet.SSRC
+		receiver.active = true
+		if dir := receiver.flags.GetRecordDir(); dir != "" {
+			recorder, err := record.Create(dir, receiver.name())
+			if err != nil {
+				return err
+			}
+			receiver.record = recorder
+		}
+	}
+	receiver.last = time.Now()
+	for _, packet := range receiver.jitter.Push(packet) {
+		if err := receiver.feed(packet); err != nil {
+			return err
+		}
+	}
+
+	// Return success
+	return nil
+}
+
+// Return the time since the last packet of the current stream was received,
+// or zero when there is no current stream
+func (receiver *rtpReceiver) Idle() time.Duration {
+	if !receiver.active {
+		return 0
+	}
+	return time.Since(receiver.last)
+}
+
+// Feed the packets remaining in the jitter buffer and transcribe the
+// buffered audio
+func (receiver *rtpReceiver) Flush() error {
+	if !receiver.active {
+		return nil
+	}
+	for _, packet := range receiver.jitter.Flush() {
+		if err := receiver.feed(packet); err != nil {
+			return err
+		}
+	}
+	if err := receiver.stream.Flush(); err != nil {
+		return err
+	}
+	receiver.flags.Logger().Info("stream ended", "ssrc", fmt.Sprintf("0x%08x", receiver.ssrc), "lost", receiver.jitter.Lost(), "late", receiver.jitter.Late())
+	if receiver.record != nil {
+		if err := receiver.record.Close(); err != nil {
+			return err
+		}
+		receiver.flags.Logger().Info("recorded stream", "path", receiver.record.Path())
+		receiver.record = nil
+	}
+	receiver.observe.End("rtp", receiver.name(), receiver.stream.Context(), receiver.samples)
+	receiver.jitter.Reset()
+	receiver.samples = 0
+	receiver.active = false
+	receiver.timed = false
+
+	// Return success
+	return nil
+}
+
+// Decode a packet and feed it into the stream, filling gaps in the
+// timestamps with silence
+func (receiver *rtpReceiver) feed(packet *rtp.Packet) error {
+	data, clock, err := receiver.decode(packet)
+	if err != nil {
+		receiver.flags.Logger().Warn("unable to decode packet", "packet", packet.String(), "error", err)
+		return nil
+	} else if data == nil {
+		return nil
+	}
+
+	// Fill any gap since the previous packet
+	if gap := int32(packet.Timestamp - receiver.next); receiver.timed && gap > 0 && gap < int32(rtpMaxGap.Seconds()*float64(clock)) {
+		if err := receiver.write(make([]float32, int(gap)*whisper.SampleRate/clock)); err != nil {
+			return err
+		}
+	}
+	receiver.next = packet.Timestamp + uint32(len(data)*clock/whisper.SampleRate)
+	receiver.timed = true
+
+	// Feed the samples
+	return receiver.write(data)
+}
+
+// Record samples, when -record-dir is set, and feed them into the stream
+func (receiver *rtpReceiver) write(data []float32) error {
+	if receiver.record != nil {
+		if err := receiver.record.Write(data); err != nil {
+			return err
+		}
+	}
+	receiver.samples += len(data)
+	return receiver.stream.Feed(data)
+}
+
+// Return the name of the current stream
+func (receiver *rtpReceiver) name() string {
+	return fmt.Sprintf("rtp-0x%08x", receiver.ssrc)
+}
+
+// Decode a payload to samples at the whisper sample rate, and return the
+// RTP clock rate of the payload type. Unknown payload types return no data.
+func (receiver *rtpReceiver) decode(packet *rtp.Packet) ([]float32, int, error) {
+	switch packet.PayloadType {
+	case rtp.PayloadPCMU:
+		// There is no state between packets, so linear resampling is used
+		return resample.Linear(g711.PCMUToFloat32(packet.Payload), 8000, whisper.SampleRate), 8000, nil
+	case rtp.PayloadPCMA:
+		return resample.Linear(g711.PCMAToFloat32(packet.Payload), 8000, whisper.SampleRate), 8000, nil
+	case uint8(receiver.flags.GetRTPOpusPayloadType()):
+		if receiver.opus == nil {
+			dec, err := opus.NewDecoder(whisper.SampleRate, 1)
+			if err != nil {
+				return nil, 0, err
+			}
+			receiver.opus = dec
+		}
+		data, err := receiver.opus.Decode(packet.Payload)
+		return data, opus.OpusRate, err
+	default:
+		return nil, 0, nil
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	gocontext "context"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	// Package imports
+	api "github.com/ggerganov/whisper.cpp/bindings/go/pkg/server"
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Serve the HTTP transcription endpoints on the -listen address until the
+// go context is cancelled, then release the models. Requests select a model
+// by name, which is set with name=path, or is otherwise the model filename
+// without the "ggml-" prefix and extension, for example "base.en". The
+// models are reloaded on SIGHUP.
+func Serve(ctx gocontext.Context, flags *Flags) error {
+	pools := make(map[string]whisper.Pool)
+	defer func() {
+		for _, pool := range pools {
+			pool.Close()
+		}
+	}()
+	names := flags.GetModelNames()
+	for i, path := range flags.GetModels() {
+		if _, exists := pools[names[i]]; exists {
+			return fmt.Errorf("duplicate model name: %q", names[i])
+		}
+		pool, err := newPool(flags, names[i], path, whisper.PoolWait)
+		if err != nil {
+			return err
+		}
+		pools[names[i]] = pool
+	}
+	ReloadOnHangup(ctx, flags, pools)
+	server, err := NewServer(flags, pools)
+	if err != nil {
+		return err
+	}
+	return ListenAndServe(ctx, flags, flags.GetListen(), server.Handler())
+}
+
+// NewServer returns the HTTP transcription endpoints for the pools, with
+// the parameters, post-processing and limits set by the flags. The first
+// model given with the -model flag is the default.
+func NewServer(flags *Flags, pools map[string]whisper.Pool) (*api.Server, error) {
+	filter, err := flags.GetPostprocess()
+	if err != nil {
+		return nil, err
+	}
+	return api.New(api.Config{
+		Pools:  pools,
+		Model:  flags.GetModelName(),
+		Params: flags.SetParams,
+		Decode: func(r io.ReadSeeker) ([]float32, error) {
+			return Decode(r, flags)
+		},
+		Process: func(ctx gocontext.Context, context whisper.Context, data []float32) ([]whisper.Segment, error) {
+			segments, err := process(ctx, context, data, flags.GetOffset(), flags.GetDuration(), nil, nil, flags)
+			if err != nil {
+				return nil, err
+			}
+			if minConfidence := flags.GetConfidenceMin(); minConfidence > 0 && flags.IsConfidenceDrop() {
+				segments = markConfidence(context, segments, minConfidence, true)
+			}
+			return segments, nil
+		},
+		Filter:         filter,
+		Confidence:     flags.GetConfidenceMin(),
+		Tokens:         flags.GetAuthTokens(),
+		Origins:        flags.GetCORSOrigins(),
+		Admission:      NewAdmission(flags),
+		MaxBytes:       int64(flags.GetMaxBytes()),
+		MaxAudio:       flags.GetMaxAudio(),
+		MaxSessionTime: flags.GetMaxSessionTime(),
+		Logger:         flags.Logger(),
+	})
+}
+
+// ListenAndServe serves requests on the address until an error occurs or
+// the go context is cancelled. On cancellation, the server stops accepting
+// connections and waits for in-flight transcriptions to complete, for up to
+// the -shutdown-timeout duration. Requests are served over TLS when a
+// certificate is set with the -tls-cert and -tls-key flags, or
+// -tls-self-signed is specified.
+func ListenAndServe(ctx gocontext.Context, flags *Flags, addr string, handler http.Handler) error {
+	config, err := TLSConfig(flags)
+	if err != nil {
+		return err
+	}
+	httpserver := &http.Server{
+		Addr:      addr,
+		Handler:   handler,
+		TLSConfig: config,
+	}
+
+	// Serve requests in the background
+	errs := make(chan error, 1)
+	go func() {
+		if config != nil {
+			flags.Logger().Info("listening", "addr", addr, "tls", true)
+			errs <- httpserver.ListenAndServeTLS("", "")
+		} else {
+			flags.Logger().Info("listening", "addr", addr, "tls", false)
+			errs <- httpserver.ListenAndServe()
+		}
+	}()
+
+	// Wait for an error or cancellation
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	// Drain in-flight requests
+	flags.Logger().Info("shutting down", "timeout", flags.GetShutdownTimeout())
+	shutdown, cancel := gocontext.WithTimeout(gocontext.Background(), flags.GetShutdownTimeout())
+	defer cancel()
+	if err := httpserver.Shutdown(shutdown); err != nil {
+		httpserver.Close()
+		return err
+	}
+
+	// Return success
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Return a pool of -pool-size contexts for a model, and log the metadata of
+// the model
+func newPool(flags *Flags, name, path string, policy whisper.PoolPolicy) (whisper.Pool, error) {
+	params := flags.GetContextParams()
+	newPool, shared := whisper.NewPoolWithParams, flags.IsSharedModel() && params.OpenVINODevice == ""
+	if !flags.IsSharedModel() {
+		newPool = whisper.NewPoolWithCopies
+	}
+	pool, err := newPool(path, params, flags.GetPoolSize(), policy)
+	if err != nil {
+		return nil, err
+	}
+	flags.Logger().Info("loaded model", append([]any{"model", name, "path", path, "pool_size", pool.Size(), "shared", shared}, modelInfo(pool.Info())...)...)
+
+	// Return success
+	return pool, nil
+}
+
+// Return the metadata of a model as logging attributes
+func modelInfo(info whisper.ModelInfo) []any {
+	return []any{
+		"type", info.Type,
+		"quantization", info.Quantization,
+		"multilingual", info.Multilingual,
+		"vocab", info.Vocab,
+		"mels", info.Mels,
+	}
+}
+
+// Return the name and path of a -model flag value, which is either name=path
+// or a path. The name of a path is derived with modelName.
+func splitModel(str string) (string, string) {
+	if i := strings.Index(str, "="); i > 0 && !strings.ContainsAny(str[:i], `/\`) {
+		return str[:i], str[i+1:]
+	}
+	return modelName(str), str
+}
+
+// Return the model name for a model path, for example "base.en" for
+// "models/ggml-base.en.bin"
+func modelName(path string) string {
+	name := filepath.Base(path)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	return strings.TrimPrefix(name, "ggml-")
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	// Package imports
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// SubtitleOptions shape the cues of SRT and VTT output
+type SubtitleOptions struct {
+	MaxLineChars int           // Maximum characters in a line, or zero to not wrap lines
+	MaxLines     int           // Maximum lines in a cue when lines are wrapped, or zero for no limit
+	Offset       time.Duration // Added to the times of the cues, after they are scaled
+	Scale        float64       // Factor the times of the cues are multiplied by, or zero for none
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// Default maximum number of lines of a subtitle cue
+	defaultMaxLines = 2
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// GetSubtitleOptions returns the options set by the -max-line-chars,
+// -max-lines, -offset-timestamps and -timestamp-scale flags
+func (flags *Flags) GetSubtitleOptions() (SubtitleOptions, error) {
+	scale := flags.Lookup("timestamp-scale").Value.(flag.Getter).Get().(float64)
+	if scale <= 0 {
+		return SubtitleOptions{}, fmt.Errorf("-timestamp-scale must be greater than zero")
+	}
+	return SubtitleOptions{
+		MaxLineChars: int(flags.Lookup("max-line-chars").Value.(flag.Getter).Get().(uint)),
+		MaxLines:     int(flags.Lookup("max-lines").Value.(flag.Getter).Get().(uint)),
+		Offset:       flags.Lookup("offset-timestamps").Value.(flag.Getter).Get().(time.Duration),
+		Scale:        scale,
+	}, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Return the time of a cue for a time in the audio, scaled and then offset
+// by the options, and no earlier than zero
+func (opts SubtitleOptions) cueTime(t time.Duration) time.Duration {
+	if opts.Scale > 0 && opts.Scale != 1 {
+		t = time.Duration(math.Round(float64(t) * opts.Scale))
+	}
+	return max(t+opts.Offset, 0)
+}
+
+// Return the subtitle cues for a segment, with the words of the segment
+// wrapped into lines of at most maxChars characters and cues of at most
+// maxLines lines. A word longer than a line is not broken. The times of a
+// cue are those of its first and last word, which, without word timestamps,
+// are interpolated over the segment by the length of the text before them.
+func shapeCues(segment whisper.Segment, maxChars, maxLines int) []whisper.Word {
+	var result []whisper.Word
+	var lines []string
+	var line string
+	var cue whisper.Word
+	for i, word := range segmentWords(segment) {
+		if i == 0 {
+			cue.Start = word.Start
+		}
+
+		// Start a new line when the word does not fit, and a new cue when
+		// the cue is full
+		if line != "" && utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word.Text) > maxChars {
+			lines = append(lines, line)
+			line = ""
+			if len(lines) >= maxLines {
+				cue.Text = strings.Join(lines, "\n")
+				result = append(result, cue)
+				cue = whisper.Word{Start: word.Start}
+				lines = nil
+			}
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word.Text
+		cue.End = word.End
+	}
+	if line != "" {
+		cue.Text = strings.Join(append(lines, line), "\n")
+		result = append(result, cue)
+	}
+	return result
+}
+
+// Return the words of a segment, from its word timestamps, or otherwise
+// with the times interpolated over the segment
+func segmentWords(segment whisper.Segment) []whisper.Word {
+	var result []whisper.Word
+	if len(segment.Words) > 0 {
+		for _, word := range segment.Words {
+			if word.Text = strings.TrimSpace(word.Text); word.Text != "" {
+				result = append(result, word)
+			}
+		}
+		return result
+	}
+	fields := strings.Fields(segment.Text)
+	total := utf8.RuneCountInString(strings.Join(fields, " "))
+	at := func(chars int) time.Duration {
+		if total == 0 {
+			return segment.Start
+		}
+		return segment.Start + (segment.End-segment.Start)*time.Duration(chars)/time.Duration(total)
+	}
+	chars := 0
+	for _, field := range fields {
+		n := utf8.RuneCountInString(field)
+		result = append(result, whisper.Word{Text: field, Start: at(chars), End: at(chars + n)})
+		chars += n + 1
+	}
+	return result
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"time"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// Validity of a generated self-signed certificate
+	selfSignedValidity = 24 * time.Hour
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// TLSConfig returns the TLS configuration for the servers, or nil when TLS
+// is not enabled. The certificate is loaded from the -tls-cert and -tls-key
+// flags, or generated when -tls-self-signed is specified.
+func TLSConfig(flags *Flags) (*tls.Config, error) {
+	var cert tls.Certificate
+	var err error
+	switch {
+	case flags.GetTLSCert() != "" || flags.GetTLSKey() != "":
+		if flags.GetTLSCert() == "" || flags.GetTLSKey() == "" {
+			return nil, errors.New("both -tls-cert and -tls-key are required")
+		}
+		cert, err = tls.LoadX509KeyPair(flags.GetTLSCert(), flags.GetTLSKey())
+	case flags.IsTLSSelfSigned():
+		flags.Logger().Warn("generating a self-signed certificate", "validity", selfSignedValidity)
+		cert, err = selfSignedCert()
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Return success
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Return a self-signed certificate for localhost and the hostname
+func selfSignedCert() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "go-whisper"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(selfSignedValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		template.DNSNames = append(template.DNSNames, hostname)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	// Return success
+	return tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+	}, nil
+}
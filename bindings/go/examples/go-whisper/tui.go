@@ -0,0 +1,374 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// tui draws a live transcript of the microphone in the terminal, with the
+// audio level, the real-time factor and statistics of the session, and reads
+// keys to pause, resume and quit
+type tui struct {
+	sync.Mutex
+	w      io.Writer
+	stty   string // Terminal settings to restore
+	stdout bool   // Segments are written to standard output as they arrive
+	keys   chan byte
+	done   chan struct{}
+	wg     sync.WaitGroup
+
+	// The session
+	start    time.Time
+	lines    []string // Transcript
+	partial  string   // Interim hypothesis
+	level    float64  // Level of the last audio read, in dBFS
+	audio    time.Duration
+	fed      time.Duration // Audio which is transcribed
+	busy     time.Duration // Time spent transcribing
+	segments int
+	words    int
+	overflow int
+	paused   bool
+	rows     int
+	cols     int
+}
+
+// tuiAction is the action of a key
+type tuiAction int
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	tuiNone tuiAction = iota
+	tuiPause
+	tuiResume
+	tuiQuit
+)
+
+const (
+	// Interval between redraws of the terminal
+	tuiInterval = 100 * time.Millisecond
+
+	// Width of the level meter in characters, and the level at its left end
+	tuiMeterWidth = 30
+	tuiMeterFloor = -60
+
+	// Lines of the terminal used for the header and footer
+	tuiHeader = 4
+	tuiFooter = 1
+)
+
+const (
+	altScreen  = "\033[?1049h\033[?25l" // switch to the alternate screen and hide the cursor
+	mainScreen = "\033[?25h\033[?1049l" // show the cursor and switch back to the main screen
+	homeScreen = "\033[H\033[2J"        // move the cursor to the top left and clear the screen
+)
+
+var (
+	errNoTerminal = errors.New("-tui requires a terminal")
+	errNoStty     = errors.New("-tui requires the stty command, which is not available on this platform")
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Return the terminal UI of -tui, or nil when it is not used. The terminal is
+// put into cbreak mode with stty, so that keys are read as they are pressed,
+// and the UI is drawn on the alternate screen until it is closed. Segments
+// are also written to standard output as they arrive when it is not the
+// terminal, and otherwise when the UI is closed. The UI is drawn without a
+// terminal UI library to avoid the dependency, so it is not available where
+// there is no stty, such as on Windows.
+func newTUI(flags *Flags) (*tui, error) {
+	if !flags.IsTUI() {
+		return nil, nil
+	} else if !isTerminal(flags.Output()) || !isTerminal(os.Stdin) {
+		return nil, errNoTerminal
+	} else if _, err := exec.LookPath("stty"); err != nil {
+		return nil, errNoStty
+	}
+	stty, err := runStty("-g")
+	if err != nil {
+		return nil, err
+	} else if _, err := runStty("-icanon", "-echo", "min", "1"); err != nil {
+		return nil, err
+	}
+	tui := &tui{
+		w:      flags.Output(),
+		stty:   strings.TrimSpace(stty),
+		stdout: !isTerminal(os.Stdout),
+		keys:   make(chan byte, 16),
+		done:   make(chan struct{}),
+		start:  time.Now(),
+		level:  tuiMeterFloor,
+	}
+	tui.resize()
+	fmt.Fprint(tui.w, altScreen)
+
+	// Read keys, which ends when the process exits
+	go func() {
+		r := bufio.NewReader(os.Stdin)
+		for {
+			key, err := r.ReadByte()
+			if err != nil {
+				return
+			}
+			tui.keys <- key
+		}
+	}()
+
+	// Redraw at an interval, and query the size of the terminal each second
+	tui.wg.Add(1)
+	go func() {
+		defer tui.wg.Done()
+		ticker := time.NewTicker(tuiInterval)
+		defer ticker.Stop()
+		for n := 1; ; n++ {
+			select {
+			case <-ticker.C:
+				if n%int(time.Second/tuiInterval) == 0 {
+					tui.resize()
+				}
+				tui.draw()
+			case <-tui.done:
+				return
+			}
+		}
+	}()
+
+	// Return success
+	return tui, nil
+}
+
+// Close restores the terminal, and writes the transcript to standard output
+// when it was not written as the segments arrived
+func (tui *tui) Close() error {
+	if tui == nil {
+		return nil
+	}
+	close(tui.done)
+	tui.wg.Wait()
+	fmt.Fprint(tui.w, mainScreen)
+	if !tui.stdout {
+		for _, line := range tui.lines {
+			fmt.Fprintln(os.Stdout, line)
+		}
+	}
+	_, err := runStty(tui.stty)
+	return err
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Poll returns the action of a key pressed since the last poll: space or p
+// to pause and resume, and q to quit
+func (tui *tui) Poll() tuiAction {
+	if tui == nil {
+		return tuiNone
+	}
+	for {
+		select {
+		case key := <-tui.keys:
+			switch key {
+			case ' ', 'p', 'P':
+				tui.Lock()
+				tui.paused = !tui.paused
+				paused := tui.paused
+				tui.Unlock()
+				tui.draw()
+				if paused {
+					return tuiPause
+				}
+				return tuiResume
+			case 'q', 'Q':
+				return tuiQuit
+			}
+		default:
+			return tuiNone
+		}
+	}
+}
+
+// Paused returns true when the transcription is paused
+func (tui *tui) Paused() bool {
+	if tui == nil {
+		return false
+	}
+	tui.Lock()
+	defer tui.Unlock()
+	return tui.paused
+}
+
+// Audio sets the level from audio read from the device
+func (tui *tui) Audio(data []float32) {
+	if tui == nil || len(data) == 0 {
+		return
+	}
+	var sum float64
+	for _, v := range data {
+		sum += float64(v) * float64(v)
+	}
+	tui.Lock()
+	defer tui.Unlock()
+	tui.audio += toDuration(len(data))
+	tui.level = max(20*math.Log10(math.Sqrt(sum/float64(len(data)))), tuiMeterFloor)
+}
+
+// Fed adds audio which is transcribed, and the time it took
+func (tui *tui) Fed(audio, elapsed time.Duration) {
+	if tui == nil {
+		return
+	}
+	tui.Lock()
+	defer tui.Unlock()
+	tui.fed += audio
+	tui.busy += elapsed
+}
+
+// Overflow counts audio dropped by the device when transcription falls
+// behind
+func (tui *tui) Overflow() {
+	if tui == nil {
+		return
+	}
+	tui.Lock()
+	defer tui.Unlock()
+	tui.overflow++
+}
+
+// Partial sets the interim hypothesis
+func (tui *tui) Partial(text string) {
+	if tui == nil {
+		return
+	}
+	tui.Lock()
+	defer tui.Unlock()
+	tui.partial = strings.TrimSpace(text)
+}
+
+// Segment adds text output of segments to the transcript, and clears the
+// interim hypothesis
+func (tui *tui) Segment(text string, words int) {
+	if tui == nil {
+		return
+	}
+	tui.Lock()
+	defer tui.Unlock()
+	tui.lines = append(tui.lines, strings.Split(strings.TrimRight(text, "\n"), "\n")...)
+	tui.partial = ""
+	tui.segments++
+	tui.words += words
+	if tui.stdout {
+		fmt.Fprint(os.Stdout, text)
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Draw the header with the state, the level meter and the statistics, the
+// end of the transcript which fits the terminal, and the keys as the footer
+func (tui *tui) draw() {
+	tui.Lock()
+	defer tui.Unlock()
+	var str strings.Builder
+	str.WriteString(homeScreen)
+
+	// Header
+	state := "recording"
+	if tui.paused {
+		state = "paused"
+	}
+	fmt.Fprintf(&str, "go-whisper  %s  %s\r\n", state, clock(time.Since(tui.start)))
+	n := min(int(float64(tuiMeterWidth)*(tui.level-tuiMeterFloor)/-tuiMeterFloor), tuiMeterWidth)
+	fmt.Fprintf(&str, "level [%s%s] %4.0f dB\r\n", strings.Repeat("#", n), strings.Repeat("-", tuiMeterWidth-n), tui.level)
+	fmt.Fprintf(&str, "audio %s  transcribed %s", clock(tui.audio), clock(tui.fed))
+	if tui.fed > 0 {
+		fmt.Fprintf(&str, "  %.2fx real time", rtf(tui.busy, tui.fed))
+	}
+	fmt.Fprintf(&str, "  segments %d  words %d", tui.segments, tui.words)
+	if tui.overflow > 0 {
+		fmt.Fprintf(&str, "  overflows %d", tui.overflow)
+	}
+	str.WriteString("\r\n")
+	str.WriteString(strings.Repeat("-", tui.cols) + "\r\n")
+
+	// Transcript, followed by the interim hypothesis
+	var lines []string
+	for _, line := range tui.lines {
+		lines = append(lines, wrap(line, tui.cols)...)
+	}
+	if tui.partial != "" {
+		for _, line := range wrap("..."+tui.partial, tui.cols) {
+			lines = append(lines, Colorize(line, 12))
+		}
+	}
+	height := max(tui.rows-tuiHeader-tuiFooter, 0)
+	lines = lines[max(len(lines)-height, 0):]
+	for _, line := range lines {
+		str.WriteString(line + "\r\n")
+	}
+	str.WriteString(strings.Repeat("\r\n", height-len(lines)))
+
+	// Footer
+	str.WriteString("space pause/resume  q quit")
+	fmt.Fprint(tui.w, str.String())
+}
+
+// Query the size of the terminal, which is 80 by 24 when it is not known
+func (tui *tui) resize() {
+	rows, cols := 24, 80
+	if size, err := runStty("size"); err == nil {
+		fmt.Sscan(size, &rows, &cols)
+	}
+	tui.Lock()
+	defer tui.Unlock()
+	tui.rows, tui.cols = max(rows, tuiHeader+tuiFooter+1), max(cols, 1)
+}
+
+// Run stty on the terminal of standard input, and return its output
+func runStty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	stderr := new(bytes.Buffer)
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if line := strings.TrimSpace(stderr.String()); line != "" {
+			return "", fmt.Errorf("stty: %w: %s", err, line)
+		}
+		return "", fmt.Errorf("stty: %w", err)
+	}
+	return string(out), nil
+}
+
+// Wrap a line into lines of at most width characters, breaking at spaces
+// where possible
+func wrap(line string, width int) []string {
+	var result []string
+	for utf8.RuneCountInString(line) > width {
+		runes := []rune(line)
+		i := strings.LastIndex(string(runes[:width]), " ")
+		if i <= 0 {
+			i = len(string(runes[:width]))
+		}
+		result = append(result, line[:i])
+		line = strings.TrimLeft(line[i:], " ")
+	}
+	return append(result, line)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	gocontext "context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// Prefix of the files in the cache which hold the content hash of the
+	// audio downloaded from a URL
+	urlPrefix = "url-"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Fetch downloads the audio at a URL to the -url-cache directory, and
+// returns the path of the file. The audio is stored under the SHA-256 hash
+// of its content, so that the same audio downloaded from different URLs is
+// stored once, and a URL which has been downloaded before is read from the
+// cache. With -use-yt-dlp, the best audio stream of the page at the URL is
+// downloaded with yt-dlp.
+func Fetch(ctx gocontext.Context, rawurl string, flags *Flags) (string, error) {
+	logger := flags.Logger()
+	dir, err := flags.GetURLCache()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	// Return the cached audio for the URL
+	key := sha256.Sum256([]byte(rawurl))
+	link := filepath.Join(dir, urlPrefix+hex.EncodeToString(key[:]))
+	if sum, err := os.ReadFile(link); err == nil {
+		path := filepath.Join(dir, string(sum))
+		if _, err := os.Stat(path); err == nil {
+			logger.Debug("cached", "url", rawurl, "path", path)
+			return path, nil
+		}
+	}
+
+	// Download to a temporary file, computing the hash as it is written
+	w, err := os.CreateTemp(dir, "*.part")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(w.Name())
+	defer w.Close()
+	checksum := sha256.New()
+	logger.Info("downloading", "url", rawurl)
+	if flags.IsUseYtDlp() {
+		err = downloadYtDlp(ctx, rawurl, io.MultiWriter(w, checksum), flags)
+	} else {
+		err = download(ctx, rawurl, io.MultiWriter(w, checksum))
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	// Move the audio into place, unless the same audio is already cached
+	sum := hex.EncodeToString(checksum.Sum(nil))
+	path := filepath.Join(dir, sum)
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		if err := os.Rename(w.Name(), path); err != nil {
+			return "", err
+		}
+	} else if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(link, []byte(sum), 0644); err != nil {
+		return "", err
+	}
+	logger.Info("downloaded", "url", rawurl, "path", path)
+
+	// Return success
+	return path, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Return true when the argument is an http or https URL
+func isURL(arg string) bool {
+	return strings.HasPrefix(arg, "http://") || strings.HasPrefix(arg, "https://")
+}
+
+// Return the name of the file at a URL without its extension, or "url" when
+// the URL has no path
+func urlBase(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return "url"
+	}
+	base := strings.TrimSuffix(path.Base(u.Path), path.Ext(u.Path))
+	if base == "" || base == "." || base == "/" {
+		return "url"
+	}
+	return base
+}
+
+// Download the body of a URL to w
+func download(ctx gocontext.Context, rawurl string, w io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawurl, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", rawurl, resp.Status)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// Download the best audio stream of the page at a URL to w with yt-dlp
+func downloadYtDlp(ctx gocontext.Context, rawurl string, w io.Writer, flags *Flags) error {
+	cmd := exec.CommandContext(ctx, flags.GetYtDlp(),
+		"--quiet", "--no-warnings", "--no-progress", "--no-playlist",
+		"-f", "bestaudio/best", "-o", "-", rawurl,
+	)
+	stderr := new(bytes.Buffer)
+	cmd.Stdout = w
+	cmd.Stderr = stderr
+	flags.Logger().Debug("downloading", "command", cmd.String())
+	if err := cmd.Run(); err != nil {
+		if line := strings.TrimSpace(stderr.String()); line != "" {
+			lines := strings.Split(line, "\n")
+			return fmt.Errorf("yt-dlp: %w: %s", err, strings.TrimSpace(lines[len(lines)-1]))
+		}
+		return fmt.Errorf("yt-dlp: %w", err)
+	}
+
+	// Return success
+	return nil
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	gocontext "context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	// Package imports
+	api "github.com/ggerganov/whisper.cpp/bindings/go/pkg/server"
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// WebhookEvent is the body POSTed to the -webhook-url
+type WebhookEvent struct {
+	Event      string          `json:"event"`   // "segment" for each final segment, or "end" when the session ends
+	Source     string          `json:"source"`  // The server the session was received on
+	Session    string          `json:"session"` // Identifier of the session
+	Transcript *api.Transcript `json:"transcript"`
+}
+
+// webhook delivers the transcripts of streaming sessions to the
+// -webhook-url, retrying with exponential backoff. A nil webhook delivers
+// nothing.
+type webhook struct {
+	sync.Mutex
+	flags    *Flags
+	client   *http.Client
+	ctx      gocontext.Context
+	cancel   gocontext.CancelFunc
+	sessions map[string]*webhookSession
+	wg       sync.WaitGroup
+}
+
+// The segments of a session, held until it ends
+type webhookSession struct {
+	source   string
+	segments []whisper.Segment
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	WebhookSegment = "segment"
+	WebhookEnd     = "end"
+)
+
+const (
+	// Timeout of each delivery attempt
+	webhookTimeout = 10 * time.Second
+
+	// Delay before the first retry, which doubles with each retry up to the
+	// maximum
+	webhookBackoff    = time.Second
+	webhookMaxBackoff = 30 * time.Second
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Return the webhook for the -webhook-url, or nil when it is not set
+func newWebhook(flags *Flags) *webhook {
+	if flags.GetWebhookURL() == "" {
+		return nil
+	}
+	ctx, cancel := gocontext.WithCancel(gocontext.Background())
+	return &webhook{
+		flags:    flags,
+		client:   &http.Client{Timeout: webhookTimeout},
+		ctx:      ctx,
+		cancel:   cancel,
+		sessions: make(map[string]*webhookSession),
+	}
+}
+
+// Close waits for pending deliveries for up to the -shutdown-timeout
+// duration, and then abandons them
+func (hook *webhook) Close() error {
+	if hook == nil {
+		return nil
+	}
+	done := make(chan struct{})
+	go func() {
+		hook.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(hook.flags.GetShutdownTimeout()):
+		hook.cancel()
+		<-done
+	}
+	hook.cancel()
+
+	// Return success
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Segment records a final segment of a session, and delivers it when
+// -webhook-segments is set
+func (hook *webhook) Segment(source, id string, context whisper.Context, segment whisper.Segment) {
+	if hook == nil {
+		return
+	}
+	if hook.flags.IsWebhookSegments() {
+		hook.deliver(&WebhookEvent{
+			Event:      WebhookSegment,
+			Source:     source,
+			Session:    id,
+			Transcript: api.NewTranscript(context, []whisper.Segment{segment}, 0, 0, 0, hook.flags.GetConfidenceMin()),
+		})
+		return
+	}
+	hook.Lock()
+	defer hook.Unlock()
+	session, exists := hook.sessions[id]
+	if !exists {
+		session = &webhookSession{source: source}
+		hook.sessions[id] = session
+	}
+	session.segments = append(session.segments, segment)
+}
+
+// End delivers the transcript of a session once it has ended, with the
+// number of samples received. Unless -webhook-segments is set, the
+// transcript contains all the segments of the session.
+func (hook *webhook) End(source, id string, context whisper.Context, samples int) {
+	if hook == nil {
+		return
+	}
+	hook.Lock()
+	session := hook.sessions[id]
+	delete(hook.sessions, id)
+	hook.Unlock()
+	var segments []whisper.Segment
+	if session != nil {
+		segments = session.segments
+	}
+	hook.deliver(&WebhookEvent{
+		Event:      WebhookEnd,
+		Source:     source,
+		Session:    id,
+		Transcript: api.NewTranscript(context, segments, samples, 0, 0, hook.flags.GetConfidenceMin()),
+	})
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Deliver an event in the background. Server errors and failed requests are
+// retried up to -webhook-retries times, and other client errors are not.
+func (hook *webhook) deliver(event *WebhookEvent) {
+	event.Transcript.Session = event.Session
+	event.Transcript.Model = hook.flags.GetModelName()
+	data, err := json.Marshal(event)
+	if err != nil {
+		hook.flags.Logger().Error("unable to encode webhook", "session", event.Session, "error", err)
+		return
+	}
+	hook.wg.Add(1)
+	go func() {
+		defer hook.wg.Done()
+		logger := hook.flags.Logger().With("event", event.Event, "session", event.Session)
+		backoff := webhookBackoff
+		for attempt := uint(0); ; attempt++ {
+			retry, err := hook.post(data)
+			if err == nil {
+				logger.Debug("delivered webhook", "attempts", attempt+1)
+				return
+			} else if !retry || attempt >= hook.flags.GetWebhookRetries() {
+				logger.Error("unable to deliver webhook", "attempts", attempt+1, "error", err)
+				return
+			}
+			logger.Warn("retrying webhook", "attempt", attempt+1, "backoff", backoff, "error", err)
+			select {
+			case <-time.After(backoff):
+			case <-hook.ctx.Done():
+				logger.Error("abandoned webhook", "attempts", attempt+1)
+				return
+			}
+			backoff = min(backoff*2, webhookMaxBackoff)
+		}
+	}()
+}
+
+// POST the body, and return whether a failure should be retried
+func (hook *webhook) post(data []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(hook.ctx, http.MethodPost, hook.flags.GetWebhookURL(), bytes.NewReader(data))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := hook.client.Do(req)
+	if err != nil {
+		return hook.ctx.Err() == nil, err
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("webhook returned %s", resp.Status)
+	default:
+		return false, fmt.Errorf("webhook returned %s", resp.Status)
+	}
+}
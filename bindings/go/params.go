@@ -2,6 +2,7 @@ package whisper
 
 import (
 	"fmt"
+	"unsafe"
 )
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -9,6 +10,7 @@ import (
 
 /*
 #include <whisper.h>
+#include <stdlib.h>
 */
 import "C"
 
@@ -51,6 +53,21 @@ func (p *Params) SetSpeedup(v bool) {
 	p.speed_up = toBool(v)
 }
 
+// Suppress blank outputs at the beginning of a segment
+func (p *Params) SetSuppressBlank(v bool) {
+	p.suppress_blank = toBool(v)
+}
+
+// Suppress non-speech tokens, such as "[Music]" or "(applause)"
+func (p *Params) SetSuppressNonSpeechTokens(v bool) {
+	p.suppress_non_speech_tokens = toBool(v)
+}
+
+// Enable tinydiarize speaker turn detection
+func (p *Params) SetTdrzEnable(v bool) {
+	p.tdrz_enable = toBool(v)
+}
+
 // Set language id
 func (p *Params) SetLanguage(lang int) error {
 	if lang == -1 {
@@ -94,6 +111,16 @@ func (p *Params) SetDuration(duration_ms int) {
 	p.duration_ms = C.int(duration_ms)
 }
 
+// Start offset in ms
+func (p *Params) Offset() int {
+	return int(p.offset_ms)
+}
+
+// Audio duration to process in ms, or zero for the rest of the audio
+func (p *Params) Duration() int {
+	return int(p.duration_ms)
+}
+
 // Set timestamp token probability threshold (~0.01)
 func (p *Params) SetTokenThreshold(t float32) {
 	p.thold_pt = C.float(t)
@@ -123,9 +150,76 @@ func (p *Params) SetAudioCtx(n int) {
 	p.audio_ctx = C.int(n)
 }
 
-// Set initial prompt
+// Set initial decoding temperature
+func (p *Params) SetTemperature(t float32) {
+	p.temperature = C.float(t)
+}
+
+// Set the temperature increment when decoding fails, and is retried at a
+// higher temperature. Zero disables the fallback.
+func (p *Params) SetTemperatureFallback(t float32) {
+	p.temperature_inc = C.float(t)
+}
+
+// Set the entropy threshold. Decoding fails, and falls back to a higher
+// temperature, when the entropy of the tokens is below the threshold (~2.4)
+func (p *Params) SetEntropyThreshold(t float32) {
+	p.entropy_thold = C.float(t)
+}
+
+// Set the average log probability threshold. Decoding fails, and falls back
+// to a higher temperature, when the average log probability of the tokens
+// is below the threshold (~-1.0)
+func (p *Params) SetLogprobThreshold(t float32) {
+	p.logprob_thold = C.float(t)
+}
+
+// Set the no speech probability threshold (~0.6). This is not yet used by
+// whisper.cpp.
+func (p *Params) SetNoSpeechThreshold(t float32) {
+	p.no_speech_thold = C.float(t)
+}
+
+// Set the number of beams for beam search decoding. A beam size greater
+// than one selects the beam search strategy, otherwise greedy decoding is
+// used.
+func (p *Params) SetBeamSize(n int) {
+	if n > 1 {
+		p.strategy = C.WHISPER_SAMPLING_BEAM_SEARCH
+		p.beam_search.beam_size = C.int(n)
+	} else {
+		p.strategy = C.WHISPER_SAMPLING_GREEDY
+		p.beam_search.beam_size = -1
+	}
+}
+
+// Set the number of candidates sampled at a non-zero temperature, of which
+// the best is kept
+func (p *Params) SetBestOf(n int) {
+	p.greedy.best_of = C.int(n)
+}
+
+// Set initial prompt, which is passed to the decoder as previous text to
+// bias it towards the vocabulary and style of the prompt. An empty string
+// clears the prompt.
 func (p *Params) SetInitialPrompt(prompt string) {
-	p.initial_prompt = C.CString(prompt)
+	if p.initial_prompt != nil {
+		C.free(unsafe.Pointer(p.initial_prompt))
+		p.initial_prompt = nil
+	}
+	if prompt != "" {
+		p.initial_prompt = C.CString(prompt)
+	}
+}
+
+// Use the GPU, when whisper.cpp is built with GPU support
+func (p *ContextParams) SetUseGPU(v bool) {
+	p.use_gpu = toBool(v)
+}
+
+// Return true if the GPU is used
+func (p *ContextParams) UseGPU() bool {
+	return bool(p.use_gpu)
 }
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -152,7 +246,18 @@ func (p *Params) String() string {
 	str += fmt.Sprintf(" offset_ms=%d", p.offset_ms)
 	str += fmt.Sprintf(" duration_ms=%d", p.duration_ms)
 	str += fmt.Sprintf(" audio_ctx=%d", p.audio_ctx)
-	str += fmt.Sprintf(" initial_prompt=%s", C.GoString(p.initial_prompt))
+	str += fmt.Sprintf(" temperature=%.2f", p.temperature)
+	str += fmt.Sprintf(" temperature_inc=%.2f", p.temperature_inc)
+	str += fmt.Sprintf(" entropy_thold=%.2f", p.entropy_thold)
+	str += fmt.Sprintf(" logprob_thold=%.2f", p.logprob_thold)
+	str += fmt.Sprintf(" no_speech_thold=%.2f", p.no_speech_thold)
+	str += fmt.Sprintf(" best_of=%d", p.greedy.best_of)
+	if p.strategy == C.WHISPER_SAMPLING_BEAM_SEARCH {
+		str += fmt.Sprintf(" beam_size=%d", p.beam_search.beam_size)
+	}
+	if p.initial_prompt != nil {
+		str += fmt.Sprintf(" initial_prompt=%q", C.GoString(p.initial_prompt))
+	}
 	if p.translate {
 		str += " translate"
 	}
@@ -180,6 +285,23 @@ func (p *Params) String() string {
 	if p.speed_up {
 		str += " speed_up"
 	}
+	if p.tdrz_enable {
+		str += " tdrz_enable"
+	}
+	if p.suppress_blank {
+		str += " suppress_blank"
+	}
+	if p.suppress_non_speech_tokens {
+		str += " suppress_non_speech_tokens"
+	}
 
 	return str + ">"
 }
+
+func (p *ContextParams) String() string {
+	str := "<whisper.context_params"
+	if p.use_gpu {
+		str += " use_gpu"
+	}
+	return str + ">"
+}
@@ -0,0 +1,125 @@
+package audiosocket
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Kind is the type of a message
+type Kind uint8
+
+// Message is an AudioSocket message
+type Message struct {
+	Kind    Kind
+	Payload []byte
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	KindHangup Kind = 0x00 // The call has ended
+	KindUUID   Kind = 0x01 // The UUID of the call, sent first
+	KindDTMF   Kind = 0x03 // A DTMF digit, as an ASCII character
+	KindAudio  Kind = 0x10 // 16-bit signed linear little-endian audio at 8 kHz
+	KindError  Kind = 0xff // An error, with an optional error code
+)
+
+const (
+	// SampleRate is the sample rate of audio messages
+	SampleRate = 8000
+
+	headerSize = 3
+	uuidSize   = 16
+)
+
+var (
+	ErrInvalidMessage = errors.New("invalid audiosocket message")
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Read a message
+func Read(r io.Reader) (*Message, error) {
+	var header [headerSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	message := &Message{
+		Kind:    Kind(header[0]),
+		Payload: make([]byte, binary.BigEndian.Uint16(header[1:])),
+	}
+	if _, err := io.ReadFull(r, message.Payload); err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return nil, err
+	}
+
+	// Return success
+	return message, nil
+}
+
+// Write a message
+func Write(w io.Writer, message *Message) error {
+	if len(message.Payload) > math.MaxUint16 {
+		return ErrInvalidMessage
+	}
+	data := make([]byte, headerSize, headerSize+len(message.Payload))
+	data[0] = byte(message.Kind)
+	binary.BigEndian.PutUint16(data[1:], uint16(len(message.Payload)))
+	_, err := w.Write(append(data, message.Payload...))
+	return err
+}
+
+// Return the call UUID of a UUID message
+func (message *Message) UUID() (string, error) {
+	if message.Kind != KindUUID || len(message.Payload) != uuidSize {
+		return "", ErrInvalidMessage
+	}
+	str := hex.EncodeToString(message.Payload)
+	return str[0:8] + "-" + str[8:12] + "-" + str[12:16] + "-" + str[16:20] + "-" + str[20:], nil
+}
+
+// Return the samples of an audio message
+func (message *Message) Samples() ([]float32, error) {
+	if message.Kind != KindAudio || len(message.Payload)%2 != 0 {
+		return nil, ErrInvalidMessage
+	}
+	result := make([]float32, len(message.Payload)/2)
+	for i := range result {
+		result[i] = float32(int16(binary.LittleEndian.Uint16(message.Payload[i*2:]))) / math.MaxInt16
+	}
+	return result, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (kind Kind) String() string {
+	switch kind {
+	case KindHangup:
+		return "hangup"
+	case KindUUID:
+		return "uuid"
+	case KindDTMF:
+		return "dtmf"
+	case KindAudio:
+		return "audio"
+	case KindError:
+		return "error"
+	default:
+		return fmt.Sprintf("0x%02x", uint8(kind))
+	}
+}
+
+func (message *Message) String() string {
+	return fmt.Sprintf("<audiosocket.message kind=%v len=%d>", message.Kind, len(message.Payload))
+}
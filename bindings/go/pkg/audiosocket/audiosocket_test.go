@@ -0,0 +1,63 @@
+package audiosocket_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	// Packages
+	audiosocket "github.com/ggerganov/whisper.cpp/bindings/go/pkg/audiosocket"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func Test_AudioSocket_000(t *testing.T) {
+	assert := assert.New(t)
+
+	// Write a UUID, audio and hangup
+	var buf bytes.Buffer
+	uuid := []byte{0x40, 0x32, 0x5e, 0xc2, 0x5e, 0xfd, 0x4b, 0xd3, 0x80, 0x5f, 0x53, 0x57, 0x6e, 0x58, 0x1d, 0x13}
+	assert.NoError(audiosocket.Write(&buf, &audiosocket.Message{Kind: audiosocket.KindUUID, Payload: uuid}))
+	assert.NoError(audiosocket.Write(&buf, &audiosocket.Message{Kind: audiosocket.KindAudio, Payload: []byte{0xff, 0x7f, 0x00, 0x00, 0x01, 0x80}}))
+	assert.NoError(audiosocket.Write(&buf, &audiosocket.Message{Kind: audiosocket.KindHangup}))
+	assert.Equal([]byte{0x01, 0x00, 0x10}, buf.Bytes()[:3])
+
+	// Read them back
+	message, err := audiosocket.Read(&buf)
+	assert.NoError(err)
+	assert.Equal(audiosocket.KindUUID, message.Kind)
+	str, err := message.UUID()
+	assert.NoError(err)
+	assert.Equal("40325ec2-5efd-4bd3-805f-53576e581d13", str)
+
+	message, err = audiosocket.Read(&buf)
+	assert.NoError(err)
+	assert.Equal(audiosocket.KindAudio, message.Kind)
+	samples, err := message.Samples()
+	assert.NoError(err)
+	assert.Equal([]float32{1, 0, -1}, samples)
+	_, err = message.UUID()
+	assert.ErrorIs(err, audiosocket.ErrInvalidMessage)
+
+	message, err = audiosocket.Read(&buf)
+	assert.NoError(err)
+	assert.Equal(audiosocket.KindHangup, message.Kind)
+	assert.Empty(message.Payload)
+
+	// End of the connection
+	_, err = audiosocket.Read(&buf)
+	assert.ErrorIs(err, io.EOF)
+}
+
+func Test_AudioSocket_001(t *testing.T) {
+	assert := assert.New(t)
+
+	// A truncated payload is an unexpected EOF
+	_, err := audiosocket.Read(bytes.NewReader([]byte{0x10, 0x00, 0x04, 0x00, 0x00}))
+	assert.ErrorIs(err, io.ErrUnexpectedEOF)
+
+	// Audio must be whole samples
+	message := &audiosocket.Message{Kind: audiosocket.KindAudio, Payload: []byte{0x00}}
+	_, err = message.Samples()
+	assert.ErrorIs(err, audiosocket.ErrInvalidMessage)
+	assert.Equal("audio", message.Kind.String())
+}
@@ -0,0 +1,8 @@
+/*
+Package audiosocket reads and writes the messages of the Asterisk AudioSocket
+protocol, a simple framing of call audio over TCP. Each message has a one byte
+type, a two byte big-endian payload length and the payload. A connection starts
+with the call UUID, followed by 16-bit signed linear audio at 8 kHz, and ends
+with a hangup message.
+*/
+package audiosocket
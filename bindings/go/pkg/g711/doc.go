@@ -0,0 +1,5 @@
+/*
+Package g711 decodes G.711 µ-law (PCMU) and A-law (PCMA) telephony audio to
+linear PCM samples.
+*/
+package g711
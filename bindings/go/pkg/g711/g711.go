@@ -0,0 +1,95 @@
+package g711
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+var (
+	// Expansion tables from 8-bit codewords to 16-bit linear PCM
+	ulaw [256]int16
+	alaw [256]int16
+)
+
+func init() {
+	for i := range ulaw {
+		ulaw[i] = expandULaw(byte(i))
+		alaw[i] = expandALaw(byte(i))
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// PCMUToInt16 decodes µ-law (PCMU) codewords to 16-bit linear PCM samples
+func PCMUToInt16(data []byte) []int16 {
+	return decode(data, &ulaw)
+}
+
+// PCMAToInt16 decodes A-law (PCMA) codewords to 16-bit linear PCM samples
+func PCMAToInt16(data []byte) []int16 {
+	return decode(data, &alaw)
+}
+
+// PCMUToFloat32 decodes µ-law (PCMU) codewords to samples in the range
+// [-1, 1), one sample per byte
+func PCMUToFloat32(data []byte) []float32 {
+	return decodeFloat32(data, &ulaw)
+}
+
+// PCMAToFloat32 decodes A-law (PCMA) codewords to samples in the range
+// [-1, 1), one sample per byte
+func PCMAToFloat32(data []byte) []float32 {
+	return decodeFloat32(data, &alaw)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func decode(data []byte, table *[256]int16) []int16 {
+	result := make([]int16, len(data))
+	for i, v := range data {
+		result[i] = table[v]
+	}
+	return result
+}
+
+func decodeFloat32(data []byte, table *[256]int16) []float32 {
+	result := make([]float32, len(data))
+	for i, v := range data {
+		result[i] = float32(table[v]) / 32768
+	}
+	return result
+}
+
+// Expand a µ-law codeword, as described in ITU-T G.711. Codewords are
+// transmitted inverted, and the magnitude is stored as a 3-bit segment
+// number and 4-bit step with a bias of 0x84.
+func expandULaw(v byte) int16 {
+	v = ^v
+	t := (int16(v&0x0F) << 3) + 0x84
+	t <<= (v & 0x70) >> 4
+	if v&0x80 != 0 {
+		return 0x84 - t
+	}
+	return t - 0x84
+}
+
+// Expand an A-law codeword, as described in ITU-T G.711. Even bits are
+// inverted on transmission, and the magnitude is stored as a 3-bit segment
+// number and 4-bit step.
+func expandALaw(v byte) int16 {
+	v ^= 0x55
+	t := int16(v&0x0F) << 4
+	switch seg := (v & 0x70) >> 4; seg {
+	case 0:
+		t += 0x08
+	case 1:
+		t += 0x108
+	default:
+		t += 0x108
+		t <<= seg - 1
+	}
+	if v&0x80 != 0 {
+		return t
+	}
+	return -t
+}
@@ -0,0 +1,72 @@
+package g711_test
+
+import (
+	"testing"
+
+	// Packages
+	g711 "github.com/ggerganov/whisper.cpp/bindings/go/pkg/g711"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func Test_G711_000(t *testing.T) {
+	assert := assert.New(t)
+
+	// Reference values from ITU-T G.711 µ-law
+	vectors := map[byte]int16{
+		0xFF: 0,
+		0x7F: 0,
+		0x80: 32124,
+		0x00: -32124,
+		0xF0: 120,
+		0x70: -120,
+		0xEF: 132,
+		0x8F: 16764,
+		0x0F: -16764,
+		0xCF: 924,
+	}
+	for v, expected := range vectors {
+		assert.Equal(expected, g711.PCMUToInt16([]byte{v})[0], "PCMU 0x%02X", v)
+	}
+}
+
+func Test_G711_001(t *testing.T) {
+	assert := assert.New(t)
+
+	// Reference values from ITU-T G.711 A-law
+	vectors := map[byte]int16{
+		0xD5: 8,
+		0x55: -8,
+		0xAA: 32256,
+		0x2A: -32256,
+		0xC5: 264,
+		0x45: -264,
+		0xF5: 528,
+		0x80: 5504,
+	}
+	for v, expected := range vectors {
+		assert.Equal(expected, g711.PCMAToInt16([]byte{v})[0], "PCMA 0x%02X", v)
+	}
+}
+
+func Test_G711_002(t *testing.T) {
+	assert := assert.New(t)
+
+	// One sample per byte, in the range [-1, 1)
+	data := make([]byte, 256)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	for _, samples := range [][]float32{g711.PCMUToFloat32(data), g711.PCMAToFloat32(data)} {
+		assert.Len(samples, len(data))
+		for _, v := range samples {
+			assert.GreaterOrEqual(v, float32(-1))
+			assert.Less(v, float32(1))
+		}
+	}
+
+	// Codes are symmetrical about zero
+	for i := 0; i < 128; i++ {
+		assert.Equal(-g711.PCMUToInt16([]byte{byte(i)})[0], g711.PCMUToInt16([]byte{byte(i | 0x80)})[0])
+		assert.Equal(-g711.PCMAToInt16([]byte{byte(i)})[0], g711.PCMAToInt16([]byte{byte(i | 0x80)})[0])
+	}
+}
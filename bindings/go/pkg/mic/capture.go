@@ -0,0 +1,184 @@
+//go:build portaudio
+
+package mic
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+/*
+#cgo pkg-config: portaudio-2.0
+#include <portaudio.h>
+*/
+import "C"
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Capture reads audio from an input device
+type Capture struct {
+	stream   unsafe.Pointer
+	channels int
+	rate     int
+	buf      []float32 // Interleaved samples, when there is more than one channel
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Open the default input device, and start capturing mono audio at the
+// sample rate, in buffers of the given number of frames
+func Open(rate, frames int) (*Capture, error) {
+	if err := C.Pa_Initialize(); err != C.paNoError {
+		return nil, toError(err)
+	}
+	device := C.Pa_GetDefaultInputDevice()
+	if device == C.paNoDevice {
+		C.Pa_Terminate()
+		return nil, ErrNoDevice
+	}
+	return open(device, 1, rate, frames)
+}
+
+// OpenDevice opens an input device returned by Devices, and starts capturing
+// audio at the default sample rate of the device, in buffers of the given
+// number of frames. The audio is mixed to mono.
+func OpenDevice(device Device, frames int) (*Capture, error) {
+	if err := C.Pa_Initialize(); err != C.paNoError {
+		return nil, toError(err)
+	}
+	if device.Index < 0 || device.Index >= int(C.Pa_GetDeviceCount()) {
+		C.Pa_Terminate()
+		return nil, ErrNoDevice
+	}
+	return open(C.PaDeviceIndex(device.Index), max(1, min(device.Channels, 2)), int(device.Rate), frames)
+}
+
+// Stop capturing and release the device
+func (capture *Capture) Close() error {
+	if capture.stream == nil {
+		return nil
+	}
+	C.Pa_StopStream(capture.stream)
+	err := C.Pa_CloseStream(capture.stream)
+	capture.stream = nil
+	C.Pa_Terminate()
+	if err != C.paNoError {
+		return toError(err)
+	}
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Devices returns the input devices
+func Devices() ([]Device, error) {
+	if err := C.Pa_Initialize(); err != C.paNoError {
+		return nil, toError(err)
+	}
+	defer C.Pa_Terminate()
+	n := C.Pa_GetDeviceCount()
+	if n < 0 {
+		return nil, toError(C.PaError(n))
+	}
+	var result []Device
+	for i := C.PaDeviceIndex(0); i < n; i++ {
+		info := C.Pa_GetDeviceInfo(i)
+		if info == nil || info.maxInputChannels <= 0 {
+			continue
+		}
+		device := Device{
+			Index:    int(i),
+			Name:     C.GoString(info.name),
+			Channels: int(info.maxInputChannels),
+			Rate:     float64(info.defaultSampleRate),
+			Default:  i == C.Pa_GetDefaultInputDevice(),
+		}
+		if host := C.Pa_GetHostApiInfo(info.hostApi); host != nil {
+			device.Host = C.GoString(host.name)
+		}
+		result = append(result, device)
+	}
+	return result, nil
+}
+
+// Rate returns the sample rate of the audio captured
+func (capture *Capture) Rate() int {
+	return capture.rate
+}
+
+// Read fills the buffer with captured mono samples, blocking until they are
+// available. ErrOverflow is returned with the samples when audio was lost
+// because it was not read in time.
+func (capture *Capture) Read(buf []float32) error {
+	if len(buf) == 0 {
+		return nil
+	}
+	data := buf
+	if capture.channels > 1 {
+		if cap(capture.buf) < len(buf)*capture.channels {
+			capture.buf = make([]float32, len(buf)*capture.channels)
+		}
+		data = capture.buf[:len(buf)*capture.channels]
+	}
+	var result error
+	switch err := C.Pa_ReadStream(capture.stream, unsafe.Pointer(&data[0]), C.ulong(len(buf))); err {
+	case C.paNoError:
+	case C.paInputOverflowed:
+		result = ErrOverflow
+	default:
+		return toError(err)
+	}
+
+	// Mix the channels
+	if capture.channels > 1 {
+		for i := range buf {
+			var v float32
+			for _, sample := range data[i*capture.channels : (i+1)*capture.channels] {
+				v += sample
+			}
+			buf[i] = v / float32(capture.channels)
+		}
+	}
+
+	// Return success, or the overflow
+	return result
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Open and start a stream on an input device. PortAudio has been
+// initialized, and is terminated on error.
+func open(device C.PaDeviceIndex, channels, rate, frames int) (*Capture, error) {
+	info := C.Pa_GetDeviceInfo(device)
+	if info == nil {
+		C.Pa_Terminate()
+		return nil, ErrNoDevice
+	}
+	params := C.PaStreamParameters{
+		device:           device,
+		channelCount:     C.int(channels),
+		sampleFormat:     C.paFloat32,
+		suggestedLatency: info.defaultHighInputLatency,
+	}
+	capture := &Capture{channels: channels, rate: rate}
+	if err := C.Pa_OpenStream(&capture.stream, &params, nil, C.double(rate), C.ulong(frames), C.paNoFlag, nil, nil); err != C.paNoError {
+		C.Pa_Terminate()
+		return nil, toError(err)
+	}
+	if err := C.Pa_StartStream(capture.stream); err != C.paNoError {
+		C.Pa_CloseStream(capture.stream)
+		C.Pa_Terminate()
+		return nil, toError(err)
+	}
+
+	// Return success
+	return capture, nil
+}
+
+func toError(err C.PaError) error {
+	return fmt.Errorf("portaudio: %s", C.GoString(C.Pa_GetErrorText(err)))
+}
@@ -0,0 +1,47 @@
+//go:build !portaudio
+
+package mic
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Capture reads audio from an input device
+type Capture struct{}
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Open returns ErrNotSupported, as the package was built without the
+// portaudio build tag
+func Open(rate, frames int) (*Capture, error) {
+	return nil, ErrNotSupported
+}
+
+// OpenDevice returns ErrNotSupported, as the package was built without the
+// portaudio build tag
+func OpenDevice(device Device, frames int) (*Capture, error) {
+	return nil, ErrNotSupported
+}
+
+// Stop capturing and release the device
+func (capture *Capture) Close() error {
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Devices returns ErrNotSupported
+func Devices() ([]Device, error) {
+	return nil, ErrNotSupported
+}
+
+// Rate returns the sample rate of the audio captured
+func (capture *Capture) Rate() int {
+	return 0
+}
+
+// Read fills the buffer with captured samples
+func (capture *Capture) Read(buf []float32) error {
+	return ErrNotSupported
+}
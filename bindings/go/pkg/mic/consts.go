@@ -0,0 +1,13 @@
+package mic
+
+import "errors"
+
+///////////////////////////////////////////////////////////////////////////////
+// ERRORS
+
+var (
+	ErrNotSupported = errors.New("microphone capture not supported, build with the portaudio tag")
+	ErrOverflow     = errors.New("input overflowed, audio was lost")
+	ErrNoDevice     = errors.New("no such input device")
+	ErrNoLoopback   = errors.New("no loopback device, which captures the audio output of the system")
+)
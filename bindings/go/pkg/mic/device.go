@@ -0,0 +1,68 @@
+package mic
+
+import (
+	"strings"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Device is an audio input device
+type Device struct {
+	Index    int     // Index of the device, which identifies it to OpenDevice
+	Name     string  // Name of the device
+	Host     string  // Host API of the device, such as ALSA or Windows WASAPI
+	Channels int     // Maximum number of input channels
+	Rate     float64 // Default sample rate
+	Default  bool    // True for the default input device
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// IsLoopback returns true when the device captures the audio output of the
+// system, as with the monitor sources of PulseAudio and PipeWire, and the
+// loopback devices of WASAPI
+func (device Device) IsLoopback() bool {
+	name := strings.ToLower(device.Name)
+	return strings.HasPrefix(name, "monitor of ") || strings.HasSuffix(name, ".monitor") || strings.Contains(name, "[loopback]")
+}
+
+// Find returns the first device whose name contains the string, ignoring
+// case, or ErrNoDevice
+func Find(devices []Device, name string) (Device, error) {
+	for _, device := range devices {
+		if strings.Contains(strings.ToLower(device.Name), strings.ToLower(name)) {
+			return device, nil
+		}
+	}
+	return Device{}, ErrNoDevice
+}
+
+// FindLoopback returns the first loopback device, or ErrNoLoopback
+func FindLoopback(devices []Device) (Device, error) {
+	for _, device := range devices {
+		if device.IsLoopback() {
+			return device, nil
+		}
+	}
+	return Device{}, ErrNoLoopback
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (device Device) String() string {
+	var str strings.Builder
+	str.WriteString(device.Name)
+	if device.Host != "" {
+		str.WriteString(" (" + device.Host + ")")
+	}
+	if device.Default {
+		str.WriteString(" [default]")
+	}
+	if device.IsLoopback() && !strings.Contains(strings.ToLower(device.Name), "[loopback]") {
+		str.WriteString(" [loopback]")
+	}
+	return str.String()
+}
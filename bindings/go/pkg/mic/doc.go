@@ -0,0 +1,12 @@
+/*
+Package mic captures mono float32 audio from the default input device, such
+as a microphone, for transcribing in real time.
+
+Capture uses PortAudio and is only available when built with the
+"portaudio" build tag, for example:
+
+	go build -tags portaudio ./...
+
+Without the build tag, Open returns ErrNotSupported.
+*/
+package mic
@@ -0,0 +1,48 @@
+//go:build !portaudio
+
+package mic_test
+
+import (
+	"testing"
+
+	// Packages
+	mic "github.com/ggerganov/whisper.cpp/bindings/go/pkg/mic"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func Test_Mic_000(t *testing.T) {
+	assert := assert.New(t)
+
+	// Capture is not supported without the portaudio build tag
+	capture, err := mic.Open(16000, 1600)
+	assert.ErrorIs(err, mic.ErrNotSupported)
+	assert.Nil(capture)
+}
+
+func Test_Mic_001(t *testing.T) {
+	assert := assert.New(t)
+	devices := []mic.Device{
+		{Index: 0, Name: "Built-in Audio Analog Stereo", Default: true},
+		{Index: 1, Name: "Monitor of Built-in Audio Analog Stereo"},
+		{Index: 2, Name: "Speakers (Realtek Audio) [Loopback]", Host: "Windows WASAPI"},
+	}
+
+	// Loopback devices are identified by name
+	assert.False(devices[0].IsLoopback())
+	assert.True(devices[1].IsLoopback())
+	assert.True(devices[2].IsLoopback())
+
+	// Devices are found by part of the name, ignoring case
+	device, err := mic.Find(devices, "realtek")
+	assert.NoError(err)
+	assert.Equal(2, device.Index)
+	_, err = mic.Find(devices, "usb")
+	assert.ErrorIs(err, mic.ErrNoDevice)
+
+	// The first loopback device is found
+	device, err = mic.FindLoopback(devices)
+	assert.NoError(err)
+	assert.Equal(1, device.Index)
+	_, err = mic.FindLoopback(devices[:1])
+	assert.ErrorIs(err, mic.ErrNoLoopback)
+}
@@ -0,0 +1,16 @@
+/*
+Package mrcp implements the parts of MRCPv2 (RFC 6787) needed to serve a
+speech recognizer resource to IVR platforms such as FreeSWITCH, or Asterisk
+through UniMRCP.
+
+A session is established with a SIP INVITE, whose SDP offer requests a
+control channel for the speechrecog resource and an RTP audio stream. The
+answer assigns a channel identifier, and the client then connects to the
+control port over TCP and sends requests such as RECOGNIZE on the channel.
+Results are returned in a RECOGNITION-COMPLETE event with an NLSML body.
+
+The package reads and writes MRCPv2 and SIP messages, and parses and answers
+SDP offers. It does not implement SIP transactions, so retransmissions are
+left to the caller.
+*/
+package mrcp
@@ -0,0 +1,327 @@
+package mrcp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Kind is the type of a message
+type Kind int
+
+// State is the request state of a response or event
+type State string
+
+// Message is an MRCPv2 request, response or event
+type Message struct {
+	Kind      Kind
+	Name      string // Method of a request, or name of an event
+	RequestID uint32
+	Status    int   // Status code of a response
+	State     State // Request state of a response or event
+	Header    textproto.MIMEHeader
+	Body      []byte
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	KindRequest Kind = iota + 1
+	KindResponse
+	KindEvent
+)
+
+const (
+	StatePending    State = "PENDING"
+	StateInProgress State = "IN-PROGRESS"
+	StateComplete   State = "COMPLETE"
+)
+
+// Methods of the speechrecog resource
+const (
+	MethodSetParams        = "SET-PARAMS"
+	MethodGetParams        = "GET-PARAMS"
+	MethodDefineGrammar    = "DEFINE-GRAMMAR"
+	MethodRecognize        = "RECOGNIZE"
+	MethodStop             = "STOP"
+	MethodStartInputTimers = "START-INPUT-TIMERS"
+)
+
+// Events of the speechrecog resource
+const (
+	EventStartOfInput        = "START-OF-INPUT"
+	EventRecognitionComplete = "RECOGNITION-COMPLETE"
+)
+
+// Status codes of responses
+const (
+	StatusSuccess              = 200
+	StatusMethodNotAllowed     = 401
+	StatusMethodNotValid       = 402 // The method is not valid in this state
+	StatusResourceNotAllocated = 405
+	StatusMethodFailed         = 407
+	StatusUnsupportedValue     = 409 // Unsupported value of a header field
+)
+
+// Completion causes of RECOGNITION-COMPLETE events
+const (
+	CauseSuccess        = "000 success"
+	CauseNoMatch        = "001 no-match"
+	CauseNoInputTimeout = "002 no-input-timeout"
+	CauseError          = "006 recognizer-error"
+)
+
+// Header fields
+const (
+	HeaderChannelIdentifier   = "Channel-Identifier"
+	HeaderContentType         = "Content-Type"
+	HeaderContentLength       = "Content-Length"
+	HeaderCompletionCause     = "Completion-Cause"
+	HeaderActiveRequestIdList = "Active-Request-Id-List"
+	HeaderNoInputTimeout      = "No-Input-Timeout"
+	HeaderStartInputTimers    = "Start-Input-Timers"
+	HeaderInputType           = "Input-Type"
+	HeaderSpeechLanguage      = "Speech-Language"
+)
+
+const (
+	// Version is the protocol version at the start of each message
+	Version = "MRCP/2.0"
+
+	// Resource is the name of the speech recognizer resource
+	Resource = "speechrecog"
+)
+
+var (
+	ErrInvalidMessage = errors.New("invalid mrcp message")
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// NewResponse returns a response to a request, on the same channel
+func NewResponse(request *Message, status int, state State) *Message {
+	return &Message{
+		Kind:      KindResponse,
+		RequestID: request.RequestID,
+		Status:    status,
+		State:     state,
+		Header:    channelHeader(request),
+	}
+}
+
+// NewEvent returns an event for a request, on the same channel
+func NewEvent(request *Message, name string, state State) *Message {
+	return &Message{
+		Kind:      KindEvent,
+		Name:      name,
+		RequestID: request.RequestID,
+		State:     state,
+		Header:    channelHeader(request),
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Read a message
+func Read(r *bufio.Reader) (*Message, error) {
+	reader := textproto.NewReader(r)
+	line, err := reader.ReadLine()
+	if err != nil {
+		return nil, err
+	}
+	message, err := parseStartLine(line)
+	if err != nil {
+		return nil, err
+	}
+	if message.Header, err = reader.ReadMIMEHeader(); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+
+	// Read the body
+	if value := message.Header.Get(HeaderContentLength); value != "" {
+		n, err := strconv.ParseUint(value, 10, 31)
+		if err != nil {
+			return nil, ErrInvalidMessage
+		}
+		message.Body = make([]byte, n)
+		if _, err := io.ReadFull(r, message.Body); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+	}
+
+	// Return success
+	return message, nil
+}
+
+// Write a message
+func Write(w io.Writer, message *Message) error {
+	data, err := message.Bytes()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// Bytes returns the encoded message. The Content-Length header is set from
+// the body.
+func (message *Message) Bytes() ([]byte, error) {
+	// Encode the header fields in a stable order
+	var rest strings.Builder
+	keys := make([]string, 0, len(message.Header))
+	for key := range message.Header {
+		if key != HeaderContentLength {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		for _, value := range message.Header[key] {
+			fmt.Fprintf(&rest, "%s: %s\r\n", key, value)
+		}
+	}
+	if len(message.Body) > 0 {
+		fmt.Fprintf(&rest, "%s: %d\r\n", HeaderContentLength, len(message.Body))
+	}
+	rest.WriteString("\r\n")
+	rest.Write(message.Body)
+
+	// The message length includes the start line, which contains the length
+	var format string
+	var args []any
+	switch message.Kind {
+	case KindRequest:
+		format, args = "%s %d %s %d\r\n", []any{message.Name, message.RequestID}
+	case KindResponse:
+		format, args = "%s %d %d %d %s\r\n", []any{message.RequestID, message.Status, message.State}
+	case KindEvent:
+		format, args = "%s %d %s %d %s\r\n", []any{message.Name, message.RequestID, message.State}
+	default:
+		return nil, ErrInvalidMessage
+	}
+	length := rest.Len()
+	for {
+		line := fmt.Sprintf(format, append([]any{Version, length}, args...)...)
+		if n := len(line) + rest.Len(); n != length {
+			length = n
+			continue
+		}
+		return append([]byte(line), rest.String()...), nil
+	}
+}
+
+// Channel returns the channel identifier of the message, without the
+// resource name
+func (message *Message) Channel() string {
+	channel, _, _ := strings.Cut(message.Header.Get(HeaderChannelIdentifier), "@")
+	return channel
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (k Kind) String() string {
+	switch k {
+	case KindRequest:
+		return "request"
+	case KindResponse:
+		return "response"
+	case KindEvent:
+		return "event"
+	default:
+		return "unknown"
+	}
+}
+
+func (message *Message) String() string {
+	str := "<mrcp.message"
+	str += fmt.Sprintf(" kind=%v", message.Kind)
+	if message.Name != "" {
+		str += fmt.Sprintf(" name=%q", message.Name)
+	}
+	str += fmt.Sprintf(" request_id=%d", message.RequestID)
+	if message.Kind == KindResponse {
+		str += fmt.Sprintf(" status=%d", message.Status)
+	}
+	if message.State != "" {
+		str += fmt.Sprintf(" state=%q", message.State)
+	}
+	if channel := message.Header.Get(HeaderChannelIdentifier); channel != "" {
+		str += fmt.Sprintf(" channel=%q", channel)
+	}
+	if len(message.Body) > 0 {
+		str += fmt.Sprintf(" len=%d", len(message.Body))
+	}
+	return str + ">"
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Parse the start line of a message, which is one of:
+//
+//	MRCP/2.0 length method request-id
+//	MRCP/2.0 length request-id status-code request-state
+//	MRCP/2.0 length event-name request-id request-state
+func parseStartLine(line string) (*Message, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 || fields[0] != Version {
+		return nil, ErrInvalidMessage
+	}
+	message := new(Message)
+	var id string
+	switch {
+	case len(fields) == 4:
+		message.Kind, message.Name, id = KindRequest, fields[2], fields[3]
+	case len(fields) == 5 && isDigits(fields[2]):
+		status, err := strconv.Atoi(fields[3])
+		if err != nil {
+			return nil, ErrInvalidMessage
+		}
+		message.Kind, id, message.Status, message.State = KindResponse, fields[2], status, State(fields[4])
+	case len(fields) == 5:
+		message.Kind, message.Name, id, message.State = KindEvent, fields[2], fields[3], State(fields[4])
+	default:
+		return nil, ErrInvalidMessage
+	}
+	n, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return nil, ErrInvalidMessage
+	}
+	message.RequestID = uint32(n)
+
+	// Return success
+	return message, nil
+}
+
+// Return the channel identifier header of a request
+func channelHeader(request *Message) textproto.MIMEHeader {
+	header := make(textproto.MIMEHeader)
+	if channel := request.Header.Get(HeaderChannelIdentifier); channel != "" {
+		header.Set(HeaderChannelIdentifier, channel)
+	}
+	return header
+}
+
+func isDigits(str string) bool {
+	return strings.Trim(str, "0123456789") == ""
+}
+
+// A message which ends before it is complete is unexpected
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,156 @@
+package mrcp_test
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	// Packages
+	mrcp "github.com/ggerganov/whisper.cpp/bindings/go/pkg/mrcp"
+	assert "github.com/stretchr/testify/assert"
+)
+
+const offer = "v=0\r\n" +
+	"o=- 1 1 IN IP4 10.0.0.1\r\n" +
+	"s=-\r\n" +
+	"c=IN IP4 10.0.0.1\r\n" +
+	"t=0 0\r\n" +
+	"m=application 9 TCP/MRCPv2 1\r\n" +
+	"a=setup:active\r\n" +
+	"a=connection:new\r\n" +
+	"a=resource:speechrecog\r\n" +
+	"a=cmid:1\r\n" +
+	"m=audio 4000 RTP/AVP 0 8 101\r\n" +
+	"a=rtpmap:0 PCMU/8000\r\n" +
+	"a=rtpmap:101 telephone-event/8000\r\n" +
+	"a=sendonly\r\n" +
+	"a=mid:1\r\n"
+
+func Test_MRCP_000(t *testing.T) {
+	assert := assert.New(t)
+
+	// Read a request with a body
+	data := "MRCP/2.0 000 DEFINE-GRAMMAR 543257\r\n" +
+		"Channel-Identifier: 32AECB23433801@speechrecog\r\n" +
+		"Content-Type: application/srgs+xml\r\n" +
+		"Content-Length: 7\r\n" +
+		"\r\n" +
+		"<gram/>"
+	message, err := mrcp.Read(bufio.NewReader(strings.NewReader(data)))
+	assert.NoError(err)
+	assert.Equal(mrcp.KindRequest, message.Kind)
+	assert.Equal(mrcp.MethodDefineGrammar, message.Name)
+	assert.Equal(uint32(543257), message.RequestID)
+	assert.Equal("32AECB23433801", message.Channel())
+	assert.Equal("<gram/>", string(message.Body))
+}
+
+func Test_MRCP_001(t *testing.T) {
+	assert := assert.New(t)
+	request := &mrcp.Message{Kind: mrcp.KindRequest, Name: mrcp.MethodRecognize, RequestID: 7}
+	request.Header = map[string][]string{mrcp.HeaderChannelIdentifier: {"abc@speechrecog"}}
+
+	// The message length includes the start line
+	response := mrcp.NewResponse(request, mrcp.StatusSuccess, mrcp.StateInProgress)
+	data, err := response.Bytes()
+	assert.NoError(err)
+	assert.Equal("MRCP/2.0 70 7 200 IN-PROGRESS\r\nChannel-Identifier: abc@speechrecog\r\n\r\n", string(data))
+	assert.Len(data, 70)
+
+	// Events are read back
+	event := mrcp.NewEvent(request, mrcp.EventRecognitionComplete, mrcp.StateComplete)
+	event.Header.Set(mrcp.HeaderCompletionCause, mrcp.CauseSuccess)
+	event.Body = []byte("result")
+	var buf bytes.Buffer
+	assert.NoError(mrcp.Write(&buf, event))
+	assert.NoError(mrcp.Write(&buf, response))
+	reader := bufio.NewReader(&buf)
+	message, err := mrcp.Read(reader)
+	assert.NoError(err)
+	assert.Equal(mrcp.KindEvent, message.Kind)
+	assert.Equal(mrcp.EventRecognitionComplete, message.Name)
+	assert.Equal(mrcp.StateComplete, message.State)
+	assert.Equal(mrcp.CauseSuccess, message.Header.Get(mrcp.HeaderCompletionCause))
+	assert.Equal("result", string(message.Body))
+	message, err = mrcp.Read(reader)
+	assert.NoError(err)
+	assert.Equal(mrcp.KindResponse, message.Kind)
+	assert.Equal(mrcp.StatusSuccess, message.Status)
+	assert.Equal(uint32(7), message.RequestID)
+
+	// A truncated message is unexpected
+	_, err = mrcp.Read(bufio.NewReader(bytes.NewReader(data[:len(data)-2])))
+	assert.ErrorIs(err, io.ErrUnexpectedEOF)
+
+	// Another protocol is invalid
+	_, err = mrcp.Read(bufio.NewReader(strings.NewReader("SIP/2.0 200 OK\r\n\r\n")))
+	assert.ErrorIs(err, mrcp.ErrInvalidMessage)
+}
+
+func Test_MRCP_002(t *testing.T) {
+	assert := assert.New(t)
+	data, err := mrcp.NLSML("", "one & two", 1.5)
+	assert.NoError(err)
+	assert.Contains(string(data), `<interpretation confidence="1.00">`)
+	assert.Contains(string(data), `<instance>one &amp; two</instance>`)
+	assert.Contains(string(data), `<input mode="speech">one &amp; two</input>`)
+}
+
+func Test_MRCP_003(t *testing.T) {
+	assert := assert.New(t)
+
+	// Parse an offer
+	sdp, err := mrcp.ParseSDP([]byte(offer))
+	assert.NoError(err)
+	assert.Equal("10.0.0.1", sdp.Addr)
+	assert.Equal(mrcp.Resource, sdp.Resource)
+	assert.Equal("1", sdp.ControlMid)
+	assert.Equal(4000, sdp.AudioPort)
+	assert.Equal("1", sdp.AudioMid)
+	assert.Equal([]uint8{0, 8, 101}, sdp.Payloads)
+	assert.Equal("PCMU/8000", sdp.Rtpmap[0])
+
+	// Answer it
+	sdp.Addr, sdp.ControlPort, sdp.AudioPort, sdp.Channel = "10.0.0.2", 1544, 5000, "abc"
+	sdp.Payloads = []uint8{0}
+	answer := string(sdp.Bytes())
+	assert.Contains(answer, "m=application 1544 TCP/MRCPv2 1\r\n")
+	assert.Contains(answer, "a=channel:abc@speechrecog\r\n")
+	assert.Contains(answer, "m=audio 5000 RTP/AVP 0\r\na=rtpmap:0 PCMU/8000\r\na=recvonly\r\n")
+
+	// An offer without a control channel is invalid
+	_, err = mrcp.ParseSDP([]byte("v=0\r\nm=audio 4000 RTP/AVP 0\r\n"))
+	assert.ErrorIs(err, mrcp.ErrInvalidMessage)
+}
+
+func Test_MRCP_004(t *testing.T) {
+	assert := assert.New(t)
+	data := "INVITE sip:mresources@10.0.0.2:5060 SIP/2.0\r\n" +
+		"v: SIP/2.0/UDP 10.0.0.1:5060;branch=z9hG4bK1\r\n" +
+		"From: <sip:client@10.0.0.1>;tag=1\r\n" +
+		"To: <sip:mresources@10.0.0.2>\r\n" +
+		"Call-ID: 1234@10.0.0.1\r\n" +
+		"CSeq: 1 INVITE\r\n" +
+		"Content-Type: application/sdp\r\n" +
+		"Content-Length: 3\r\n" +
+		"\r\n" +
+		"v=0garbage"
+	request, err := mrcp.ParseSIP([]byte(data))
+	assert.NoError(err)
+	assert.Equal(mrcp.SIPInvite, request.Method)
+	assert.Equal("1234@10.0.0.1", request.CallID())
+	assert.Equal("SIP/2.0/UDP 10.0.0.1:5060;branch=z9hG4bK1", request.Header.Get("Via"))
+	assert.Equal("v=0", string(request.Body))
+
+	// The response copies the transaction fields
+	response := mrcp.NewSIPResponse(request, 200, "OK")
+	response, err = mrcp.ParseSIP(response.Bytes())
+	assert.NoError(err)
+	assert.Equal(200, response.Status)
+	assert.Equal("OK", response.Reason)
+	assert.Equal("1 INVITE", response.Header.Get("CSeq"))
+	assert.Equal("1234@10.0.0.1", response.CallID())
+	assert.Empty(response.Body)
+}
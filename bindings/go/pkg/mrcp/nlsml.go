@@ -0,0 +1,55 @@
+package mrcp
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type nlsmlResult struct {
+	XMLName        xml.Name            `xml:"result"`
+	Interpretation nlsmlInterpretation `xml:"interpretation"`
+}
+
+type nlsmlInterpretation struct {
+	Grammar    string     `xml:"grammar,attr,omitempty"`
+	Confidence string     `xml:"confidence,attr"`
+	Instance   string     `xml:"instance"`
+	Input      nlsmlInput `xml:"input"`
+}
+
+type nlsmlInput struct {
+	Mode string `xml:"mode,attr"`
+	Text string `xml:",chardata"`
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// ContentTypeNLSML is the content type of recognition results
+	ContentTypeNLSML = "application/nlsml+xml"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// NLSML returns a recognition result for the text, with a confidence between
+// zero and one. The text is both the input and the instance of the
+// interpretation, and the grammar is omitted when empty.
+func NLSML(grammar, text string, confidence float32) ([]byte, error) {
+	data, err := xml.MarshalIndent(nlsmlResult{
+		Interpretation: nlsmlInterpretation{
+			Grammar:    grammar,
+			Confidence: fmt.Sprintf("%.2f", min(max(confidence, 0), 1)),
+			Instance:   text,
+			Input:      nlsmlInput{Mode: "speech", Text: text},
+		},
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
@@ -0,0 +1,157 @@
+package mrcp
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// SDP describes a session with an MRCPv2 control channel and an RTP audio
+// stream, as offered by a client or answered by the server
+type SDP struct {
+	Addr        string           // Connection address
+	ControlPort int              // Port of the control channel
+	Resource    string           // Resource requested for the control channel
+	Channel     string           // Channel identifier assigned in the answer
+	ControlMid  string           // Media identifier of the audio stream controlled by the channel
+	AudioPort   int              // Port of the audio stream
+	AudioMid    string           // Media identifier of the audio stream
+	Payloads    []uint8          // Payload types of the audio stream, in order of preference
+	Rtpmap      map[uint8]string // Encodings of the payload types with an rtpmap attribute
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// ParseSDP parses an SDP offer. ErrInvalidMessage is returned when the offer
+// does not include both a control channel and an audio stream.
+func ParseSDP(data []byte) (*SDP, error) {
+	sdp := &SDP{Rtpmap: make(map[uint8]string)}
+	var media string
+	var control, audio bool
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "c":
+			// The session connection address, or that of the control channel
+			// or audio stream
+			if fields := strings.Fields(value); len(fields) == 3 && media != "other" {
+				sdp.Addr = fields[2]
+			}
+		case "m":
+			fields := strings.Fields(value)
+			if len(fields) < 3 {
+				return nil, ErrInvalidMessage
+			}
+			port, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return nil, ErrInvalidMessage
+			}
+			media = fields[0]
+			switch {
+			case media == "application" && strings.HasSuffix(fields[2], "MRCPv2"):
+				sdp.ControlPort, control = port, true
+			case media == "audio" && !audio:
+				sdp.AudioPort, audio = port, true
+				for _, field := range fields[3:] {
+					if pt, err := strconv.ParseUint(field, 10, 7); err == nil {
+						sdp.Payloads = append(sdp.Payloads, uint8(pt))
+					}
+				}
+			default:
+				media = "other"
+			}
+		case "a":
+			name, value, _ := strings.Cut(value, ":")
+			switch {
+			case media == "application" && name == "resource":
+				sdp.Resource = value
+			case media == "application" && name == "channel":
+				sdp.Channel = value
+			case media == "application" && name == "cmid":
+				sdp.ControlMid = value
+			case media == "audio" && name == "mid":
+				sdp.AudioMid = value
+			case media == "audio" && name == "rtpmap":
+				if pt, encoding, ok := strings.Cut(value, " "); ok {
+					if pt, err := strconv.ParseUint(pt, 10, 7); err == nil {
+						sdp.Rtpmap[uint8(pt)] = encoding
+					}
+				}
+			}
+		}
+	}
+	if !control || !audio {
+		return nil, ErrInvalidMessage
+	}
+
+	// Return success
+	return sdp, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Bytes returns the session as an answer from the server, which accepts a
+// new control channel connection and receives audio
+func (sdp *SDP) Bytes() []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "v=0\r\n")
+	fmt.Fprintf(&buf, "o=- 0 0 IN IP4 %s\r\n", sdp.Addr)
+	fmt.Fprintf(&buf, "s=-\r\n")
+	fmt.Fprintf(&buf, "c=IN IP4 %s\r\n", sdp.Addr)
+	fmt.Fprintf(&buf, "t=0 0\r\n")
+
+	// The control channel
+	fmt.Fprintf(&buf, "m=application %d TCP/MRCPv2 1\r\n", sdp.ControlPort)
+	fmt.Fprintf(&buf, "a=setup:passive\r\n")
+	fmt.Fprintf(&buf, "a=connection:new\r\n")
+	fmt.Fprintf(&buf, "a=channel:%s@%s\r\n", sdp.Channel, sdp.Resource)
+	if sdp.ControlMid != "" {
+		fmt.Fprintf(&buf, "a=cmid:%s\r\n", sdp.ControlMid)
+	}
+
+	// The audio stream
+	payloads := make([]string, len(sdp.Payloads))
+	for i, pt := range sdp.Payloads {
+		payloads[i] = strconv.Itoa(int(pt))
+	}
+	fmt.Fprintf(&buf, "m=audio %d RTP/AVP %s\r\n", sdp.AudioPort, strings.Join(payloads, " "))
+	for _, pt := range sdp.Payloads {
+		if encoding, exists := sdp.Rtpmap[pt]; exists {
+			fmt.Fprintf(&buf, "a=rtpmap:%d %s\r\n", pt, encoding)
+		}
+	}
+	fmt.Fprintf(&buf, "a=recvonly\r\n")
+	if sdp.AudioMid != "" {
+		fmt.Fprintf(&buf, "a=mid:%s\r\n", sdp.AudioMid)
+	}
+	return buf.Bytes()
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (sdp *SDP) String() string {
+	str := "<mrcp.sdp"
+	str += fmt.Sprintf(" addr=%q", sdp.Addr)
+	str += fmt.Sprintf(" control_port=%d", sdp.ControlPort)
+	if sdp.Resource != "" {
+		str += fmt.Sprintf(" resource=%q", sdp.Resource)
+	}
+	if sdp.Channel != "" {
+		str += fmt.Sprintf(" channel=%q", sdp.Channel)
+	}
+	str += fmt.Sprintf(" audio_port=%d", sdp.AudioPort)
+	str += fmt.Sprintf(" payloads=%v", sdp.Payloads)
+	return str + ">"
+}
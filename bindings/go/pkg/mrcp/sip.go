@@ -0,0 +1,180 @@
+package mrcp
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/textproto"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// SIPMessage is a SIP request or response, as used to establish and end a
+// session
+type SIPMessage struct {
+	Method string // Method of a request, or empty for a response
+	URI    string // Request URI
+	Status int    // Status code of a response
+	Reason string // Reason phrase of a response
+	Header textproto.MIMEHeader
+	Body   []byte
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	SIPVersion = "SIP/2.0"
+)
+
+// Methods of SIP requests
+const (
+	SIPInvite  = "INVITE"
+	SIPAck     = "ACK"
+	SIPBye     = "BYE"
+	SIPCancel  = "CANCEL"
+	SIPOptions = "OPTIONS"
+)
+
+// The full names of compact header fields
+var sipCompact = map[string]string{
+	"V": "Via",
+	"F": "From",
+	"T": "To",
+	"I": "Call-Id",
+	"M": "Contact",
+	"L": "Content-Length",
+	"C": "Content-Type",
+}
+
+// Header fields copied from a request to its response
+var sipCopied = []string{"Via", "From", "To", "Call-Id", "Cseq"}
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// ParseSIP parses a SIP message received in a datagram
+func ParseSIP(data []byte) (*SIPMessage, error) {
+	reader := textproto.NewReader(bufio.NewReader(bytes.NewReader(data)))
+	line, err := reader.ReadLine()
+	if err != nil {
+		return nil, ErrInvalidMessage
+	}
+	message := new(SIPMessage)
+	fields := strings.SplitN(line, " ", 3)
+	if len(fields) != 3 {
+		return nil, ErrInvalidMessage
+	} else if fields[0] == SIPVersion {
+		if message.Status, err = strconv.Atoi(fields[1]); err != nil {
+			return nil, ErrInvalidMessage
+		}
+		message.Reason = fields[2]
+	} else if fields[2] == SIPVersion {
+		message.Method, message.URI = fields[0], fields[1]
+	} else {
+		return nil, ErrInvalidMessage
+	}
+
+	// Read the header, expanding compact field names
+	header, err := reader.ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return nil, ErrInvalidMessage
+	}
+	message.Header = make(textproto.MIMEHeader, len(header))
+	for key, values := range header {
+		if name, exists := sipCompact[key]; exists {
+			key = name
+		}
+		message.Header[key] = append(message.Header[key], values...)
+	}
+
+	// The body is the rest of the datagram, up to the content length
+	body, _ := io.ReadAll(reader.R)
+	if value := message.Header.Get("Content-Length"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 || n > len(body) {
+			return nil, ErrInvalidMessage
+		}
+		body = body[:n]
+	}
+	message.Body = body
+
+	// Return success
+	return message, nil
+}
+
+// NewSIPResponse returns a response to a request, with the header fields
+// which identify the transaction copied from the request
+func NewSIPResponse(request *SIPMessage, status int, reason string) *SIPMessage {
+	response := &SIPMessage{
+		Status: status,
+		Reason: reason,
+		Header: make(textproto.MIMEHeader),
+	}
+	for _, key := range sipCopied {
+		if values := request.Header.Values(key); len(values) > 0 {
+			response.Header[key] = slices.Clone(values)
+		}
+	}
+	return response
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Bytes returns the encoded message. The Content-Length header is set from
+// the body.
+func (message *SIPMessage) Bytes() []byte {
+	var buf bytes.Buffer
+	if message.Method != "" {
+		fmt.Fprintf(&buf, "%s %s %s\r\n", message.Method, message.URI, SIPVersion)
+	} else {
+		fmt.Fprintf(&buf, "%s %d %s\r\n", SIPVersion, message.Status, message.Reason)
+	}
+
+	// The transaction fields come first, and the others in a stable order
+	keys := make([]string, 0, len(message.Header))
+	for key := range message.Header {
+		if key != "Content-Length" && !slices.Contains(sipCopied, key) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	for _, key := range append(slices.Clone(sipCopied), keys...) {
+		for _, value := range message.Header[key] {
+			fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+		}
+	}
+	fmt.Fprintf(&buf, "Content-Length: %d\r\n\r\n", len(message.Body))
+	buf.Write(message.Body)
+	return buf.Bytes()
+}
+
+// CallID returns the call identifier of the message
+func (message *SIPMessage) CallID() string {
+	return message.Header.Get("Call-Id")
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (message *SIPMessage) String() string {
+	str := "<mrcp.sip"
+	if message.Method != "" {
+		str += fmt.Sprintf(" method=%q uri=%q", message.Method, message.URI)
+	} else {
+		str += fmt.Sprintf(" status=%d reason=%q", message.Status, message.Reason)
+	}
+	if callID := message.CallID(); callID != "" {
+		str += fmt.Sprintf(" call_id=%q", callID)
+	}
+	if len(message.Body) > 0 {
+		str += fmt.Sprintf(" len=%d", len(message.Body))
+	}
+	return str + ">"
+}
@@ -0,0 +1,23 @@
+package opus
+
+import "errors"
+
+///////////////////////////////////////////////////////////////////////////////
+// ERRORS
+
+var (
+	ErrNotSupported  = errors.New("opus decoding not supported, build with the opus tag")
+	ErrInvalidPage   = errors.New("invalid ogg page")
+	ErrInvalidHeader = errors.New("invalid opus header")
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// Sample rate of the Opus pre-skip and granule positions
+	OpusRate = 48000
+
+	// Maximum duration of an Opus packet, 120ms, in samples at OpusRate
+	maxFrameSize = 5760
+)
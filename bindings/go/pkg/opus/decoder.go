@@ -0,0 +1,74 @@
+//go:build opus
+
+package opus
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+/*
+#cgo pkg-config: opus
+#include <opus.h>
+*/
+import "C"
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Decoder decodes Opus packets to mono samples
+type Decoder struct {
+	dec      *C.OpusDecoder
+	channels int
+	pcm      []float32
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// NewDecoder returns a decoder for packets with the given number of channels,
+// returning samples at the given rate, which must be one of 8000, 12000,
+// 16000, 24000 or 48000
+func NewDecoder(rate, channels int) (*Decoder, error) {
+	var err C.int
+	dec := C.opus_decoder_create(C.opus_int32(rate), C.int(channels), &err)
+	if err != C.OPUS_OK {
+		return nil, fmt.Errorf("opus: %s", C.GoString(C.opus_strerror(err)))
+	}
+	return &Decoder{
+		dec:      dec,
+		channels: channels,
+		pcm:      make([]float32, maxFrameSize*rate/OpusRate*channels),
+	}, nil
+}
+
+// Close the decoder and release resources
+func (decoder *Decoder) Close() error {
+	if decoder.dec != nil {
+		C.opus_decoder_destroy(decoder.dec)
+		decoder.dec = nil
+	}
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Decode a packet to mono samples
+func (decoder *Decoder) Decode(packet []byte) ([]float32, error) {
+	if len(packet) == 0 {
+		return nil, nil
+	}
+	n := C.opus_decode_float(
+		decoder.dec,
+		(*C.uchar)(unsafe.Pointer(&packet[0])),
+		C.opus_int32(len(packet)),
+		(*C.float)(unsafe.Pointer(&decoder.pcm[0])),
+		C.int(len(decoder.pcm)/decoder.channels),
+		0,
+	)
+	if n < 0 {
+		return nil, fmt.Errorf("opus: %s", C.GoString(C.opus_strerror(n)))
+	}
+	return downmix(decoder.pcm[:int(n)*decoder.channels], decoder.channels), nil
+}
@@ -0,0 +1,31 @@
+//go:build !opus
+
+package opus
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Decoder decodes Opus packets to mono samples
+type Decoder struct{}
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// NewDecoder returns ErrNotSupported, as the package was built without the
+// opus build tag
+func NewDecoder(rate, channels int) (*Decoder, error) {
+	return nil, ErrNotSupported
+}
+
+// Close the decoder and release resources
+func (decoder *Decoder) Close() error {
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Decode a packet to mono samples
+func (decoder *Decoder) Decode(packet []byte) ([]float32, error) {
+	return nil, ErrNotSupported
+}
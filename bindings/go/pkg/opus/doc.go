@@ -0,0 +1,14 @@
+/*
+Package opus decodes Opus audio, as sent by browsers with MediaRecorder or
+WebRTC, to mono float32 samples. Audio can be raw Opus packets, or an Ogg/Opus
+stream which is demuxed as pages arrive.
+
+Decoding uses libopus and is only available when built with the "opus" build
+tag, for example:
+
+	go build -tags opus ./...
+
+Without the build tag, NewDecoder returns ErrNotSupported. The Ogg demuxer is
+always available.
+*/
+package opus
@@ -0,0 +1,151 @@
+package opus
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Demuxer extracts packets from an Ogg stream. Data can be written in chunks
+// of any size, and packets which span pages are reassembled. Only the first
+// logical stream is returned, and pages of other streams are skipped.
+type Demuxer struct {
+	buf    []byte // Data which does not yet make up a whole page
+	packet []byte // Packet which continues on the next page
+	serial uint32 // Serial number of the logical stream
+	bos    bool   // True once the first page has been read
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	oggHeaderSize = 27
+
+	oggContinued = 0x01 // Page continues a packet from the previous page
+)
+
+var (
+	oggMagic = []byte("OggS")
+	oggCRC   [256]uint32
+)
+
+func init() {
+	for i := range oggCRC {
+		r := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if r&0x80000000 != 0 {
+				r = r<<1 ^ 0x04c11db7
+			} else {
+				r <<= 1
+			}
+		}
+		oggCRC[i] = r
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Write data to the demuxer and return the packets which were completed
+func (demuxer *Demuxer) Write(data []byte) ([][]byte, error) {
+	var result [][]byte
+	demuxer.buf = append(demuxer.buf, data...)
+	for {
+		page, n, err := demuxer.nextPage()
+		if err != nil {
+			return result, err
+		} else if n == 0 {
+			break
+		}
+		result = append(result, demuxer.readPage(page)...)
+		demuxer.buf = demuxer.buf[n:]
+	}
+
+	// Retain the data which does not make up a whole page
+	demuxer.buf = append(demuxer.buf[:0:0], demuxer.buf...)
+
+	// Return packets
+	return result, nil
+}
+
+// Reset the demuxer, discarding any buffered data
+func (demuxer *Demuxer) Reset() {
+	demuxer.buf = nil
+	demuxer.packet = nil
+	demuxer.bos = false
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Return the next complete page in the buffer and its length, or zero length
+// when more data is needed
+func (demuxer *Demuxer) nextPage() ([]byte, int, error) {
+	buf := demuxer.buf
+	if len(buf) < oggHeaderSize {
+		return nil, 0, nil
+	}
+	if !bytes.Equal(buf[:4], oggMagic) || buf[4] != 0 {
+		return nil, 0, ErrInvalidPage
+	}
+	nsegs := int(buf[26])
+	if len(buf) < oggHeaderSize+nsegs {
+		return nil, 0, nil
+	}
+	size := oggHeaderSize + nsegs
+	for _, v := range buf[oggHeaderSize : oggHeaderSize+nsegs] {
+		size += int(v)
+	}
+	if len(buf) < size {
+		return nil, 0, nil
+	}
+	page := buf[:size]
+	if binary.LittleEndian.Uint32(page[22:]) != crc(page) {
+		return nil, 0, ErrInvalidPage
+	}
+
+	// Return the page
+	return page, size, nil
+}
+
+// Return the packets completed by a page
+func (demuxer *Demuxer) readPage(page []byte) [][]byte {
+	serial := binary.LittleEndian.Uint32(page[14:])
+	if !demuxer.bos {
+		demuxer.bos = true
+		demuxer.serial = serial
+	} else if serial != demuxer.serial {
+		return nil
+	}
+	if page[5]&oggContinued == 0 {
+		demuxer.packet = nil
+	}
+
+	var result [][]byte
+	nsegs := int(page[26])
+	data := page[oggHeaderSize+nsegs:]
+	for _, v := range page[oggHeaderSize : oggHeaderSize+nsegs] {
+		demuxer.packet = append(demuxer.packet, data[:v]...)
+		data = data[v:]
+		if v < 255 {
+			result = append(result, demuxer.packet)
+			demuxer.packet = nil
+		}
+	}
+	return result
+}
+
+// Return the checksum of a page, calculated with the checksum field as zero
+func crc(page []byte) uint32 {
+	var result uint32
+	for i, v := range page {
+		if i >= 22 && i < 26 {
+			v = 0
+		}
+		result = result<<8 ^ oggCRC[byte(result>>24)^v]
+	}
+	return result
+}
@@ -0,0 +1,87 @@
+package opus_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	// Packages
+	opus "github.com/ggerganov/whisper.cpp/bindings/go/pkg/opus"
+	assert "github.com/stretchr/testify/assert"
+)
+
+// Return an Ogg page containing the segments
+func page(serial uint32, flags byte, lacing []byte, data []byte) []byte {
+	buf := new(bytes.Buffer)
+	buf.WriteString("OggS")
+	buf.WriteByte(0)
+	buf.WriteByte(flags)
+	binary.Write(buf, binary.LittleEndian, uint64(0))
+	binary.Write(buf, binary.LittleEndian, serial)
+	binary.Write(buf, binary.LittleEndian, uint32(0))
+	binary.Write(buf, binary.LittleEndian, uint32(0))
+	buf.WriteByte(byte(len(lacing)))
+	buf.Write(lacing)
+	buf.Write(data)
+
+	// Set the checksum
+	result := buf.Bytes()
+	var crc uint32
+	for _, v := range result {
+		crc ^= uint32(v) << 24
+		for i := 0; i < 8; i++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	binary.LittleEndian.PutUint32(result[22:], crc)
+	return result
+}
+
+func Test_Opus_000(t *testing.T) {
+	assert := assert.New(t)
+
+	// Two packets on one page, and a packet which spans two pages
+	long := bytes.Repeat([]byte{'c'}, 300)
+	stream := append(page(1, 0x02, []byte{3, 2, 255}, []byte("aaabb"+string(long[:255]))),
+		page(1, 0x01, []byte{45}, long[255:])...)
+
+	// Write the stream one byte at a time
+	var demuxer opus.Demuxer
+	var packets [][]byte
+	for i := range stream {
+		result, err := demuxer.Write(stream[i : i+1])
+		assert.NoError(err)
+		packets = append(packets, result...)
+	}
+	assert.Equal([][]byte{[]byte("aaa"), []byte("bb"), long}, packets)
+}
+
+func Test_Opus_001(t *testing.T) {
+	assert := assert.New(t)
+
+	// Pages of other logical streams are skipped
+	var demuxer opus.Demuxer
+	packets, err := demuxer.Write(append(page(1, 0x02, []byte{1}, []byte("a")), page(2, 0x02, []byte{1}, []byte("b"))...))
+	assert.NoError(err)
+	assert.Equal([][]byte{[]byte("a")}, packets)
+
+	// A corrupted page is an error
+	data := page(1, 0, []byte{1}, []byte("c"))
+	data[len(data)-1] = 'd'
+	_, err = demuxer.Write(data)
+	assert.ErrorIs(err, opus.ErrInvalidPage)
+}
+
+func Test_Opus_002(t *testing.T) {
+	assert := assert.New(t)
+
+	// A stream which does not start with the identification header is an error
+	decoder := opus.NewOggDecoder(16000)
+	defer decoder.Close()
+	_, err := decoder.Write(page(1, 0x02, []byte{8}, []byte("OpusTags")))
+	assert.ErrorIs(err, opus.ErrInvalidHeader)
+}
@@ -0,0 +1,139 @@
+package opus
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// OggDecoder decodes an Ogg/Opus stream to mono samples. The stream is
+// written in chunks of any size, for example as websocket frames arrive.
+type OggDecoder struct {
+	rate    int
+	demuxer Demuxer
+	decoder *Decoder
+	tags    bool // True once the comment header has been read
+	skip    int  // Samples still to be skipped from the start of the stream
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+var (
+	opusHead = []byte("OpusHead")
+	opusTags = []byte("OpusTags")
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// NewOggDecoder returns a decoder for an Ogg/Opus stream, returning samples
+// at the given rate, which must be one of 8000, 12000, 16000, 24000 or 48000
+func NewOggDecoder(rate int) *OggDecoder {
+	return &OggDecoder{rate: rate}
+}
+
+// Close the decoder and release resources
+func (decoder *OggDecoder) Close() error {
+	if decoder.decoder == nil {
+		return nil
+	}
+	err := decoder.decoder.Close()
+	decoder.decoder = nil
+	return err
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Write data to the decoder and return the samples which were decoded
+func (decoder *OggDecoder) Write(data []byte) ([]float32, error) {
+	packets, err := decoder.demuxer.Write(data)
+	if err != nil {
+		return nil, err
+	}
+	var result []float32
+	for _, packet := range packets {
+		samples, err := decoder.decode(packet)
+		if err != nil {
+			return result, err
+		}
+		result = append(result, samples...)
+	}
+
+	// Return samples
+	return result, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func (decoder *OggDecoder) decode(packet []byte) ([]float32, error) {
+	// The first packet is the identification header
+	if decoder.decoder == nil {
+		channels, preskip, err := readHead(packet)
+		if err != nil {
+			return nil, err
+		}
+		if decoder.decoder, err = NewDecoder(decoder.rate, channels); err != nil {
+			return nil, err
+		}
+		decoder.skip = preskip * decoder.rate / OpusRate
+		return nil, nil
+	}
+
+	// The second packet is the comment header
+	if !decoder.tags {
+		if !bytes.HasPrefix(packet, opusTags) {
+			return nil, ErrInvalidHeader
+		}
+		decoder.tags = true
+		return nil, nil
+	}
+
+	// Decode audio, discarding the pre-skip samples
+	samples, err := decoder.decoder.Decode(packet)
+	if err != nil {
+		return nil, err
+	}
+	if decoder.skip > 0 {
+		n := decoder.skip
+		if n > len(samples) {
+			n = len(samples)
+		}
+		samples = samples[n:]
+		decoder.skip -= n
+	}
+	return samples, nil
+}
+
+// Return the number of channels and pre-skip samples from the
+// identification header
+func readHead(packet []byte) (int, int, error) {
+	if len(packet) < 19 || !bytes.HasPrefix(packet, opusHead) || packet[8]>>4 != 0 {
+		return 0, 0, ErrInvalidHeader
+	}
+	channels := int(packet[9])
+	if channels == 0 {
+		return 0, 0, ErrInvalidHeader
+	}
+	return channels, int(binary.LittleEndian.Uint16(packet[10:])), nil
+}
+
+// Mix interleaved samples down to mono
+func downmix(data []float32, channels int) []float32 {
+	if channels == 1 {
+		return append([]float32(nil), data...)
+	}
+	result := make([]float32, len(data)/channels)
+	for i := range result {
+		var sum float32
+		for _, v := range data[i*channels : (i+1)*channels] {
+			sum += v
+		}
+		result[i] = sum / float32(channels)
+	}
+	return result
+}
@@ -0,0 +1,7 @@
+/*
+Package postprocess provides filters which clean up the segments returned by
+a speech-to-text context before they are output, such as trimming whitespace,
+merging short segments, masking profanity and redacting sensitive text. Filters
+are combined into a pipeline with Chain.
+*/
+package postprocess
@@ -0,0 +1,78 @@
+package postprocess
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	// Packages
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+var (
+	// CardNumber matches runs of 13 to 19 digits, optionally separated by
+	// spaces or dashes, such as payment card numbers
+	CardNumber = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+
+	// SSN matches US social security numbers
+	SSN = regexp.MustCompile(`\b\d{3}[ -]\d{2}[ -]\d{4}\b`)
+
+	// PhoneNumber matches ten digit phone numbers, with an optional country
+	// code and area code in parentheses
+	PhoneNumber = regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{3}\)|\b\d{3})[ .-]?\d{3}[ .-]?\d{4}\b`)
+)
+
+// The patterns redacted by PII, and their placeholders, in the order they
+// are applied
+var pii = []struct {
+	re          *regexp.Regexp
+	placeholder string
+}{
+	{CardNumber, "[CARD]"},
+	{SSN, "[SSN]"},
+	{PhoneNumber, "[PHONE]"},
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// PII returns a filter which replaces card numbers, social security numbers
+// and phone numbers in the text of each segment with placeholders. Numbers
+// are often split across words, so when the text of a segment is redacted,
+// the digits of all its words are masked.
+func PII() Filter {
+	return func(segments []whisper.Segment) []whisper.Segment {
+		for i := range segments {
+			text := segments[i].Text
+			for _, p := range pii {
+				text = p.re.ReplaceAllString(text, p.placeholder)
+			}
+			if text == segments[i].Text {
+				continue
+			}
+			segments[i].Text = text
+			words := make([]whisper.Word, len(segments[i].Words))
+			for j, word := range segments[i].Words {
+				word.Text = maskDigits(word.Text)
+				words[j] = word
+			}
+			segments[i].Words = words
+		}
+		return segments
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func maskDigits(str string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return Mask
+		}
+		return r
+	}, str)
+}
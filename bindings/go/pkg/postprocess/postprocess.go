@@ -0,0 +1,168 @@
+package postprocess
+
+import (
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	// Packages
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Filter transforms segments, returning the segments to output
+type Filter func([]whisper.Segment) []whisper.Segment
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+// Mask replaces text which is masked or redacted
+const Mask = '*'
+
+// DefaultProfanity is the list of words masked by Profanity when no words
+// are given
+var DefaultProfanity = []string{
+	"arse", "arsehole", "ass", "asshole", "bastard", "bitch", "bollocks",
+	"bullshit", "crap", "damn", "dick", "fuck", "fucked", "fucking", "piss",
+	"pissed", "prick", "shit", "shitty", "wanker",
+}
+
+// Digits matches runs of four or more digits, optionally separated by
+// spaces or dashes, such as card and account numbers
+var Digits = regexp.MustCompile(`\d(?:[ -]?\d){3,}`)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Chain returns a filter which applies each filter in turn. Nil filters are
+// skipped.
+func Chain(filters ...Filter) Filter {
+	return func(segments []whisper.Segment) []whisper.Segment {
+		for _, filter := range filters {
+			if filter != nil {
+				segments = filter(segments)
+			}
+		}
+		return segments
+	}
+}
+
+// TrimSpace removes leading and trailing whitespace from the text of each
+// segment, and drops segments which are left empty
+func TrimSpace(segments []whisper.Segment) []whisper.Segment {
+	result := segments[:0]
+	for _, segment := range segments {
+		segment.Text = strings.TrimSpace(segment.Text)
+		if segment.Text != "" {
+			result = append(result, segment)
+		}
+	}
+	return renumber(result)
+}
+
+// MergeShort returns a filter which merges each segment shorter than min
+// into the segment which follows it, or into the preceding segment when it
+// is the last. Segments are not merged across a speaker turn.
+func MergeShort(min time.Duration) Filter {
+	return func(segments []whisper.Segment) []whisper.Segment {
+		var result []whisper.Segment
+		for _, segment := range segments {
+			if n := len(result); n > 0 && isShort(result[n-1], min) && !result[n-1].SpeakerTurnNext {
+				result[n-1] = merge(result[n-1], segment)
+			} else {
+				result = append(result, segment)
+			}
+		}
+		if n := len(result); n > 1 && isShort(result[n-1], min) && !result[n-2].SpeakerTurnNext {
+			result = append(result[:n-2], merge(result[n-2], result[n-1]))
+		}
+		return renumber(result)
+	}
+}
+
+// Profanity returns a filter which masks each letter but the first of the
+// words, matched as whole words regardless of case. DefaultProfanity is
+// used when no words are given.
+func Profanity(words ...string) Filter {
+	if len(words) == 0 {
+		words = DefaultProfanity
+	}
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = regexp.QuoteMeta(word)
+	}
+	re := regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`)
+	return replace(re, func(match string) string {
+		_, n := utf8.DecodeRuneInString(match)
+		return match[:n] + strings.Repeat(string(Mask), utf8.RuneCountInString(match)-1)
+	})
+}
+
+// Redact returns a filter which replaces text matching the expression with
+// the replacement, or masks each letter and digit of the match when the
+// replacement is empty
+func Redact(re *regexp.Regexp, replacement string) Filter {
+	return replace(re, func(match string) string {
+		if replacement != "" {
+			return replacement
+		}
+		return strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return Mask
+			}
+			return r
+		}, match)
+	})
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Return a filter which replaces matches in the text of each segment and
+// of its words
+func replace(re *regexp.Regexp, fn func(string) string) Filter {
+	return func(segments []whisper.Segment) []whisper.Segment {
+		for i := range segments {
+			segments[i].Text = re.ReplaceAllStringFunc(segments[i].Text, fn)
+			if len(segments[i].Words) == 0 {
+				continue
+			}
+			words := make([]whisper.Word, len(segments[i].Words))
+			for j, word := range segments[i].Words {
+				word.Text = re.ReplaceAllStringFunc(word.Text, fn)
+				words[j] = word
+			}
+			segments[i].Words = words
+		}
+		return segments
+	}
+}
+
+// Return true if a segment is shorter than min
+func isShort(segment whisper.Segment, min time.Duration) bool {
+	return segment.End-segment.Start < min
+}
+
+// Merge two consecutive segments
+func merge(a, b whisper.Segment) whisper.Segment {
+	a.End = b.End
+	a.Text = strings.TrimSpace(a.Text + " " + b.Text)
+	a.Tokens = append(append([]whisper.Token(nil), a.Tokens...), b.Tokens...)
+	a.Words = append(append([]whisper.Word(nil), a.Words...), b.Words...)
+	a.SpeakerTurnNext = b.SpeakerTurnNext
+	return a
+}
+
+// Number the segments from the number of the first
+func renumber(segments []whisper.Segment) []whisper.Segment {
+	for i := range segments {
+		if i > 0 {
+			segments[i].Num = segments[0].Num + i
+		}
+	}
+	return segments
+}
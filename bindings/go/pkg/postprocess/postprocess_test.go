@@ -0,0 +1,112 @@
+package postprocess_test
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	// Packages
+	postprocess "github.com/ggerganov/whisper.cpp/bindings/go/pkg/postprocess"
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	assert "github.com/stretchr/testify/assert"
+)
+
+// Return a segment with the text, from start to end seconds
+func segment(num int, start, end float64, text string) whisper.Segment {
+	return whisper.Segment{
+		Num:   num,
+		Start: time.Duration(start * float64(time.Second)),
+		End:   time.Duration(end * float64(time.Second)),
+		Text:  text,
+	}
+}
+
+func Test_Postprocess_000(t *testing.T) {
+	assert := assert.New(t)
+
+	// Whitespace is trimmed, and empty segments dropped
+	result := postprocess.TrimSpace([]whisper.Segment{
+		segment(0, 0, 1, "  hello "),
+		segment(1, 1, 2, " "),
+		segment(2, 2, 3, "world\n"),
+	})
+	assert.Len(result, 2)
+	assert.Equal("hello", result[0].Text)
+	assert.Equal("world", result[1].Text)
+	assert.Equal(1, result[1].Num)
+}
+
+func Test_Postprocess_001(t *testing.T) {
+	assert := assert.New(t)
+	filter := postprocess.MergeShort(time.Second)
+
+	// Short segments are merged into the following segment
+	result := filter([]whisper.Segment{
+		segment(0, 0, 0.5, "yes"),
+		segment(1, 0.5, 3, "that is right"),
+		segment(2, 3, 6, "see you then"),
+		segment(3, 6, 6.2, "bye"),
+	})
+	assert.Len(result, 2)
+	assert.Equal("yes that is right", result[0].Text)
+	assert.Equal(time.Duration(0), result[0].Start)
+	assert.Equal(3*time.Second, result[0].End)
+	assert.Equal("see you then bye", result[1].Text)
+	assert.Equal(1, result[1].Num)
+
+	// Segments are not merged across a speaker turn
+	turn := segment(0, 0, 0.5, "hello")
+	turn.SpeakerTurnNext = true
+	result = filter([]whisper.Segment{turn, segment(1, 0.5, 3, "hi there")})
+	assert.Len(result, 2)
+}
+
+func Test_Postprocess_002(t *testing.T) {
+	assert := assert.New(t)
+
+	// Profanity is masked as whole words, regardless of case
+	result := postprocess.Profanity()([]whisper.Segment{segment(0, 0, 1, "Damn, that class was shit")})
+	assert.Equal("D***, that class was s***", result[0].Text)
+	result = postprocess.Profanity("heck")([]whisper.Segment{segment(0, 0, 1, "what the heck")})
+	assert.Equal("what the h***", result[0].Text)
+
+	// Digit runs are redacted
+	result = postprocess.Redact(postprocess.Digits, "")([]whisper.Segment{segment(0, 0, 1, "card 4111 1111-1111 1111, room 12")})
+	assert.Equal("card **** ****-**** ****, room 12", result[0].Text)
+	result = postprocess.Redact(regexp.MustCompile(`(?i)secret \w+`), "[REDACTED]")([]whisper.Segment{segment(0, 0, 1, "the Secret word")})
+	assert.Equal("the [REDACTED]", result[0].Text)
+}
+
+func Test_Postprocess_003(t *testing.T) {
+	assert := assert.New(t)
+
+	// Filters are applied in order, skipping nil filters
+	filter := postprocess.Chain(postprocess.TrimSpace, nil, postprocess.Profanity())
+	result := filter([]whisper.Segment{segment(0, 0, 1, " oh crap ")})
+	assert.Equal("oh c***", result[0].Text)
+}
+
+func Test_Postprocess_004(t *testing.T) {
+	assert := assert.New(t)
+	filter := postprocess.PII()
+
+	// Card numbers, social security numbers and phone numbers are redacted
+	for text, expected := range map[string]string{
+		"my card is 4111 1111 1111 1111 thanks": "my card is [CARD] thanks",
+		"it's 4111-1111-1111-1111.":             "it's [CARD].",
+		"social is 123-45-6789":                 "social is [SSN]",
+		"call me on (555) 123-4567 later":       "call me on [PHONE] later",
+		"or +1 555.123.4567":                    "or [PHONE]",
+		"I have 3 cats and 12 dogs":             "I have 3 cats and 12 dogs",
+	} {
+		result := filter([]whisper.Segment{segment(0, 0, 1, text)})
+		assert.Equal(expected, result[0].Text, text)
+	}
+
+	// The digits of the words of a redacted segment are masked
+	redacted := segment(0, 0, 1, "card 4111 1111 1111 1111")
+	redacted.Words = []whisper.Word{{Text: "card"}, {Text: "4111"}, {Text: "1111"}, {Text: "1111"}, {Text: "1111"}}
+	result := filter([]whisper.Segment{redacted})
+	assert.Equal("card", result[0].Words[0].Text)
+	assert.Equal("****", result[0].Words[1].Text)
+}
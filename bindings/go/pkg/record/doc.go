@@ -0,0 +1,5 @@
+/*
+Package record writes the audio received by a streaming session to a WAV file,
+for debugging, quality assurance and reprocessing with a larger model later.
+*/
+package record
@@ -0,0 +1,113 @@
+package record
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	// Packages
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	audio "github.com/go-audio/audio"
+	wav "github.com/go-audio/wav"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Recorder writes mono audio at the whisper sample rate to a 16-bit WAV file
+type Recorder struct {
+	path string
+	fh   *os.File
+	enc  *wav.Encoder
+	buf  *audio.IntBuffer
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// Layout of the time in recording filenames
+	TimeLayout = "20060102-150405.000"
+
+	bitDepth = 16
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Create a WAV file in the directory, named with the current time and the
+// session name, for example "20240102-150405.000-0x1234abcd.wav". The
+// directory is created if it does not exist.
+func Create(dir, name string) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.wav", time.Now().Format(TimeLayout), name))
+	fh, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	recorder := &Recorder{
+		path: path,
+		fh:   fh,
+		enc:  wav.NewEncoder(fh, whisper.SampleRate, bitDepth, 1, 1),
+		buf: &audio.IntBuffer{
+			Format:         &audio.Format{NumChannels: 1, SampleRate: whisper.SampleRate},
+			SourceBitDepth: bitDepth,
+		},
+	}
+
+	// Return success
+	return recorder, nil
+}
+
+// Close the recorder, completing the WAV header
+func (recorder *Recorder) Close() error {
+	var result error
+	if recorder.enc != nil {
+		if err := recorder.enc.Close(); err != nil {
+			result = err
+		}
+	}
+	if recorder.fh != nil {
+		if err := recorder.fh.Close(); err != nil && result == nil {
+			result = err
+		}
+	}
+
+	// Release resources
+	recorder.enc = nil
+	recorder.fh = nil
+
+	// Return any error
+	return result
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (recorder *Recorder) String() string {
+	return fmt.Sprintf("<record.recorder path=%q>", recorder.path)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return the path of the WAV file
+func (recorder *Recorder) Path() string {
+	return recorder.path
+}
+
+// Write mono samples at the whisper sample rate
+func (recorder *Recorder) Write(data []float32) error {
+	if recorder.enc == nil {
+		return os.ErrClosed
+	}
+	recorder.buf.Data = recorder.buf.Data[:0]
+	for _, v := range data {
+		recorder.buf.Data = append(recorder.buf.Data, int(math.Round(float64(max(-1, min(1, v)))*math.MaxInt16)))
+	}
+	return recorder.enc.Write(recorder.buf)
+}
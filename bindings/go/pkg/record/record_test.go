@@ -0,0 +1,40 @@
+package record_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	// Packages
+	record "github.com/ggerganov/whisper.cpp/bindings/go/pkg/record"
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	wav "github.com/go-audio/wav"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func Test_Record_000(t *testing.T) {
+	assert := assert.New(t)
+	dir := filepath.Join(t.TempDir(), "sessions")
+
+	// Record a second of audio in two writes
+	recorder, err := record.Create(dir, "test")
+	assert.NoError(err)
+	assert.True(strings.HasSuffix(recorder.Path(), "-test.wav"))
+	assert.NoError(recorder.Write(make([]float32, whisper.SampleRate/2)))
+	assert.NoError(recorder.Write([]float32{-2, -1, 0, 1, 2}))
+	assert.NoError(recorder.Write(make([]float32, whisper.SampleRate/2-5)))
+	assert.NoError(recorder.Close())
+	assert.Error(recorder.Write([]float32{0}))
+
+	// Read it back
+	fh, err := os.Open(recorder.Path())
+	assert.NoError(err)
+	defer fh.Close()
+	buf, err := wav.NewDecoder(fh).FullPCMBuffer()
+	assert.NoError(err)
+	assert.Equal(whisper.SampleRate, buf.Format.SampleRate)
+	assert.Equal(1, buf.Format.NumChannels)
+	assert.Len(buf.Data, whisper.SampleRate)
+	assert.Equal([]int{-32767, -32767, 0, 32767, 32767}, buf.Data[whisper.SampleRate/2:whisper.SampleRate/2+5])
+}
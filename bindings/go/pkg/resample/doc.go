@@ -0,0 +1,5 @@
+/*
+Package resample converts mono audio between sample rates, for example from
+8 kHz telephony audio or 44.1/48 kHz files to the 16 kHz expected by whisper.
+*/
+package resample
@@ -0,0 +1,104 @@
+package resample
+
+import (
+	"math"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Resampler converts samples from one sample rate to another
+type Resampler func(data []float32, from, to int) []float32
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// Number of zero crossings of the sinc kernel on each side of a sample
+	SincZeroCrossings = 16
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Linear resamples by linear interpolation between neighbouring samples.
+// It is fast, but does not filter out frequencies above the new Nyquist
+// frequency when downsampling.
+func Linear(data []float32, from, to int) []float32 {
+	if from == to || len(data) == 0 {
+		return append([]float32(nil), data...)
+	}
+	step := float64(from) / float64(to)
+	result := make([]float32, outputLen(len(data), from, to))
+	for i := range result {
+		t := float64(i) * step
+		k := int(t)
+		if k+1 >= len(data) {
+			result[i] = data[len(data)-1]
+			continue
+		}
+		f := float32(t - float64(k))
+		result[i] = data[k]*(1-f) + data[k+1]*f
+	}
+	return result
+}
+
+// Sinc resamples by band-limited interpolation with a Hann-windowed sinc
+// kernel. When downsampling, the kernel cutoff is lowered to the new Nyquist
+// frequency to avoid aliasing.
+func Sinc(data []float32, from, to int) []float32 {
+	if from == to || len(data) == 0 {
+		return append([]float32(nil), data...)
+	}
+	step := float64(from) / float64(to)
+	cutoff := math.Min(1, float64(to)/float64(from))
+	width := float64(SincZeroCrossings) / cutoff
+	result := make([]float32, outputLen(len(data), from, to))
+	for i := range result {
+		t := float64(i) * step
+		k0 := int(math.Ceil(t - width))
+		k1 := int(math.Floor(t + width))
+		if k0 < 0 {
+			k0 = 0
+		}
+		if k1 >= len(data) {
+			k1 = len(data) - 1
+		}
+
+		// Weights are normalized so the gain is unity, including at the edges
+		var sum, weights float64
+		for k := k0; k <= k1; k++ {
+			x := t - float64(k)
+			w := cutoff * sinc(cutoff*x) * hann(x/width)
+			sum += w * float64(data[k])
+			weights += w
+		}
+		if weights != 0 {
+			result[i] = float32(sum / weights)
+		}
+	}
+	return result
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func outputLen(n, from, to int) int {
+	return int(int64(n) * int64(to) / int64(from))
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	x *= math.Pi
+	return math.Sin(x) / x
+}
+
+// Hann window over the range [-1, 1]
+func hann(x float64) float64 {
+	if x <= -1 || x >= 1 {
+		return 0
+	}
+	return 0.5 + 0.5*math.Cos(math.Pi*x)
+}
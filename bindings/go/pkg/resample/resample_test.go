@@ -0,0 +1,70 @@
+package resample_test
+
+import (
+	"math"
+	"testing"
+
+	// Packages
+	resample "github.com/ggerganov/whisper.cpp/bindings/go/pkg/resample"
+	assert "github.com/stretchr/testify/assert"
+)
+
+// Return one second of a sine wave at the given sample rate
+func tone(rate int, freq float64) []float32 {
+	result := make([]float32, rate)
+	for i := range result {
+		result[i] = float32(math.Sin(2 * math.Pi * freq * float64(i) / float64(rate)))
+	}
+	return result
+}
+
+func Test_Resample_000(t *testing.T) {
+	assert := assert.New(t)
+	for _, fn := range []resample.Resampler{resample.Linear, resample.Sinc} {
+		// Same rate returns a copy
+		data := tone(16000, 440)
+		result := fn(data, 16000, 16000)
+		assert.Equal(data, result)
+
+		// Empty input
+		assert.Empty(fn(nil, 8000, 16000))
+
+		// Length is scaled by the ratio of the sample rates
+		assert.Len(fn(tone(8000, 440), 8000, 16000), 16000)
+		assert.Len(fn(tone(44100, 440), 44100, 16000), 16000)
+		assert.Len(fn(tone(48000, 440), 48000, 16000), 16000)
+	}
+}
+
+func Test_Resample_001(t *testing.T) {
+	assert := assert.New(t)
+	for _, rate := range []int{8000, 44100, 48000} {
+		// A tone below both Nyquist frequencies is preserved. Ignore the
+		// edges, where the kernel is truncated.
+		expected := tone(16000, 440)
+		for name, fn := range map[string]resample.Resampler{"linear": resample.Linear, "sinc": resample.Sinc} {
+			delta := 0.001
+			if name == "linear" {
+				delta = 0.02
+			}
+			result := fn(tone(rate, 440), rate, 16000)
+			for i := 1000; i < len(result)-1000; i++ {
+				if !assert.InDelta(expected[i], result[i], delta, "%s %d Hz sample %d", name, rate, i) {
+					break
+				}
+			}
+		}
+	}
+}
+
+func Test_Resample_002(t *testing.T) {
+	assert := assert.New(t)
+
+	// A tone above the new Nyquist frequency is filtered out by sinc
+	result := resample.Sinc(tone(48000, 12000), 48000, 16000)
+	var energy float64
+	for i := 1000; i < len(result)-1000; i++ {
+		energy += float64(result[i]) * float64(result[i])
+	}
+	assert.Less(math.Sqrt(energy/float64(len(result)-2000)), 0.05)
+}
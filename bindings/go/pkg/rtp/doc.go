@@ -0,0 +1,6 @@
+/*
+Package rtp parses RTP packets received over UDP, and reorders them into
+sequence order with a jitter buffer, so that the payloads can be decoded and
+passed to the speech-to-text context as a continuous stream.
+*/
+package rtp
@@ -0,0 +1,110 @@
+package rtp
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// JitterBuffer reorders packets into sequence order. Packets are held until
+// the next packet in sequence arrives, or until more than depth packets are
+// buffered, in which case the missing packets are treated as lost.
+type JitterBuffer struct {
+	depth   int
+	packets map[uint16]*Packet
+	next    uint16 // Sequence number of the next packet to return
+	started bool
+
+	lost, late int
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// NewJitterBuffer returns a jitter buffer which holds up to depth packets
+// while waiting for a missing packet
+func NewJitterBuffer(depth int) *JitterBuffer {
+	return &JitterBuffer{
+		depth:   depth,
+		packets: make(map[uint16]*Packet, depth+1),
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Push a packet into the buffer, and return the packets which are ready in
+// sequence order. Packets which arrive after a later packet has already been
+// returned, and duplicate packets, are dropped.
+func (jb *JitterBuffer) Push(packet *Packet) []*Packet {
+	if !jb.started {
+		jb.started = true
+		jb.next = packet.Sequence
+	}
+	if int16(packet.Sequence-jb.next) < 0 {
+		jb.late++
+		return nil
+	}
+	if _, exists := jb.packets[packet.Sequence]; exists {
+		jb.late++
+		return nil
+	}
+	jb.packets[packet.Sequence] = packet
+	return jb.pop(jb.depth)
+}
+
+// Flush returns all the buffered packets in sequence order
+func (jb *JitterBuffer) Flush() []*Packet {
+	return jb.pop(0)
+}
+
+// Reset the buffer, discarding any buffered packets
+func (jb *JitterBuffer) Reset() {
+	jb.packets = make(map[uint16]*Packet, jb.depth+1)
+	jb.started = false
+	jb.lost, jb.late = 0, 0
+}
+
+// Lost returns the number of packets which were skipped
+func (jb *JitterBuffer) Lost() int {
+	return jb.lost
+}
+
+// Late returns the number of packets which were dropped as late or
+// duplicated
+func (jb *JitterBuffer) Late() int {
+	return jb.late
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Return packets in sequence order, skipping missing packets while more
+// than depth packets are buffered
+func (jb *JitterBuffer) pop(depth int) []*Packet {
+	var result []*Packet
+	for len(jb.packets) > 0 {
+		if packet, exists := jb.packets[jb.next]; exists {
+			result = append(result, packet)
+			delete(jb.packets, jb.next)
+			jb.next++
+		} else if len(jb.packets) > depth {
+			next := jb.earliest()
+			jb.lost += int(next - jb.next)
+			jb.next = next
+		} else {
+			break
+		}
+	}
+	return result
+}
+
+// Return the earliest buffered sequence number
+func (jb *JitterBuffer) earliest() uint16 {
+	var result uint16
+	first := true
+	for seq := range jb.packets {
+		if first || seq-jb.next < result-jb.next {
+			result = seq
+			first = false
+		}
+	}
+	return result
+}
@@ -0,0 +1,93 @@
+package rtp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Packet is a parsed RTP packet
+type Packet struct {
+	PayloadType uint8
+	Marker      bool
+	Sequence    uint16
+	Timestamp   uint32
+	SSRC        uint32
+	Payload     []byte
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	PayloadPCMU = 0 // Static payload type for G.711 µ-law
+	PayloadPCMA = 8 // Static payload type for G.711 A-law
+)
+
+const (
+	headerSize = 12
+	version    = 2
+)
+
+var (
+	ErrInvalidPacket = errors.New("invalid rtp packet")
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Parse an RTP packet. The payload refers to the data, so the data should
+// not be modified while the packet is in use.
+func Parse(data []byte) (*Packet, error) {
+	if len(data) < headerSize || data[0]>>6 != version {
+		return nil, ErrInvalidPacket
+	}
+	packet := &Packet{
+		Marker:      data[1]&0x80 != 0,
+		PayloadType: data[1] & 0x7f,
+		Sequence:    binary.BigEndian.Uint16(data[2:]),
+		Timestamp:   binary.BigEndian.Uint32(data[4:]),
+		SSRC:        binary.BigEndian.Uint32(data[8:]),
+	}
+
+	// Skip the contributing sources and the header extension
+	start := headerSize + 4*int(data[0]&0x0f)
+	if data[0]&0x10 != 0 {
+		if len(data) < start+4 {
+			return nil, ErrInvalidPacket
+		}
+		start += 4 + 4*int(binary.BigEndian.Uint16(data[start+2:]))
+	}
+
+	// Remove the padding
+	end := len(data)
+	if data[0]&0x20 != 0 {
+		end -= int(data[len(data)-1])
+	}
+	if start > end {
+		return nil, ErrInvalidPacket
+	}
+	packet.Payload = data[start:end]
+
+	// Return success
+	return packet, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (packet *Packet) String() string {
+	str := "<rtp.packet"
+	str += fmt.Sprintf(" pt=%d", packet.PayloadType)
+	str += fmt.Sprintf(" seq=%d", packet.Sequence)
+	str += fmt.Sprintf(" ts=%d", packet.Timestamp)
+	str += fmt.Sprintf(" ssrc=0x%08x", packet.SSRC)
+	str += fmt.Sprintf(" len=%d", len(packet.Payload))
+	if packet.Marker {
+		str += " marker"
+	}
+	return str + ">"
+}
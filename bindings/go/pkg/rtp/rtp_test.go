@@ -0,0 +1,93 @@
+package rtp_test
+
+import (
+	"encoding/binary"
+	"testing"
+
+	// Packages
+	rtp "github.com/ggerganov/whisper.cpp/bindings/go/pkg/rtp"
+	assert "github.com/stretchr/testify/assert"
+)
+
+// Return a packet with the sequence number
+func packet(seq uint16) *rtp.Packet {
+	return &rtp.Packet{Sequence: seq, Timestamp: uint32(seq) * 160}
+}
+
+// Return the sequence numbers of the packets
+func sequence(packets []*rtp.Packet) []uint16 {
+	result := make([]uint16, 0, len(packets))
+	for _, packet := range packets {
+		result = append(result, packet.Sequence)
+	}
+	return result
+}
+
+func Test_RTP_000(t *testing.T) {
+	assert := assert.New(t)
+
+	// A packet with one contributing source, a header extension and padding
+	data := []byte{0xB1, 0x88, 0x12, 0x34, 0, 0, 0x01, 0x40, 0xDE, 0xAD, 0xBE, 0xEF}
+	data = append(data, 0, 0, 0, 1)                   // CSRC
+	data = append(data, 0xBE, 0xDE, 0, 1, 1, 2, 3, 4) // Extension of one word
+	data = append(data, 'a', 'b', 'c', 0, 0, 3)       // Payload and padding
+	packet, err := rtp.Parse(data)
+	assert.NoError(err)
+	assert.Equal(uint8(rtp.PayloadPCMA), packet.PayloadType)
+	assert.True(packet.Marker)
+	assert.Equal(uint16(0x1234), packet.Sequence)
+	assert.Equal(uint32(320), packet.Timestamp)
+	assert.Equal(uint32(0xDEADBEEF), packet.SSRC)
+	assert.Equal([]byte("abc"), packet.Payload)
+
+	// Truncated and non-version 2 packets are invalid
+	_, err = rtp.Parse(data[:8])
+	assert.ErrorIs(err, rtp.ErrInvalidPacket)
+	data[0] = 0x40
+	_, err = rtp.Parse(data)
+	assert.ErrorIs(err, rtp.ErrInvalidPacket)
+
+	// The payload of a minimal packet
+	data = make([]byte, 12, 14)
+	data[0] = 0x80
+	binary.BigEndian.PutUint16(data[2:], 7)
+	packet, err = rtp.Parse(append(data, 1, 2))
+	assert.NoError(err)
+	assert.Equal(uint16(7), packet.Sequence)
+	assert.Equal([]byte{1, 2}, packet.Payload)
+}
+
+func Test_RTP_001(t *testing.T) {
+	assert := assert.New(t)
+	jb := rtp.NewJitterBuffer(3)
+
+	// Packets are reordered
+	assert.Equal([]uint16{10}, sequence(jb.Push(packet(10))))
+	assert.Empty(jb.Push(packet(12)))
+	assert.Equal([]uint16{11, 12}, sequence(jb.Push(packet(11))))
+
+	// Late and duplicate packets are dropped
+	assert.Empty(jb.Push(packet(11)))
+	assert.Empty(jb.Push(packet(14)))
+	assert.Empty(jb.Push(packet(14)))
+	assert.Equal(2, jb.Late())
+	assert.Equal([]uint16{14}, sequence(jb.Flush()))
+}
+
+func Test_RTP_002(t *testing.T) {
+	assert := assert.New(t)
+	jb := rtp.NewJitterBuffer(2)
+
+	// A missing packet is skipped once more than depth packets are buffered,
+	// and sequence numbers wrap around
+	assert.Equal([]uint16{65534}, sequence(jb.Push(packet(65534))))
+	assert.Empty(jb.Push(packet(0)))
+	assert.Empty(jb.Push(packet(1)))
+	assert.Equal([]uint16{0, 1, 2}, sequence(jb.Push(packet(2))))
+	assert.Equal(1, jb.Lost())
+
+	// Flush returns the buffered packets in order
+	assert.Empty(jb.Push(packet(5)))
+	assert.Empty(jb.Push(packet(4)))
+	assert.Equal([]uint16{4, 5}, sequence(jb.Flush()))
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	gocontext "context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Admission limits the number of concurrent sessions served, with a bounded
+// queue of sessions waiting for one to end. A nil admission accepts all
+// sessions.
+type Admission struct {
+	sessions chan struct{} // Holds a value for each session in progress
+	queue    chan struct{} // Holds a value for each session waiting
+	timeout  time.Duration // Longest wait in the queue, or zero to wait until cancelled
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// Seconds after which clients are asked to retry a rejected request
+	retryAfter = 1
+)
+
+var (
+	ErrTooManySessions = errors.New("too many concurrent sessions")
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// NewAdmission returns the admission control for up to sessions concurrent
+// sessions, with up to queue sessions waiting for up to the timeout, or
+// until they are cancelled when the timeout is zero. Returns nil when the
+// number of sessions is zero, so that the sessions are not limited.
+func NewAdmission(sessions, queue int, timeout time.Duration) *Admission {
+	if sessions <= 0 {
+		return nil
+	}
+	return &Admission{
+		sessions: make(chan struct{}, sessions),
+		queue:    make(chan struct{}, max(queue, 0)),
+		timeout:  timeout,
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Acquire admits a session, waiting in the queue when the limit is reached.
+// Returns ErrTooManySessions when the queue is full or the wait times out,
+// or the error of the go context when it is cancelled while waiting. Each
+// session admitted must be released.
+func (admission *Admission) Acquire(ctx gocontext.Context) error {
+	if admission == nil || admission.TryAcquire() {
+		return nil
+	}
+
+	// Join the queue
+	select {
+	case admission.queue <- struct{}{}:
+		defer func() { <-admission.queue }()
+	default:
+		return ErrTooManySessions
+	}
+
+	// Wait for a session to end
+	var timeout <-chan time.Time
+	if admission.timeout > 0 {
+		timer := time.NewTimer(admission.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case admission.sessions <- struct{}{}:
+		return nil
+	case <-timeout:
+		return ErrTooManySessions
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TryAcquire admits a session without waiting, and returns false when the
+// limit is reached
+func (admission *Admission) TryAcquire() bool {
+	if admission == nil {
+		return true
+	}
+	select {
+	case admission.sessions <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Release ends a session admitted with Acquire or TryAcquire
+func (admission *Admission) Release() {
+	if admission != nil {
+		<-admission.sessions
+	}
+}
+
+// LimitRequests returns a handler which admits each request, and rejects
+// those which are not admitted with 429 Too Many Requests
+func LimitRequests(admission *Admission, next http.Handler) http.Handler {
+	if admission == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := admission.Acquire(r.Context()); errors.Is(err, ErrTooManySessions) {
+			RequestLogger(r.Context()).Warn("rejected", "error", err)
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			writeError(w, http.StatusTooManyRequests, err)
+			return
+		} else if err != nil {
+			writeError(w, http.StatusServiceUnavailable, err)
+			return
+		}
+		defer admission.Release()
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	headerAPIKey = "X-API-Key"
+	bearerPrefix = "Bearer "
+)
+
+var (
+	ErrUnauthorized = errors.New("missing or invalid API token")
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Authenticate returns a handler which rejects requests without a valid API
+// token. The token is sent as a bearer token in the Authorization header, in
+// the X-API-Key header, or as the "token" query parameter. When no tokens are
+// configured, all requests are accepted.
+func Authenticate(tokens []string, next http.Handler) http.Handler {
+	if len(tokens) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("token")
+		if key := r.Header.Get(headerAPIKey); key != "" {
+			token = key
+		} else if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, bearerPrefix) {
+			token = strings.TrimPrefix(auth, bearerPrefix)
+		}
+		if !IsAuthorized(tokens, token) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, ErrUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// IsAuthorized returns true if the token matches one of the tokens,
+// comparing in constant time
+func IsAuthorized(tokens []string, token string) bool {
+	if token == "" {
+		return false
+	}
+	result := 0
+	for _, v := range tokens {
+		result |= subtle.ConstantTimeCompare([]byte(v), []byte(token))
+	}
+	return result == 1
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// Seconds a browser can cache the result of a preflight request
+	corsMaxAge = 600
+
+	// Request headers browsers can send, and response headers they can read
+	corsAllowHeaders  = "Authorization, Content-Type, " + headerAPIKey
+	corsExposeHeaders = headerRequestId + ", Retry-After"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// AllowOrigins returns a handler which allows browser apps served from the
+// origins, such as "https://app.example.com", to call the endpoints, or any
+// origin with "*". Preflight requests from an allowed origin are answered,
+// and those from other origins are rejected with 403 Forbidden. Other
+// requests are passed on, with the CORS headers when the origin is allowed.
+// Credentials are not allowed, so API tokens must be sent in a header
+// rather than as a cookie. When no origins are configured, requests are
+// passed on without the CORS headers, and browsers do not allow other
+// origins to read the responses.
+func AllowOrigins(origins []string, next http.Handler) http.Handler {
+	if len(origins) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Origin")
+		allowed := slices.Contains(origins, "*") || slices.Contains(origins, origin)
+
+		// Answer a preflight request
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+			if !allowed {
+				writeError(w, http.StatusForbidden, fmt.Errorf("origin not allowed: %q", origin))
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join([]string{http.MethodPost, http.MethodOptions}, ", "))
+			w.Header().Set("Access-Control-Allow-Headers", corsAllowHeaders)
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(corsMaxAge))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		// Otherwise, allow the origin to read the response
+		if allowed {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Expose-Headers", corsExposeHeaders)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,11 @@
+/*
+Package server provides the HTTP transcription endpoints of the go-whisper
+example as handlers, so that they can be mounted inside other Go services.
+Audio uploaded as a multipart form is transcribed with a context from the pool
+for the model, and the transcript is returned as JSON. The package also
+provides the middleware the endpoints are served with, which authenticates,
+logs and limits requests.
+
+The gRPC streaming service is in the grpc subpackage.
+*/
+package server
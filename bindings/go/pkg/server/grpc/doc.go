@@ -0,0 +1,15 @@
+/*
+Package grpc provides a gRPC speech recognition service with a bidirectional
+streaming RPC, modeled after the streaming API of the Google Speech API.
+Clients send a configuration followed by audio chunks, and the server returns
+interim and final results with time offsets and confidence. Each stream is
+identified by a session identifier, returned in the x-request-id header
+metadata of the response.
+
+The service is defined in whisper.proto. To regenerate the Go code, run
+`go generate` in this directory with buf, protoc-gen-go and protoc-gen-go-grpc
+installed.
+*/
+package grpc
+
+//go:generate buf generate --template buf.gen.yaml --path whisper.proto
@@ -0,0 +1,185 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	// Packages
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// LagPolicy is applied to a stream when the audio received waits for longer
+// than the maximum lag to be decoded, such as when a client sends audio in
+// real time faster than it can be transcribed
+type LagPolicy int
+
+// Holds the audio received by a stream until it is fed to the streaming
+// context, applying the lag policy
+type backlog struct {
+	sync.Mutex
+	policy   LagPolicy
+	max      int       // Maximum number of samples waiting
+	data     []float32 // Samples waiting
+	skipped  int       // Number of samples dropped since the last call to next
+	received int64     // Number of samples received
+	lagging  bool      // Set once a lag event has been sent, until the backlog is emptied
+	err      error     // Set when no more audio will be received
+	ready    chan struct{}
+	space    chan struct{}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	LagBlock  LagPolicy = iota // Stop receiving until the audio is decoded, so that the client is held back
+	LagDrop                    // Drop the oldest audio above the maximum lag
+	LagSkip                    // Drop all the audio waiting, skipping to the latest
+	LagNotify                  // Stop receiving as with LagBlock, and send a DECODE_LAGGING event
+)
+
+// DefaultMaxLag is the amount of audio which waits to be decoded before the
+// lag policy is applied
+const DefaultMaxLag = 5 * time.Second
+
+// Returned by backlog.next when no audio is received within the timeout
+var errIdle = errors.New("idle")
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+func newBacklog(policy LagPolicy, max time.Duration) *backlog {
+	if max <= 0 {
+		max = DefaultMaxLag
+	}
+	return &backlog{
+		policy: policy,
+		max:    int(max.Seconds() * whisper.SampleRate),
+		ready:  make(chan struct{}, 1),
+		space:  make(chan struct{}, 1),
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (policy LagPolicy) String() string {
+	switch policy {
+	case LagBlock:
+		return "block"
+	case LagDrop:
+		return "drop"
+	case LagSkip:
+		return "skip"
+	case LagNotify:
+		return "notify"
+	default:
+		return "unknown"
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Add the audio received. With the LagBlock and LagNotify policies, this
+// waits while the backlog is full, and the first time the backlog fills, the
+// lag function is called with the time of the audio received. Returns false
+// when the go context is cancelled while waiting.
+func (backlog *backlog) push(ctx context.Context, data []float32, lag func(time.Duration)) bool {
+	for {
+		backlog.Lock()
+		full := len(backlog.data) > 0 && len(backlog.data)+len(data) > backlog.max
+		if full && (backlog.policy == LagBlock || backlog.policy == LagNotify) {
+			notify := backlog.policy == LagNotify && !backlog.lagging
+			backlog.lagging = true
+			t := time.Duration(backlog.received) * time.Second / whisper.SampleRate
+			backlog.Unlock()
+			if notify && lag != nil {
+				lag(t)
+			}
+			select {
+			case <-backlog.space:
+				continue
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		// Drop the oldest audio when the backlog is full
+		backlog.data = append(backlog.data, data...)
+		backlog.received += int64(len(data))
+		if n := len(backlog.data) - backlog.max; n > 0 && backlog.policy == LagDrop {
+			backlog.drop(n)
+		} else if n > 0 && backlog.policy == LagSkip {
+			backlog.drop(len(backlog.data) - len(data))
+		}
+		backlog.Unlock()
+		signal(backlog.ready)
+		return true
+	}
+}
+
+// End the backlog. The error is returned by next once the audio waiting has
+// been taken.
+func (backlog *backlog) close(err error) {
+	backlog.Lock()
+	if backlog.err == nil {
+		backlog.err = err
+	}
+	backlog.Unlock()
+	signal(backlog.ready)
+}
+
+// Return the audio waiting and the number of samples dropped before it,
+// waiting for audio to be received. Returns the error the backlog was closed
+// with once all the audio has been taken, errIdle when no audio is received
+// within the timeout, when not zero, or errSessionExpired when the expired
+// channel receives first.
+func (backlog *backlog) next(timeout time.Duration, expired <-chan time.Time) ([]float32, int, error) {
+	var idle <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		idle = timer.C
+	}
+	for {
+		backlog.Lock()
+		if len(backlog.data) > 0 || backlog.skipped > 0 {
+			data, skipped := backlog.data, backlog.skipped
+			backlog.data, backlog.skipped, backlog.lagging = nil, 0, false
+			backlog.Unlock()
+			signal(backlog.space)
+			return data, skipped, nil
+		} else if backlog.err != nil {
+			backlog.Unlock()
+			return nil, 0, backlog.err
+		}
+		backlog.Unlock()
+		select {
+		case <-backlog.ready:
+		case <-idle:
+			return nil, 0, errIdle
+		case <-expired:
+			return nil, 0, errSessionExpired
+		}
+	}
+}
+
+// Drop samples from the start of the backlog
+func (backlog *backlog) drop(n int) {
+	backlog.data = append(backlog.data[:0], backlog.data[n:]...)
+	backlog.skipped += n
+}
+
+// Signal a channel without blocking, when it is not already signalled
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,644 @@
+package grpc
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	// Packages
+	g711 "github.com/ggerganov/whisper.cpp/bindings/go/pkg/g711"
+	opus "github.com/ggerganov/whisper.cpp/bindings/go/pkg/opus"
+	postprocess "github.com/ggerganov/whisper.cpp/bindings/go/pkg/postprocess"
+	record "github.com/ggerganov/whisper.cpp/bindings/go/pkg/record"
+	resample "github.com/ggerganov/whisper.cpp/bindings/go/pkg/resample"
+	api "github.com/ggerganov/whisper.cpp/bindings/go/pkg/server"
+	vad "github.com/ggerganov/whisper.cpp/bindings/go/pkg/vad"
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	codes "google.golang.org/grpc/codes"
+	metadata "google.golang.org/grpc/metadata"
+	status "google.golang.org/grpc/status"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Server implements the speech recognition service, obtaining a context
+// from the pool for each stream
+type Server struct {
+	UnimplementedSpeechServer
+
+	pool        whisper.Pool
+	pools       map[string]whisper.Pool // Pools of other models, keyed by name
+	resampler   resample.Resampler
+	filter      postprocess.Filter
+	observer    Observer
+	recordDir   string        // Directory the audio of each stream is recorded to
+	idleTimeout time.Duration // Time without requests after which a stream is ended
+
+	// Limits of each stream, which are not applied when zero
+	maxAudio   time.Duration // Audio received
+	maxSession time.Duration // Time since the stream started
+	maxBytes   int64         // Bytes of audio content received
+
+	// Applied when the audio received waits for longer than the maximum lag
+	lagPolicy LagPolicy
+	maxLag    time.Duration
+
+	// Voice activity detection, for streams with voice activity events
+	vadThreshold float32
+	vadHangover  time.Duration
+}
+
+// Observer receives the final results of each stream, for example to
+// deliver transcripts to another service. Streams are identified by the
+// session identifier of their go context, or otherwise a random UUID.
+type Observer interface {
+	// Segment is called with each final segment, after filtering
+	Segment(session string, context whisper.Context, segment whisper.Segment)
+
+	// End is called when a stream ends, with the number of samples received
+	End(session string, context whisper.Context, samples int)
+}
+
+// A recognition stream
+type recognizer struct {
+	sync.Mutex
+	stream   Speech_StreamingRecognizeServer
+	id       string
+	context  whisper.Context
+	config   *StreamingRecognitionConfig
+	filter   postprocess.Filter
+	observer Observer
+	err      error // The first error sending a response
+}
+
+// Decodes audio chunks to mono float32 samples at the whisper sample rate
+type decoder struct {
+	encoding  RecognitionConfig_AudioEncoding
+	rate      int
+	channels  int
+	resampler resample.Resampler
+	buf       []byte // Partial frame carried over between chunks
+
+	// Opus decoders
+	ogg  *opus.OggDecoder
+	opus *opus.Decoder
+}
+
+// Make sure server adheres to the interface
+var _ SpeechServer = (*Server)(nil)
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+// DefaultInterimInterval is the amount of audio between interim results
+const DefaultInterimInterval = time.Second
+
+// The maximum number of interleaved channels
+const maxChannels = 8
+
+// The header metadata of a response with the session identifier of the stream
+const headerSessionId = "x-request-id"
+
+// Returned when a stream has run for the session limit, or has sent more
+// than the limit of bytes
+var (
+	errSessionExpired = errors.New("session expired")
+	errBytesExceeded  = errors.New("bytes exceeded")
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// NewServer returns a speech recognition service which obtains contexts from
+// the pool. The resampler converts audio which is not at the whisper sample
+// rate, and when nil, linear resampling is used. Each chunk is resampled on
+// its own, so a resampler with a short kernel is preferable.
+func NewServer(pool whisper.Pool, resampler resample.Resampler) *Server {
+	if resampler == nil {
+		resampler = resample.Linear
+	}
+	return &Server{
+		pool:      pool,
+		resampler: resampler,
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// SetModel adds a pool for a model, which streams select by name in their
+// configuration. Streams which do not select a model use the pool the
+// server was created with.
+func (server *Server) SetModel(name string, pool whisper.Pool) {
+	if server.pools == nil {
+		server.pools = make(map[string]whisper.Pool)
+	}
+	server.pools[name] = pool
+}
+
+// SetRecordDir sets the directory the decoded audio of each stream is
+// recorded to, as a WAV file. Audio is not recorded when the directory is
+// empty.
+func (server *Server) SetRecordDir(dir string) {
+	server.recordDir = dir
+}
+
+// SetIdleTimeout sets the time after which a stream is ended when no
+// requests are received, so that half-open connections release their
+// context. There is no timeout when the duration is zero.
+func (server *Server) SetIdleTimeout(timeout time.Duration) {
+	server.idleTimeout = timeout
+}
+
+// SetLimits sets the limits of each stream on the duration of audio, the
+// time since the stream started and the bytes of audio content received.
+// Once a limit is reached, the audio received is transcribed and the stream
+// ends with the RESOURCE_EXHAUSTED or DEADLINE_EXCEEDED code. A limit is not
+// applied when zero.
+func (server *Server) SetLimits(audio, session time.Duration, bytes int64) {
+	server.maxAudio = audio
+	server.maxSession = session
+	server.maxBytes = bytes
+}
+
+// SetLag sets the policy applied to a stream when the audio received waits
+// for longer than the maximum lag to be decoded. With LagBlock, the default,
+// audio is not received until it can be decoded, so that the client is held
+// back by flow control. LagDrop drops the oldest audio waiting, keeping the
+// maximum lag, and LagSkip drops all the audio waiting except the latest
+// received. Dropped audio is counted in the times of later results. LagNotify
+// blocks as with LagBlock, and sends an event with the DECODE_LAGGING type
+// each time decoding falls behind. A zero maximum selects DefaultMaxLag.
+func (server *Server) SetLag(policy LagPolicy, max time.Duration) {
+	server.lagPolicy = policy
+	server.maxLag = max
+}
+
+// SetVAD sets the energy threshold and hangover of the voice activity
+// detector used by streams which request voice activity events. A zero
+// threshold or hangover selects the default value.
+func (server *Server) SetVAD(threshold float32, hangover time.Duration) {
+	server.vadThreshold = threshold
+	server.vadHangover = hangover
+}
+
+// SetFilter sets the filter applied to segments before they are sent as
+// results, such as to redact sensitive text. Segments which are removed by
+// the filter are not sent.
+func (server *Server) SetFilter(filter postprocess.Filter) {
+	server.filter = filter
+}
+
+// SetObserver sets the observer notified of the final results of each
+// stream, or nil to remove it
+func (server *Server) SetObserver(observer Observer) {
+	server.observer = observer
+}
+
+// StreamingRecognize receives the configuration followed by audio, and sends
+// results as segments are decoded
+func (server *Server) StreamingRecognize(stream Speech_StreamingRecognizeServer) error {
+	// The first request contains the configuration
+	req, err := stream.Recv()
+	if err == io.EOF {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	config := req.GetStreamingConfig()
+	if config == nil {
+		return status.Error(codes.InvalidArgument, "the first request must contain the streaming configuration")
+	}
+	decoder, err := newDecoder(config.GetConfig(), server.resampler)
+	if err != nil {
+		return err
+	}
+	closeDecoder := true
+	defer func() {
+		if closeDecoder {
+			decoder.Close()
+		}
+	}()
+
+	// Obtain a context from the pool for the model
+	pool := server.pool
+	if name := config.GetConfig().GetModel(); name != "" {
+		if pool = server.pools[name]; pool == nil {
+			return status.Errorf(codes.InvalidArgument, "unknown model: %q", name)
+		}
+	}
+	context, err := pool.Get(stream.Context())
+	if err != nil {
+		return toStatus(err)
+	}
+	defer pool.Put(context)
+	if err := setParams(context, config.GetConfig()); err != nil {
+		return err
+	}
+
+	// Identify the stream, returning the identifier in the header of the
+	// response, and notify the observer when it ends
+	id := api.SessionId(stream.Context())
+	if id == "" {
+		id = api.NewSessionId()
+	}
+	if err := stream.SetHeader(metadata.Pairs(headerSessionId, id)); err != nil {
+		return err
+	}
+	var samples int
+	if server.observer != nil {
+		defer func() {
+			server.observer.End(id, context, samples)
+		}()
+	}
+
+	// Record the decoded audio
+	var recorder *record.Recorder
+	if server.recordDir != "" {
+		recorder, err = record.Create(server.recordDir, "grpc-"+id)
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+		defer recorder.Close()
+	}
+
+	// Create a streaming context which sends results
+	recognizer := &recognizer{
+		stream:   stream,
+		id:       id,
+		context:  context,
+		config:   config,
+		filter:   server.filter,
+		observer: server.observer,
+	}
+	streaming, err := whisper.NewStreamingContext(context, recognizer.final)
+	if err != nil {
+		return toStatus(err)
+	}
+	if config.GetInterimResults() {
+		streaming.SetPartialCallback(recognizer.interim, DefaultInterimInterval)
+	}
+	if config.GetVoiceActivityEvents() {
+		streaming.SetVAD(vad.New(whisper.SampleRate, server.vadThreshold, server.vadHangover), recognizer.event)
+	}
+
+	// Receive and decode audio in the background, so that the stream can
+	// time out, and the lag policy can be applied when decoding falls behind.
+	// The receiver closes the audio decoder once it ends.
+	backlog := newBacklog(server.lagPolicy, server.maxLag)
+	closeDecoder = false
+	go func() {
+		defer decoder.Close()
+		var bytes int64
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				backlog.close(err)
+				return
+			}
+			if req.GetStreamingConfig() != nil {
+				backlog.close(status.Error(codes.InvalidArgument, "the streaming configuration must only be sent in the first request"))
+				return
+			}
+			if bytes += int64(len(req.GetAudioContent())); server.maxBytes > 0 && bytes > server.maxBytes {
+				backlog.close(errBytesExceeded)
+				return
+			}
+			data, err := decoder.decode(req.GetAudioContent())
+			if errors.Is(err, opus.ErrNotSupported) {
+				backlog.close(toStatus(err))
+				return
+			} else if err != nil {
+				backlog.close(status.Error(codes.InvalidArgument, err.Error()))
+				return
+			}
+			if !backlog.push(stream.Context(), data, recognizer.lagging) {
+				return
+			}
+		}
+	}()
+
+	// End the stream once it has run for the session limit
+	var expired <-chan time.Time
+	if server.maxSession > 0 {
+		timer := time.NewTimer(server.maxSession)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	// Feed audio until the client closes the send direction, or a limit is
+	// reached. Audio dropped by the lag policy is skipped.
+	var limit error
+	for limit == nil {
+		data, skipped, err := backlog.next(server.idleTimeout, expired)
+		if err == io.EOF {
+			break
+		} else if err == errIdle {
+			return status.Errorf(codes.DeadlineExceeded, "no audio received for %v", server.idleTimeout)
+		} else if err == errSessionExpired {
+			limit = status.Errorf(codes.DeadlineExceeded, "the session exceeded the limit of %v", server.maxSession)
+			break
+		} else if err == errBytesExceeded {
+			limit = status.Errorf(codes.ResourceExhausted, "the session exceeded the limit of %d bytes of audio", server.maxBytes)
+			break
+		} else if err != nil {
+			return err
+		}
+		if skipped > 0 {
+			streaming.Skip(time.Duration(skipped) * time.Second / whisper.SampleRate)
+			samples += skipped
+		}
+		if n := int(server.maxAudio.Seconds() * whisper.SampleRate); n > 0 && samples+len(data) > n {
+			data = data[:max(0, n-samples)]
+			limit = status.Errorf(codes.ResourceExhausted, "the session exceeded the limit of %v of audio", server.maxAudio)
+		}
+		if recorder != nil {
+			if err := recorder.Write(data); err != nil {
+				return status.Error(codes.Internal, err.Error())
+			}
+		}
+		if err := streaming.Feed(data); err != nil {
+			return toStatus(err)
+		}
+		samples += len(data)
+		if err := recognizer.Err(); err != nil {
+			return err
+		}
+	}
+
+	// Process the remaining audio
+	if err := streaming.Flush(); err != nil {
+		return toStatus(err)
+	}
+
+	// Return any error sending the results, or the limit reached
+	if err := recognizer.Err(); err != nil {
+		return err
+	}
+	return limit
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Set the context parameters from the recognition configuration
+func setParams(context whisper.Context, config *RecognitionConfig) error {
+	if lang := config.GetLanguageCode(); lang != "" {
+		if err := context.SetLanguage(lang); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	if config.GetTranslate() {
+		if !context.IsMultilingual() {
+			return status.Error(codes.InvalidArgument, whisper.ErrModelNotMultilingual.Error())
+		}
+		context.SetTranslate(true)
+	}
+
+	// Word timestamps are always enabled, as the confidence of a result is
+	// calculated from the words
+	context.SetWordTimestamps(true)
+
+	// Return success
+	return nil
+}
+
+// Return the first error sending a response
+func (recognizer *recognizer) Err() error {
+	recognizer.Lock()
+	defer recognizer.Unlock()
+	return recognizer.err
+}
+
+func (recognizer *recognizer) final(segment whisper.Segment) {
+	recognizer.send(segment, true)
+}
+
+func (recognizer *recognizer) interim(segment whisper.Segment) {
+	recognizer.send(segment, false)
+}
+
+// Send a segment as a result, recording the first error
+func (recognizer *recognizer) send(segment whisper.Segment, final bool) {
+	recognizer.Lock()
+	defer recognizer.Unlock()
+	if recognizer.err != nil {
+		return
+	}
+	if recognizer.filter != nil {
+		segments := recognizer.filter([]whisper.Segment{segment})
+		if len(segments) == 0 {
+			return
+		}
+		segment = segments[0]
+	}
+	if final && recognizer.observer != nil {
+		recognizer.observer.Segment(recognizer.id, recognizer.context, segment)
+	}
+	result := &StreamingRecognitionResult{
+		Alternatives: []*SpeechRecognitionAlternative{
+			toAlternative(segment, recognizer.config.GetConfig().GetEnableWordTimeOffsets()),
+		},
+		IsFinal:      final,
+		StartTime:    durationpb.New(segment.Start),
+		EndTime:      durationpb.New(segment.End),
+		LanguageCode: segment.Language,
+	}
+	recognizer.err = recognizer.stream.Send(&StreamingRecognizeResponse{
+		Results: []*StreamingRecognitionResult{result},
+	})
+}
+
+// Send a voice activity event, recording the first error. An utterance ends
+// once its final results have been sent.
+func (recognizer *recognizer) event(evt vad.Event) {
+	recognizer.Lock()
+	defer recognizer.Unlock()
+	if recognizer.err != nil {
+		return
+	}
+	response := &StreamingRecognizeResponse{
+		SpeechEventTime: durationpb.New(evt.Time),
+	}
+	switch evt.Type {
+	case vad.SpeechStart:
+		response.SpeechEventType = StreamingRecognizeResponse_UTTERANCE_START
+	case vad.SpeechEnd:
+		response.SpeechEventType = StreamingRecognizeResponse_UTTERANCE_END
+	default:
+		return
+	}
+	recognizer.err = recognizer.stream.Send(response)
+}
+
+// Send an event when decoding has fallen behind the audio received, with the
+// time of the audio received, recording the first error
+func (recognizer *recognizer) lagging(t time.Duration) {
+	recognizer.Lock()
+	defer recognizer.Unlock()
+	if recognizer.err != nil {
+		return
+	}
+	recognizer.err = recognizer.stream.Send(&StreamingRecognizeResponse{
+		SpeechEventType: StreamingRecognizeResponse_DECODE_LAGGING,
+		SpeechEventTime: durationpb.New(t),
+	})
+}
+
+func toAlternative(segment whisper.Segment, words bool) *SpeechRecognitionAlternative {
+	result := &SpeechRecognitionAlternative{
+		Transcript: segment.Text,
+	}
+	for _, word := range segment.Words {
+		result.Confidence += word.P
+		if words {
+			result.Words = append(result.Words, &WordInfo{
+				StartTime:  durationpb.New(word.Start),
+				EndTime:    durationpb.New(word.End),
+				Word:       strings.TrimSpace(word.Text),
+				Confidence: word.P,
+			})
+		}
+	}
+	if len(segment.Words) > 0 {
+		result.Confidence /= float32(len(segment.Words))
+	}
+	return result
+}
+
+// Convert an error to a gRPC status error
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, whisper.ErrPoolExhausted):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, whisper.ErrPoolClosed):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, opus.ErrNotSupported):
+		return status.Error(codes.Unimplemented, err.Error())
+	default:
+		return status.FromContextError(err).Err()
+	}
+}
+
+func newDecoder(config *RecognitionConfig, resampler resample.Resampler) (*decoder, error) {
+	decoder := &decoder{
+		encoding:  config.GetEncoding(),
+		rate:      int(config.GetSampleRateHertz()),
+		channels:  int(config.GetAudioChannelCount()),
+		resampler: resampler,
+	}
+	if decoder.rate == 0 {
+		decoder.rate = whisper.SampleRate
+	} else if decoder.rate < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid sample rate: %d", decoder.rate)
+	}
+	if decoder.channels == 0 {
+		decoder.channels = 1
+	} else if decoder.channels < 0 || decoder.channels > maxChannels {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid channel count: %d", decoder.channels)
+	}
+	switch decoder.encoding {
+	case RecognitionConfig_OGG_OPUS:
+		decoder.ogg = opus.NewOggDecoder(whisper.SampleRate)
+	case RecognitionConfig_OPUS:
+		// Opus packets are decoded directly to mono at the whisper sample rate
+		dec, err := opus.NewDecoder(whisper.SampleRate, decoder.channels)
+		if err != nil {
+			return nil, toStatus(err)
+		}
+		decoder.opus = dec
+	default:
+		if _, exists := RecognitionConfig_AudioEncoding_name[int32(decoder.encoding)]; !exists {
+			return nil, status.Errorf(codes.InvalidArgument, "unsupported encoding: %v", decoder.encoding)
+		}
+	}
+
+	// Return success
+	return decoder, nil
+}
+
+// Release the Opus decoders
+func (decoder *decoder) Close() error {
+	if decoder.ogg != nil {
+		return decoder.ogg.Close()
+	}
+	if decoder.opus != nil {
+		return decoder.opus.Close()
+	}
+	return nil
+}
+
+// Decode a chunk of audio. Bytes which do not make up a whole sample are
+// retained and decoded with the next chunk.
+func (decoder *decoder) decode(data []byte) ([]float32, error) {
+	switch decoder.encoding {
+	case RecognitionConfig_OGG_OPUS:
+		return decoder.ogg.Write(data)
+	case RecognitionConfig_OPUS:
+		return decoder.opus.Decode(data)
+	}
+	if len(decoder.buf) > 0 {
+		data = append(decoder.buf, data...)
+	}
+
+	// Decode whole frames, carrying over any partial frame
+	var size int
+	switch decoder.encoding {
+	case RecognitionConfig_MULAW, RecognitionConfig_ALAW:
+		size = 1
+	case RecognitionConfig_FLOAT32:
+		size = 4
+	default:
+		size = 2
+	}
+	n := len(data) / (size * decoder.channels) * size * decoder.channels
+	data, rest := data[:n], data[n:]
+	var result []float32
+	switch decoder.encoding {
+	case RecognitionConfig_MULAW:
+		result = g711.PCMUToFloat32(data)
+	case RecognitionConfig_ALAW:
+		result = g711.PCMAToFloat32(data)
+	case RecognitionConfig_FLOAT32:
+		result = make([]float32, len(data)/4)
+		for i := range result {
+			result[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+		}
+	default:
+		result = make([]float32, len(data)/2)
+		for i := range result {
+			result[i] = float32(int16(binary.LittleEndian.Uint16(data[i*2:]))) / math.MaxInt16
+		}
+	}
+	decoder.buf = append(decoder.buf[:0], rest...)
+
+	// Average the channels
+	if decoder.channels > 1 {
+		result = mix(result, decoder.channels)
+	}
+
+	// Resample to the whisper sample rate
+	if decoder.rate != whisper.SampleRate {
+		result = decoder.resampler(result, decoder.rate, whisper.SampleRate)
+	}
+	return result, nil
+}
+
+// Average interleaved channels to mono
+func mix(data []float32, channels int) []float32 {
+	result := make([]float32, len(data)/channels)
+	for i := range result {
+		var sum float32
+		for _, v := range data[i*channels : (i+1)*channels] {
+			sum += v
+		}
+		result[i] = sum / float32(channels)
+	}
+	return result
+}
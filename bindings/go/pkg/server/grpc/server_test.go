@@ -0,0 +1,327 @@
+package grpc_test
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"math"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	// Packages
+	server "github.com/ggerganov/whisper.cpp/bindings/go/pkg/server/grpc"
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	wav "github.com/go-audio/wav"
+	assert "github.com/stretchr/testify/assert"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	insecure "google.golang.org/grpc/credentials/insecure"
+	status "google.golang.org/grpc/status"
+	bufconn "google.golang.org/grpc/test/bufconn"
+)
+
+const (
+	ModelPath  = "../../../models/ggml-tiny.bin"
+	SamplePath = "../../../samples/jfk.wav"
+)
+
+// Serve the speech service in-process and return a client connected to it
+func newClient(t *testing.T, pool whisper.Pool) server.SpeechClient {
+	return newClientWithServer(t, server.NewServer(pool, nil))
+}
+
+// Serve a speech server in-process and return a client connected to it
+func newClientWithServer(t *testing.T, speech *server.Server) server.SpeechClient {
+	listener := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	server.RegisterSpeechServer(s, speech)
+	go s.Serve(listener)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.Dial("bufconn",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return server.NewSpeechClient(conn)
+}
+
+func Test_GRPC_000(t *testing.T) {
+	assert := assert.New(t)
+	client := newClient(t, nil)
+
+	// Audio before the configuration is rejected
+	stream, err := client.StreamingRecognize(context.Background())
+	assert.NoError(err)
+	assert.NoError(stream.Send(&server.StreamingRecognizeRequest{
+		StreamingRequest: &server.StreamingRecognizeRequest_AudioContent{AudioContent: make([]byte, 320)},
+	}))
+	_, err = stream.Recv()
+	assert.Equal(codes.InvalidArgument, status.Code(err))
+
+	// An unknown model is rejected
+	stream, err = client.StreamingRecognize(context.Background())
+	assert.NoError(err)
+	assert.NoError(stream.Send(&server.StreamingRecognizeRequest{
+		StreamingRequest: &server.StreamingRecognizeRequest_StreamingConfig{
+			StreamingConfig: &server.StreamingRecognitionConfig{
+				Config: &server.RecognitionConfig{Model: "large-v3"},
+			},
+		},
+	}))
+	_, err = stream.Recv()
+	assert.Equal(codes.InvalidArgument, status.Code(err))
+}
+
+func Test_GRPC_001(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+	if _, err := os.Stat(SamplePath); os.IsNotExist(err) {
+		t.Skip("Skipping test, sample not found:", SamplePath)
+	}
+
+	// Create a pool and a client
+	pool, err := whisper.NewPool(ModelPath, 1, whisper.PoolWait)
+	assert.NoError(err)
+	defer pool.Close()
+	client := newClient(t, pool)
+
+	// Read the samples as 16-bit PCM
+	fh, err := os.Open(SamplePath)
+	assert.NoError(err)
+	defer fh.Close()
+	buf, err := wav.NewDecoder(fh).FullPCMBuffer()
+	assert.NoError(err)
+	data := make([]byte, len(buf.Data)*2)
+	for i, v := range buf.Data {
+		binary.LittleEndian.PutUint16(data[i*2:], uint16(int16(v)))
+	}
+
+	// Send the configuration followed by the audio in one second chunks
+	stream, err := client.StreamingRecognize(context.Background())
+	assert.NoError(err)
+	assert.NoError(stream.Send(&server.StreamingRecognizeRequest{
+		StreamingRequest: &server.StreamingRecognizeRequest_StreamingConfig{
+			StreamingConfig: &server.StreamingRecognitionConfig{
+				Config: &server.RecognitionConfig{
+					Encoding:              server.RecognitionConfig_LINEAR16,
+					SampleRateHertz:       int32(buf.Format.SampleRate),
+					EnableWordTimeOffsets: true,
+				},
+				InterimResults: true,
+			},
+		},
+	}))
+	for i := 0; i < len(data); i += buf.Format.SampleRate * 2 {
+		j := i + buf.Format.SampleRate*2
+		if j > len(data) {
+			j = len(data)
+		}
+		assert.NoError(stream.Send(&server.StreamingRecognizeRequest{
+			StreamingRequest: &server.StreamingRecognizeRequest_AudioContent{AudioContent: data[i:j]},
+		}))
+	}
+	assert.NoError(stream.CloseSend())
+
+	// The stream is identified in the header metadata
+	header, err := stream.Header()
+	assert.NoError(err)
+	assert.Len(header.Get("x-request-id"), 1)
+
+	// Receive the results
+	var final int
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if !assert.NoError(err) {
+			break
+		}
+		for _, result := range resp.Results {
+			assert.NotEmpty(result.Alternatives)
+			if result.IsFinal {
+				final++
+				assert.NotEmpty(result.Alternatives[0].Words)
+			}
+			t.Log(result.IsFinal, result.StartTime.AsDuration(), result.Alternatives[0].Transcript)
+		}
+	}
+	assert.NotZero(final)
+}
+
+func Test_GRPC_002(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+
+	// Create a pool and a client
+	pool, err := whisper.NewPool(ModelPath, 1, whisper.PoolWait)
+	assert.NoError(err)
+	defer pool.Close()
+	client := newClient(t, pool)
+
+	// One second of tone between two seconds of silence, as 16-bit PCM
+	data := make([]byte, 5*whisper.SampleRate*2)
+	for i := 2 * whisper.SampleRate; i < 3*whisper.SampleRate; i++ {
+		binary.LittleEndian.PutUint16(data[i*2:], uint16(int16(math.MaxInt16/2*math.Sin(float64(i)/4))))
+	}
+
+	// Send the configuration with voice activity events, followed by the audio
+	stream, err := client.StreamingRecognize(context.Background())
+	assert.NoError(err)
+	assert.NoError(stream.Send(&server.StreamingRecognizeRequest{
+		StreamingRequest: &server.StreamingRecognizeRequest_StreamingConfig{
+			StreamingConfig: &server.StreamingRecognitionConfig{
+				Config:              &server.RecognitionConfig{Encoding: server.RecognitionConfig_LINEAR16},
+				VoiceActivityEvents: true,
+			},
+		},
+	}))
+	assert.NoError(stream.Send(&server.StreamingRecognizeRequest{
+		StreamingRequest: &server.StreamingRecognizeRequest_AudioContent{AudioContent: data},
+	}))
+	assert.NoError(stream.CloseSend())
+
+	// The utterance starts with the tone, and ends after its results
+	var events []server.StreamingRecognizeResponse_SpeechEventType
+	var times []time.Duration
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if !assert.NoError(err) {
+			break
+		}
+		if resp.SpeechEventType != server.StreamingRecognizeResponse_SPEECH_EVENT_UNSPECIFIED {
+			assert.Empty(resp.Results)
+			events = append(events, resp.SpeechEventType)
+			times = append(times, resp.SpeechEventTime.AsDuration())
+		}
+	}
+	assert.Equal([]server.StreamingRecognizeResponse_SpeechEventType{
+		server.StreamingRecognizeResponse_UTTERANCE_START,
+		server.StreamingRecognizeResponse_UTTERANCE_END,
+	}, events)
+	if assert.Len(times, 2) {
+		assert.InDelta(2*time.Second, times[0], float64(100*time.Millisecond))
+		assert.Greater(times[1], 3*time.Second)
+	}
+}
+
+func Test_GRPC_003(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+
+	// Create a pool and a server which limits the audio of each stream
+	pool, err := whisper.NewPool(ModelPath, 1, whisper.PoolWait)
+	assert.NoError(err)
+	defer pool.Close()
+	speech := server.NewServer(pool, nil)
+	speech.SetLimits(time.Second, 0, 3*whisper.SampleRate*2)
+	client := newClientWithServer(t, speech)
+
+	// Send the configuration followed by silence, in half second chunks
+	send := func(seconds int) error {
+		stream, err := client.StreamingRecognize(context.Background())
+		assert.NoError(err)
+		assert.NoError(stream.Send(&server.StreamingRecognizeRequest{
+			StreamingRequest: &server.StreamingRecognizeRequest_StreamingConfig{
+				StreamingConfig: &server.StreamingRecognitionConfig{
+					Config: &server.RecognitionConfig{Encoding: server.RecognitionConfig_LINEAR16},
+				},
+			},
+		}))
+		for i := 0; i < seconds*2; i++ {
+			if err := stream.Send(&server.StreamingRecognizeRequest{
+				StreamingRequest: &server.StreamingRecognizeRequest_AudioContent{AudioContent: make([]byte, whisper.SampleRate)},
+			}); err != nil {
+				break
+			}
+		}
+		stream.CloseSend()
+		for {
+			if _, err := stream.Recv(); err == io.EOF {
+				return nil
+			} else if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Streams within the limits end normally, and the others once the limit
+	// is reached
+	assert.NoError(send(1))
+	assert.Equal(codes.ResourceExhausted, status.Code(send(2)))
+
+	// The limit on the bytes received is reached before the audio limit
+	speech.SetLimits(0, 0, whisper.SampleRate)
+	assert.Equal(codes.ResourceExhausted, status.Code(send(2)))
+}
+
+func Test_GRPC_004(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+
+	// Create a pool and a server which notifies clients when decoding falls
+	// behind by half a second
+	pool, err := whisper.NewPool(ModelPath, 1, whisper.PoolWait)
+	assert.NoError(err)
+	defer pool.Close()
+	speech := server.NewServer(pool, nil)
+	speech.SetLag(server.LagNotify, 500*time.Millisecond)
+	client := newClientWithServer(t, speech)
+
+	// Send twenty seconds of silence at once, in half second chunks, so
+	// that audio is received while the first window is decoded
+	stream, err := client.StreamingRecognize(context.Background())
+	assert.NoError(err)
+	assert.NoError(stream.Send(&server.StreamingRecognizeRequest{
+		StreamingRequest: &server.StreamingRecognizeRequest_StreamingConfig{
+			StreamingConfig: &server.StreamingRecognitionConfig{
+				Config: &server.RecognitionConfig{Encoding: server.RecognitionConfig_LINEAR16},
+			},
+		},
+	}))
+	go func() {
+		for i := 0; i < 40; i++ {
+			if err := stream.Send(&server.StreamingRecognizeRequest{
+				StreamingRequest: &server.StreamingRecognizeRequest_AudioContent{AudioContent: make([]byte, whisper.SampleRate)},
+			}); err != nil {
+				return
+			}
+		}
+		stream.CloseSend()
+	}()
+
+	// The client is notified, and the stream ends normally
+	var events int
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if !assert.NoError(err) {
+			break
+		}
+		if resp.SpeechEventType == server.StreamingRecognizeResponse_DECODE_LAGGING {
+			events++
+		}
+	}
+	assert.NotZero(events)
+}
@@ -0,0 +1,975 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.31.0
+// 	protoc        (unknown)
+// source: whisper.proto
+
+// The speech recognition service, modeled after the streaming API of the
+// Google Speech API.
+
+package grpc
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type RecognitionConfig_AudioEncoding int32
+
+const (
+	RecognitionConfig_ENCODING_UNSPECIFIED RecognitionConfig_AudioEncoding = 0 // Same as LINEAR16
+	RecognitionConfig_LINEAR16             RecognitionConfig_AudioEncoding = 1 // 16-bit signed little-endian samples
+	RecognitionConfig_MULAW                RecognitionConfig_AudioEncoding = 2 // 8-bit G.711 u-law samples
+	RecognitionConfig_ALAW                 RecognitionConfig_AudioEncoding = 3 // 8-bit G.711 A-law samples
+	RecognitionConfig_FLOAT32              RecognitionConfig_AudioEncoding = 4 // 32-bit float little-endian samples
+	RecognitionConfig_OGG_OPUS             RecognitionConfig_AudioEncoding = 5 // Ogg/Opus stream, in chunks of any size
+	RecognitionConfig_OPUS                 RecognitionConfig_AudioEncoding = 6 // Opus packets, one packet per request
+)
+
+// Enum value maps for RecognitionConfig_AudioEncoding.
+var (
+	RecognitionConfig_AudioEncoding_name = map[int32]string{
+		0: "ENCODING_UNSPECIFIED",
+		1: "LINEAR16",
+		2: "MULAW",
+		3: "ALAW",
+		4: "FLOAT32",
+		5: "OGG_OPUS",
+		6: "OPUS",
+	}
+	RecognitionConfig_AudioEncoding_value = map[string]int32{
+		"ENCODING_UNSPECIFIED": 0,
+		"LINEAR16":             1,
+		"MULAW":                2,
+		"ALAW":                 3,
+		"FLOAT32":              4,
+		"OGG_OPUS":             5,
+		"OPUS":                 6,
+	}
+)
+
+func (x RecognitionConfig_AudioEncoding) Enum() *RecognitionConfig_AudioEncoding {
+	p := new(RecognitionConfig_AudioEncoding)
+	*p = x
+	return p
+}
+
+func (x RecognitionConfig_AudioEncoding) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (RecognitionConfig_AudioEncoding) Descriptor() protoreflect.EnumDescriptor {
+	return file_whisper_proto_enumTypes[0].Descriptor()
+}
+
+func (RecognitionConfig_AudioEncoding) Type() protoreflect.EnumType {
+	return &file_whisper_proto_enumTypes[0]
+}
+
+func (x RecognitionConfig_AudioEncoding) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use RecognitionConfig_AudioEncoding.Descriptor instead.
+func (RecognitionConfig_AudioEncoding) EnumDescriptor() ([]byte, []int) {
+	return file_whisper_proto_rawDescGZIP(), []int{2, 0}
+}
+
+type StreamingRecognizeResponse_SpeechEventType int32
+
+const (
+	StreamingRecognizeResponse_SPEECH_EVENT_UNSPECIFIED StreamingRecognizeResponse_SpeechEventType = 0 // No event, the response contains results
+	StreamingRecognizeResponse_UTTERANCE_START          StreamingRecognizeResponse_SpeechEventType = 1 // Speech has started
+	StreamingRecognizeResponse_UTTERANCE_END            StreamingRecognizeResponse_SpeechEventType = 2 // Speech has ended, after its final results
+	StreamingRecognizeResponse_DECODE_LAGGING           StreamingRecognizeResponse_SpeechEventType = 3 // Decoding has fallen behind the audio sent
+)
+
+// Enum value maps for StreamingRecognizeResponse_SpeechEventType.
+var (
+	StreamingRecognizeResponse_SpeechEventType_name = map[int32]string{
+		0: "SPEECH_EVENT_UNSPECIFIED",
+		1: "UTTERANCE_START",
+		2: "UTTERANCE_END",
+		3: "DECODE_LAGGING",
+	}
+	StreamingRecognizeResponse_SpeechEventType_value = map[string]int32{
+		"SPEECH_EVENT_UNSPECIFIED": 0,
+		"UTTERANCE_START":          1,
+		"UTTERANCE_END":            2,
+		"DECODE_LAGGING":           3,
+	}
+)
+
+func (x StreamingRecognizeResponse_SpeechEventType) Enum() *StreamingRecognizeResponse_SpeechEventType {
+	p := new(StreamingRecognizeResponse_SpeechEventType)
+	*p = x
+	return p
+}
+
+func (x StreamingRecognizeResponse_SpeechEventType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (StreamingRecognizeResponse_SpeechEventType) Descriptor() protoreflect.EnumDescriptor {
+	return file_whisper_proto_enumTypes[1].Descriptor()
+}
+
+func (StreamingRecognizeResponse_SpeechEventType) Type() protoreflect.EnumType {
+	return &file_whisper_proto_enumTypes[1]
+}
+
+func (x StreamingRecognizeResponse_SpeechEventType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use StreamingRecognizeResponse_SpeechEventType.Descriptor instead.
+func (StreamingRecognizeResponse_SpeechEventType) EnumDescriptor() ([]byte, []int) {
+	return file_whisper_proto_rawDescGZIP(), []int{3, 0}
+}
+
+type StreamingRecognizeRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to StreamingRequest:
+	//	*StreamingRecognizeRequest_StreamingConfig
+	//	*StreamingRecognizeRequest_AudioContent
+	StreamingRequest isStreamingRecognizeRequest_StreamingRequest `protobuf_oneof:"streaming_request"`
+}
+
+func (x *StreamingRecognizeRequest) Reset() {
+	*x = StreamingRecognizeRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_whisper_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StreamingRecognizeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamingRecognizeRequest) ProtoMessage() {}
+
+func (x *StreamingRecognizeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_whisper_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamingRecognizeRequest.ProtoReflect.Descriptor instead.
+func (*StreamingRecognizeRequest) Descriptor() ([]byte, []int) {
+	return file_whisper_proto_rawDescGZIP(), []int{0}
+}
+
+func (m *StreamingRecognizeRequest) GetStreamingRequest() isStreamingRecognizeRequest_StreamingRequest {
+	if m != nil {
+		return m.StreamingRequest
+	}
+	return nil
+}
+
+func (x *StreamingRecognizeRequest) GetStreamingConfig() *StreamingRecognitionConfig {
+	if x, ok := x.GetStreamingRequest().(*StreamingRecognizeRequest_StreamingConfig); ok {
+		return x.StreamingConfig
+	}
+	return nil
+}
+
+func (x *StreamingRecognizeRequest) GetAudioContent() []byte {
+	if x, ok := x.GetStreamingRequest().(*StreamingRecognizeRequest_AudioContent); ok {
+		return x.AudioContent
+	}
+	return nil
+}
+
+type isStreamingRecognizeRequest_StreamingRequest interface {
+	isStreamingRecognizeRequest_StreamingRequest()
+}
+
+type StreamingRecognizeRequest_StreamingConfig struct {
+	// The configuration, which must be the first request and only the
+	// first request
+	StreamingConfig *StreamingRecognitionConfig `protobuf:"bytes,1,opt,name=streaming_config,json=streamingConfig,proto3,oneof"`
+}
+
+type StreamingRecognizeRequest_AudioContent struct {
+	// Audio data, encoded as described by the configuration. Any number of
+	// samples can be sent in each request.
+	AudioContent []byte `protobuf:"bytes,2,opt,name=audio_content,json=audioContent,proto3,oneof"`
+}
+
+func (*StreamingRecognizeRequest_StreamingConfig) isStreamingRecognizeRequest_StreamingRequest() {}
+
+func (*StreamingRecognizeRequest_AudioContent) isStreamingRecognizeRequest_StreamingRequest() {}
+
+type StreamingRecognitionConfig struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Config *RecognitionConfig `protobuf:"bytes,1,opt,name=config,proto3" json:"config,omitempty"`
+	// When true, interim results are returned before a result is final
+	InterimResults bool `protobuf:"varint,2,opt,name=interim_results,json=interimResults,proto3" json:"interim_results,omitempty"`
+	// When true, voice activity detection is used to transcribe only the
+	// utterances, and an event is returned when each utterance starts and
+	// ends, so that the client can implement barge-in and turn-taking
+	VoiceActivityEvents bool `protobuf:"varint,3,opt,name=voice_activity_events,json=voiceActivityEvents,proto3" json:"voice_activity_events,omitempty"`
+}
+
+func (x *StreamingRecognitionConfig) Reset() {
+	*x = StreamingRecognitionConfig{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_whisper_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StreamingRecognitionConfig) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamingRecognitionConfig) ProtoMessage() {}
+
+func (x *StreamingRecognitionConfig) ProtoReflect() protoreflect.Message {
+	mi := &file_whisper_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamingRecognitionConfig.ProtoReflect.Descriptor instead.
+func (*StreamingRecognitionConfig) Descriptor() ([]byte, []int) {
+	return file_whisper_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *StreamingRecognitionConfig) GetConfig() *RecognitionConfig {
+	if x != nil {
+		return x.Config
+	}
+	return nil
+}
+
+func (x *StreamingRecognitionConfig) GetInterimResults() bool {
+	if x != nil {
+		return x.InterimResults
+	}
+	return false
+}
+
+func (x *StreamingRecognitionConfig) GetVoiceActivityEvents() bool {
+	if x != nil {
+		return x.VoiceActivityEvents
+	}
+	return false
+}
+
+type RecognitionConfig struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Encoding RecognitionConfig_AudioEncoding `protobuf:"varint,1,opt,name=encoding,proto3,enum=whisper.v1.RecognitionConfig_AudioEncoding" json:"encoding,omitempty"`
+	// Sample rate of the audio. Zero is the same as 16000, and audio at
+	// other rates is resampled. The sample rate is ignored for Opus audio.
+	SampleRateHertz int32 `protobuf:"varint,2,opt,name=sample_rate_hertz,json=sampleRateHertz,proto3" json:"sample_rate_hertz,omitempty"`
+	// Language of the audio, or "auto" to detect the language. When empty,
+	// the default language of the server is used.
+	LanguageCode string `protobuf:"bytes,3,opt,name=language_code,json=languageCode,proto3" json:"language_code,omitempty"`
+	// When true, the audio is translated into English
+	Translate bool `protobuf:"varint,4,opt,name=translate,proto3" json:"translate,omitempty"`
+	// When true, results include the time offsets of each word
+	EnableWordTimeOffsets bool `protobuf:"varint,5,opt,name=enable_word_time_offsets,json=enableWordTimeOffsets,proto3" json:"enable_word_time_offsets,omitempty"`
+	// Number of interleaved channels, which are averaged to mono. Zero is the
+	// same as one. Ogg/Opus streams declare the number of channels in their
+	// header, so it is ignored for Ogg/Opus audio.
+	AudioChannelCount int32 `protobuf:"varint,6,opt,name=audio_channel_count,json=audioChannelCount,proto3" json:"audio_channel_count,omitempty"`
+	// Name of the model, when the server serves several models. When empty,
+	// the default model of the server is used.
+	Model string `protobuf:"bytes,7,opt,name=model,proto3" json:"model,omitempty"`
+}
+
+func (x *RecognitionConfig) Reset() {
+	*x = RecognitionConfig{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_whisper_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RecognitionConfig) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RecognitionConfig) ProtoMessage() {}
+
+func (x *RecognitionConfig) ProtoReflect() protoreflect.Message {
+	mi := &file_whisper_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RecognitionConfig.ProtoReflect.Descriptor instead.
+func (*RecognitionConfig) Descriptor() ([]byte, []int) {
+	return file_whisper_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *RecognitionConfig) GetEncoding() RecognitionConfig_AudioEncoding {
+	if x != nil {
+		return x.Encoding
+	}
+	return RecognitionConfig_ENCODING_UNSPECIFIED
+}
+
+func (x *RecognitionConfig) GetSampleRateHertz() int32 {
+	if x != nil {
+		return x.SampleRateHertz
+	}
+	return 0
+}
+
+func (x *RecognitionConfig) GetLanguageCode() string {
+	if x != nil {
+		return x.LanguageCode
+	}
+	return ""
+}
+
+func (x *RecognitionConfig) GetTranslate() bool {
+	if x != nil {
+		return x.Translate
+	}
+	return false
+}
+
+func (x *RecognitionConfig) GetEnableWordTimeOffsets() bool {
+	if x != nil {
+		return x.EnableWordTimeOffsets
+	}
+	return false
+}
+
+func (x *RecognitionConfig) GetAudioChannelCount() int32 {
+	if x != nil {
+		return x.AudioChannelCount
+	}
+	return 0
+}
+
+func (x *RecognitionConfig) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+type StreamingRecognizeResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Results []*StreamingRecognitionResult `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	// Voice activity event, when voice_activity_events is set, or a lag event
+	SpeechEventType StreamingRecognizeResponse_SpeechEventType `protobuf:"varint,2,opt,name=speech_event_type,json=speechEventType,proto3,enum=whisper.v1.StreamingRecognizeResponse_SpeechEventType" json:"speech_event_type,omitempty"`
+	// Time offset of the event from the start of the audio
+	SpeechEventTime *durationpb.Duration `protobuf:"bytes,3,opt,name=speech_event_time,json=speechEventTime,proto3" json:"speech_event_time,omitempty"`
+}
+
+func (x *StreamingRecognizeResponse) Reset() {
+	*x = StreamingRecognizeResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_whisper_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StreamingRecognizeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamingRecognizeResponse) ProtoMessage() {}
+
+func (x *StreamingRecognizeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_whisper_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamingRecognizeResponse.ProtoReflect.Descriptor instead.
+func (*StreamingRecognizeResponse) Descriptor() ([]byte, []int) {
+	return file_whisper_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *StreamingRecognizeResponse) GetResults() []*StreamingRecognitionResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+func (x *StreamingRecognizeResponse) GetSpeechEventType() StreamingRecognizeResponse_SpeechEventType {
+	if x != nil {
+		return x.SpeechEventType
+	}
+	return StreamingRecognizeResponse_SPEECH_EVENT_UNSPECIFIED
+}
+
+func (x *StreamingRecognizeResponse) GetSpeechEventTime() *durationpb.Duration {
+	if x != nil {
+		return x.SpeechEventTime
+	}
+	return nil
+}
+
+type StreamingRecognitionResult struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Alternatives []*SpeechRecognitionAlternative `protobuf:"bytes,1,rep,name=alternatives,proto3" json:"alternatives,omitempty"`
+	// When false, this is an interim result which may be replaced by a
+	// following result. When true, the result will not change.
+	IsFinal bool `protobuf:"varint,2,opt,name=is_final,json=isFinal,proto3" json:"is_final,omitempty"`
+	// Time offsets of the result from the start of the audio
+	StartTime *durationpb.Duration `protobuf:"bytes,3,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`
+	EndTime   *durationpb.Duration `protobuf:"bytes,4,opt,name=end_time,json=endTime,proto3" json:"end_time,omitempty"`
+	// Language of the result
+	LanguageCode string `protobuf:"bytes,5,opt,name=language_code,json=languageCode,proto3" json:"language_code,omitempty"`
+}
+
+func (x *StreamingRecognitionResult) Reset() {
+	*x = StreamingRecognitionResult{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_whisper_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StreamingRecognitionResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamingRecognitionResult) ProtoMessage() {}
+
+func (x *StreamingRecognitionResult) ProtoReflect() protoreflect.Message {
+	mi := &file_whisper_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamingRecognitionResult.ProtoReflect.Descriptor instead.
+func (*StreamingRecognitionResult) Descriptor() ([]byte, []int) {
+	return file_whisper_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *StreamingRecognitionResult) GetAlternatives() []*SpeechRecognitionAlternative {
+	if x != nil {
+		return x.Alternatives
+	}
+	return nil
+}
+
+func (x *StreamingRecognitionResult) GetIsFinal() bool {
+	if x != nil {
+		return x.IsFinal
+	}
+	return false
+}
+
+func (x *StreamingRecognitionResult) GetStartTime() *durationpb.Duration {
+	if x != nil {
+		return x.StartTime
+	}
+	return nil
+}
+
+func (x *StreamingRecognitionResult) GetEndTime() *durationpb.Duration {
+	if x != nil {
+		return x.EndTime
+	}
+	return nil
+}
+
+func (x *StreamingRecognitionResult) GetLanguageCode() string {
+	if x != nil {
+		return x.LanguageCode
+	}
+	return ""
+}
+
+type SpeechRecognitionAlternative struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Transcript string `protobuf:"bytes,1,opt,name=transcript,proto3" json:"transcript,omitempty"`
+	// Mean probability of the words, between 0 and 1
+	Confidence float32 `protobuf:"fixed32,2,opt,name=confidence,proto3" json:"confidence,omitempty"`
+	// Words of the transcript, when word time offsets are enabled
+	Words []*WordInfo `protobuf:"bytes,3,rep,name=words,proto3" json:"words,omitempty"`
+}
+
+func (x *SpeechRecognitionAlternative) Reset() {
+	*x = SpeechRecognitionAlternative{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_whisper_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SpeechRecognitionAlternative) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SpeechRecognitionAlternative) ProtoMessage() {}
+
+func (x *SpeechRecognitionAlternative) ProtoReflect() protoreflect.Message {
+	mi := &file_whisper_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SpeechRecognitionAlternative.ProtoReflect.Descriptor instead.
+func (*SpeechRecognitionAlternative) Descriptor() ([]byte, []int) {
+	return file_whisper_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *SpeechRecognitionAlternative) GetTranscript() string {
+	if x != nil {
+		return x.Transcript
+	}
+	return ""
+}
+
+func (x *SpeechRecognitionAlternative) GetConfidence() float32 {
+	if x != nil {
+		return x.Confidence
+	}
+	return 0
+}
+
+func (x *SpeechRecognitionAlternative) GetWords() []*WordInfo {
+	if x != nil {
+		return x.Words
+	}
+	return nil
+}
+
+type WordInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	StartTime  *durationpb.Duration `protobuf:"bytes,1,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`
+	EndTime    *durationpb.Duration `protobuf:"bytes,2,opt,name=end_time,json=endTime,proto3" json:"end_time,omitempty"`
+	Word       string               `protobuf:"bytes,3,opt,name=word,proto3" json:"word,omitempty"`
+	Confidence float32              `protobuf:"fixed32,4,opt,name=confidence,proto3" json:"confidence,omitempty"`
+}
+
+func (x *WordInfo) Reset() {
+	*x = WordInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_whisper_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WordInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WordInfo) ProtoMessage() {}
+
+func (x *WordInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_whisper_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WordInfo.ProtoReflect.Descriptor instead.
+func (*WordInfo) Descriptor() ([]byte, []int) {
+	return file_whisper_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *WordInfo) GetStartTime() *durationpb.Duration {
+	if x != nil {
+		return x.StartTime
+	}
+	return nil
+}
+
+func (x *WordInfo) GetEndTime() *durationpb.Duration {
+	if x != nil {
+		return x.EndTime
+	}
+	return nil
+}
+
+func (x *WordInfo) GetWord() string {
+	if x != nil {
+		return x.Word
+	}
+	return ""
+}
+
+func (x *WordInfo) GetConfidence() float32 {
+	if x != nil {
+		return x.Confidence
+	}
+	return 0
+}
+
+var File_whisper_proto protoreflect.FileDescriptor
+
+var file_whisper_proto_rawDesc = []byte{
+	0x0a, 0x0d, 0x77, 0x68, 0x69, 0x73, 0x70, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12,
+	0x0a, 0x77, 0x68, 0x69, 0x73, 0x70, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x1a, 0x1e, 0x67, 0x6f, 0x6f,
+	0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x64, 0x75, 0x72,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xac, 0x01, 0x0a, 0x19,
+	0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x63, 0x6f, 0x67, 0x6e, 0x69,
+	0x7a, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x53, 0x0a, 0x10, 0x73, 0x74, 0x72,
+	0x65, 0x61, 0x6d, 0x69, 0x6e, 0x67, 0x5f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x26, 0x2e, 0x77, 0x68, 0x69, 0x73, 0x70, 0x65, 0x72, 0x2e, 0x76, 0x31,
+	0x2e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x63, 0x6f, 0x67, 0x6e,
+	0x69, 0x74, 0x69, 0x6f, 0x6e, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x48, 0x00, 0x52, 0x0f, 0x73,
+	0x74, 0x72, 0x65, 0x61, 0x6d, 0x69, 0x6e, 0x67, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x25,
+	0x0a, 0x0d, 0x61, 0x75, 0x64, 0x69, 0x6f, 0x5f, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x0c, 0x48, 0x00, 0x52, 0x0c, 0x61, 0x75, 0x64, 0x69, 0x6f, 0x43, 0x6f,
+	0x6e, 0x74, 0x65, 0x6e, 0x74, 0x42, 0x13, 0x0a, 0x11, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x69,
+	0x6e, 0x67, 0x5f, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0xb0, 0x01, 0x0a, 0x1a, 0x53,
+	0x74, 0x72, 0x65, 0x61, 0x6d, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x63, 0x6f, 0x67, 0x6e, 0x69, 0x74,
+	0x69, 0x6f, 0x6e, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x35, 0x0a, 0x06, 0x63, 0x6f, 0x6e,
+	0x66, 0x69, 0x67, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x77, 0x68, 0x69, 0x73,
+	0x70, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65, 0x63, 0x6f, 0x67, 0x6e, 0x69, 0x74, 0x69,
+	0x6f, 0x6e, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52, 0x06, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67,
+	0x12, 0x27, 0x0a, 0x0f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x69, 0x6d, 0x5f, 0x72, 0x65, 0x73, 0x75,
+	0x6c, 0x74, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0e, 0x69, 0x6e, 0x74, 0x65, 0x72,
+	0x69, 0x6d, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x12, 0x32, 0x0a, 0x15, 0x76, 0x6f, 0x69,
+	0x63, 0x65, 0x5f, 0x61, 0x63, 0x74, 0x69, 0x76, 0x69, 0x74, 0x79, 0x5f, 0x65, 0x76, 0x65, 0x6e,
+	0x74, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x13, 0x76, 0x6f, 0x69, 0x63, 0x65, 0x41,
+	0x63, 0x74, 0x69, 0x76, 0x69, 0x74, 0x79, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x22, 0xbd, 0x03,
+	0x0a, 0x11, 0x52, 0x65, 0x63, 0x6f, 0x67, 0x6e, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x43, 0x6f, 0x6e,
+	0x66, 0x69, 0x67, 0x12, 0x47, 0x0a, 0x08, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x2b, 0x2e, 0x77, 0x68, 0x69, 0x73, 0x70, 0x65, 0x72, 0x2e,
+	0x76, 0x31, 0x2e, 0x52, 0x65, 0x63, 0x6f, 0x67, 0x6e, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x43, 0x6f,
+	0x6e, 0x66, 0x69, 0x67, 0x2e, 0x41, 0x75, 0x64, 0x69, 0x6f, 0x45, 0x6e, 0x63, 0x6f, 0x64, 0x69,
+	0x6e, 0x67, 0x52, 0x08, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x12, 0x2a, 0x0a, 0x11,
+	0x73, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x5f, 0x72, 0x61, 0x74, 0x65, 0x5f, 0x68, 0x65, 0x72, 0x74,
+	0x7a, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0f, 0x73, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x52,
+	0x61, 0x74, 0x65, 0x48, 0x65, 0x72, 0x74, 0x7a, 0x12, 0x23, 0x0a, 0x0d, 0x6c, 0x61, 0x6e, 0x67,
+	0x75, 0x61, 0x67, 0x65, 0x5f, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x0c, 0x6c, 0x61, 0x6e, 0x67, 0x75, 0x61, 0x67, 0x65, 0x43, 0x6f, 0x64, 0x65, 0x12, 0x1c, 0x0a,
+	0x09, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x6c, 0x61, 0x74, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x09, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x6c, 0x61, 0x74, 0x65, 0x12, 0x37, 0x0a, 0x18, 0x65,
+	0x6e, 0x61, 0x62, 0x6c, 0x65, 0x5f, 0x77, 0x6f, 0x72, 0x64, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x5f,
+	0x6f, 0x66, 0x66, 0x73, 0x65, 0x74, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x15, 0x65,
+	0x6e, 0x61, 0x62, 0x6c, 0x65, 0x57, 0x6f, 0x72, 0x64, 0x54, 0x69, 0x6d, 0x65, 0x4f, 0x66, 0x66,
+	0x73, 0x65, 0x74, 0x73, 0x12, 0x2e, 0x0a, 0x13, 0x61, 0x75, 0x64, 0x69, 0x6f, 0x5f, 0x63, 0x68,
+	0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x11, 0x61, 0x75, 0x64, 0x69, 0x6f, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x43,
+	0x6f, 0x75, 0x6e, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x6d, 0x6f, 0x64, 0x65, 0x6c, 0x18, 0x07, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x05, 0x6d, 0x6f, 0x64, 0x65, 0x6c, 0x22, 0x71, 0x0a, 0x0d, 0x41, 0x75,
+	0x64, 0x69, 0x6f, 0x45, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x12, 0x18, 0x0a, 0x14, 0x45,
+	0x4e, 0x43, 0x4f, 0x44, 0x49, 0x4e, 0x47, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46,
+	0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x0c, 0x0a, 0x08, 0x4c, 0x49, 0x4e, 0x45, 0x41, 0x52, 0x31,
+	0x36, 0x10, 0x01, 0x12, 0x09, 0x0a, 0x05, 0x4d, 0x55, 0x4c, 0x41, 0x57, 0x10, 0x02, 0x12, 0x08,
+	0x0a, 0x04, 0x41, 0x4c, 0x41, 0x57, 0x10, 0x03, 0x12, 0x0b, 0x0a, 0x07, 0x46, 0x4c, 0x4f, 0x41,
+	0x54, 0x33, 0x32, 0x10, 0x04, 0x12, 0x0c, 0x0a, 0x08, 0x4f, 0x47, 0x47, 0x5f, 0x4f, 0x50, 0x55,
+	0x53, 0x10, 0x05, 0x12, 0x08, 0x0a, 0x04, 0x4f, 0x50, 0x55, 0x53, 0x10, 0x06, 0x22, 0xf6, 0x02,
+	0x0a, 0x1a, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x63, 0x6f, 0x67,
+	0x6e, 0x69, 0x7a, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x40, 0x0a, 0x07,
+	0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x26, 0x2e,
+	0x77, 0x68, 0x69, 0x73, 0x70, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x74, 0x72, 0x65, 0x61,
+	0x6d, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x63, 0x6f, 0x67, 0x6e, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x52,
+	0x65, 0x73, 0x75, 0x6c, 0x74, 0x52, 0x07, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x12, 0x62,
+	0x0a, 0x11, 0x73, 0x70, 0x65, 0x65, 0x63, 0x68, 0x5f, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x5f, 0x74,
+	0x79, 0x70, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x36, 0x2e, 0x77, 0x68, 0x69, 0x73,
+	0x70, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x69, 0x6e, 0x67,
+	0x52, 0x65, 0x63, 0x6f, 0x67, 0x6e, 0x69, 0x7a, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x2e, 0x53, 0x70, 0x65, 0x65, 0x63, 0x68, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x54, 0x79, 0x70,
+	0x65, 0x52, 0x0f, 0x73, 0x70, 0x65, 0x65, 0x63, 0x68, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x54, 0x79,
+	0x70, 0x65, 0x12, 0x45, 0x0a, 0x11, 0x73, 0x70, 0x65, 0x65, 0x63, 0x68, 0x5f, 0x65, 0x76, 0x65,
+	0x6e, 0x74, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e,
+	0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e,
+	0x44, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x0f, 0x73, 0x70, 0x65, 0x65, 0x63, 0x68,
+	0x45, 0x76, 0x65, 0x6e, 0x74, 0x54, 0x69, 0x6d, 0x65, 0x22, 0x6b, 0x0a, 0x0f, 0x53, 0x70, 0x65,
+	0x65, 0x63, 0x68, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x54, 0x79, 0x70, 0x65, 0x12, 0x1c, 0x0a, 0x18,
+	0x53, 0x50, 0x45, 0x45, 0x43, 0x48, 0x5f, 0x45, 0x56, 0x45, 0x4e, 0x54, 0x5f, 0x55, 0x4e, 0x53,
+	0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x13, 0x0a, 0x0f, 0x55, 0x54,
+	0x54, 0x45, 0x52, 0x41, 0x4e, 0x43, 0x45, 0x5f, 0x53, 0x54, 0x41, 0x52, 0x54, 0x10, 0x01, 0x12,
+	0x11, 0x0a, 0x0d, 0x55, 0x54, 0x54, 0x45, 0x52, 0x41, 0x4e, 0x43, 0x45, 0x5f, 0x45, 0x4e, 0x44,
+	0x10, 0x02, 0x12, 0x12, 0x0a, 0x0e, 0x44, 0x45, 0x43, 0x4f, 0x44, 0x45, 0x5f, 0x4c, 0x41, 0x47,
+	0x47, 0x49, 0x4e, 0x47, 0x10, 0x03, 0x22, 0x9a, 0x02, 0x0a, 0x1a, 0x53, 0x74, 0x72, 0x65, 0x61,
+	0x6d, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x63, 0x6f, 0x67, 0x6e, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x52,
+	0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x4c, 0x0a, 0x0c, 0x61, 0x6c, 0x74, 0x65, 0x72, 0x6e, 0x61,
+	0x74, 0x69, 0x76, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x28, 0x2e, 0x77, 0x68,
+	0x69, 0x73, 0x70, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x70, 0x65, 0x65, 0x63, 0x68, 0x52,
+	0x65, 0x63, 0x6f, 0x67, 0x6e, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x41, 0x6c, 0x74, 0x65, 0x72, 0x6e,
+	0x61, 0x74, 0x69, 0x76, 0x65, 0x52, 0x0c, 0x61, 0x6c, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x69,
+	0x76, 0x65, 0x73, 0x12, 0x19, 0x0a, 0x08, 0x69, 0x73, 0x5f, 0x66, 0x69, 0x6e, 0x61, 0x6c, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x69, 0x73, 0x46, 0x69, 0x6e, 0x61, 0x6c, 0x12, 0x38,
+	0x0a, 0x0a, 0x73, 0x74, 0x61, 0x72, 0x74, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x19, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x62, 0x75, 0x66, 0x2e, 0x44, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x09, 0x73,
+	0x74, 0x61, 0x72, 0x74, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x34, 0x0a, 0x08, 0x65, 0x6e, 0x64, 0x5f,
+	0x74, 0x69, 0x6d, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x67, 0x6f, 0x6f,
+	0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x44, 0x75, 0x72,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x07, 0x65, 0x6e, 0x64, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x23,
+	0x0a, 0x0d, 0x6c, 0x61, 0x6e, 0x67, 0x75, 0x61, 0x67, 0x65, 0x5f, 0x63, 0x6f, 0x64, 0x65, 0x18,
+	0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x6c, 0x61, 0x6e, 0x67, 0x75, 0x61, 0x67, 0x65, 0x43,
+	0x6f, 0x64, 0x65, 0x22, 0x8a, 0x01, 0x0a, 0x1c, 0x53, 0x70, 0x65, 0x65, 0x63, 0x68, 0x52, 0x65,
+	0x63, 0x6f, 0x67, 0x6e, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x41, 0x6c, 0x74, 0x65, 0x72, 0x6e, 0x61,
+	0x74, 0x69, 0x76, 0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x63, 0x72, 0x69,
+	0x70, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x63,
+	0x72, 0x69, 0x70, 0x74, 0x12, 0x1e, 0x0a, 0x0a, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x64, 0x65, 0x6e,
+	0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x02, 0x52, 0x0a, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x64,
+	0x65, 0x6e, 0x63, 0x65, 0x12, 0x2a, 0x0a, 0x05, 0x77, 0x6f, 0x72, 0x64, 0x73, 0x18, 0x03, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x77, 0x68, 0x69, 0x73, 0x70, 0x65, 0x72, 0x2e, 0x76, 0x31,
+	0x2e, 0x57, 0x6f, 0x72, 0x64, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x05, 0x77, 0x6f, 0x72, 0x64, 0x73,
+	0x22, 0xae, 0x01, 0x0a, 0x08, 0x57, 0x6f, 0x72, 0x64, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x38, 0x0a,
+	0x0a, 0x73, 0x74, 0x61, 0x72, 0x74, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x19, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x62, 0x75, 0x66, 0x2e, 0x44, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x09, 0x73, 0x74,
+	0x61, 0x72, 0x74, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x34, 0x0a, 0x08, 0x65, 0x6e, 0x64, 0x5f, 0x74,
+	0x69, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x67, 0x6f, 0x6f, 0x67,
+	0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x44, 0x75, 0x72, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x52, 0x07, 0x65, 0x6e, 0x64, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x12, 0x0a,
+	0x04, 0x77, 0x6f, 0x72, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x77, 0x6f, 0x72,
+	0x64, 0x12, 0x1e, 0x0a, 0x0a, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x64, 0x65, 0x6e, 0x63, 0x65, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x02, 0x52, 0x0a, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x64, 0x65, 0x6e, 0x63,
+	0x65, 0x32, 0x71, 0x0a, 0x06, 0x53, 0x70, 0x65, 0x65, 0x63, 0x68, 0x12, 0x67, 0x0a, 0x12, 0x53,
+	0x74, 0x72, 0x65, 0x61, 0x6d, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x63, 0x6f, 0x67, 0x6e, 0x69, 0x7a,
+	0x65, 0x12, 0x25, 0x2e, 0x77, 0x68, 0x69, 0x73, 0x70, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x53,
+	0x74, 0x72, 0x65, 0x61, 0x6d, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x63, 0x6f, 0x67, 0x6e, 0x69, 0x7a,
+	0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x26, 0x2e, 0x77, 0x68, 0x69, 0x73, 0x70,
+	0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x69, 0x6e, 0x67, 0x52,
+	0x65, 0x63, 0x6f, 0x67, 0x6e, 0x69, 0x7a, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x28, 0x01, 0x30, 0x01, 0x42, 0x3e, 0x5a, 0x3c, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63,
+	0x6f, 0x6d, 0x2f, 0x67, 0x67, 0x65, 0x72, 0x67, 0x61, 0x6e, 0x6f, 0x76, 0x2f, 0x77, 0x68, 0x69,
+	0x73, 0x70, 0x65, 0x72, 0x2e, 0x63, 0x70, 0x70, 0x2f, 0x62, 0x69, 0x6e, 0x64, 0x69, 0x6e, 0x67,
+	0x73, 0x2f, 0x67, 0x6f, 0x2f, 0x70, 0x6b, 0x67, 0x2f, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2f,
+	0x67, 0x72, 0x70, 0x63, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_whisper_proto_rawDescOnce sync.Once
+	file_whisper_proto_rawDescData = file_whisper_proto_rawDesc
+)
+
+func file_whisper_proto_rawDescGZIP() []byte {
+	file_whisper_proto_rawDescOnce.Do(func() {
+		file_whisper_proto_rawDescData = protoimpl.X.CompressGZIP(file_whisper_proto_rawDescData)
+	})
+	return file_whisper_proto_rawDescData
+}
+
+var file_whisper_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_whisper_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_whisper_proto_goTypes = []interface{}{
+	(RecognitionConfig_AudioEncoding)(0),            // 0: whisper.v1.RecognitionConfig.AudioEncoding
+	(StreamingRecognizeResponse_SpeechEventType)(0), // 1: whisper.v1.StreamingRecognizeResponse.SpeechEventType
+	(*StreamingRecognizeRequest)(nil),               // 2: whisper.v1.StreamingRecognizeRequest
+	(*StreamingRecognitionConfig)(nil),              // 3: whisper.v1.StreamingRecognitionConfig
+	(*RecognitionConfig)(nil),                       // 4: whisper.v1.RecognitionConfig
+	(*StreamingRecognizeResponse)(nil),              // 5: whisper.v1.StreamingRecognizeResponse
+	(*StreamingRecognitionResult)(nil),              // 6: whisper.v1.StreamingRecognitionResult
+	(*SpeechRecognitionAlternative)(nil),            // 7: whisper.v1.SpeechRecognitionAlternative
+	(*WordInfo)(nil),                                // 8: whisper.v1.WordInfo
+	(*durationpb.Duration)(nil),                     // 9: google.protobuf.Duration
+}
+var file_whisper_proto_depIdxs = []int32{
+	3,  // 0: whisper.v1.StreamingRecognizeRequest.streaming_config:type_name -> whisper.v1.StreamingRecognitionConfig
+	4,  // 1: whisper.v1.StreamingRecognitionConfig.config:type_name -> whisper.v1.RecognitionConfig
+	0,  // 2: whisper.v1.RecognitionConfig.encoding:type_name -> whisper.v1.RecognitionConfig.AudioEncoding
+	6,  // 3: whisper.v1.StreamingRecognizeResponse.results:type_name -> whisper.v1.StreamingRecognitionResult
+	1,  // 4: whisper.v1.StreamingRecognizeResponse.speech_event_type:type_name -> whisper.v1.StreamingRecognizeResponse.SpeechEventType
+	9,  // 5: whisper.v1.StreamingRecognizeResponse.speech_event_time:type_name -> google.protobuf.Duration
+	7,  // 6: whisper.v1.StreamingRecognitionResult.alternatives:type_name -> whisper.v1.SpeechRecognitionAlternative
+	9,  // 7: whisper.v1.StreamingRecognitionResult.start_time:type_name -> google.protobuf.Duration
+	9,  // 8: whisper.v1.StreamingRecognitionResult.end_time:type_name -> google.protobuf.Duration
+	8,  // 9: whisper.v1.SpeechRecognitionAlternative.words:type_name -> whisper.v1.WordInfo
+	9,  // 10: whisper.v1.WordInfo.start_time:type_name -> google.protobuf.Duration
+	9,  // 11: whisper.v1.WordInfo.end_time:type_name -> google.protobuf.Duration
+	2,  // 12: whisper.v1.Speech.StreamingRecognize:input_type -> whisper.v1.StreamingRecognizeRequest
+	5,  // 13: whisper.v1.Speech.StreamingRecognize:output_type -> whisper.v1.StreamingRecognizeResponse
+	13, // [13:14] is the sub-list for method output_type
+	12, // [12:13] is the sub-list for method input_type
+	12, // [12:12] is the sub-list for extension type_name
+	12, // [12:12] is the sub-list for extension extendee
+	0,  // [0:12] is the sub-list for field type_name
+}
+
+func init() { file_whisper_proto_init() }
+func file_whisper_proto_init() {
+	if File_whisper_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_whisper_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StreamingRecognizeRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_whisper_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StreamingRecognitionConfig); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_whisper_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RecognitionConfig); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_whisper_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StreamingRecognizeResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_whisper_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StreamingRecognitionResult); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_whisper_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SpeechRecognitionAlternative); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_whisper_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WordInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_whisper_proto_msgTypes[0].OneofWrappers = []interface{}{
+		(*StreamingRecognizeRequest_StreamingConfig)(nil),
+		(*StreamingRecognizeRequest_AudioContent)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_whisper_proto_rawDesc,
+			NumEnums:      2,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_whisper_proto_goTypes,
+		DependencyIndexes: file_whisper_proto_depIdxs,
+		EnumInfos:         file_whisper_proto_enumTypes,
+		MessageInfos:      file_whisper_proto_msgTypes,
+	}.Build()
+	File_whisper_proto = out.File
+	file_whisper_proto_rawDesc = nil
+	file_whisper_proto_goTypes = nil
+	file_whisper_proto_depIdxs = nil
+}
@@ -0,0 +1,154 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: whisper.proto
+
+// The speech recognition service, modeled after the streaming API of the
+// Google Speech API.
+
+package grpc
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Speech_StreamingRecognize_FullMethodName = "/whisper.v1.Speech/StreamingRecognize"
+)
+
+// SpeechClient is the client API for Speech service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type SpeechClient interface {
+	// Perform bidirectional streaming speech recognition. The first request
+	// contains the configuration, and the following requests contain audio.
+	// Results are returned while audio is being sent, and the stream is closed
+	// by the server once the client has closed the send direction and the
+	// remaining audio has been processed.
+	StreamingRecognize(ctx context.Context, opts ...grpc.CallOption) (Speech_StreamingRecognizeClient, error)
+}
+
+type speechClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSpeechClient(cc grpc.ClientConnInterface) SpeechClient {
+	return &speechClient{cc}
+}
+
+func (c *speechClient) StreamingRecognize(ctx context.Context, opts ...grpc.CallOption) (Speech_StreamingRecognizeClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Speech_ServiceDesc.Streams[0], Speech_StreamingRecognize_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &speechStreamingRecognizeClient{stream}
+	return x, nil
+}
+
+type Speech_StreamingRecognizeClient interface {
+	Send(*StreamingRecognizeRequest) error
+	Recv() (*StreamingRecognizeResponse, error)
+	grpc.ClientStream
+}
+
+type speechStreamingRecognizeClient struct {
+	grpc.ClientStream
+}
+
+func (x *speechStreamingRecognizeClient) Send(m *StreamingRecognizeRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *speechStreamingRecognizeClient) Recv() (*StreamingRecognizeResponse, error) {
+	m := new(StreamingRecognizeResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SpeechServer is the server API for Speech service.
+// All implementations must embed UnimplementedSpeechServer
+// for forward compatibility
+type SpeechServer interface {
+	// Perform bidirectional streaming speech recognition. The first request
+	// contains the configuration, and the following requests contain audio.
+	// Results are returned while audio is being sent, and the stream is closed
+	// by the server once the client has closed the send direction and the
+	// remaining audio has been processed.
+	StreamingRecognize(Speech_StreamingRecognizeServer) error
+	mustEmbedUnimplementedSpeechServer()
+}
+
+// UnimplementedSpeechServer must be embedded to have forward compatible implementations.
+type UnimplementedSpeechServer struct {
+}
+
+func (UnimplementedSpeechServer) StreamingRecognize(Speech_StreamingRecognizeServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamingRecognize not implemented")
+}
+func (UnimplementedSpeechServer) mustEmbedUnimplementedSpeechServer() {}
+
+// UnsafeSpeechServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SpeechServer will
+// result in compilation errors.
+type UnsafeSpeechServer interface {
+	mustEmbedUnimplementedSpeechServer()
+}
+
+func RegisterSpeechServer(s grpc.ServiceRegistrar, srv SpeechServer) {
+	s.RegisterService(&Speech_ServiceDesc, srv)
+}
+
+func _Speech_StreamingRecognize_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(SpeechServer).StreamingRecognize(&speechStreamingRecognizeServer{stream})
+}
+
+type Speech_StreamingRecognizeServer interface {
+	Send(*StreamingRecognizeResponse) error
+	Recv() (*StreamingRecognizeRequest, error)
+	grpc.ServerStream
+}
+
+type speechStreamingRecognizeServer struct {
+	grpc.ServerStream
+}
+
+func (x *speechStreamingRecognizeServer) Send(m *StreamingRecognizeResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *speechStreamingRecognizeServer) Recv() (*StreamingRecognizeRequest, error) {
+	m := new(StreamingRecognizeRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Speech_ServiceDesc is the grpc.ServiceDesc for Speech service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Speech_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "whisper.v1.Speech",
+	HandlerType: (*SpeechServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamingRecognize",
+			Handler:       _Speech_StreamingRecognize_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "whisper.proto",
+}
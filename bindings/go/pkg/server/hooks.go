@@ -0,0 +1,44 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	// Packages
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Hooks are called during each transcription, for example to bill for the
+// audio, store the audio or transcripts, or route requests to a model. Any
+// of them can be nil. They are called from the goroutine of the request.
+type Hooks struct {
+	// Called once the form of the request is parsed, before the audio is
+	// decoded. The hook can change the model of the session. Returning an
+	// error rejects the request with 403 Forbidden, and the session does not
+	// start.
+	OnSessionStart func(*Session) error
+
+	// Called with the decoded audio, before it is transcribed. Uploaded audio
+	// is passed as one chunk.
+	OnAudioChunk func(*Session, []float32)
+
+	// Called with each segment of the transcript, after it is filtered
+	OnSegment func(*Session, whisper.Context, whisper.Segment)
+
+	// Called when a session which started ends, with the transcript, or the
+	// error returned to the client
+	OnSessionEnd func(*Session, *Transcript, error)
+}
+
+// Session is a transcription request
+type Session struct {
+	Id      string        // Identifier of the session, as returned in the X-Request-Id header
+	Request *http.Request // The request, with its form parsed
+	Model   string        // Name of the model, which OnSessionStart can change
+	Start   time.Time     // Time the request started
+	Audio   time.Duration // Duration of the audio, once it is decoded
+	Data    any           // Set by the hooks, to carry state from the start to the end of the session
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	gocontext "context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Records the status code of a response
+type statusWriter struct {
+	http.ResponseWriter
+	code int
+}
+
+// Keys for the request logger and the session identifier in a go context
+type loggerKey struct{}
+type sessionKey struct{}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	headerRequestId = "X-Request-Id"
+
+	// Longest session identifier accepted from a client
+	maxSessionId = 64
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// LogRequests returns a handler which assigns each request a session
+// identifier, and logs the request once it has completed. The identifier is
+// the X-Request-Id header of the request, when it is set, so that a client
+// can trace a request end to end, and is otherwise a random UUID. It is
+// returned in the X-Request-Id header. The handler can obtain a logger
+// carrying the identifier with RequestLogger, and the identifier with
+// SessionId.
+func LogRequests(logger *slog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		id := r.Header.Get(headerRequestId)
+		if !isSessionId(id) {
+			id = NewSessionId()
+		}
+		logger := logger.With("session", id)
+		w.Header().Set(headerRequestId, id)
+		sw := &statusWriter{ResponseWriter: w, code: http.StatusOK}
+		ctx := gocontext.WithValue(WithSessionId(r.Context(), id), loggerKey{}, logger)
+		next.ServeHTTP(sw, r.WithContext(ctx))
+		logger.Info("request", "method", r.Method, "path", r.URL.Path, "remote", r.RemoteAddr, "status", sw.code, "duration", time.Since(start))
+	})
+}
+
+// RequestLogger returns the logger for a request, or the default logger if
+// the request was not passed through LogRequests
+func RequestLogger(ctx gocontext.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// WithSessionId returns a go context carrying a session identifier
+func WithSessionId(ctx gocontext.Context, id string) gocontext.Context {
+	return gocontext.WithValue(ctx, sessionKey{}, id)
+}
+
+// SessionId returns the session identifier carried by a go context, or an
+// empty string when it has none
+func SessionId(ctx gocontext.Context) string {
+	id, _ := ctx.Value(sessionKey{}).(string)
+	return id
+}
+
+// NewSessionId returns a random (version 4) UUID, to identify a session
+func NewSessionId() string {
+	var uuid [16]byte
+	if _, err := rand.Read(uuid[:]); err != nil {
+		return "unknown"
+	}
+	uuid[6] = uuid[6]&0x0f | 0x40
+	uuid[8] = uuid[8]&0x3f | 0x80
+	str := hex.EncodeToString(uuid[:])
+	return str[:8] + "-" + str[8:12] + "-" + str[12:16] + "-" + str[16:20] + "-" + str[20:]
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.code = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Unwrap returns the underlying response writer, for http.ResponseController
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Return true when a session identifier sent by a client is safe to log
+// and return, with up to 64 letters, digits and the characters "-._:"
+func isSessionId(id string) bool {
+	if id == "" || len(id) > maxSessionId {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', strings.ContainsRune("-._:", c):
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,424 @@
+package server
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	// Packages
+	postprocess "github.com/ggerganov/whisper.cpp/bindings/go/pkg/postprocess"
+	resample "github.com/ggerganov/whisper.cpp/bindings/go/pkg/resample"
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	wav "github.com/go-audio/wav"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Config configures the transcription endpoints. Only the pools are
+// required.
+type Config struct {
+	// Pools of contexts, keyed by the model name requests select them by,
+	// and the name of the default model. The default can be empty when
+	// there is one pool. The pools are not closed by the server.
+	Pools map[string]whisper.Pool
+	Model string
+
+	// Sets the parameters of a context before each transcription, or nil to
+	// keep the parameters of the pool. The language, prompt and translation
+	// of the request are set after it.
+	Params func(whisper.Context) error
+
+	// Decodes uploaded audio to mono samples at the whisper sample rate, or
+	// nil to decode WAV files
+	Decode func(io.ReadSeeker) ([]float32, error)
+
+	// Transcribes the audio, or nil to process it in one pass. The segments
+	// are filtered after it, when Filter is not nil.
+	Process func(gocontext.Context, whisper.Context, []float32) ([]whisper.Segment, error)
+	Filter  postprocess.Filter
+
+	// Text tokens and words with a probability below the confidence, when
+	// not zero, are tagged as low confidence in the transcript
+	Confidence float32
+
+	// API tokens which requests must send, or none to accept all requests
+	Tokens []string
+
+	// Origins of the browser apps which can call the endpoints, or "*" for
+	// any origin
+	Origins []string
+
+	// Limits of each request, which are not applied when zero or nil
+	Admission      *Admission    // Concurrent transcriptions
+	MaxBytes       int64         // Bytes of the request body
+	MaxAudio       time.Duration // Duration of the uploaded audio
+	MaxSessionTime time.Duration // Time since the request started
+
+	// Called during each transcription
+	Hooks Hooks
+
+	// Logs the requests, or nil to use the default logger
+	Logger *slog.Logger
+}
+
+// Server serves the HTTP transcription endpoints
+type Server struct {
+	config Config
+	pools  map[string]whisper.Pool
+}
+
+// ReloadResponse is returned when a model is reloaded
+type ReloadResponse struct {
+	Model string `json:"model"`
+	Path  string `json:"path"`
+}
+
+// ResponseError is returned when a request fails
+type ResponseError struct {
+	Error string `json:"error"`
+	Limit string `json:"limit,omitempty"` // The limit exceeded: max-bytes, max-audio or max-session-time
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	maxUploadSize = 32 << 20 // Uploads larger than this are stored on disk while parsing
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// New returns a server for the configuration, or an error when there are no
+// pools, or the default model has no pool
+func New(config Config) (*Server, error) {
+	if len(config.Pools) == 0 {
+		return nil, fmt.Errorf("no models")
+	}
+	if config.Model == "" {
+		if len(config.Pools) > 1 {
+			return nil, fmt.Errorf("no default model")
+		}
+		for name := range config.Pools {
+			config.Model = name
+		}
+	}
+	if _, exists := config.Pools[config.Model]; !exists {
+		return nil, fmt.Errorf("unknown model: %q", config.Model)
+	}
+	if config.Decode == nil {
+		config.Decode = decodeWAV
+	}
+	if config.Process == nil {
+		config.Process = process
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+
+	// Return success
+	return &Server{config: config, pools: maps.Clone(config.Pools)}, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Handler returns the HTTP handler for all the endpoints, which are
+// /transcribe, /transcribe/{model} and /admin/reload-model. Requests are
+// logged, and when API tokens are configured, must be authenticated. When
+// origins are configured, browser apps from them can call the endpoints.
+func (server *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	transcribe := server.TranscribeHandler()
+	mux.Handle("/transcribe", transcribe)
+	mux.Handle("/transcribe/", transcribe)
+	mux.Handle("/admin/reload-model", server.ReloadHandler())
+	return LogRequests(server.config.Logger, AllowOrigins(server.config.Origins, Authenticate(server.config.Tokens, mux)))
+}
+
+// TranscribeHandler returns the handler which transcribes an uploaded audio
+// file, limited by the admission control. It is not authenticated, so that
+// it can be mounted behind the authentication of another service.
+//
+// The audio is sent as the "file" field of a multipart form. The model can
+// be selected by name with the path /transcribe/{model}, or with the "model"
+// field or query parameter, the language with the "language" field, the
+// initial prompt with the "prompt" field, and translation to English with
+// the "translate" field.
+func (server *Server) TranscribeHandler() http.Handler {
+	return LimitRequests(server.config.Admission, http.HandlerFunc(server.transcribe))
+}
+
+// ReloadHandler returns the handler which reloads a model, for new requests,
+// from the "path" field or query parameter, or from its current path when
+// not set. The model is selected by name with the "model" field or query
+// parameter. Requests in progress complete with the previous model. It is
+// not authenticated, so that it can be mounted behind the authentication of
+// another service.
+func (server *Server) ReloadHandler() http.Handler {
+	return http.HandlerFunc(server.reloadModel)
+}
+
+// Reload the model of a pool from a path, and log the result
+func (server *Server) Reload(model, path string) error {
+	pool, exists := server.pools[model]
+	if !exists {
+		return fmt.Errorf("unknown model: %q", model)
+	}
+	server.config.Logger.Info("reloading model", "model", model, "path", path)
+	if err := pool.Reload(path); err != nil {
+		server.config.Logger.Error("unable to reload model", "model", model, "path", path, "error", err)
+		return err
+	}
+	server.config.Logger.Info("reloaded model", "model", model, "path", path)
+
+	// Return success
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func (server *Server) transcribe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed: %s", r.Method))
+		return
+	}
+
+	// End the request once it has run for the maximum session time
+	ctx := r.Context()
+	if server.config.MaxSessionTime > 0 {
+		var cancel gocontext.CancelFunc
+		ctx, cancel = gocontext.WithTimeout(ctx, server.config.MaxSessionTime)
+		defer cancel()
+	}
+
+	// Parse the form, within the limit of bytes
+	t0 := time.Now()
+	if server.config.MaxBytes > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, server.config.MaxBytes)
+	}
+	var maxerr *http.MaxBytesError
+	if err := r.ParseMultipartForm(maxUploadSize); errors.As(err, &maxerr) {
+		writeLimit(w, http.StatusRequestEntityTooLarge, "max-bytes", fmt.Errorf("the request exceeded the limit of %d bytes", maxerr.Limit))
+		return
+	} else if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// Start the session, and report how it ends
+	session := &Session{Id: SessionId(r.Context()), Request: r, Model: requestModel(r), Start: t0}
+	if session.Id == "" {
+		session.Id = NewSessionId()
+	}
+	if session.Model == "" {
+		session.Model = server.config.Model
+	}
+	hooks := server.config.Hooks
+	if hooks.OnSessionStart != nil {
+		if err := hooks.OnSessionStart(session); err != nil {
+			writeError(w, http.StatusForbidden, err)
+			return
+		}
+	}
+	var transcript *Transcript
+	var failure error
+	if hooks.OnSessionEnd != nil {
+		defer func() { hooks.OnSessionEnd(session, transcript, failure) }()
+	}
+	fail := func(code int, limit string, err error) {
+		failure = err
+		writeLimit(w, code, limit, err)
+	}
+
+	// Decode the audio, within the limit of audio
+	fh, _, err := r.FormFile("file")
+	if err != nil {
+		fail(http.StatusBadRequest, "", err)
+		return
+	}
+	defer fh.Close()
+	data, err := server.config.Decode(fh)
+	if err != nil {
+		fail(http.StatusBadRequest, "", err)
+		return
+	}
+	session.Audio = time.Duration(len(data)) * time.Second / whisper.SampleRate
+	if server.config.MaxAudio > 0 && len(data) > int(server.config.MaxAudio.Seconds()*whisper.SampleRate) {
+		fail(http.StatusRequestEntityTooLarge, "max-audio", fmt.Errorf("the audio exceeded the limit of %v", server.config.MaxAudio))
+		return
+	}
+	if hooks.OnAudioChunk != nil {
+		hooks.OnAudioChunk(session, data)
+	}
+
+	// Obtain a context from the pool for the model
+	pool, exists := server.pools[session.Model]
+	if !exists {
+		fail(http.StatusNotFound, "", fmt.Errorf("unknown model: %q", session.Model))
+		return
+	}
+	context, err := pool.Get(ctx)
+	if err != nil {
+		fail(http.StatusServiceUnavailable, "", err)
+		return
+	}
+	defer pool.Put(context)
+	if server.config.Params != nil {
+		if err := server.config.Params(context); err != nil {
+			fail(http.StatusInternalServerError, "", err)
+			return
+		}
+	}
+	if lang := r.FormValue("language"); lang != "" {
+		if err := context.SetLanguage(lang); err != nil {
+			fail(http.StatusBadRequest, "", err)
+			return
+		}
+	}
+	if prompt := r.FormValue("prompt"); prompt != "" {
+		context.SetInitialPrompt(prompt)
+	}
+	if translate := r.FormValue("translate"); translate != "" {
+		if v, err := strconv.ParseBool(translate); err != nil {
+			fail(http.StatusBadRequest, "", err)
+			return
+		} else if v && !context.IsMultilingual() {
+			fail(http.StatusBadRequest, "", whisper.ErrModelNotMultilingual)
+			return
+		} else {
+			context.SetTranslate(v)
+		}
+	}
+
+	// Process the audio
+	t1 := time.Now()
+	segments, err := server.config.Process(ctx, context, data)
+	if errors.Is(err, gocontext.DeadlineExceeded) {
+		fail(http.StatusServiceUnavailable, "max-session-time", fmt.Errorf("the request exceeded the limit of %v", server.config.MaxSessionTime))
+		return
+	} else if err != nil {
+		fail(http.StatusInternalServerError, "", err)
+		return
+	}
+	t2 := time.Now()
+	if server.config.Filter != nil {
+		segments = server.config.Filter(segments)
+	}
+	if hooks.OnSegment != nil {
+		for _, segment := range segments {
+			hooks.OnSegment(session, context, segment)
+		}
+	}
+
+	// Return the transcript
+	timings := context.Timings()
+	RequestLogger(r.Context()).Info("transcribed", "model", session.Model, "audio", session.Audio, "decode", t1.Sub(t0), "process", t2.Sub(t1), "segments", len(segments),
+		"mel", timings.Mel, "encode", timings.Encode, "sample", timings.Sample, "decoder", timings.Decode, "rtf", math.Round(timings.RTF*1000)/1000)
+	transcript = NewTranscript(context, segments, len(data), t1.Sub(t0), t2.Sub(t1), server.config.Confidence)
+	transcript.Session = session.Id
+	transcript.Model = session.Model
+	writeJSON(w, http.StatusOK, transcript)
+}
+
+func (server *Server) reloadModel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed: %s", r.Method))
+		return
+	}
+	model := r.FormValue("model")
+	if model == "" {
+		model = server.config.Model
+	}
+	pool, exists := server.pools[model]
+	if !exists {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown model: %q", model))
+		return
+	}
+	path := r.FormValue("path")
+	if path == "" {
+		path = pool.Path()
+	}
+	if err := server.Reload(model, path); errors.Is(err, fs.ErrNotExist) {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, ReloadResponse{Model: model, Path: path})
+}
+
+// Return the model selected by the path /transcribe/{model}, or by the
+// "model" field or query parameter
+func requestModel(r *http.Request) string {
+	if model := strings.TrimPrefix(r.URL.Path, "/transcribe/"); model != r.URL.Path {
+		return model
+	}
+	return r.FormValue("model")
+}
+
+// Process audio in one pass, and return the segments. No segment callback
+// is passed, as it would put the context into single segment mode.
+func process(ctx gocontext.Context, context whisper.Context, data []float32) ([]whisper.Segment, error) {
+	if err := context.ProcessContext(ctx, data, nil, nil); err != nil {
+		return nil, err
+	}
+	return context.Segments(), nil
+}
+
+// Decode a WAV file, mixing the channels and resampling to the whisper
+// sample rate
+func decodeWAV(r io.ReadSeeker) ([]float32, error) {
+	dec := wav.NewDecoder(r)
+	buf, err := dec.FullPCMBuffer()
+	if err != nil {
+		return nil, err
+	} else if dec.NumChans < 1 {
+		return nil, fmt.Errorf("invalid number of channels: %d", dec.NumChans)
+	}
+	data := buf.AsFloat32Buffer().Data
+	if channels := int(dec.NumChans); channels > 1 {
+		mono := make([]float32, len(data)/channels)
+		for i := range mono {
+			for ch := 0; ch < channels; ch++ {
+				mono[i] += data[i*channels+ch]
+			}
+			mono[i] /= float32(channels)
+		}
+		data = mono
+	}
+	if rate := int(dec.SampleRate); rate != whisper.SampleRate {
+		data = resample.Linear(data, rate, whisper.SampleRate)
+	}
+	return data, nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, ResponseError{Error: err.Error()})
+}
+
+// Write the error for a request which exceeded a limit
+func writeLimit(w http.ResponseWriter, code int, limit string, err error) {
+	writeJSON(w, code, ResponseError{Error: err.Error(), Limit: limit})
+}
@@ -0,0 +1,349 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	// Packages
+	server "github.com/ggerganov/whisper.cpp/bindings/go/pkg/server"
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	assert "github.com/stretchr/testify/assert"
+)
+
+const (
+	ModelPath  = "../../models/ggml-tiny.bin"
+	SamplePath = "../../samples/jfk.wav"
+)
+
+// Return a multipart request uploading the file as the "file" field
+func newUpload(t *testing.T, url, path string) *http.Request {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := new(bytes.Buffer)
+	form := multipart.NewWriter(body)
+	w, err := form.CreateFormFile("file", "audio.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(data)
+	form.Close()
+	r := httptest.NewRequest(http.MethodPost, url, body)
+	r.Header.Set("Content-Type", form.FormDataContentType())
+	return r
+}
+
+func Test_Server_000(t *testing.T) {
+	assert := assert.New(t)
+
+	// A server needs a pool for the default model
+	_, err := server.New(server.Config{})
+	assert.Error(err)
+	_, err = server.New(server.Config{Pools: map[string]whisper.Pool{"tiny": nil, "base": nil}})
+	assert.Error(err)
+	_, err = server.New(server.Config{Pools: map[string]whisper.Pool{"tiny": nil}, Model: "base"})
+	assert.Error(err)
+	api, err := server.New(server.Config{Pools: map[string]whisper.Pool{"tiny": nil}, Tokens: []string{"secret"}})
+	assert.NoError(err)
+
+	// Requests without a token are rejected
+	w := httptest.NewRecorder()
+	api.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/transcribe", nil))
+	assert.Equal(http.StatusUnauthorized, w.Code)
+	assert.NotEmpty(w.Header().Get("X-Request-Id"))
+
+	// Other methods are not allowed
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/transcribe", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	api.Handler().ServeHTTP(w, r)
+	assert.Equal(http.StatusMethodNotAllowed, w.Code)
+
+	// The handlers are not authenticated
+	w = httptest.NewRecorder()
+	api.ReloadHandler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/?model=base", nil))
+	assert.Equal(http.StatusNotFound, w.Code)
+	var response server.ResponseError
+	assert.NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Contains(response.Error, "base")
+}
+
+func Test_Server_001(t *testing.T) {
+	assert := assert.New(t)
+
+	// Sessions are not limited without an admission control
+	var admission *server.Admission
+	assert.Nil(server.NewAdmission(0, 0, 0))
+	assert.True(admission.TryAcquire())
+	assert.NoError(admission.Acquire(context.Background()))
+	admission.Release()
+
+	// A session waits in the queue for one to end, and is rejected when the
+	// queue is full or the wait times out
+	admission = server.NewAdmission(1, 1, 50*time.Millisecond)
+	assert.True(admission.TryAcquire())
+	assert.False(admission.TryAcquire())
+	assert.ErrorIs(admission.Acquire(context.Background()), server.ErrTooManySessions)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		admission.Release()
+	}()
+	assert.NoError(admission.Acquire(context.Background()))
+
+	// Requests which are not admitted are rejected
+	handler := server.LimitRequests(admission, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+	assert.Equal(http.StatusTooManyRequests, w.Code)
+	assert.Equal("1", w.Header().Get("Retry-After"))
+	admission.Release()
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+	assert.Equal(http.StatusOK, w.Code)
+
+	// Tokens are compared exactly
+	assert.True(server.IsAuthorized([]string{"a", "secret"}, "secret"))
+	assert.False(server.IsAuthorized([]string{"secret"}, "secre"))
+	assert.False(server.IsAuthorized([]string{""}, ""))
+}
+
+func Test_Server_002(t *testing.T) {
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+	if _, err := os.Stat(SamplePath); os.IsNotExist(err) {
+		t.Skip("Skipping test, sample not found:", SamplePath)
+	}
+	assert := assert.New(t)
+	pool, err := whisper.NewPool(ModelPath, 1, whisper.PoolWait)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer pool.Close()
+
+	// Mount the transcription endpoint inside another service, with the
+	// model selected by the path
+	var processed bool
+	api, err := server.New(server.Config{
+		Pools: map[string]whisper.Pool{"tiny": pool},
+		Process: func(ctx context.Context, context whisper.Context, data []float32) ([]whisper.Segment, error) {
+			processed = true
+			return []whisper.Segment{{Text: "hello", End: time.Second}}, nil
+		},
+	})
+	assert.NoError(err)
+	mux := http.NewServeMux()
+	mux.Handle("/api/", http.StripPrefix("/api", api.TranscribeHandler()))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, newUpload(t, "/api/transcribe/tiny", SamplePath))
+	assert.Equal(http.StatusOK, w.Code)
+	assert.True(processed)
+	var transcript server.Transcript
+	assert.NoError(json.Unmarshal(w.Body.Bytes(), &transcript))
+	assert.Equal("tiny", transcript.Model)
+	assert.NotEmpty(transcript.Session)
+	assert.InDelta(11000, transcript.Timings.Audio, 100)
+	if assert.Len(transcript.Segments, 1) {
+		assert.Equal("hello", transcript.Segments[0].Text)
+		assert.Equal(int64(1000), transcript.Segments[0].End)
+	}
+
+	// An unknown model is not found, and audio which is not WAV is rejected
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, newUpload(t, "/api/transcribe/base", SamplePath))
+	assert.Equal(http.StatusNotFound, w.Code)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, newUpload(t, "/api/transcribe", "server_test.go"))
+	assert.Equal(http.StatusBadRequest, w.Code)
+
+	// Audio over the limit is rejected
+	api, err = server.New(server.Config{Pools: map[string]whisper.Pool{"tiny": pool}, MaxAudio: 5 * time.Second})
+	assert.NoError(err)
+	w = httptest.NewRecorder()
+	api.Handler().ServeHTTP(w, newUpload(t, "/transcribe", SamplePath))
+	assert.Equal(http.StatusRequestEntityTooLarge, w.Code)
+	var response server.ResponseError
+	assert.NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal("max-audio", response.Limit)
+
+	// The audio is processed in one pass by default
+	api, err = server.New(server.Config{Pools: map[string]whisper.Pool{"tiny": pool}})
+	assert.NoError(err)
+	w = httptest.NewRecorder()
+	api.Handler().ServeHTTP(w, newUpload(t, "/transcribe?model=tiny", SamplePath))
+	assert.Equal(http.StatusOK, w.Code)
+}
+
+func Test_Server_003(t *testing.T) {
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+	if _, err := os.Stat(SamplePath); os.IsNotExist(err) {
+		t.Skip("Skipping test, sample not found:", SamplePath)
+	}
+	assert := assert.New(t)
+	pool, err := whisper.NewPool(ModelPath, 1, whisper.PoolWait)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer pool.Close()
+
+	// Route requests for an alias to the model, reject those without a
+	// customer, and carry the customer to the end of the session
+	var samples, segments int
+	var ended []*server.Session
+	var transcripts []*server.Transcript
+	var errs []error
+	api, err := server.New(server.Config{
+		Pools: map[string]whisper.Pool{"tiny": pool},
+		Process: func(ctx context.Context, context whisper.Context, data []float32) ([]whisper.Segment, error) {
+			return []whisper.Segment{{Text: "hello"}, {Text: "world"}}, nil
+		},
+		Hooks: server.Hooks{
+			OnSessionStart: func(session *server.Session) error {
+				if session.Request.FormValue("customer") == "" {
+					return errors.New("no customer")
+				}
+				if session.Model == "fast" {
+					session.Model = "tiny"
+				}
+				session.Data = session.Request.FormValue("customer")
+				return nil
+			},
+			OnAudioChunk: func(session *server.Session, data []float32) {
+				samples += len(data)
+			},
+			OnSegment: func(session *server.Session, context whisper.Context, segment whisper.Segment) {
+				segments++
+			},
+			OnSessionEnd: func(session *server.Session, transcript *server.Transcript, err error) {
+				ended = append(ended, session)
+				transcripts = append(transcripts, transcript)
+				errs = append(errs, err)
+			},
+		},
+	})
+	assert.NoError(err)
+	w := httptest.NewRecorder()
+	api.Handler().ServeHTTP(w, newUpload(t, "/transcribe/fast", SamplePath))
+	assert.Equal(http.StatusForbidden, w.Code)
+	assert.Empty(ended)
+
+	w = httptest.NewRecorder()
+	api.Handler().ServeHTTP(w, newUpload(t, "/transcribe/fast?customer=acme", SamplePath))
+	assert.Equal(http.StatusOK, w.Code)
+	assert.InDelta(11*whisper.SampleRate, samples, whisper.SampleRate/10)
+	assert.Equal(2, segments)
+	if assert.Len(ended, 1) {
+		assert.Equal(w.Header().Get("X-Request-Id"), ended[0].Id)
+		assert.Equal("tiny", ended[0].Model)
+		assert.Equal("acme", ended[0].Data)
+		assert.InDelta(11*time.Second, ended[0].Audio, float64(100*time.Millisecond))
+		assert.Equal("tiny", transcripts[0].Model)
+		assert.NoError(errs[0])
+	}
+
+	// A session which fails ends with the error
+	w = httptest.NewRecorder()
+	api.Handler().ServeHTTP(w, newUpload(t, "/transcribe?customer=acme", "server_test.go"))
+	assert.Equal(http.StatusBadRequest, w.Code)
+	if assert.Len(ended, 2) {
+		assert.Nil(transcripts[1])
+		assert.Error(errs[1])
+	}
+}
+
+func Test_Server_004(t *testing.T) {
+	assert := assert.New(t)
+	api, err := server.New(server.Config{
+		Pools:   map[string]whisper.Pool{"tiny": nil},
+		Tokens:  []string{"secret"},
+		Origins: []string{"https://app.example.com"},
+	})
+	assert.NoError(err)
+
+	// A preflight request from an allowed origin is answered without a token
+	r := httptest.NewRequest(http.MethodOptions, "/transcribe", nil)
+	r.Header.Set("Origin", "https://app.example.com")
+	r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	w := httptest.NewRecorder()
+	api.Handler().ServeHTTP(w, r)
+	assert.Equal(http.StatusNoContent, w.Code)
+	assert.Equal("https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(w.Header().Get("Access-Control-Allow-Methods"), http.MethodPost)
+	assert.Contains(w.Header().Get("Access-Control-Allow-Headers"), "Authorization")
+
+	// A preflight request from another origin is rejected
+	r.Header.Set("Origin", "https://evil.example.com")
+	w = httptest.NewRecorder()
+	api.Handler().ServeHTTP(w, r)
+	assert.Equal(http.StatusForbidden, w.Code)
+	assert.Empty(w.Header().Get("Access-Control-Allow-Origin"))
+
+	// The allowed origin can read the responses, including errors
+	r = httptest.NewRequest(http.MethodPost, "/transcribe", nil)
+	r.Header.Set("Origin", "https://app.example.com")
+	w = httptest.NewRecorder()
+	api.Handler().ServeHTTP(w, r)
+	assert.Equal(http.StatusUnauthorized, w.Code)
+	assert.Equal("https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(w.Header().Get("Access-Control-Expose-Headers"), "X-Request-Id")
+
+	// Without origins, no CORS headers are sent
+	handler := server.AllowOrigins(nil, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Empty(w.Header().Get("Access-Control-Allow-Origin"))
+
+	// Any origin is allowed with "*"
+	handler = server.AllowOrigins([]string{"*"}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	r.Header.Set("Origin", "https://other.example.com")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal("https://other.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func Test_Server_005(t *testing.T) {
+	assert := assert.New(t)
+	var session string
+	handler := server.LogRequests(slog.New(slog.NewTextHandler(io.Discard, nil)), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session = server.SessionId(r.Context())
+	}))
+
+	// A random UUID is assigned to each request
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+	assert.Regexp(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, session)
+	assert.Equal(session, w.Header().Get("X-Request-Id"))
+	assert.NotEqual(session, server.NewSessionId())
+
+	// The identifier of the client is used when it is valid
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.Header.Set("X-Request-Id", "call-42")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal("call-42", session)
+	assert.Equal("call-42", w.Header().Get("X-Request-Id"))
+
+	// and is replaced otherwise
+	r.Header.Set("X-Request-Id", "call 42\n")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Len(session, 36)
+	assert.Equal(session, w.Header().Get("X-Request-Id"))
+
+	// A go context without an identifier has an empty one
+	assert.Empty(server.SessionId(context.Background()))
+	assert.Equal("id", server.SessionId(server.WithSessionId(context.Background(), "id")))
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"time"
+
+	// Packages
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Transcript is the machine-readable result of a transcription, with all
+// times in milliseconds
+type Transcript struct {
+	Session  string              `json:"session,omitempty"`
+	Model    string              `json:"model,omitempty"`
+	Language string              `json:"language"`
+	Segments []TranscriptSegment `json:"segments"`
+	Timings  TranscriptTimings   `json:"timings"`
+}
+
+// TranscriptSegment is a transcribed segment
+type TranscriptSegment struct {
+	Num      int               `json:"num"`
+	Start    int64             `json:"start"`
+	End      int64             `json:"end"`
+	Language string            `json:"language,omitempty"`
+	Text     string            `json:"text"`
+	Tokens   []TranscriptToken `json:"tokens,omitempty"`
+	Words    []TranscriptWord  `json:"words,omitempty"`
+
+	SpeakerTurnNext bool                    `json:"speaker_turn_next,omitempty"`
+	Alternatives    []TranscriptAlternative `json:"alternatives,omitempty"`
+}
+
+// TranscriptToken is a transcribed token, with its probability
+type TranscriptToken struct {
+	Id    int     `json:"id"`
+	Text  string  `json:"text"`
+	P     float32 `json:"p"`
+	Start int64   `json:"start"`
+	End   int64   `json:"end"`
+
+	LowConfidence bool `json:"low_confidence,omitempty"`
+}
+
+// TranscriptWord is a transcribed word, when word timestamps are enabled
+type TranscriptWord struct {
+	Text  string  `json:"text"`
+	P     float32 `json:"p"`
+	Start int64   `json:"start"`
+	End   int64   `json:"end"`
+
+	LowConfidence bool `json:"low_confidence,omitempty"`
+}
+
+// TranscriptAlternative is a hypothesis of the text of a segment, with the
+// mean probability of its tokens, when alternatives are decoded
+type TranscriptAlternative struct {
+	Text       string  `json:"text"`
+	Confidence float32 `json:"confidence"`
+}
+
+// TranscriptTimings are the durations of the audio and of each
+// processing stage
+type TranscriptTimings struct {
+	Audio   int64 `json:"audio"`
+	Decode  int64 `json:"decode"`
+	Process int64 `json:"process"`
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// NewTranscript returns the transcript for segments processed by the context.
+// The audio duration is derived from the number of samples. When confidence
+// is not zero, text tokens and words with a probability below it are tagged
+// as low confidence.
+func NewTranscript(context whisper.Context, segments []whisper.Segment, samples int, decode, process time.Duration, confidence float32) *Transcript {
+	transcript := &Transcript{
+		Language: context.DetectedLanguage(),
+		Segments: make([]TranscriptSegment, 0, len(segments)),
+		Timings: TranscriptTimings{
+			Audio:   int64(samples) * 1000 / whisper.SampleRate,
+			Decode:  decode.Milliseconds(),
+			Process: process.Milliseconds(),
+		},
+	}
+	for _, segment := range segments {
+		transcript.Segments = append(transcript.Segments, toTranscriptSegment(context, segment, confidence))
+	}
+	return transcript
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func toTranscriptSegment(context whisper.Context, segment whisper.Segment, confidence float32) TranscriptSegment {
+	result := TranscriptSegment{
+		Num:      segment.Num,
+		Start:    segment.Start.Milliseconds(),
+		End:      segment.End.Milliseconds(),
+		Language: segment.Language,
+		Text:     segment.Text,
+
+		SpeakerTurnNext: segment.SpeakerTurnNext,
+	}
+	for _, token := range segment.Tokens {
+		result.Tokens = append(result.Tokens, TranscriptToken{
+			Id:    token.Id,
+			Text:  token.Text,
+			P:     token.P,
+			Start: token.Start.Milliseconds(),
+			End:   token.End.Milliseconds(),
+
+			LowConfidence: context.IsText(token) && token.P < confidence,
+		})
+	}
+	for _, word := range segment.Words {
+		result.Words = append(result.Words, TranscriptWord{
+			Text:  word.Text,
+			P:     word.P,
+			Start: word.Start.Milliseconds(),
+			End:   word.End.Milliseconds(),
+
+			LowConfidence: word.P < confidence,
+		})
+	}
+	for _, alternative := range segment.Alternatives {
+		result.Alternatives = append(result.Alternatives, TranscriptAlternative{
+			Text:       alternative.Text,
+			Confidence: alternative.Confidence,
+		})
+	}
+	return result
+}
@@ -0,0 +1,13 @@
+/*
+Package sink publishes transcribed segments to a message bus in real time, so
+that other services can consume them as they are decoded. A sink is opened
+from a URL, whose scheme selects the bus:
+
+	kafka://broker1:9092,broker2:9092?topic=transcripts
+	nats://localhost:4222?subject=transcripts
+	redis://localhost:6379/0?stream=transcripts
+
+Each segment is published as a JSON Message. Kafka messages are keyed by the
+session, so that the segments of a session are kept in order on a partition.
+*/
+package sink
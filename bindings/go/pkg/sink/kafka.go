@@ -0,0 +1,65 @@
+package sink
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	// Package imports
+	kafka "github.com/segmentio/kafka-go"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type kafkaSink struct {
+	writer *kafka.Writer
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// Messages are batched for at most this duration before they are written
+	kafkaBatchTimeout = 10 * time.Millisecond
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Return a sink which writes to the topic on the brokers of the URL
+func newKafka(u *url.URL) (*kafkaSink, error) {
+	topic, err := param(u, "topic")
+	if err != nil {
+		return nil, err
+	}
+	return &kafkaSink{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(strings.Split(u.Host, ",")...),
+			Topic:        topic,
+			Balancer:     &kafka.Hash{},
+			BatchTimeout: kafkaBatchTimeout,
+		},
+	}, nil
+}
+
+func (sink *kafkaSink) Close() error {
+	return sink.writer.Close()
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Publish a message, keyed by its session
+func (sink *kafkaSink) Publish(ctx gocontext.Context, message *Message) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return sink.writer.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(message.Session),
+		Value: data,
+	})
+}
@@ -0,0 +1,57 @@
+package sink
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"net/url"
+
+	// Package imports
+	nats "github.com/nats-io/nats.go"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type natsSink struct {
+	conn    *nats.Conn
+	subject string
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Return a sink which publishes on the subject of the URL
+func newNATS(u *url.URL) (*natsSink, error) {
+	subject, err := param(u, "subject")
+	if err != nil {
+		return nil, err
+	}
+	server := *u
+	server.RawQuery = ""
+	conn, err := nats.Connect(server.String())
+	if err != nil {
+		return nil, err
+	}
+	return &natsSink{
+		conn:    conn,
+		subject: subject,
+	}, nil
+}
+
+// Close publishes any buffered messages, then closes the connection
+func (sink *natsSink) Close() error {
+	return sink.conn.Drain()
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Publish a message. Messages are buffered by the connection, so the go
+// context is not used.
+func (sink *natsSink) Publish(_ gocontext.Context, message *Message) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return sink.conn.Publish(sink.subject, data)
+}
@@ -0,0 +1,65 @@
+package sink
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"net/url"
+
+	// Package imports
+	redis "github.com/redis/go-redis/v9"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type redisSink struct {
+	client *redis.Client
+	stream string
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Return a sink which adds entries to the stream of the URL. The other
+// query parameters are passed to the client.
+func newRedis(u *url.URL) (*redisSink, error) {
+	stream, err := param(u, "stream")
+	if err != nil {
+		return nil, err
+	}
+	server := *u
+	query := server.Query()
+	query.Del("stream")
+	server.RawQuery = query.Encode()
+	opts, err := redis.ParseURL(server.String())
+	if err != nil {
+		return nil, err
+	}
+	return &redisSink{
+		client: redis.NewClient(opts),
+		stream: stream,
+	}, nil
+}
+
+func (sink *redisSink) Close() error {
+	return sink.client.Close()
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Publish a message as a stream entry, with the session and the encoded
+// message as fields
+func (sink *redisSink) Publish(ctx gocontext.Context, message *Message) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return sink.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: sink.stream,
+		Values: map[string]any{
+			"session": message.Session,
+			"message": data,
+		},
+	}).Err()
+}
@@ -0,0 +1,106 @@
+package sink
+
+import (
+	gocontext "context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+
+	// Package imports
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Sink publishes messages to a message bus
+type Sink interface {
+	io.Closer
+
+	// Publish a message. Messages are published in the order they are
+	// given.
+	Publish(gocontext.Context, *Message) error
+}
+
+// Message is a transcribed segment of a session, with all times in
+// milliseconds from the start of the session
+type Message struct {
+	Source   string `json:"source"`  // The server the session was received on
+	Session  string `json:"session"` // Identifier of the session
+	Language string `json:"language,omitempty"`
+	Num      int    `json:"num"`
+	Start    int64  `json:"start"`
+	End      int64  `json:"end"`
+	Text     string `json:"text"`
+
+	SpeakerTurnNext bool `json:"speaker_turn_next,omitempty"`
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+var (
+	ErrUnsupportedScheme = errors.New("unsupported sink scheme")
+	ErrMissingParameter  = errors.New("missing sink parameter")
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Open returns the sink for a URL, with the scheme kafka, nats, redis or
+// rediss. The topic, subject or stream is set with a query parameter.
+func Open(str string) (Sink, error) {
+	u, err := url.Parse(str)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "kafka":
+		return newKafka(u)
+	case "nats":
+		return newNATS(u)
+	case "redis", "rediss":
+		return newRedis(u)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedScheme, u.Scheme)
+	}
+}
+
+// NewMessage returns the message for a segment of a session
+func NewMessage(source, session, language string, segment whisper.Segment) *Message {
+	return &Message{
+		Source:          source,
+		Session:         session,
+		Language:        language,
+		Num:             segment.Num,
+		Start:           segment.Start.Milliseconds(),
+		End:             segment.End.Milliseconds(),
+		Text:            segment.Text,
+		SpeakerTurnNext: segment.SpeakerTurnNext,
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (message *Message) String() string {
+	str := "<sink.message"
+	str += fmt.Sprintf(" source=%q", message.Source)
+	str += fmt.Sprintf(" session=%q", message.Session)
+	str += fmt.Sprintf(" num=%d", message.Num)
+	str += fmt.Sprintf(" text=%q", message.Text)
+	return str + ">"
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Return a query parameter of the URL, which is required
+func param(u *url.URL, key string) (string, error) {
+	value := u.Query().Get(key)
+	if value == "" {
+		return "", fmt.Errorf("%w: %q", ErrMissingParameter, key)
+	}
+	return value, nil
+}
@@ -0,0 +1,47 @@
+package sink_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	// Packages
+	sink "github.com/ggerganov/whisper.cpp/bindings/go/pkg/sink"
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func Test_Sink_000(t *testing.T) {
+	assert := assert.New(t)
+
+	// Unsupported schemes and missing parameters are rejected
+	_, err := sink.Open("amqp://localhost/transcripts")
+	assert.ErrorIs(err, sink.ErrUnsupportedScheme)
+	_, err = sink.Open("kafka://localhost:9092")
+	assert.ErrorIs(err, sink.ErrMissingParameter)
+	_, err = sink.Open("nats://localhost:4222")
+	assert.ErrorIs(err, sink.ErrMissingParameter)
+	_, err = sink.Open("redis://localhost:6379/0")
+	assert.ErrorIs(err, sink.ErrMissingParameter)
+
+	// Kafka and Redis connect when the first message is published
+	kafka, err := sink.Open("kafka://broker1:9092,broker2:9092?topic=transcripts")
+	assert.NoError(err)
+	assert.NoError(kafka.Close())
+	redis, err := sink.Open("redis://localhost:6379/0?stream=transcripts&dial_timeout=1s")
+	assert.NoError(err)
+	assert.NoError(redis.Close())
+}
+
+func Test_Sink_001(t *testing.T) {
+	assert := assert.New(t)
+	message := sink.NewMessage("rtp", "rtp-0x00000001", "en", whisper.Segment{
+		Num:   2,
+		Start: 1500 * time.Millisecond,
+		End:   3 * time.Second,
+		Text:  "Hello",
+	})
+	data, err := json.Marshal(message)
+	assert.NoError(err)
+	assert.JSONEq(`{"source":"rtp","session":"rtp-0x00000001","language":"en","num":2,"start":1500,"end":3000,"text":"Hello"}`, string(data))
+}
@@ -0,0 +1,5 @@
+/*
+Package vad provides voice activity detection, used to gate audio so that only
+speech regions are passed to the speech-to-text context.
+*/
+package vad
@@ -0,0 +1,197 @@
+package vad
+
+import (
+	"math"
+	"time"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Detector is an energy-based voice activity detector. Audio is analysed in
+// fixed-size frames and a speech region is opened when the RMS energy of a
+// frame exceeds the threshold. The region is closed once the energy has
+// stayed below the threshold for the hangover time.
+type Detector struct {
+	rate      int     // Sample rate, samples per second
+	frame     int     // Frame size in samples
+	threshold float32 // RMS energy threshold for speech
+	hangover  int     // Samples of silence before a speech region is closed
+
+	speech  bool      // True when inside a speech region
+	silence int       // Samples of silence in the current speech region
+	pos     int64     // Number of samples analysed
+	buf     []float32 // Partial frame carried over between calls
+}
+
+// EventType is the type of a voice activity event
+type EventType int
+
+// Event is emitted when a speech region opens or closes
+type Event struct {
+	Type EventType
+
+	// Offset of the event from the start of the stream, in samples
+	Offset int64
+
+	// Offset of the event from the start of the stream
+	Time time.Duration
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	SpeechStart EventType = iota + 1 // Start of a speech region
+	SpeechEnd                        // End of a speech region
+)
+
+const (
+	DefaultThreshold = 0.01                   // Default RMS energy threshold
+	DefaultHangover  = 500 * time.Millisecond // Default hangover time
+	FrameDuration    = 30 * time.Millisecond  // Duration of an analysis frame
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// New returns a detector for mono audio at the given sample rate. A zero
+// threshold or hangover selects the default value.
+func New(rate int, threshold float32, hangover time.Duration) *Detector {
+	detector := new(Detector)
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+	if hangover <= 0 {
+		hangover = DefaultHangover
+	}
+	detector.rate = rate
+	detector.frame = int(int64(rate) * int64(FrameDuration) / int64(time.Second))
+	detector.threshold = threshold
+	detector.hangover = int(int64(rate) * int64(hangover) / int64(time.Second))
+	if detector.frame < 1 {
+		detector.frame = 1
+	}
+
+	// Return success
+	return detector
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Threshold returns the RMS energy threshold
+func (detector *Detector) Threshold() float32 {
+	return detector.threshold
+}
+
+// Hangover returns the hangover time
+func (detector *Detector) Hangover() time.Duration {
+	return detector.toDuration(int64(detector.hangover))
+}
+
+// IsSpeech returns true when the detector is inside a speech region
+func (detector *Detector) IsSpeech() bool {
+	return detector.speech
+}
+
+// Process analyses the samples and returns the events which occurred, in
+// order. Samples which do not fill a complete frame are retained and
+// analysed with the next call.
+func (detector *Detector) Process(samples []float32) []Event {
+	var result []Event
+
+	data := samples
+	if len(detector.buf) > 0 {
+		data = append(detector.buf, samples...)
+	}
+	for len(data) >= detector.frame {
+		if evt, ok := detector.next(data[:detector.frame]); ok {
+			result = append(result, evt)
+		}
+		data = data[detector.frame:]
+	}
+	detector.buf = append(detector.buf[:0], data...)
+
+	// Return events
+	return result
+}
+
+// Skip advances the detector over samples which are not analysed, such as
+// audio which is dropped, together with any retained samples. The detector
+// returns to the non-speech state without an event.
+func (detector *Detector) Skip(n int64) {
+	detector.speech = false
+	detector.silence = 0
+	detector.pos += int64(len(detector.buf)) + n
+	detector.buf = detector.buf[:0]
+}
+
+// Reset returns the detector to the initial, non-speech state
+func (detector *Detector) Reset() {
+	detector.speech = false
+	detector.silence = 0
+	detector.pos = 0
+	detector.buf = detector.buf[:0]
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (t EventType) String() string {
+	switch t {
+	case SpeechStart:
+		return "SpeechStart"
+	case SpeechEnd:
+		return "SpeechEnd"
+	default:
+		return "None"
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Analyse a single frame and return an event if the state changed
+func (detector *Detector) next(frame []float32) (Event, bool) {
+	start := detector.pos
+	detector.pos += int64(len(frame))
+
+	active := rms(frame) >= detector.threshold
+	switch {
+	case active && !detector.speech:
+		detector.speech = true
+		detector.silence = 0
+		return detector.event(SpeechStart, start), true
+	case active:
+		detector.silence = 0
+	case detector.speech:
+		detector.silence += len(frame)
+		if detector.silence >= detector.hangover {
+			detector.speech = false
+			detector.silence = 0
+			return detector.event(SpeechEnd, detector.pos), true
+		}
+	}
+	return Event{}, false
+}
+
+func (detector *Detector) event(t EventType, offset int64) Event {
+	return Event{
+		Type:   t,
+		Offset: offset,
+		Time:   detector.toDuration(offset),
+	}
+}
+
+func (detector *Detector) toDuration(n int64) time.Duration {
+	return time.Duration(n) * time.Second / time.Duration(detector.rate)
+}
+
+func rms(frame []float32) float32 {
+	var sum float64
+	for _, v := range frame {
+		sum += float64(v) * float64(v)
+	}
+	return float32(math.Sqrt(sum / float64(len(frame))))
+}
@@ -0,0 +1,92 @@
+package vad_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	// Packages
+	vad "github.com/ggerganov/whisper.cpp/bindings/go/pkg/vad"
+	assert "github.com/stretchr/testify/assert"
+)
+
+const (
+	SampleRate = 16000
+)
+
+// Return a sine wave of the given duration and amplitude
+func tone(d time.Duration, amplitude float64) []float32 {
+	result := make([]float32, int(d.Seconds()*SampleRate))
+	for i := range result {
+		result[i] = float32(amplitude * math.Sin(2*math.Pi*440*float64(i)/SampleRate))
+	}
+	return result
+}
+
+func Test_VAD_000(t *testing.T) {
+	assert := assert.New(t)
+	detector := vad.New(SampleRate, 0, 0)
+	assert.NotNil(detector)
+	assert.Equal(float32(vad.DefaultThreshold), detector.Threshold())
+	assert.Equal(vad.DefaultHangover, detector.Hangover())
+	assert.False(detector.IsSpeech())
+}
+
+func Test_VAD_001(t *testing.T) {
+	assert := assert.New(t)
+	detector := vad.New(SampleRate, 0.05, 300*time.Millisecond)
+
+	// Silence produces no events
+	assert.Empty(detector.Process(tone(time.Second, 0)))
+	assert.False(detector.IsSpeech())
+
+	// Speech opens a region
+	events := detector.Process(tone(time.Second, 0.5))
+	assert.Len(events, 1)
+	assert.Equal(vad.SpeechStart, events[0].Type)
+	assert.InDelta(time.Second, events[0].Time, float64(vad.FrameDuration))
+	assert.True(detector.IsSpeech())
+
+	// Silence shorter than the hangover does not close the region
+	assert.Empty(detector.Process(tone(200*time.Millisecond, 0)))
+	assert.True(detector.IsSpeech())
+
+	// Silence longer than the hangover closes the region
+	events = detector.Process(tone(time.Second, 0))
+	assert.Len(events, 1)
+	assert.Equal(vad.SpeechEnd, events[0].Type)
+	assert.GreaterOrEqual(events[0].Time, 2*time.Second+300*time.Millisecond)
+	assert.False(detector.IsSpeech())
+}
+
+func Test_VAD_002(t *testing.T) {
+	assert := assert.New(t)
+	detector := vad.New(SampleRate, 0.05, 300*time.Millisecond)
+
+	// Feeding samples in odd-sized chunks gives the same result
+	var events []vad.Event
+	data := append(tone(time.Second, 0), tone(time.Second, 0.5)...)
+	for i := 0; i < len(data); i += 77 {
+		j := i + 77
+		if j > len(data) {
+			j = len(data)
+		}
+		events = append(events, detector.Process(data[i:j])...)
+	}
+	assert.Len(events, 1)
+	assert.Equal(vad.SpeechStart, events[0].Type)
+	assert.InDelta(SampleRate, events[0].Offset, float64(vad.FrameDuration.Seconds()*SampleRate))
+
+	// Skipped samples end the region without an event, and are counted in
+	// the offsets of later events
+	detector.Skip(SampleRate)
+	assert.False(detector.IsSpeech())
+	events = detector.Process(tone(time.Second, 0.5))
+	assert.Len(events, 1)
+	assert.Equal(vad.SpeechStart, events[0].Type)
+	assert.Equal(int64(3*SampleRate), events[0].Offset)
+
+	// Reset returns to the initial state
+	detector.Reset()
+	assert.False(detector.IsSpeech())
+}
@@ -2,6 +2,7 @@ package whisper
 
 import (
 	"errors"
+	"time"
 
 	// Bindings
 	whisper "github.com/ggerganov/whisper.cpp/bindings/go"
@@ -11,11 +12,22 @@ import (
 // ERRORS
 
 var (
-	ErrUnableToLoadModel    = errors.New("unable to load model")
-	ErrInternalAppError     = errors.New("internal application error")
-	ErrProcessingFailed     = errors.New("processing failed")
-	ErrUnsupportedLanguage  = errors.New("unsupported language")
-	ErrModelNotMultilingual = errors.New("model is not multilingual")
+	ErrUnableToLoadModel        = errors.New("unable to load model")
+	ErrInternalAppError         = errors.New("internal application error")
+	ErrProcessingFailed         = errors.New("processing failed")
+	ErrUnsupportedLanguage      = errors.New("unsupported language")
+	ErrModelNotMultilingual     = errors.New("model is not multilingual")
+	ErrPoolExhausted            = errors.New("no context available")
+	ErrPoolClosed               = errors.New("pool is closed")
+	ErrUnknownModel             = errors.New("unknown model")
+	ErrChecksumMismatch         = errors.New("checksum mismatch")
+	ErrOpenVINOUnavailable      = errors.New("unable to initialize the OpenVINO encoder")
+	ErrUnableToCreateState      = errors.New("unable to create decoding state")
+	ErrContextInUse             = errors.New("decoding state is in use")
+	ErrAborted                  = errors.New("processing aborted")
+	ErrInvalidMel               = errors.New("invalid mel spectrogram")
+	ErrMelUnavailable           = errors.New("mel spectrogram cannot be read from this whisper.cpp")
+	ErrEncoderOutputUnavailable = errors.New("encoder output cannot be read from this whisper.cpp")
 )
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -26,3 +38,30 @@ const SampleRate = whisper.SampleRate
 
 // SampleBits is the number of bytes per sample.
 const SampleBits = whisper.SampleBits
+
+// MelFrame is the time between the frames of a log mel spectrogram.
+const MelFrame = time.Duration(whisper.HopLength) * time.Second / whisper.SampleRate
+
+// EmbeddingFrame is the time between the frames of the output of the encoder,
+// each of which is computed from two frames of the log mel spectrogram.
+const EmbeddingFrame = 2 * MelFrame
+
+const (
+	// Temperature at which alternatives are sampled
+	alternativeTemperature = 0.5
+
+	// Minimum audio decoded for the alternatives of a segment, as whisper
+	// does not decode less than a second
+	minAlternativeAudio = 1100 * time.Millisecond
+
+	// Audio decoded in each window of whisper_full
+	windowDuration = 30 * time.Second
+
+	// Time between timestamp tokens, the first of which is the "begin" token
+	timestampStep = 20 * time.Millisecond
+)
+
+const (
+	PoolWait   PoolPolicy = iota // Wait until a context becomes available
+	PoolReject                   // Return ErrPoolExhausted when no context is available
+)
@@ -1,10 +1,14 @@
 package whisper
 
 import (
+	gocontext "context"
 	"fmt"
 	"io"
 	"runtime"
+	"slices"
+	"sort"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	// Bindings
@@ -17,33 +21,128 @@ import (
 type context struct {
 	n      int
 	model  *model
+	state  *whisper.State // The decoding state, or nil for the default state of the model
 	params whisper.Params
+	words  bool
+	prompt string
+
+	// Hotwords, and the token sequences which are boosted
+	hotwords []string
+	boost    float32
+	boosted  [][]whisper.Token
+
+	// Logits filter
+	filter LogitsFilterCallback
+
+	// Token callback, and the token ids of the sequence being decoded, which
+	// are tracked while decoding with the parameters of the context
+	token    TokenCallback
+	sequence []whisper.Token
+	tracking bool
+
+	// The function which aborts processing, and true when it did
+	abort   func() bool
+	aborted bool
+
+	// Encoder begin callback
+	encoderBegin EncoderBeginCallback
+
+	// The number of hypotheses of each segment, and the segments of the last
+	// call to Process when alternatives are decoded
+	alternatives int
+	segments     []Segment
+	language     int
+
+	// The decode windows of the last call to Process, when the language is
+	// detected for each of them
+	windows []window
+
+	// The log mel spectrogram processed when there is no audio data
+	mel *Mel
+
+	// True when the next call to Process starts without the text of the
+	// previous call as context, and true while the context is processing
+	reset bool
+	busy  atomic.Bool
+
+	// The timings of the last call to Process
+	timings Timings
+}
+
+// The results of the last call to whisper_full, which are in the default
+// state of the model or in a state
+type results interface {
+	Whisper_full_lang_id() int
+	Whisper_full_n_segments() int
+	Whisper_full_get_segment_t0(int) int64
+	Whisper_full_get_segment_t1(int) int64
+	Whisper_full_get_segment_speaker_turn_next(int) bool
+	Whisper_full_get_segment_text(int) string
+	Whisper_full_n_tokens(int) int
+	Whisper_full_get_token_text(int, int) string
+	Whisper_full_get_token_id(int, int) whisper.Token
+	Whisper_full_get_token_data(int, int) whisper.TokenData
+	Whisper_full_get_token_p(int, int) float32
+}
+
+// The results in a state, whose token text is read with the vocabulary of
+// the model
+type stateResults struct {
+	*whisper.State
+	ctx *whisper.Context
+}
+
+// A decode window of whisper_full, which starts at an offset into the audio,
+// and the language detected for it
+type window struct {
+	start    time.Duration
+	language int
 }
 
 // Make sure context adheres to the interface
-var _ Context = (*context)(nil)
+var _ StateContext = (*context)(nil)
 
 ///////////////////////////////////////////////////////////////////////////////
 // LIFECYCLE
 
-func newContext(model *model, params whisper.Params) (Context, error) {
+func newContext(model *model, state *whisper.State, params whisper.Params) (*context, error) {
 	context := new(context)
 	context.model = model
+	context.state = state
 	context.params = params
+	context.reset = true
 
 	// Return success
 	return context, nil
 }
 
+// Close releases the decoding state of a context created with
+// Model.NewState
+func (context *context) Close() error {
+	context.idle("Close")
+	if context.state != nil {
+		context.state.Whisper_free_state()
+	}
+
+	// Release resources
+	context.state = nil
+
+	// Return success
+	return nil
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 // PUBLIC METHODS
 
 // Set the language to use for speech recognition.
 func (context *context) SetLanguage(lang string) error {
+	context.idle("SetLanguage")
+	context.model.RLock()
+	defer context.model.RUnlock()
 	if context.model.ctx == nil {
 		return ErrInternalAppError
 	}
-	if !context.model.IsMultilingual() {
+	if !context.model.isMultilingual() {
 		return ErrModelNotMultilingual
 	}
 
@@ -71,72 +170,237 @@ func (context *context) Language() string {
 	return whisper.Whisper_lang_str(context.params.Language())
 }
 
+// Get the language detected by the last call to Process, or the language
+// which was set if auto-detection was not used
+func (context *context) DetectedLanguage() string {
+	context.idleResults("DetectedLanguage")
+	context.model.RLock()
+	defer context.model.RUnlock()
+	if context.model.ctx == nil {
+		return ""
+	}
+	if context.segments != nil {
+		return whisper.Whisper_lang_str(context.language)
+	}
+	return whisper.Whisper_lang_str(context.results().Whisper_full_lang_id())
+}
+
 // Set translate flag
 func (context *context) SetTranslate(v bool) {
+	context.idle("SetTranslate")
 	context.params.SetTranslate(v)
 }
 
 // Set speedup flag
 func (context *context) SetSpeedup(v bool) {
+	context.idle("SetSpeedup")
 	context.params.SetSpeedup(v)
 }
 
 func (context *context) SetSplitOnWord(v bool) {
+	context.idle("SetSplitOnWord")
 	context.params.SetSplitOnWord(v)
 }
 
-// Set number of threads to use
+// Set number of threads to use, or zero to use one thread per CPU
 func (context *context) SetThreads(v uint) {
+	context.idle("SetThreads")
+	if v == 0 {
+		v = uint(runtime.NumCPU())
+	}
 	context.params.SetThreads(int(v))
 }
 
 // Set time offset
 func (context *context) SetOffset(v time.Duration) {
+	context.idle("SetOffset")
 	context.params.SetOffset(int(v.Milliseconds()))
 }
 
 // Set duration of audio to process
 func (context *context) SetDuration(v time.Duration) {
+	context.idle("SetDuration")
 	context.params.SetDuration(int(v.Milliseconds()))
 }
 
 // Set timestamp token probability threshold (~0.01)
 func (context *context) SetTokenThreshold(t float32) {
+	context.idle("SetTokenThreshold")
 	context.params.SetTokenThreshold(t)
 }
 
 // Set timestamp token sum probability threshold (~0.01)
 func (context *context) SetTokenSumThreshold(t float32) {
+	context.idle("SetTokenSumThreshold")
 	context.params.SetTokenSumThreshold(t)
 }
 
-// Set max segment length in characters
+// Set max segment length in characters (0 = no limit). Segments are split
+// using token timestamps, so they are enabled when a limit is set.
 func (context *context) SetMaxSegmentLength(n uint) {
+	context.idle("SetMaxSegmentLength")
 	context.params.SetMaxSegmentLength(int(n))
+	if n > 0 {
+		context.params.SetTokenTimestamps(true)
+	}
 }
 
 // Set token timestamps flag
 func (context *context) SetTokenTimestamps(b bool) {
+	context.idle("SetTokenTimestamps")
 	context.params.SetTokenTimestamps(b)
 }
 
+// Set word timestamps flag. Word timestamps are derived from
+// token timestamps, so this also sets the token timestamps flag
+func (context *context) SetWordTimestamps(b bool) {
+	context.idle("SetWordTimestamps")
+	context.words = b
+	if b {
+		context.params.SetTokenTimestamps(true)
+	}
+}
+
 // Set max tokens per segment (0 = no limit)
 func (context *context) SetMaxTokensPerSegment(n uint) {
+	context.idle("SetMaxTokensPerSegment")
 	context.params.SetMaxTokensPerSegment(int(n))
 }
 
 // Set audio encoder context
 func (context *context) SetAudioCtx(n uint) {
+	context.idle("SetAudioCtx")
 	context.params.SetAudioCtx(int(n))
 }
 
-// Set initial prompt
+// Set tinydiarize speaker turn detection flag
+func (context *context) SetDiarize(v bool) {
+	context.idle("SetDiarize")
+	context.params.SetTdrzEnable(v)
+}
+
+// Set initial decoding temperature. Zero is the most deterministic, and
+// higher temperatures sample more varied text.
+func (context *context) SetTemperature(t float32) {
+	context.idle("SetTemperature")
+	context.params.SetTemperature(t)
+}
+
+// Set the temperature increment used when decoding fails the entropy or
+// log probability thresholds, and is retried at a higher temperature.
+// Zero disables the fallback.
+func (context *context) SetTemperatureFallback(t float32) {
+	context.idle("SetTemperatureFallback")
+	context.params.SetTemperatureFallback(t)
+}
+
+// Set entropy threshold (~2.4). When the entropy of the decoded tokens is
+// below the threshold, which indicates repetition, decoding falls back to a
+// higher temperature.
+func (context *context) SetEntropyThreshold(t float32) {
+	context.idle("SetEntropyThreshold")
+	context.params.SetEntropyThreshold(t)
+}
+
+// Set average log probability threshold (~-1.0). When the average log
+// probability of the decoded tokens is below the threshold, decoding falls
+// back to a higher temperature.
+func (context *context) SetLogprobThreshold(t float32) {
+	context.idle("SetLogprobThreshold")
+	context.params.SetLogprobThreshold(t)
+}
+
+// Set no speech probability threshold (~0.6), above which a segment is
+// treated as silence. This is not yet used by whisper.cpp.
+func (context *context) SetNoSpeechThreshold(t float32) {
+	context.idle("SetNoSpeechThreshold")
+	context.params.SetNoSpeechThreshold(t)
+}
+
+// Set beam size. A beam size greater than one decodes with beam search,
+// which is more accurate but slower, otherwise greedy decoding is used.
+func (context *context) SetBeamSize(n uint) {
+	context.idle("SetBeamSize")
+	context.params.SetBeamSize(int(n))
+}
+
+// Set the number of candidates sampled at a non-zero temperature, of which
+// the most probable is kept
+func (context *context) SetBestOf(n uint) {
+	context.idle("SetBestOf")
+	context.params.SetBestOf(int(n))
+}
+
+// Set suppress blank flag. When set, which is the default, blank outputs at
+// the start of a segment are suppressed.
+func (context *context) SetSuppressBlank(v bool) {
+	context.idle("SetSuppressBlank")
+	context.params.SetSuppressBlank(v)
+}
+
+// Set suppress non-speech tokens flag. When set, tokens for non-speech
+// annotations such as "[Music]" or "(applause)" are suppressed, which
+// reduces artifacts in telephony audio.
+func (context *context) SetSuppressNonSpeech(v bool) {
+	context.idle("SetSuppressNonSpeech")
+	context.params.SetSuppressNonSpeechTokens(v)
+}
+
+// Set no context flag. When set, which is the default, each window is
+// decoded without the text decoded before it as context. Conditioning on the
+// previous text keeps names and style consistent, but on telephony audio it
+// often causes the decoder to repeat itself.
+func (context *context) SetNoContext(v bool) {
+	context.idle("SetNoContext")
+	context.params.SetNoContext(v)
+}
+
+// Set initial prompt, to bias the decoder towards domain vocabulary such as
+// names, jargon or phone numbers. An empty string clears the prompt.
 func (context *context) SetInitialPrompt(prompt string) {
-	context.params.SetInitialPrompt(prompt)
+	context.idle("SetInitialPrompt")
+	context.prompt = prompt
+	context.setPrompt()
+}
+
+// Set hotwords, such as names and domain terms, which are appended to the
+// initial prompt. When boost is not zero, it is also added to the logits of
+// the tokens which spell each hotword, as the hotword is decoded.
+func (context *context) SetHotwords(words []string, boost float32) error {
+	context.idle("SetHotwords")
+	context.model.RLock()
+	defer context.model.RUnlock()
+	if context.model.ctx == nil {
+		return ErrInternalAppError
+	}
+	var boosted [][]whisper.Token
+	if boost != 0 {
+		for _, word := range words {
+			// Boost the word both at the start of the text and after a space
+			for _, text := range []string{word, " " + word} {
+				tokens := make([]whisper.Token, len(text)+1)
+				n, err := context.model.ctx.Whisper_tokenize(text, tokens)
+				if err != nil {
+					return err
+				}
+				if n > 0 {
+					boosted = append(boosted, tokens[:n])
+				}
+			}
+		}
+	}
+	context.hotwords = words
+	context.boost = boost
+	context.boosted = boosted
+	context.setPrompt()
+
+	// Return success
+	return nil
 }
 
 // ResetTimings resets the mode timings. Should be called before processing
 func (context *context) ResetTimings() {
+	context.idle("ResetTimings")
 	context.model.ctx.Whisper_reset_timings()
 }
 
@@ -145,6 +409,12 @@ func (context *context) PrintTimings() {
 	context.model.ctx.Whisper_print_timings()
 }
 
+// Timings returns the time spent in each stage of the last call to Process,
+// including decoding any alternatives, and its real-time factor
+func (context *context) Timings() Timings {
+	return context.timings
+}
+
 // SystemInfo returns the system information
 func (context *context) SystemInfo() string {
 	return fmt.Sprintf("system_info: n_threads = %d / %d | %s\n",
@@ -158,6 +428,9 @@ func (context *context) SystemInfo() string {
 // Make sure to call whisper_pcm_to_mel() or whisper_set_mel() first.
 // Returns the probabilities of all languages.
 func (context *context) WhisperLangAutoDetect(offset_ms int, n_threads int) ([]float32, error) {
+	if context.state != nil {
+		return context.model.ctx.Whisper_lang_auto_detect_with_state(context.state, offset_ms, n_threads)
+	}
 	langProbs, err := context.model.ctx.Whisper_lang_auto_detect(offset_ms, n_threads)
 	if err != nil {
 		return nil, err
@@ -165,15 +438,221 @@ func (context *context) WhisperLangAutoDetect(offset_ms int, n_threads int) ([]f
 	return langProbs, nil
 }
 
+// Detect the spoken language of the sample data
+func (context *context) DetectLanguage(data []float32) (string, []LanguageProb, error) {
+	release, err := context.acquire()
+	if err != nil {
+		return "", nil, err
+	}
+	defer release()
+	if !context.model.isMultilingual() {
+		return "", nil, ErrModelNotMultilingual
+	}
+	if len(data) == 0 {
+		return "", nil, ErrProcessingFailed
+	}
+
+	// Compute the mel spectrogram and the language probabilities
+	threads := context.params.Threads()
+	var probs []float32
+	if context.state != nil {
+		if err := context.model.ctx.Whisper_pcm_to_mel_with_state(context.state, data, threads); err != nil {
+			return "", nil, err
+		}
+		probs, err = context.model.ctx.Whisper_lang_auto_detect_with_state(context.state, 0, threads)
+	} else {
+		if err := context.model.ctx.Whisper_pcm_to_mel(data, threads); err != nil {
+			return "", nil, err
+		}
+		probs, err = context.model.ctx.Whisper_lang_auto_detect(0, threads)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Sort languages by descending probability
+	result := make([]LanguageProb, 0, len(probs))
+	for id, p := range probs {
+		result = append(result, LanguageProb{
+			Language: whisper.Whisper_lang_str(id),
+			P:        p,
+		})
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].P > result[j].P
+	})
+
+	// Return success
+	return result[0].Language, result, nil
+}
+
+// Compute the log mel spectrogram of the sample data
+func (context *context) PCMToMel(data []float32) (Mel, error) {
+	if !whisper.HasGetMel {
+		return Mel{}, ErrMelUnavailable
+	} else if len(data) == 0 {
+		return Mel{}, ErrProcessingFailed
+	}
+	release, err := context.acquire()
+	if err != nil {
+		return Mel{}, err
+	}
+	defer release()
+
+	// Compute the mel spectrogram in the decoding state, and copy it
+	var mel []float32
+	var mels int
+	threads := context.params.Threads()
+	if context.state != nil {
+		if err := context.model.ctx.Whisper_pcm_to_mel_with_state(context.state, data, threads); err != nil {
+			return Mel{}, err
+		}
+		mel, mels = context.state.Whisper_get_mel()
+	} else {
+		if err := context.model.ctx.Whisper_pcm_to_mel(data, threads); err != nil {
+			return Mel{}, err
+		}
+		mel, mels = context.model.ctx.Whisper_get_mel()
+	}
+	if mels == 0 {
+		return Mel{}, ErrProcessingFailed
+	}
+
+	// Return success
+	return Mel{Mels: mels, Frames: len(mel) / mels, Data: mel}, nil
+}
+
+// Set the log mel spectrogram processed when there is no sample data. An
+// empty spectrogram clears it.
+func (context *context) SetMel(mel Mel) error {
+	context.idle("SetMel")
+	context.model.RLock()
+	defer context.model.RUnlock()
+	if context.model.ctx == nil {
+		return ErrInternalAppError
+	}
+	if mel.Frames == 0 && len(mel.Data) == 0 {
+		context.mel = nil
+		return nil
+	}
+	if mel.Mels != context.model.ctx.Whisper_model_n_mels() || mel.Frames <= 0 || len(mel.Data) != mel.Mels*mel.Frames {
+		return ErrInvalidMel
+	}
+	mel.Data = slices.Clone(mel.Data)
+	context.mel = &mel
+
+	// Return success
+	return nil
+}
+
+// Run the encoder on the sample data, or the log mel spectrogram which is
+// set when there is no sample data, in windows of 30 seconds, and return the
+// output of each window up to the end of the audio
+func (context *context) Encode(data []float32) (Embedding, error) {
+	if len(data) == 0 && context.mel == nil {
+		return Embedding{}, ErrProcessingFailed
+	}
+	if !whisper.HasEncoderOutput {
+		return Embedding{}, ErrEncoderOutputUnavailable
+	}
+	release, err := context.acquire()
+	if err != nil {
+		return Embedding{}, err
+	}
+	defer release()
+
+	// Compute or set the mel spectrogram in the decoding state
+	threads := context.params.Threads()
+	if len(data) == 0 {
+		err = context.setMel(*context.mel)
+	} else if context.state != nil {
+		err = context.model.ctx.Whisper_pcm_to_mel_with_state(context.state, data, threads)
+	} else {
+		err = context.model.ctx.Whisper_pcm_to_mel(data, threads)
+	}
+	if err != nil {
+		return Embedding{}, err
+	}
+	frames := context.model.ctx.Whisper_n_len()
+	if context.state != nil {
+		frames = context.state.Whisper_n_len()
+	}
+
+	// Encode each window, which is two mel frames for each frame of output
+	result := Embedding{Width: context.model.ctx.Whisper_model_n_audio_state()}
+	for offset := 0; offset < frames; {
+		var output []float32
+		if context.state != nil {
+			if err := context.model.ctx.Whisper_encode_with_state(context.state, offset, threads); err != nil {
+				return Embedding{}, err
+			}
+			output = context.state.Whisper_get_encoder_output()
+		} else {
+			if err := context.model.ctx.Whisper_encode(offset, threads); err != nil {
+				return Embedding{}, err
+			}
+			output = context.model.ctx.Whisper_get_encoder_output()
+		}
+		window := len(output) / result.Width
+		if window == 0 {
+			return Embedding{}, ErrProcessingFailed
+		}
+		n := min(window, (frames-offset+1)/2)
+		result.Data = append(result.Data, output[:n*result.Width]...)
+		result.Frames += n
+		offset += 2 * window
+	}
+
+	// Return success
+	return result, nil
+}
+
 // Process new sample data and return any errors
 func (context *context) Process(
 	data []float32,
 	callNewSegment SegmentCallback,
 	callProgress ProgressCallback,
 ) error {
-	if context.model.ctx == nil {
-		return ErrInternalAppError
+	return context.ProcessContext(gocontext.Background(), data, callNewSegment, callProgress)
+}
+
+// Process new sample data and return any errors. Processing is aborted
+// when the go context is cancelled, and the context error is returned
+func (context *context) ProcessContext(
+	ctx gocontext.Context,
+	data []float32,
+	callNewSegment SegmentCallback,
+	callProgress ProgressCallback,
+) error {
+	release, err := context.acquire()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// Without sample data, process the log mel spectrogram which is set
+	samples := len(data)
+	if samples == 0 {
+		if context.mel == nil {
+			return ErrProcessingFailed
+		} else if err := context.setMel(*context.mel); err != nil {
+			return err
+		}
+		samples = context.mel.Frames * whisper.HopLength
 	}
+
+	// Reset the segment cursor, and time the stages
+	context.n = 0
+	start, before := time.Now(), context.stageTimings()
+	defer func() {
+		context.timings = context.stageTimings().sub(before)
+		context.timings.Audio = context.audio(samples)
+		context.timings.Total = time.Since(start)
+		if context.timings.Audio > 0 {
+			context.timings.RTF = context.timings.Total.Seconds() / context.timings.Audio.Seconds()
+		}
+	}()
+
 	// If the callback is defined then we force on single_segment mode
 	if callNewSegment != nil {
 		context.params.SetSingleSegment(true)
@@ -187,43 +666,84 @@ func (context *context) Process(
 				num_segments := context.model.ctx.Whisper_full_n_segments()
 				s0 := num_segments - new
 				for i := s0; i < num_segments; i++ {
-					callNewSegment(toSegment(context.model.ctx, i))
+					callNewSegment(context.toSegment(i))
 				}
 			}
 		}); err != nil {
 			return err
 		}
-	} else if err := context.model.ctx.Whisper_full(context.params, data, nil, func(new int) {
+	} else if err := context.full(data, func(new int) {
 		if callNewSegment != nil {
-			num_segments := context.model.ctx.Whisper_full_n_segments()
+			num_segments := context.results().Whisper_full_n_segments()
 			s0 := num_segments - new
 			for i := s0; i < num_segments; i++ {
-				callNewSegment(toSegment(context.model.ctx, i))
+				callNewSegment(context.toSegment(i))
 			}
 		}
 	}, func(progress int) {
 		if callProgress != nil {
 			callProgress(progress)
 		}
-	}); err != nil {
+	}, context.toAbort(ctx)); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		} else if context.aborted {
+			return ErrAborted
+		}
 		return err
 	}
 
+	// Decode the alternatives of each segment
+	context.segments = nil
+	if context.alternatives > 1 && len(data) > 0 {
+		if err := context.decodeAlternatives(ctx, data); err != nil {
+			context.segments = nil
+			if ctx.Err() != nil {
+				return ctx.Err()
+			} else if context.aborted {
+				return ErrAborted
+			}
+			return err
+		}
+	}
+
 	// Return success
 	return nil
 }
 
+// Clear the segments of the last call to Process, and start the next call
+// without its text as context, so that the context can be reused for an
+// unrelated input. The parameters are kept.
+func (context *context) Reset() {
+	context.idle("Reset")
+	context.n = 0
+	context.mel = nil
+	context.segments = []Segment{}
+	context.language = -1
+	context.reset = true
+}
+
 // Return the next segment of tokens
 func (context *context) NextSegment() (Segment, error) {
+	context.idleResults("NextSegment")
+	context.model.RLock()
+	defer context.model.RUnlock()
 	if context.model.ctx == nil {
 		return Segment{}, ErrInternalAppError
 	}
-	if context.n >= context.model.ctx.Whisper_full_n_segments() {
+	if context.segments != nil {
+		if context.n >= len(context.segments) {
+			return Segment{}, io.EOF
+		}
+		context.n++
+		return context.segments[context.n-1], nil
+	}
+	if context.n >= context.results().Whisper_full_n_segments() {
 		return Segment{}, io.EOF
 	}
 
 	// Populate result
-	result := toSegment(context.model.ctx, context.n)
+	result := context.toSegment(context.n)
 
 	// Increment the cursor
 	context.n++
@@ -232,6 +752,26 @@ func (context *context) NextSegment() (Segment, error) {
 	return result, nil
 }
 
+// Return the segments of the last call to Process
+func (context *context) Segments() []Segment {
+	context.idleResults("Segments")
+	context.model.RLock()
+	defer context.model.RUnlock()
+	if context.model.ctx == nil {
+		return nil
+	}
+	if context.segments != nil {
+		return slices.Clone(context.segments)
+	}
+	result := make([]Segment, context.results().Whisper_full_n_segments())
+	for i := range result {
+		result[i] = context.toSegment(i)
+	}
+
+	// Return success
+	return result
+}
+
 // Test for text tokens
 func (context *context) IsText(t Token) bool {
 	switch {
@@ -294,17 +834,554 @@ func (context *context) IsLANG(t Token, lang string) bool {
 ///////////////////////////////////////////////////////////////////////////////
 // PRIVATE METHODS
 
-func toSegment(ctx *whisper.Context, n int) Segment {
-	return Segment{
-		Num:    n,
-		Text:   strings.TrimSpace(ctx.Whisper_full_get_segment_text(n)),
-		Start:  time.Duration(ctx.Whisper_full_get_segment_t0(n)) * time.Millisecond * 10,
-		End:    time.Duration(ctx.Whisper_full_get_segment_t1(n)) * time.Millisecond * 10,
-		Tokens: toTokens(ctx, n),
+// Return the results of the last call to Process
+func (context *context) results() results {
+	if context.state != nil {
+		return stateResults{context.state, context.model.ctx}
+	}
+	return context.model.ctx
+}
+
+// Run whisper_full on the default state of the model, or on the state. After
+// a reset, the text of the previous call is not used as context.
+func (context *context) full(data []float32, callNewSegment func(int), callProgress func(int), callAbort func() bool) error {
+	params := context.params
+	if context.reset {
+		params.SetNoContext(true)
+		context.reset = false
+	}
+	context.sequence, context.tracking = context.sequence[:0], true
+	defer func() { context.tracking = false }()
+
+	// Record the start of each decode window when the language is detected,
+	// as whisper_full only detects it for the first window
+	windows := context.decodeWindows()
+	*windows = (*windows)[:0]
+	detect := params.Language() == -1 && context.model.isMultilingual()
+	var callEncoderBegin func() bool
+	if fn := context.encoderBegin; fn != nil || detect {
+		prev, segments := time.Duration(-1), 0
+		callEncoderBegin = func() bool {
+			// A window follows the last segment, or the whole of a previous
+			// window without segments
+			start, n := context.windowStart(), context.results().Whisper_full_n_segments()
+			if prev >= 0 && n == segments {
+				start = prev + windowDuration
+			}
+			prev, segments = start, n
+			if detect {
+				*windows = append(*windows, window{start: start, language: -1})
+			}
+			return fn == nil || fn(start)
+		}
+	}
+	if err := context.fullWithParams(params, data, callEncoderBegin, callNewSegment, callProgress, callAbort); err != nil {
+		return err
+	}
+	context.detectWindows(*windows)
+
+	// Return success
+	return nil
+}
+
+// Return the decode windows of the results, which are those of the model for
+// contexts which share its default state
+func (context *context) decodeWindows() *[]window {
+	if context.state == nil {
+		return &context.model.windows
+	}
+	return &context.windows
+}
+
+// Detect the language of each decode window from the log mel spectrogram
+// left in the decoding state. The first window is detected by whisper_full,
+// and a window which cannot be detected keeps its language.
+func (context *context) detectWindows(windows []window) {
+	if len(windows) == 0 {
+		return
+	}
+	language, threads := context.results().Whisper_full_lang_id(), context.params.Threads()
+	for i := range windows {
+		if i > 0 {
+			offset_ms := int(windows[i].start / time.Millisecond)
+			var probs []float32
+			var err error
+			if context.state != nil {
+				probs, err = context.model.ctx.Whisper_lang_auto_detect_with_state(context.state, offset_ms, threads)
+			} else {
+				probs, err = context.model.ctx.Whisper_lang_auto_detect(offset_ms, threads)
+			}
+			if err == nil && len(probs) > 0 {
+				language = 0
+				for id, p := range probs {
+					if p > probs[language] {
+						language = id
+					}
+				}
+			}
+		}
+		windows[i].language = language
+	}
+}
+
+// Return the language of the decode window in which a segment starts, or
+// the language of the call when the windows are not detected
+func (context *context) windowLanguage(start time.Duration) int {
+	language := context.results().Whisper_full_lang_id()
+	for _, window := range *context.decodeWindows() {
+		if window.start > start {
+			break
+		}
+		language = window.language
+	}
+	return language
+}
+
+// Set the log mel spectrogram in the decoding state
+func (context *context) setMel(mel Mel) error {
+	if context.state != nil {
+		return context.model.ctx.Whisper_set_mel_with_state(context.state, mel.Data, mel.Mels)
+	}
+	return context.model.ctx.Whisper_set_mel(mel.Data, mel.Mels)
+}
+
+// Return the time spent in each stage by the decoding state so far
+func (context *context) stageTimings() Timings {
+	var timings whisper.Timings
+	if context.state != nil {
+		timings = context.state.Whisper_get_timings()
+	} else {
+		timings = context.model.ctx.Whisper_get_timings()
+	}
+	return Timings{
+		Mel:    time.Duration(timings.Mel()) * time.Microsecond,
+		Sample: time.Duration(timings.Sample()) * time.Microsecond,
+		Encode: time.Duration(timings.Encode()) * time.Microsecond,
+		Decode: time.Duration(timings.Decode()+timings.Batchd()+timings.Prompt()) * time.Microsecond,
+	}
+}
+
+// Return the duration of the samples processed, from the offset for the
+// duration set
+func (context *context) audio(samples int) time.Duration {
+	audio := max(time.Duration(samples)*time.Second/SampleRate-time.Duration(context.params.Offset())*time.Millisecond, 0)
+	if duration := time.Duration(context.params.Duration()) * time.Millisecond; duration > 0 {
+		audio = min(audio, duration)
+	}
+	return audio
+}
+
+// Mark the context and its decoding state as in use, and return the
+// function to call when processing is done. Until then, the context counts
+// as active so that the model is not closed while processing. The lock is
+// not held meanwhile, as callbacks may call back into the model. Contexts
+// created with Model.NewContext share the default state of the model, so
+// they share its flag.
+func (context *context) acquire() (func(), error) {
+	model := context.model
+	model.RLock()
+	defer model.RUnlock()
+	if model.ctx == nil || model.closing {
+		return nil, ErrInternalAppError
+	}
+	if context.state != nil {
+		if !context.busy.CompareAndSwap(false, true) {
+			return nil, ErrContextInUse
+		}
+		model.active.Add(1)
+		return func() {
+			context.busy.Store(false)
+			model.active.Done()
+		}, nil
+	}
+	if !model.busy.CompareAndSwap(false, true) {
+		return nil, ErrContextInUse
+	}
+	context.busy.Store(true)
+	model.active.Add(1)
+	return func() {
+		context.busy.Store(false)
+		model.busy.Store(false)
+		model.active.Done()
+	}, nil
+}
+
+// Panic when the context is processing, as the method would change the
+// parameters or the results in use on another goroutine, or from within a
+// callback
+func (context *context) idle(method string) {
+	if context.busy.Load() {
+		panic("whisper: Context." + method + " called while the context is processing; use a context from one goroutine at a time")
+	}
+}
+
+// Panic when the results of the last call to Process are being written, as
+// idle, or when they are in the default state of the model, which another
+// context is processing with
+func (context *context) idleResults(method string) {
+	context.idle(method)
+	if context.state == nil && context.segments == nil && context.model.busy.Load() {
+		panic("whisper: Context." + method + " called while another context processes with the default state of the model; use Model.NewState to process concurrently")
+	}
+}
+
+// Run whisper_full with the parameters
+func (context *context) fullWithParams(params whisper.Params, data []float32, callEncoderBegin func() bool, callNewSegment func(int), callProgress func(int), callAbort func() bool) error {
+	if len(context.boosted) > 0 || context.filter != nil || context.token != nil {
+		if context.state != nil {
+			context.state.Whisper_set_logits_filter(context.filterLogits)
+			defer context.state.Whisper_set_logits_filter(nil)
+		} else {
+			context.model.ctx.Whisper_set_logits_filter(context.filterLogits)
+			defer context.model.ctx.Whisper_set_logits_filter(nil)
+		}
+	}
+	if context.state != nil {
+		return context.model.ctx.Whisper_full_with_state(context.state, params, data, callEncoderBegin, callNewSegment, callProgress, callAbort)
 	}
+	return context.model.ctx.Whisper_full_with_abort(params, data, callEncoderBegin, callNewSegment, callProgress, callAbort)
 }
 
-func toTokens(ctx *whisper.Context, n int) []Token {
+// Read the segments of the last pass, and decode the alternatives of each
+// segment by sampling its audio again
+func (context *context) decodeAlternatives(ctx gocontext.Context, data []float32) error {
+	results := context.results()
+	context.language = results.Whisper_full_lang_id()
+	segments := make([]Segment, results.Whisper_full_n_segments())
+	languages := make([]int, len(segments))
+	for i := range segments {
+		segments[i] = context.toSegment(i)
+		languages[i] = context.windowLanguage(segments[i].Start)
+	}
+
+	// Sample each segment in the language of its window, without a fallback
+	params := context.params
+	params.SetOffset(0)
+	params.SetDuration(0)
+	params.SetNoContext(true)
+	params.SetSingleSegment(true)
+	params.SetTemperature(alternativeTemperature)
+	params.SetTemperatureFallback(0)
+	params.SetBestOf(1)
+	for i := range segments {
+		if err := params.SetLanguage(languages[i]); err != nil {
+			return err
+		}
+		alternatives, err := context.sampleAlternatives(ctx, params, data, segments[i])
+		if err != nil {
+			return err
+		}
+		segments[i].Alternatives = alternatives
+	}
+	context.segments = segments
+
+	// Return success
+	return nil
+}
+
+// Return the hypotheses of a segment, the first of which is the text of the
+// segment, followed by the distinct hypotheses sampled from its audio in
+// descending order of confidence
+func (context *context) sampleAlternatives(ctx gocontext.Context, params whisper.Params, data []float32, segment Segment) ([]Alternative, error) {
+	result := []Alternative{{Text: segment.Text, Confidence: context.confidence(segment.Tokens)}}
+
+	// Extend the audio of the segment to the minimum which is decoded
+	begin := clamp(toSamples(segment.Start), 0, len(data))
+	end := clamp(toSamples(segment.End), begin, len(data))
+	if pad := toSamples(minAlternativeAudio) - (end - begin); pad > 0 {
+		begin, end = max(0, begin-pad/2-1), min(len(data), end+pad/2+1)
+	}
+
+	// Sample the hypotheses
+	for i := 1; i < context.alternatives; i++ {
+		if err := context.fullWithParams(params, data[begin:end], nil, nil, nil, context.toAbort(ctx)); err != nil {
+			return nil, err
+		}
+		results, text, tokens := context.results(), make([]string, 0, 1), []Token(nil)
+		for j := 0; j < results.Whisper_full_n_segments(); j++ {
+			text = append(text, strings.TrimSpace(results.Whisper_full_get_segment_text(j)))
+			tokens = append(tokens, toTokens(results, j)...)
+		}
+		alternative := Alternative{Text: strings.Join(text, " "), Confidence: context.confidence(tokens)}
+		if alternative.Text != "" && !slices.ContainsFunc(result, func(a Alternative) bool {
+			return a.Text == alternative.Text
+		}) {
+			result = append(result, alternative)
+		}
+	}
+	sort.SliceStable(result[1:], func(i, j int) bool {
+		return result[i+1].Confidence > result[j+1].Confidence
+	})
+
+	// Return success
+	return result, nil
+}
+
+// Return the mean probability of the text tokens
+func (context *context) confidence(tokens []Token) float32 {
+	var sum float32
+	var n int
+	for _, token := range tokens {
+		if context.IsText(token) {
+			sum += token.P
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float32(n)
+}
+
+// Set the number of hypotheses of each segment returned in
+// Segment.Alternatives, including the segment text. Alternatives are decoded
+// when the number is greater than one.
+func (context *context) SetAlternatives(n uint) {
+	context.idle("SetAlternatives")
+	context.alternatives = int(n)
+}
+
+// Set the callback which filters the logits of each token before it is
+// sampled, or nil to remove it
+func (context *context) SetLogitsFilter(fn LogitsFilterCallback) {
+	context.idle("SetLogitsFilter")
+	context.filter = fn
+}
+
+// Set the callback for tokens as they are decoded, or nil to remove it
+func (context *context) SetTokenCallback(fn TokenCallback) {
+	context.idle("SetTokenCallback")
+	context.token = fn
+}
+
+// Set the function which aborts processing when it returns true, or nil to
+// remove it
+func (context *context) SetAbortFunc(fn func() bool) {
+	context.idle("SetAbortFunc")
+	context.abort = fn
+}
+
+// Set the callback which is called before each window is encoded, or nil
+// to remove it
+func (context *context) SetEncoderBeginCallback(fn EncoderBeginCallback) {
+	context.idle("SetEncoderBeginCallback")
+	context.encoderBegin = fn
+}
+
+// Return the text of each token, indexed by token id
+func (context *context) Vocab() []string {
+	result := make([]string, context.model.ctx.Whisper_n_vocab())
+	for i := range result {
+		result[i] = context.model.ctx.Whisper_token_to_str(whisper.Token(i))
+	}
+	return result
+}
+
+// Set the initial prompt, followed by the hotwords
+func (context *context) setPrompt() {
+	prompt := context.prompt
+	if len(context.hotwords) > 0 {
+		prompt = strings.TrimSpace(prompt + " " + strings.Join(context.hotwords, ", "))
+	}
+	context.params.SetInitialPrompt(prompt)
+}
+
+// Pass the last token decoded to the token callback, boost the hotwords,
+// and then call the logits filter
+func (context *context) filterLogits(tokens []whisper.TokenData, logits []float32) {
+	if context.token != nil && context.tracking {
+		context.callToken(tokens)
+	}
+	if len(context.boosted) > 0 {
+		context.boostHotwords(tokens, logits)
+	}
+	if context.filter != nil {
+		context.filter(context.toFilterTokens(tokens), logits)
+	}
+}
+
+// Call the token callback when the tokens decoded so far extend the sequence
+// of the last call by a text token. The logits filter is called before each
+// token is sampled, so a token is passed once the logits of the next token
+// are computed. Tokens which do not extend the sequence start a new window,
+// or are of another decoder, and are not passed.
+func (context *context) callToken(tokens []whisper.TokenData) {
+	extends := len(tokens) == len(context.sequence)+1
+	for i, id := range context.sequence {
+		if !extends || tokens[i].Id() != id {
+			extends = false
+			break
+		}
+	}
+	context.sequence = context.sequence[:0]
+	for _, data := range tokens {
+		context.sequence = append(context.sequence, data.Id())
+	}
+	if !extends {
+		return
+	}
+	filtered := context.toFilterTokens(tokens)
+	token := filtered[len(filtered)-1]
+	if !context.IsText(token) {
+		return
+	}
+
+	// Find the start of the segment in the window, after the last timestamp
+	// token, and the number of segments which ended before it
+	beg := context.model.ctx.Whisper_token_beg()
+	begin, ended := 0, 0
+	for i, data := range tokens {
+		if data.Id() >= beg {
+			begin = i + 1
+			if i > 0 && tokens[i-1].Id() < beg {
+				ended++
+			}
+		}
+	}
+
+	n := context.results().Whisper_full_n_segments()
+	start := context.windowStart()
+	if begin > 0 {
+		start += time.Duration(tokens[begin-1].Id()-beg) * timestampStep
+	}
+
+	// Make the segment from the text tokens decoded so far
+	segment := Segment{Num: n + ended, Start: start, End: start}
+	var text strings.Builder
+	for _, t := range filtered[begin:] {
+		if context.IsText(t) {
+			t.Start, t.End = start, start
+			segment.Tokens = append(segment.Tokens, t)
+			text.WriteString(t.Text)
+		}
+	}
+	segment.Text = strings.TrimSpace(text.String())
+	token.Start, token.End = start, start
+	context.token(segment, token)
+}
+
+// Return the start of the window being decoded, which is the end of the last
+// segment decoded, or the offset for the first window. When a window has no
+// segments, such as in silence, the next window starts later.
+func (context *context) windowStart() time.Duration {
+	results := context.results()
+	if n := results.Whisper_full_n_segments(); n > 0 {
+		return time.Duration(results.Whisper_full_get_segment_t1(n-1)) * time.Millisecond * 10
+	}
+	return time.Duration(context.params.Offset()) * time.Millisecond
+}
+
+// Return the tokens passed to a logits filter
+func (context *context) toFilterTokens(tokens []whisper.TokenData) []Token {
+	result := make([]Token, len(tokens))
+	for i, data := range tokens {
+		result[i] = Token{
+			Id:    int(data.Id()),
+			Text:  context.model.ctx.Whisper_token_to_str(data.Id()),
+			P:     data.P(),
+			Plog:  data.Plog(),
+			Start: time.Duration(data.T0()) * time.Millisecond * 10,
+			End:   time.Duration(data.T1()) * time.Millisecond * 10,
+		}
+	}
+	return result
+}
+
+// Boost the next token of each hotword which has been partly decoded, or
+// the first token of each hotword
+func (context *context) boostHotwords(tokens []whisper.TokenData, logits []float32) {
+	for _, hotword := range context.boosted {
+		k := min(len(hotword)-1, len(tokens))
+		for ; k > 0; k-- {
+			if slices.EqualFunc(tokens[len(tokens)-k:], hotword[:k], func(data whisper.TokenData, token whisper.Token) bool {
+				return data.Id() == token
+			}) {
+				break
+			}
+		}
+		if id := int(hotword[k]); id < len(logits) {
+			logits[id] += context.boost
+		}
+	}
+}
+
+// Return the nth segment of the results
+func (context *context) toSegment(n int) Segment {
+	segment := toSegment(context.results(), context.model.ctx.Whisper_token_eot(), n, context.words)
+	segment.Language = whisper.Whisper_lang_str(context.windowLanguage(segment.Start))
+	return segment
+}
+
+func (results stateResults) Whisper_full_get_token_text(segment int, token int) string {
+	return results.ctx.Whisper_full_get_token_text_from_state(results.State, segment, token)
+}
+
+// Return the time spent in each stage since an earlier reading
+func (t Timings) sub(before Timings) Timings {
+	t.Mel -= before.Mel
+	t.Sample -= before.Sample
+	t.Encode -= before.Encode
+	t.Decode -= before.Decode
+	return t
+}
+
+// Return the abort callback for processing, which aborts when the go context
+// is cancelled or the abort function returns true
+func (context *context) toAbort(ctx gocontext.Context) func() bool {
+	context.aborted = false
+	cancelled, abort := toAbort(ctx), context.abort
+	switch {
+	case abort == nil:
+		return cancelled
+	case cancelled == nil:
+		cancelled = func() bool { return false }
+	}
+	return func() bool {
+		if cancelled() {
+			return true
+		} else if abort() {
+			context.aborted = true
+			return true
+		}
+		return false
+	}
+}
+
+// Return an abort callback for the go context, or nil if the context
+// can never be cancelled
+func toAbort(ctx gocontext.Context) func() bool {
+	done := ctx.Done()
+	if done == nil {
+		return nil
+	}
+	return func() bool {
+		select {
+		case <-done:
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+func toSegment(ctx results, eot whisper.Token, n int, words bool) Segment {
+	segment := Segment{
+		Num:      n,
+		Text:     strings.TrimSpace(ctx.Whisper_full_get_segment_text(n)),
+		Language: whisper.Whisper_lang_str(ctx.Whisper_full_lang_id()),
+		Start:    time.Duration(ctx.Whisper_full_get_segment_t0(n)) * time.Millisecond * 10,
+		End:      time.Duration(ctx.Whisper_full_get_segment_t1(n)) * time.Millisecond * 10,
+		Tokens:   toTokens(ctx, n),
+
+		SpeakerTurnNext: ctx.Whisper_full_get_segment_speaker_turn_next(n),
+	}
+	if words {
+		segment.Words = toWords(eot, segment.Tokens)
+	}
+	return segment
+}
+
+func toTokens(ctx results, n int) []Token {
 	result := make([]Token, ctx.Whisper_full_n_tokens(n))
 	for i := 0; i < len(result); i++ {
 		data := ctx.Whisper_full_get_token_data(n, i)
@@ -313,9 +1390,41 @@ func toTokens(ctx *whisper.Context, n int) []Token {
 			Id:    int(ctx.Whisper_full_get_token_id(n, i)),
 			Text:  ctx.Whisper_full_get_token_text(n, i),
 			P:     ctx.Whisper_full_get_token_p(n, i),
+			Plog:  data.Plog(),
 			Start: time.Duration(data.T0()) * time.Millisecond * 10,
 			End:   time.Duration(data.T1()) * time.Millisecond * 10,
 		}
 	}
 	return result
 }
+
+// Group text tokens into words. A token which starts with a space begins a
+// new word, and other tokens (including punctuation) continue the word.
+func toWords(eot whisper.Token, tokens []Token) []Word {
+	var result []Word
+	var n int
+	for _, token := range tokens {
+		if whisper.Token(token.Id) >= eot || token.Text == "" {
+			continue
+		}
+		if len(result) == 0 || strings.HasPrefix(token.Text, " ") {
+			if n > 0 {
+				result[len(result)-1].P /= float32(n)
+			}
+			result = append(result, Word{Start: token.Start})
+			n = 0
+		}
+		word := &result[len(result)-1]
+		word.Text += token.Text
+		word.P += token.P
+		word.End = token.End
+		n++
+	}
+	if n > 0 {
+		result[len(result)-1].P /= float32(n)
+	}
+	for i := range result {
+		result[i].Text = strings.TrimSpace(result[i].Text)
+	}
+	return result
+}
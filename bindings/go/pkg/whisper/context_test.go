@@ -1,19 +1,54 @@
 package whisper_test
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+	"unicode"
 
 	// Packages
+	vad "github.com/ggerganov/whisper.cpp/bindings/go/pkg/vad"
 	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	wav "github.com/go-audio/wav"
 	assert "github.com/stretchr/testify/assert"
 )
 
 const (
 	ModelPath  = "../../models/ggml-tiny.bin"
 	SamplePath = "../../samples/jfk.wav"
+
+	// The model the golden transcripts are made with, downloaded by
+	// "make test"
+	GoldenModelPath = "../../models/ggml-tiny.en.bin"
+)
+
+// The fixtures transcribed by the golden tests, with the golden transcripts
+// in testdata/<name>.golden. The word error rate allowed for each path is
+// greater for streaming, which transcribes the audio in windows.
+var (
+	GoldenFixtures = map[string]string{
+		"jfk": SamplePath,
+	}
+	GoldenFileTolerance   = 0.1
+	GoldenStreamTolerance = 0.25
 )
 
+// Run "go test -run Test_Whisper_021 -update" to write the golden transcripts from
+// the file path, after a change which is expected to change them
+var update = flag.Bool("update", false, "Write the golden transcripts")
+
 func Test_Whisper_000(t *testing.T) {
 	assert := assert.New(t)
 	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
@@ -53,3 +88,1337 @@ func Test_Whisper_001(t *testing.T) {
 	assert.NotNil(ctx)
 
 }
+
+func Test_Whisper_002(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+	if _, err := os.Stat(SamplePath); os.IsNotExist(err) {
+		t.Skip("Skipping test, sample not found:", SamplePath)
+	}
+
+	// Read samples
+	fh, err := os.Open(SamplePath)
+	assert.NoError(err)
+	defer fh.Close()
+	buf, err := wav.NewDecoder(fh).FullPCMBuffer()
+	assert.NoError(err)
+	data := buf.AsFloat32Buffer().Data
+
+	// Load model
+	model, err := whisper.New(ModelPath)
+	assert.NoError(err)
+	assert.NotNil(model)
+	defer model.Close()
+
+	ctx, err := model.NewContext()
+	assert.NoError(err)
+
+	// Feed the samples in 100ms chunks through a streaming context
+	var segments []whisper.Segment
+	stream, err := whisper.NewStreamingContext(ctx, func(segment whisper.Segment) {
+		segments = append(segments, segment)
+	})
+	assert.NoError(err)
+	stream.SetWindow(5 * time.Second)
+	for i := 0; i < len(data); i += whisper.SampleRate / 10 {
+		j := i + whisper.SampleRate/10
+		if j > len(data) {
+			j = len(data)
+		}
+		assert.NoError(stream.Feed(data[i:j]))
+	}
+	assert.NoError(stream.Flush())
+
+	// Segments are in order, and numbered across the stream
+	assert.NotEmpty(segments)
+	for i, segment := range segments {
+		assert.Equal(i, segment.Num)
+		if i > 0 {
+			assert.GreaterOrEqual(segment.Start, segments[i-1].Start)
+		}
+		t.Logf("[%6s->%6s] %s", segment.Start, segment.End, segment.Text)
+	}
+}
+
+func Test_Whisper_003(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+
+	// Create a pool with a single context
+	pool, err := whisper.NewPool(ModelPath, 1, whisper.PoolReject)
+	assert.NoError(err)
+	assert.NotNil(pool)
+	assert.Equal(1, pool.Size())
+
+	// The second context is rejected until the first is returned
+	ctx, err := pool.Get(context.Background())
+	assert.NoError(err)
+	assert.NotNil(ctx)
+	_, err = pool.Get(context.Background())
+	assert.ErrorIs(err, whisper.ErrPoolExhausted)
+	assert.NoError(pool.Put(ctx))
+	ctx, err = pool.Get(context.Background())
+	assert.NoError(err)
+	assert.NoError(pool.Put(ctx))
+
+	// Closed pool returns an error
+	assert.NoError(pool.Close())
+	_, err = pool.Get(context.Background())
+	assert.ErrorIs(err, whisper.ErrPoolClosed)
+}
+
+func Test_Whisper_004(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+	if _, err := os.Stat(SamplePath); os.IsNotExist(err) {
+		t.Skip("Skipping test, sample not found:", SamplePath)
+	}
+
+	// Read samples
+	fh, err := os.Open(SamplePath)
+	assert.NoError(err)
+	defer fh.Close()
+	buf, err := wav.NewDecoder(fh).FullPCMBuffer()
+	assert.NoError(err)
+	data := buf.AsFloat32Buffer().Data
+
+	// Load model
+	model, err := whisper.New(ModelPath)
+	assert.NoError(err)
+	defer model.Close()
+	ctx, err := model.NewContext()
+	assert.NoError(err)
+
+	// Detect the language of the first five seconds
+	lang, probs, err := ctx.DetectLanguage(data[:5*whisper.SampleRate])
+	assert.NoError(err)
+	assert.Equal("en", lang)
+	assert.NotEmpty(probs)
+	assert.Equal(lang, probs[0].Language)
+	for i := 1; i < len(probs); i++ {
+		assert.GreaterOrEqual(probs[i-1].P, probs[i].P)
+	}
+}
+
+func Test_Whisper_005(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+
+	// Load the model on the CPU
+	model, err := whisper.NewWithParams(ModelPath, whisper.ContextParams{UseGPU: false})
+	assert.NoError(err)
+	defer model.Close()
+
+	// The metadata is read from the model file
+	info := model.Info()
+	assert.Equal("tiny", info.Type)
+	assert.Equal(model.IsMultilingual(), info.Multilingual)
+	assert.Equal(80, info.Mels)
+	assert.NotZero(info.Vocab)
+	assert.NotEqual("unknown", info.Quantization)
+	ctx, err := model.NewContext()
+	assert.NoError(err)
+	assert.NotNil(ctx)
+
+	// Loading a missing model fails
+	_, err = whisper.NewWithParams("missing.bin", whisper.DefaultContextParams())
+	assert.Error(err)
+}
+
+func Test_Whisper_006(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+
+	// Create a pool with a single context
+	pool, err := whisper.NewPool(ModelPath, 1, whisper.PoolReject)
+	assert.NoError(err)
+	defer pool.Close()
+	assert.Equal(ModelPath, pool.Path())
+
+	// Reloading a missing model leaves the pool unchanged
+	assert.Error(pool.Reload("missing.bin"))
+	assert.Equal(ModelPath, pool.Path())
+
+	// A context in use keeps the previous model until it is returned
+	ctx, err := pool.Get(context.Background())
+	assert.NoError(err)
+	assert.NoError(pool.Reload(ModelPath))
+	_, err = pool.Get(context.Background())
+	assert.ErrorIs(err, whisper.ErrPoolExhausted)
+	assert.NoError(pool.Put(ctx))
+	ctx, err = pool.Get(context.Background())
+	assert.NoError(err)
+	assert.NoError(pool.Put(ctx))
+
+	// Idle models are replaced at once
+	assert.NoError(pool.Reload(ModelPath))
+	ctx, err = pool.Get(context.Background())
+	assert.NoError(err)
+	assert.NoError(pool.Put(ctx))
+}
+
+func Test_Whisper_007(t *testing.T) {
+	assert := assert.New(t)
+
+	// Serve a model with its checksum, as Hugging Face does
+	data := bytes.Repeat([]byte("ggml"), 1<<16)
+	sum := sha256.Sum256(data)
+	etag := hex.EncodeToString(sum[:])
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Linked-Etag", `"`+etag+`"`)
+		http.ServeContent(w, r, "ggml-tiny-q5_1.bin", time.Time{}, bytes.NewReader(data))
+	}))
+	defer server.Close()
+	defer func(url string) { whisper.DownloadURL = url }(whisper.DownloadURL)
+	whisper.DownloadURL = server.URL
+	dir := t.TempDir()
+
+	// Unknown models are rejected
+	_, err := whisper.Download(context.Background(), "huge", dir)
+	assert.ErrorIs(err, whisper.ErrUnknownModel)
+
+	// A partial download is resumed
+	assert.NoError(os.WriteFile(filepath.Join(dir, "ggml-tiny-q5_1.bin.part"), data[:1000], 0644))
+	var count, total int64
+	path, err := whisper.DownloadWithProgress(context.Background(), "tiny-q5_1", dir, func(n, size int64) {
+		count, total = n, size
+	})
+	assert.NoError(err)
+	assert.Equal(filepath.Join(dir, "ggml-tiny-q5_1.bin"), path)
+	assert.Equal(int64(len(data)), count)
+	assert.Equal(int64(len(data)), total)
+	result, err := os.ReadFile(path)
+	assert.NoError(err)
+	assert.Equal(data, result)
+	_, err = os.Stat(path + ".part")
+	assert.True(os.IsNotExist(err))
+
+	// An existing model is not downloaded again
+	path, err = whisper.Download(context.Background(), "ggml-tiny-q5_1.bin", dir)
+	assert.NoError(err)
+	assert.Equal(filepath.Join(dir, "ggml-tiny-q5_1.bin"), path)
+
+	// A download which does not match the checksum is discarded
+	etag = hex.EncodeToString(make([]byte, sha256.Size))
+	_, err = whisper.Download(context.Background(), "base-q5_1", dir)
+	assert.ErrorIs(err, whisper.ErrChecksumMismatch)
+	_, err = os.Stat(filepath.Join(dir, "ggml-base-q5_1.bin.part"))
+	assert.True(os.IsNotExist(err))
+}
+
+func Test_Whisper_008(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+	if _, err := os.Stat(SamplePath); os.IsNotExist(err) {
+		t.Skip("Skipping test, sample not found:", SamplePath)
+	}
+
+	// Read samples
+	fh, err := os.Open(SamplePath)
+	assert.NoError(err)
+	defer fh.Close()
+	buf, err := wav.NewDecoder(fh).FullPCMBuffer()
+	assert.NoError(err)
+	data := buf.AsFloat32Buffer().Data
+
+	// Load model
+	model, err := whisper.New(ModelPath)
+	assert.NoError(err)
+	defer model.Close()
+
+	// Decode concurrently with a state for each goroutine
+	var wg sync.WaitGroup
+	text := make([]string, 2)
+	for i := range text {
+		ctx, err := model.NewState()
+		if !assert.NoError(err) {
+			t.FailNow()
+		}
+		defer ctx.Close()
+		ctx.SetThreads(1)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(ctx.Process(data, nil, nil))
+			for {
+				segment, err := ctx.NextSegment()
+				if err != nil {
+					break
+				}
+				text[i] += segment.Text
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Each state holds its own results
+	assert.Equal(text[0], text[1])
+
+	// A shared pool decodes with a state for each slot
+	pool, err := whisper.NewSharedPool(ModelPath, whisper.DefaultContextParams(), 2, whisper.PoolReject)
+	assert.NoError(err)
+	defer pool.Close()
+	ctx, err := pool.Get(context.Background())
+	assert.NoError(err)
+	assert.NoError(ctx.Process(data, nil, nil))
+	if segment, err := ctx.NextSegment(); err == nil {
+		assert.Contains(text[0], segment.Text)
+	}
+	assert.NoError(pool.Put(ctx))
+	assert.NoError(pool.Reload(ModelPath))
+
+	// A pool with copies loads the model for each slot
+	copies, err := whisper.NewPoolWithCopies(ModelPath, whisper.DefaultContextParams(), 2, whisper.PoolReject)
+	assert.NoError(err)
+	defer copies.Close()
+	ctx, err = copies.Get(context.Background())
+	assert.NoError(err)
+	assert.NoError(ctx.Process(data, nil, nil))
+	assert.NoError(copies.Put(ctx))
+	assert.Equal(2, copies.Size())
+}
+
+func Test_Whisper_009(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+	if _, err := os.Stat(SamplePath); os.IsNotExist(err) {
+		t.Skip("Skipping test, sample not found:", SamplePath)
+	}
+
+	// Read samples
+	fh, err := os.Open(SamplePath)
+	assert.NoError(err)
+	defer fh.Close()
+	buf, err := wav.NewDecoder(fh).FullPCMBuffer()
+	assert.NoError(err)
+	data := buf.AsFloat32Buffer().Data
+
+	// Load model
+	model, err := whisper.New(ModelPath)
+	assert.NoError(err)
+	defer model.Close()
+	ctx, err := model.NewContext()
+	assert.NoError(err)
+
+	// Feed the samples in 100ms chunks through a sliding window
+	var segments []whisper.Segment
+	stream, err := whisper.NewStreamingContext(ctx, func(segment whisper.Segment) {
+		segments = append(segments, segment)
+	})
+	assert.NoError(err)
+	stream.SetSlidingWindow(time.Second, 3*time.Second, 200*time.Millisecond)
+	for i := 0; i < len(data); i += whisper.SampleRate / 10 {
+		j := min(i+whisper.SampleRate/10, len(data))
+		assert.NoError(stream.Feed(data[i:j]))
+	}
+	assert.NoError(stream.Flush())
+
+	// Segments are numbered across the stream, and fall within the audio
+	duration := time.Duration(len(data)) * time.Second / whisper.SampleRate
+	for i, segment := range segments {
+		assert.Equal(i, segment.Num)
+		assert.LessOrEqual(segment.End, duration+time.Second)
+		t.Logf("[%6s->%6s] %s", segment.Start, segment.End, segment.Text)
+	}
+}
+
+// A context which returns a segment of scripted tokens for each pass
+type scriptedContext struct {
+	whisper.Context
+	passes  [][]whisper.Token
+	next    []whisper.Token
+	samples []int // Number of samples processed by each pass
+}
+
+func (context *scriptedContext) Process(data []float32, _ whisper.SegmentCallback, _ whisper.ProgressCallback) error {
+	context.samples = append(context.samples, len(data))
+	if len(context.passes) > 0 {
+		context.next, context.passes = context.passes[0], context.passes[1:]
+	}
+	return nil
+}
+
+func (context *scriptedContext) NextSegment() (whisper.Segment, error) {
+	if context.next == nil {
+		return whisper.Segment{}, io.EOF
+	}
+	segment := whisper.Segment{Tokens: context.next}
+	for _, token := range context.next {
+		if context.IsText(token) {
+			segment.Text += token.Text
+		}
+	}
+	context.next = nil
+	return segment, nil
+}
+
+func (context *scriptedContext) IsText(token whisper.Token) bool {
+	return token.Id < 100
+}
+
+func Test_Whisper_010(t *testing.T) {
+	assert := assert.New(t)
+
+	// The windows overlap by the kept audio, so the second window repeats
+	// the end of the first, and the third repeats all of the second
+	eot := whisper.Token{Id: 100, Text: "[_EOT_]"}
+	ctx := &scriptedContext{passes: [][]whisper.Token{
+		{{Id: 1, Text: " one"}, {Id: 2, Text: " two"}, {Id: 3, Text: " three"}, eot},
+		{{Id: 2, Text: " two"}, {Id: 3, Text: " three"}, {Id: 4, Text: " four"}, eot},
+		{{Id: 4, Text: " four"}, eot},
+	}}
+	var text []string
+	stream, err := whisper.NewStreamingContext(ctx, func(segment whisper.Segment) {
+		text = append(text, segment.Text)
+	})
+	assert.NoError(err)
+	stream.SetSlidingWindow(time.Second, 2*time.Second, 200*time.Millisecond)
+	assert.NoError(stream.Feed(make([]float32, 3*whisper.SampleRate)))
+	assert.NoError(stream.Flush())
+
+	// The repeated tokens are removed
+	assert.Equal([]string{" one two three", " four"}, text)
+}
+
+func Test_Whisper_011(t *testing.T) {
+	assert := assert.New(t)
+
+	// Two seconds of speech between silences
+	data := make([]float32, 4*whisper.SampleRate)
+	for i := whisper.SampleRate; i < 3*whisper.SampleRate; i++ {
+		data[i] = 0.5
+	}
+
+	// Each utterance is transcribed once it ends, not each window, with the
+	// silence which ended it
+	ctx := new(scriptedContext)
+	stream, err := whisper.NewStreamingContext(ctx, nil)
+	assert.NoError(err)
+	stream.SetWindow(500 * time.Millisecond)
+	stream.SetVAD(vad.New(whisper.SampleRate, 0, 300*time.Millisecond), nil)
+	stream.SetMaxUtterance(10 * time.Second)
+	for i := 0; i < len(data); i += whisper.SampleRate / 10 {
+		assert.NoError(stream.Feed(data[i : i+whisper.SampleRate/10]))
+	}
+	assert.NoError(stream.Flush())
+	assert.Len(ctx.samples, 1)
+	assert.InDelta(2.3*whisper.SampleRate, ctx.samples[0], 0.1*whisper.SampleRate)
+
+	// An utterance longer than the maximum is transcribed in parts
+	ctx = new(scriptedContext)
+	stream, err = whisper.NewStreamingContext(ctx, nil)
+	assert.NoError(err)
+	stream.SetVAD(vad.New(whisper.SampleRate, 0, 300*time.Millisecond), nil)
+	stream.SetMaxUtterance(time.Second)
+	assert.NoError(stream.Feed(data))
+	assert.NoError(stream.Flush())
+	assert.Equal(whisper.SampleRate, ctx.samples[0])
+	assert.Equal(whisper.SampleRate, ctx.samples[1])
+}
+
+func Test_Whisper_012(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+	if _, err := os.Stat(SamplePath); os.IsNotExist(err) {
+		t.Skip("Skipping test, sample not found:", SamplePath)
+	}
+
+	// Read samples
+	fh, err := os.Open(SamplePath)
+	assert.NoError(err)
+	defer fh.Close()
+	buf, err := wav.NewDecoder(fh).FullPCMBuffer()
+	assert.NoError(err)
+	data := buf.AsFloat32Buffer().Data
+
+	// Load model
+	model, err := whisper.New(ModelPath)
+	assert.NoError(err)
+	defer model.Close()
+
+	// Boost hotwords with the default state and with a state
+	ctx, err := model.NewContext()
+	assert.NoError(err)
+	assert.NoError(ctx.SetHotwords([]string{"ask not", "Kennedy"}, 5))
+	assert.NoError(ctx.Process(data, nil, nil))
+	state, err := model.NewState()
+	assert.NoError(err)
+	defer state.Close()
+	assert.NoError(state.SetHotwords([]string{"ask not", "Kennedy"}, 5))
+	assert.NoError(state.Process(data, nil, nil))
+	for {
+		segment, err := state.NextSegment()
+		if err != nil {
+			break
+		}
+		t.Log(segment.Text)
+	}
+
+	// Setting hotwords once the model is closed returns an error
+	closed, err := whisper.New(ModelPath)
+	assert.NoError(err)
+	ctx, err = closed.NewContext()
+	assert.NoError(err)
+	assert.NoError(closed.Close())
+	assert.ErrorIs(ctx.SetHotwords([]string{"Kennedy"}, 5), whisper.ErrInternalAppError)
+}
+
+func Test_Whisper_013(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+	if _, err := os.Stat(SamplePath); os.IsNotExist(err) {
+		t.Skip("Skipping test, sample not found:", SamplePath)
+	}
+
+	// Read samples
+	fh, err := os.Open(SamplePath)
+	assert.NoError(err)
+	defer fh.Close()
+	buf, err := wav.NewDecoder(fh).FullPCMBuffer()
+	assert.NoError(err)
+	data := buf.AsFloat32Buffer().Data
+
+	// Load model
+	model, err := whisper.New(ModelPath)
+	assert.NoError(err)
+	defer model.Close()
+	ctx, err := model.NewContext()
+	assert.NoError(err)
+
+	// Suppress the text tokens which are not digits
+	vocab := ctx.Vocab()
+	assert.NotEmpty(vocab)
+	var suppress []int
+	for id, text := range vocab {
+		if ctx.IsText(whisper.Token{Id: id}) && strings.Trim(text, " 0123456789") != "" {
+			suppress = append(suppress, id)
+		}
+	}
+	var calls int
+	ctx.SetLogitsFilter(func(tokens []whisper.Token, logits []float32) {
+		calls++
+		assert.Len(logits, len(vocab))
+		for _, id := range suppress {
+			logits[id] = float32(math.Inf(-1))
+		}
+	})
+	assert.NoError(ctx.Process(data, nil, nil))
+	assert.NotZero(calls)
+
+	// Only digits are decoded
+	for {
+		segment, err := ctx.NextSegment()
+		if err != nil {
+			break
+		}
+		assert.Empty(strings.Trim(segment.Text, " 0123456789"))
+	}
+}
+
+func Test_Whisper_014(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+	if _, err := os.Stat(SamplePath); os.IsNotExist(err) {
+		t.Skip("Skipping test, sample not found:", SamplePath)
+	}
+
+	// Read samples
+	fh, err := os.Open(SamplePath)
+	assert.NoError(err)
+	defer fh.Close()
+	buf, err := wav.NewDecoder(fh).FullPCMBuffer()
+	assert.NoError(err)
+	data := buf.AsFloat32Buffer().Data
+
+	// Load model
+	model, err := whisper.New(ModelPath)
+	assert.NoError(err)
+	defer model.Close()
+	ctx, err := model.NewContext()
+	assert.NoError(err)
+
+	// Process with alternatives, each of which is distinct, and the first of
+	// which is the segment text
+	ctx.SetAlternatives(3)
+	assert.NoError(ctx.Process(data, nil, nil))
+	for {
+		segment, err := ctx.NextSegment()
+		if err != nil {
+			break
+		}
+		assert.NotEmpty(segment.Alternatives)
+		assert.LessOrEqual(len(segment.Alternatives), 3)
+		assert.Equal(segment.Text, segment.Alternatives[0].Text)
+		texts := make(map[string]bool)
+		for i, alternative := range segment.Alternatives {
+			assert.False(texts[alternative.Text])
+			texts[alternative.Text] = true
+			assert.GreaterOrEqual(alternative.Confidence, float32(0))
+			assert.LessOrEqual(alternative.Confidence, float32(1))
+			if i > 1 {
+				assert.LessOrEqual(alternative.Confidence, segment.Alternatives[i-1].Confidence)
+			}
+		}
+	}
+}
+
+func Test_Whisper_015(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+	if _, err := os.Stat(SamplePath); os.IsNotExist(err) {
+		t.Skip("Skipping test, sample not found:", SamplePath)
+	}
+
+	// Read samples
+	fh, err := os.Open(SamplePath)
+	assert.NoError(err)
+	defer fh.Close()
+	buf, err := wav.NewDecoder(fh).FullPCMBuffer()
+	assert.NoError(err)
+	data := buf.AsFloat32Buffer().Data
+
+	// Load model
+	model, err := whisper.New(ModelPath)
+	assert.NoError(err)
+	defer model.Close()
+	ctx, err := model.NewContext()
+	assert.NoError(err)
+	other, err := model.NewContext()
+	assert.NoError(err)
+	state, err := model.NewState()
+	assert.NoError(err)
+	defer state.Close()
+
+	// Contexts which share the default state cannot process at once, but a
+	// context with its own state can
+	var progress int
+	assert.NoError(ctx.Process(data, nil, func(int) {
+		if progress++; progress == 1 {
+			assert.ErrorIs(other.Process(data[:whisper.SampleRate*2], nil, nil), whisper.ErrContextInUse)
+			assert.NoError(state.Process(data[:whisper.SampleRate*2], nil, nil))
+		}
+	}))
+	assert.NotZero(progress)
+
+	// Reset clears the segments, and the context can be reused
+	ctx.Reset()
+	_, err = ctx.NextSegment()
+	assert.ErrorIs(err, io.EOF)
+	assert.NoError(ctx.Process(data, nil, nil))
+}
+
+func Test_Whisper_016(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+	if _, err := os.Stat(SamplePath); os.IsNotExist(err) {
+		t.Skip("Skipping test, sample not found:", SamplePath)
+	}
+
+	// Read samples
+	fh, err := os.Open(SamplePath)
+	assert.NoError(err)
+	defer fh.Close()
+	buf, err := wav.NewDecoder(fh).FullPCMBuffer()
+	assert.NoError(err)
+	data := buf.AsFloat32Buffer().Data
+
+	// Load model
+	model, err := whisper.New(ModelPath)
+	assert.NoError(err)
+	defer model.Close()
+	ctx, err := model.NewContext()
+	assert.NoError(err)
+	state, err := model.NewState()
+	assert.NoError(err)
+	defer state.Close()
+
+	// The timings are of the last call to Process, for the default state and
+	// for a state of its own
+	for _, context := range []whisper.Context{ctx, state} {
+		assert.Zero(context.Timings())
+		context.SetDuration(5 * time.Second)
+		assert.NoError(context.Process(data, nil, nil))
+		timings := context.Timings()
+		assert.Equal(5*time.Second, timings.Audio)
+		assert.Positive(timings.Total)
+		assert.Positive(timings.Mel)
+		assert.Positive(timings.Encode)
+		assert.LessOrEqual(timings.Mel+timings.Encode+timings.Decode+timings.Sample, timings.Total)
+		assert.InDelta(timings.Total.Seconds()/timings.Audio.Seconds(), timings.RTF, 1e-9)
+	}
+}
+
+func Test_Whisper_017(t *testing.T) {
+	assert := assert.New(t)
+
+	// Skipped audio discards the buffered audio, and is counted in the
+	// times of later segments
+	ctx := &scriptedContext{passes: [][]whisper.Token{
+		{{Id: 1, Text: " one"}},
+	}}
+	var segments []whisper.Segment
+	stream, err := whisper.NewStreamingContext(ctx, func(segment whisper.Segment) {
+		segments = append(segments, segment)
+	})
+	assert.NoError(err)
+	assert.NoError(stream.Feed(make([]float32, whisper.SampleRate)))
+	stream.Skip(2 * time.Second)
+	assert.NoError(stream.Feed(make([]float32, whisper.SampleRate)))
+	assert.NoError(stream.Flush())
+	assert.Equal([]int{whisper.SampleRate}, ctx.samples)
+	if assert.Len(segments, 1) {
+		assert.Equal(3*time.Second, segments[0].Start)
+	}
+
+	// A speech region in progress ends
+	var events []vad.EventType
+	stream, err = whisper.NewStreamingContext(new(scriptedContext), nil)
+	assert.NoError(err)
+	stream.SetVAD(vad.New(whisper.SampleRate, 0, 300*time.Millisecond), func(evt vad.Event) {
+		events = append(events, evt.Type)
+	})
+	data := make([]float32, whisper.SampleRate)
+	for i := range data {
+		data[i] = 0.5
+	}
+	assert.NoError(stream.Feed(data))
+	stream.Skip(time.Second)
+	assert.Equal([]vad.EventType{vad.SpeechStart, vad.SpeechEnd}, events)
+}
+
+func Test_Whisper_018(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+	if _, err := os.Stat(SamplePath); os.IsNotExist(err) {
+		t.Skip("Skipping test, sample not found:", SamplePath)
+	}
+
+	// Read samples
+	fh, err := os.Open(SamplePath)
+	assert.NoError(err)
+	defer fh.Close()
+	buf, err := wav.NewDecoder(fh).FullPCMBuffer()
+	assert.NoError(err)
+	data := buf.AsFloat32Buffer().Data
+
+	// Load model
+	model, err := whisper.New(ModelPath)
+	assert.NoError(err)
+	defer model.Close()
+	ctx, err := model.NewContext()
+	assert.NoError(err)
+
+	// Each token is the last of its segment so far, and the segments are
+	// passed in order
+	tokens := make(map[int][]whisper.Token)
+	num := 0
+	ctx.SetTemperatureFallback(0)
+	ctx.SetTokenCallback(func(segment whisper.Segment, token whisper.Token) {
+		assert.True(ctx.IsText(token))
+		assert.GreaterOrEqual(segment.Num, num)
+		assert.NotEmpty(segment.Tokens)
+		assert.Equal(token.Id, segment.Tokens[len(segment.Tokens)-1].Id)
+		num = segment.Num
+		tokens[segment.Num] = append(tokens[segment.Num], token)
+	})
+	assert.NoError(ctx.Process(data, nil, nil))
+
+	// The tokens passed are the text tokens of each segment
+	for {
+		segment, err := ctx.NextSegment()
+		if err != nil {
+			break
+		}
+		var text []int
+		for _, token := range segment.Tokens {
+			if ctx.IsText(token) {
+				text = append(text, token.Id)
+			}
+		}
+		var passed []int
+		for _, token := range tokens[segment.Num] {
+			passed = append(passed, token.Id)
+		}
+		assert.Equal(text, passed)
+	}
+}
+
+func Test_Whisper_019(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+	if _, err := os.Stat(SamplePath); os.IsNotExist(err) {
+		t.Skip("Skipping test, sample not found:", SamplePath)
+	}
+
+	// Read samples
+	fh, err := os.Open(SamplePath)
+	assert.NoError(err)
+	defer fh.Close()
+	buf, err := wav.NewDecoder(fh).FullPCMBuffer()
+	assert.NoError(err)
+	data := buf.AsFloat32Buffer().Data
+
+	// Load model
+	model, err := whisper.New(ModelPath)
+	assert.NoError(err)
+	defer model.Close()
+	ctx, err := model.NewContext()
+	assert.NoError(err)
+
+	// Abort once the deadline has passed
+	deadline := time.Now()
+	ctx.SetAbortFunc(func() bool {
+		return time.Now().After(deadline)
+	})
+	assert.ErrorIs(ctx.Process(data, nil, nil), whisper.ErrAborted)
+
+	// A cancelled go context takes precedence over the abort function
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(ctx.ProcessContext(cancelled, data, nil, nil), context.Canceled)
+
+	// Process in full once the abort function is removed
+	ctx.SetAbortFunc(nil)
+	assert.NoError(ctx.Process(data, nil, nil))
+}
+
+func Test_Whisper_020(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+	if _, err := os.Stat(SamplePath); os.IsNotExist(err) {
+		t.Skip("Skipping test, sample not found:", SamplePath)
+	}
+
+	// Read samples
+	fh, err := os.Open(SamplePath)
+	assert.NoError(err)
+	defer fh.Close()
+	buf, err := wav.NewDecoder(fh).FullPCMBuffer()
+	assert.NoError(err)
+	data := buf.AsFloat32Buffer().Data
+
+	// Load model
+	model, err := whisper.New(ModelPath)
+	assert.NoError(err)
+	defer model.Close()
+	ctx, err := model.NewContext()
+	assert.NoError(err)
+
+	// The first window starts at the offset
+	var starts []time.Duration
+	ctx.SetOffset(2 * time.Second)
+	ctx.SetEncoderBeginCallback(func(start time.Duration) bool {
+		starts = append(starts, start)
+		return true
+	})
+	assert.NoError(ctx.Process(data, nil, nil))
+	assert.NotEmpty(starts)
+	if len(starts) > 0 {
+		assert.Equal(2*time.Second, starts[0])
+	}
+
+	// Vetoing the first window stops processing without segments
+	ctx.SetEncoderBeginCallback(func(time.Duration) bool {
+		return false
+	})
+	assert.NoError(ctx.Process(data, nil, nil))
+	_, err = ctx.NextSegment()
+	assert.ErrorIs(err, io.EOF)
+}
+
+func Test_Whisper_021(t *testing.T) {
+	for name, path := range GoldenFixtures {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			model, data := goldenSetup(t, path)
+			defer model.Close()
+
+			// Transcribe the file in one pass
+			ctx, err := model.NewContext()
+			assert.NoError(err)
+			assert.NoError(ctx.Process(data, nil, nil))
+			var text []string
+			for {
+				segment, err := ctx.NextSegment()
+				if err != nil {
+					assert.ErrorIs(err, io.EOF)
+					break
+				}
+				text = append(text, segment.Text)
+			}
+			goldenCompare(t, name, strings.Join(text, " "), GoldenFileTolerance, *update)
+		})
+	}
+}
+
+func Test_Whisper_022(t *testing.T) {
+	for name, path := range GoldenFixtures {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			model, data := goldenSetup(t, path)
+			defer model.Close()
+
+			// Stream the file in chunks of 100ms, as it would arrive live
+			ctx, err := model.NewContext()
+			assert.NoError(err)
+			var text []string
+			stream, err := whisper.NewStreamingContext(ctx, func(segment whisper.Segment) {
+				text = append(text, segment.Text)
+			})
+			assert.NoError(err)
+			for i := 0; i < len(data); i += whisper.SampleRate / 10 {
+				assert.NoError(stream.Feed(data[i:min(i+whisper.SampleRate/10, len(data))]))
+			}
+			assert.NoError(stream.Flush())
+			goldenCompare(t, name, strings.Join(text, " "), GoldenStreamTolerance, false)
+		})
+	}
+}
+
+func Test_Whisper_023(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+
+	// Load model
+	model, err := whisper.New(ModelPath)
+	assert.NoError(err)
+	defer model.Close()
+
+	// The text of the tokens is the text which was tokenized
+	tokens, err := model.Tokenize(" ask not what your country can do for you")
+	assert.NoError(err)
+	assert.NotEmpty(tokens)
+	var text string
+	for _, token := range tokens {
+		assert.Equal(token.Text, model.TokenToString(token.Id))
+		text += token.Text
+	}
+	assert.Equal(" ask not what your country can do for you", text)
+
+	// Empty text has no tokens, and ids outside the vocabulary no text
+	tokens, err = model.Tokenize("")
+	assert.NoError(err)
+	assert.Empty(tokens)
+	assert.Empty(model.TokenToString(-1))
+	assert.Empty(model.TokenToString(model.Info().Vocab + 1000))
+}
+
+func Test_Whisper_024(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+
+	// Load model
+	model, err := whisper.New(ModelPath)
+	assert.NoError(err)
+	defer model.Close()
+	context, err := model.NewContext()
+	assert.NoError(err)
+
+	// The special tokens are those the context tests for
+	special := model.SpecialTokens()
+	assert.True(context.IsEOT(whisper.Token{Id: special.EOT}))
+	assert.True(context.IsSOT(whisper.Token{Id: special.SOT}))
+	assert.True(context.IsPREV(whisper.Token{Id: special.PREV}))
+	assert.True(context.IsSOLM(whisper.Token{Id: special.SOLM}))
+	assert.True(context.IsNOT(whisper.Token{Id: special.NOT}))
+	assert.True(context.IsBEG(whisper.Token{Id: special.BEG}))
+	assert.False(context.IsText(whisper.Token{Id: special.BEG + 1}))
+
+	// Language tokens follow the start of transcription token
+	id, err := model.LanguageToken("en")
+	assert.NoError(err)
+	assert.Equal(special.SOT+1, id)
+	assert.True(context.IsLANG(whisper.Token{Id: id}, "en"))
+	_, err = model.LanguageToken("xx")
+	assert.ErrorIs(err, whisper.ErrUnsupportedLanguage)
+}
+
+func Test_Whisper_025(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+
+	// Load model
+	model, err := whisper.New(ModelPath)
+	assert.NoError(err)
+	defer model.Close()
+
+	for _, newContext := range []func() (whisper.Context, error){
+		model.NewContext,
+		func() (whisper.Context, error) { return model.NewState() },
+	} {
+		context, err := newContext()
+		assert.NoError(err)
+
+		// The spectrogram has a frame for each 10ms of audio
+		mel, err := context.PCMToMel(make([]float32, whisper.SampleRate*2))
+		assert.NoError(err)
+		assert.Equal(model.Info().Mels, mel.Mels)
+		assert.InDelta(2*time.Second/whisper.MelFrame, mel.Frames, 2)
+		assert.Len(mel.Data, mel.Mels*mel.Frames)
+
+		// The spectrogram is processed in place of audio, until reset
+		assert.ErrorIs(context.SetMel(whisper.Mel{Mels: mel.Mels + 1, Frames: 1, Data: make([]float32, mel.Mels+1)}), whisper.ErrInvalidMel)
+		assert.NoError(context.SetMel(mel))
+		assert.NoError(context.Process(nil, nil, nil))
+		assert.InDelta(2*time.Second, context.Timings().Audio, float64(2*whisper.MelFrame))
+		context.Reset()
+		assert.ErrorIs(context.Process(nil, nil, nil), whisper.ErrProcessingFailed)
+		if state, ok := context.(whisper.StateContext); ok {
+			assert.NoError(state.Close())
+		}
+	}
+}
+
+func Test_Whisper_026(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+
+	// Load model
+	model, err := whisper.New(ModelPath)
+	assert.NoError(err)
+	defer model.Close()
+
+	for _, newContext := range []func() (whisper.Context, error){
+		model.NewContext,
+		func() (whisper.Context, error) { return model.NewState() },
+	} {
+		context, err := newContext()
+		assert.NoError(err)
+
+		// The output of the encoder ends with the audio, across the windows
+		embedding, err := context.Encode(make([]float32, whisper.SampleRate*40))
+		assert.NoError(err)
+		assert.Equal(model.Info().AudioState, embedding.Width)
+		assert.InDelta(40*time.Second/whisper.EmbeddingFrame, embedding.Frames, 2)
+		assert.Len(embedding.Data, embedding.Frames*embedding.Width)
+
+		// Without audio, the spectrogram which is set is encoded
+		_, err = context.Encode(nil)
+		assert.ErrorIs(err, whisper.ErrProcessingFailed)
+		mel, err := context.PCMToMel(make([]float32, whisper.SampleRate))
+		assert.NoError(err)
+		assert.NoError(context.SetMel(mel))
+		embedding, err = context.Encode(nil)
+		assert.NoError(err)
+		assert.Equal((mel.Frames+1)/2, embedding.Frames)
+		if state, ok := context.(whisper.StateContext); ok {
+			assert.NoError(state.Close())
+		}
+	}
+}
+
+func Test_Whisper_027(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+
+	// Load model
+	model, err := whisper.New(ModelPath)
+	assert.NoError(err)
+	defer model.Close()
+
+	// Contexts can be created concurrently
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%2 == 0 {
+				_, err := model.NewContext()
+				assert.NoError(err)
+			} else if state, err := model.NewState(); assert.NoError(err) {
+				assert.NoError(state.Close())
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Changing a context while it processes on another goroutine panics
+	context, err := model.NewState()
+	assert.NoError(err)
+	defer context.Close()
+	started, resume := make(chan struct{}), make(chan struct{})
+	var once sync.Once
+	context.SetEncoderBeginCallback(func(time.Duration) bool {
+		once.Do(func() {
+			close(started)
+			<-resume
+		})
+		return true
+	})
+	done := make(chan error)
+	go func() {
+		done <- context.Process(make([]float32, whisper.SampleRate*2), nil, nil)
+	}()
+	<-started
+	assert.PanicsWithValue("whisper: Context.SetTranslate called while the context is processing; use a context from one goroutine at a time", func() {
+		context.SetTranslate(true)
+	})
+	assert.ErrorIs(context.Process(make([]float32, whisper.SampleRate*2), nil, nil), whisper.ErrContextInUse)
+	close(resume)
+	assert.NoError(<-done)
+	assert.NotPanics(func() {
+		context.SetTranslate(true)
+	})
+}
+
+func Test_Whisper_028(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+
+	// Load model
+	model, err := whisper.New(ModelPath)
+	assert.NoError(err)
+
+	// Closing the model waits for a context which is processing, whose
+	// callbacks can call the model meanwhile
+	context, err := model.NewState()
+	assert.NoError(err)
+	started, resume := make(chan struct{}), make(chan struct{})
+	var once sync.Once
+	var info whisper.ModelInfo
+	context.SetEncoderBeginCallback(func(time.Duration) bool {
+		once.Do(func() {
+			close(started)
+			<-resume
+			info = model.Info()
+		})
+		return true
+	})
+	done := make(chan error)
+	go func() {
+		done <- context.Process(make([]float32, whisper.SampleRate*2), nil, nil)
+	}()
+	<-started
+	closed := make(chan error)
+	go func() {
+		closed <- model.Close()
+	}()
+	select {
+	case <-closed:
+		t.Fatal("model closed while a context is processing")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(resume)
+	assert.NoError(<-done)
+	assert.NoError(<-closed)
+	assert.NotZero(info.Mels)
+
+	// Once the model is closed, its contexts return an error
+	assert.ErrorIs(context.Process(make([]float32, whisper.SampleRate*2), nil, nil), whisper.ErrInternalAppError)
+	assert.NoError(context.Close())
+	_, err = model.NewContext()
+	assert.ErrorIs(err, whisper.ErrInternalAppError)
+}
+
+func Test_Whisper_029(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+
+	// Load model
+	model, err := whisper.New(ModelPath)
+	assert.NoError(err)
+	defer model.Close()
+
+	// Reading the segments of a context sharing the default state panics
+	// while another context processes with it
+	a, err := model.NewContext()
+	assert.NoError(err)
+	b, err := model.NewContext()
+	assert.NoError(err)
+	started, resume := make(chan struct{}), make(chan struct{})
+	var once sync.Once
+	a.SetEncoderBeginCallback(func(time.Duration) bool {
+		once.Do(func() {
+			close(started)
+			<-resume
+		})
+		return true
+	})
+	done := make(chan error)
+	go func() {
+		done <- a.Process(make([]float32, whisper.SampleRate*2), nil, nil)
+	}()
+	<-started
+	assert.PanicsWithValue("whisper: Context.Segments called while another context processes with the default state of the model; use Model.NewState to process concurrently", func() {
+		b.Segments()
+	})
+	assert.Panics(func() {
+		b.NextSegment()
+	})
+	close(resume)
+	assert.NoError(<-done)
+	assert.NotPanics(func() {
+		b.Segments()
+	})
+}
+
+func Test_Whisper_030(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+	if _, err := os.Stat(SamplePath); os.IsNotExist(err) {
+		t.Skip("Skipping test, sample not found:", SamplePath)
+	}
+
+	// Read samples, repeated to span several decode windows
+	fh, err := os.Open(SamplePath)
+	assert.NoError(err)
+	defer fh.Close()
+	buf, err := wav.NewDecoder(fh).FullPCMBuffer()
+	assert.NoError(err)
+	var data []float32
+	for i := 0; i < 4; i++ {
+		data = append(data, buf.AsFloat32Buffer().Data...)
+	}
+
+	// Load model
+	model, err := whisper.New(ModelPath)
+	assert.NoError(err)
+	defer model.Close()
+
+	// Each segment is tagged with the language detected for its window
+	context, err := model.NewState()
+	assert.NoError(err)
+	defer context.Close()
+	assert.NoError(context.SetLanguage("auto"))
+	var windows []time.Duration
+	context.SetEncoderBeginCallback(func(start time.Duration) bool {
+		windows = append(windows, start)
+		return true
+	})
+	assert.NoError(context.Process(data, nil, nil))
+	if assert.GreaterOrEqual(len(windows), 2) {
+		assert.Greater(windows[1], windows[0])
+	}
+	for _, segment := range context.Segments() {
+		assert.NotEmpty(segment.Language)
+		t.Log(segment.Start, segment.Language, segment.Text)
+	}
+}
+
+// Load the golden model and read the samples of a fixture, or skip the test
+// when either is missing
+func goldenSetup(t *testing.T, path string) (whisper.Model, []float32) {
+	t.Helper()
+	if _, err := os.Stat(GoldenModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", GoldenModelPath)
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		t.Skip("Skipping test, sample not found:", path)
+	}
+	fh, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+	buf, err := wav.NewDecoder(fh).FullPCMBuffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	model, err := whisper.New(GoldenModelPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return model, buf.AsFloat32Buffer().Data
+}
+
+// Compare a transcript with the golden transcript of a fixture, failing when
+// the word error rate exceeds the tolerance, or write the golden transcript
+func goldenCompare(t *testing.T, name, text string, tolerance float64, write bool) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	text = strings.TrimSpace(strings.Join(strings.Fields(text), " "))
+	if write {
+		if err := os.WriteFile(path, []byte(text+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	golden, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rate := wordErrorRate(string(golden), text)
+	t.Logf("wer=%.3f %q", rate, text)
+	if rate > tolerance {
+		t.Errorf("word error rate %.3f exceeds %.3f\n  golden: %s\n  actual: %s", rate, tolerance, strings.TrimSpace(string(golden)), text)
+	}
+}
+
+// Return the word error rate of a transcript against a reference, ignoring
+// case and punctuation
+func wordErrorRate(reference, text string) float64 {
+	words := func(str string) []string {
+		return strings.FieldsFunc(strings.ToLower(str), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+		})
+	}
+	ref, hyp := words(reference), words(text)
+	if len(ref) == 0 {
+		return float64(len(hyp))
+	}
+
+	// The edit distance between the words, a row at a time
+	prev := make([]int, len(hyp)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ref); i++ {
+		row := make([]int, len(hyp)+1)
+		row[0] = i
+		for j := 1; j <= len(hyp); j++ {
+			cost := 1
+			if ref[i-1] == hyp[j-1] {
+				cost = 0
+			}
+			row[j] = min(prev[j]+1, row[j-1]+1, prev[j-1]+cost)
+		}
+		prev = row
+	}
+	return float64(prev[len(hyp)]) / float64(len(ref))
+}
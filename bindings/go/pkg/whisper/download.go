@@ -0,0 +1,224 @@
+package whisper
+
+import (
+	gocontext "context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// DownloadProgress is called as a model is downloaded, with the number of
+// bytes of the model written so far and its total size, or -1 when the size
+// is not known
+type DownloadProgress func(count, total int64)
+
+// Writes the downloaded data to a file and to the checksum, and reports the
+// progress
+type downloadWriter struct {
+	io.Writer
+	count, total int64
+	progress     DownloadProgress
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+var (
+	// DownloadURL is the location models are downloaded from, which can be
+	// changed to download from a mirror
+	DownloadURL = "https://huggingface.co/ggerganov/whisper.cpp/resolve/main"
+
+	// DiarizeDownloadURL is the location tinydiarize models are downloaded
+	// from
+	DiarizeDownloadURL = "https://huggingface.co/akashmjn/tinydiarize-whisper.cpp/resolve/main"
+)
+
+// Models are the names of the models which can be downloaded, as listed by
+// the models/download-ggml-model.sh script
+var Models = []string{
+	"tiny", "tiny.en", "tiny-q5_1", "tiny.en-q5_1",
+	"base", "base.en", "base-q5_1", "base.en-q5_1",
+	"small", "small.en", "small.en-tdrz", "small-q5_1", "small.en-q5_1",
+	"medium", "medium.en", "medium-q5_0", "medium.en-q5_0",
+	"large-v1", "large-v2", "large-v2-q5_0", "large-v3", "large-v3-q5_0",
+}
+
+// SHA-1 checksums of the models, as listed in models/README.md. Other models
+// are verified with the SHA-256 checksum returned by Hugging Face.
+var modelSHA1 = map[string]string{
+	"tiny":          "bd577a113a864445d4c299885e0cb97d4ba92b5f",
+	"tiny.en":       "c78c86eb1a8faa21b369bcd33207cc90d64ae9df",
+	"base":          "465707469ff3a37a2b9b8d8f89f2f99de7299dac",
+	"base.en":       "137c40403d78fd54d454da0f9bd998f78703390c",
+	"small":         "55356645c2b361a969dfd0ef2c5a50d530afd8d5",
+	"small.en":      "db8a495a91d927739e50b3fc1cc4c6b8f6c2d022",
+	"small.en-tdrz": "b6c6e7e89af1a35c08e6de56b66ca6a02a2fdfa1",
+	"medium":        "fd9727b6e1217c2f614f9b698455c4ffd82463b4",
+	"medium.en":     "8c30f0e44ce9560643ebd10bbe50cd20eafd3723",
+	"large-v1":      "b1caaf735c4cc1429223d5a74f0f4d0b9b59a299",
+	"large-v2":      "0f4c8e34f21cf1a914c59d8b3ce882345ad349d6",
+	"large-v2-q5_0": "00e39f2196344e901b3a2bd5814807a769bd1630",
+	"large-v3":      "ad82bf6a9043ceed055076d0fd39f5f186ff8062",
+	"large-v3-q5_0": "e6e2ed78495d403bef4b7cff42ef4aaadcfea8de",
+}
+
+const (
+	// Header in which Hugging Face returns the SHA-256 checksum of a file
+	headerLinkedEtag = "X-Linked-Etag"
+
+	// Suffix of a partial download
+	partExt = ".part"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Download the model with a name, such as "base.en", to the directory as
+// ggml-<name>.bin, and return the path of the model. When the model already
+// exists, it is not downloaded again.
+func Download(ctx gocontext.Context, name, dir string) (string, error) {
+	return DownloadWithProgress(ctx, name, dir, nil)
+}
+
+// DownloadWithProgress downloads a model as with Download, calling the
+// progress function as data is written. An interrupted download is kept
+// alongside the model with a .part extension, and is resumed on the next
+// call. The model is verified with its checksum before it is moved into
+// place.
+func DownloadWithProgress(ctx gocontext.Context, name, dir string, progress DownloadProgress) (string, error) {
+	name = strings.TrimSuffix(strings.TrimPrefix(name, "ggml-"), ".bin")
+	if !slices.Contains(Models, name) {
+		return "", fmt.Errorf("%w: %q", ErrUnknownModel, name)
+	}
+	path := filepath.Join(dir, "ggml-"+name+".bin")
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+	url := DownloadURL
+	if strings.HasSuffix(name, "-tdrz") {
+		url = DiarizeDownloadURL
+	}
+	url += "/ggml-" + name + ".bin"
+
+	// Resume a partial download
+	w, err := os.OpenFile(path+partExt, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return "", err
+	}
+	defer w.Close()
+	offset, err := w.Seek(0, io.SeekEnd)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		// The server ignored the range, so start again
+		if err := w.Truncate(0); err != nil {
+			return "", err
+		}
+		if offset, err = w.Seek(0, io.SeekStart); err != nil {
+			return "", err
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The partial download is no shorter than the model, so start again
+		w.Close()
+		if err := os.Remove(path + partExt); err != nil {
+			return "", err
+		}
+		return DownloadWithProgress(ctx, name, dir, progress)
+	default:
+		return "", fmt.Errorf("%s: %s", url, resp.Status)
+	}
+
+	// Hash the partial download, then write and hash the remainder
+	checksum, expected := modelChecksum(name, resp)
+	if checksum != nil {
+		if _, err := w.Seek(0, io.SeekStart); err != nil {
+			return "", err
+		}
+		if _, err := io.Copy(checksum, w); err != nil {
+			return "", err
+		}
+	}
+	writer := &downloadWriter{Writer: w, count: offset, total: -1, progress: progress}
+	if checksum != nil {
+		writer.Writer = io.MultiWriter(w, checksum)
+	}
+	if resp.ContentLength >= 0 {
+		writer.total = offset + resp.ContentLength
+	}
+	if _, err := io.Copy(writer, resp.Body); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	// Verify the checksum, and discard the download if it does not match
+	if checksum != nil {
+		if actual := hex.EncodeToString(checksum.Sum(nil)); actual != expected {
+			os.Remove(path + partExt)
+			return "", fmt.Errorf("%w: %q", ErrChecksumMismatch, name)
+		}
+	}
+
+	// Move the model into place
+	if err := os.Rename(path+partExt, path); err != nil {
+		return "", err
+	}
+
+	// Return success
+	return path, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Write data and report the progress
+func (w *downloadWriter) Write(data []byte) (int, error) {
+	n, err := w.Writer.Write(data)
+	w.count += int64(n)
+	if w.progress != nil {
+		w.progress(w.count, w.total)
+	}
+	return n, err
+}
+
+// Return the hash and expected checksum of a model, or nil if the checksum
+// is not known. The Hugging Face checksum is returned with the redirect to
+// the file, so the responses are searched in turn.
+func modelChecksum(name string, resp *http.Response) (hash.Hash, string) {
+	if sum, exists := modelSHA1[name]; exists {
+		return sha1.New(), sum
+	}
+	for ; resp != nil; resp = resp.Request.Response {
+		if etag := strings.Trim(resp.Header.Get(headerLinkedEtag), `"`); len(etag) == sha256.Size*2 {
+			return sha256.New(), strings.ToLower(etag)
+		}
+	}
+	return nil, ""
+}
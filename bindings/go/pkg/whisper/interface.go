@@ -1,8 +1,12 @@
 package whisper
 
 import (
+	gocontext "context"
 	"io"
 	"time"
+
+	// Packages
+	vad "github.com/ggerganov/whisper.cpp/bindings/go/pkg/vad"
 )
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -16,50 +20,286 @@ type SegmentCallback func(Segment)
 // processing. It is called during the Process function
 type ProgressCallback func(int)
 
+// TokenCallback is the callback function for tokens as they are decoded. It
+// is called during the Process function with each text token and the
+// segment it belongs to, which holds the text and tokens of the segment
+// decoded so far. The segment is passed in full to the SegmentCallback once
+// it is complete, with the time it ends.
+type TokenCallback func(Segment, Token)
+
+// EncoderBeginCallback is called during the Process function before each
+// window of up to 30 seconds of audio is encoded, with the time the window
+// starts, which is the end of the last segment decoded, or 30 seconds after
+// the start of the previous window when it had no segments. Returning false
+// stops processing, and the segments decoded before are kept.
+type EncoderBeginCallback func(time.Duration) bool
+
+// PartialCallback is the callback function for interim hypotheses in a
+// streaming context. It is called with the text which has not yet been
+// finalized, merged into a single segment, which may change in later calls
+type PartialCallback func(Segment)
+
+// LogitsFilterCallback is called before each token is sampled, with the
+// tokens decoded so far in the segment and the logits of the next token,
+// indexed by token id. It can modify the logits, for example setting them
+// to negative infinity to suppress tokens. The tokens and logits are only
+// valid during the call, and must be copied to be kept.
+type LogitsFilterCallback func(tokens []Token, logits []float32)
+
 // Model is the interface to a whisper model. Create a new model with the
-// function whisper.New(string)
+// function whisper.New(string) or whisper.NewWithParams(string, ContextParams).
+// Contexts can be created on concurrent goroutines. Closing the model waits
+// for the contexts which are processing, after which its contexts return
+// ErrInternalAppError. Callbacks can call the model while it is closing, but
+// must not close it themselves, as Close would wait for them.
 type Model interface {
 	io.Closer
 
 	// Return a new speech-to-text context.
 	NewContext() (Context, error)
 
+	// Return a new speech-to-text context with its own decoding state, which
+	// can process on its own goroutine, concurrently with other contexts
+	// created with NewState, sharing the weights of the model. Close the
+	// context to release the state.
+	NewState() (StateContext, error)
+
 	// Return true if the model is multilingual.
 	IsMultilingual() bool
 
 	// Return all languages supported.
 	Languages() []string
+
+	// Return the metadata of the model.
+	Info() ModelInfo
+
+	// Return the tokens of text, with their ids and text, for example to
+	// count the tokens of a prompt.
+	Tokenize(text string) ([]Token, error)
+
+	// Return the text of a token id, or an empty string when the id is not
+	// in the vocabulary.
+	TokenToString(id int) string
+
+	// Return the ids of the special tokens of the model, for example to
+	// build custom decoding logic.
+	SpecialTokens() SpecialTokens
+
+	// Return the id of the token of a language, or ErrUnsupportedLanguage
+	// when the language is not recognized.
+	LanguageToken(lang string) (int, error)
 }
 
-// Context is the speach recognition context.
+// StateContext is a context with its own decoding state, created with
+// Model.NewState. It must be closed before the model.
+type StateContext interface {
+	Context
+	io.Closer
+}
+
+// ModelInfo is the metadata of a model, as read from the model file
+type ModelInfo struct {
+	Type         string // Size of the model, such as "tiny" or "large"
+	Quantization string // Type of the weights, such as "f16" or "q5_0"
+	Multilingual bool   // True if the model supports languages other than English
+	Vocab        int    // Number of tokens in the vocabulary
+	Mels         int    // Number of mel frequency bands, 128 for large-v3 and 80 otherwise
+	AudioCtx     int    // Number of audio frames in the encoder context
+	AudioState   int    // Width of the encoder
+	AudioHead    int    // Number of encoder attention heads
+	AudioLayer   int    // Number of encoder layers
+	TextCtx      int    // Number of tokens in the decoder context
+	TextState    int    // Width of the decoder
+	TextHead     int    // Number of decoder attention heads
+	TextLayer    int    // Number of decoder layers
+}
+
+// SpecialTokens are the ids of the special tokens of a model, which are
+// returned by Model.SpecialTokens
+type SpecialTokens struct {
+	EOT        int // End of transcription
+	SOT        int // Start of transcription
+	PREV       int // Start of the previous text, passed as a prompt
+	SOLM       int // Start of language model
+	NOT        int // No timestamps
+	BEG        int // Timestamp at zero, with each following id 20ms later
+	Translate  int // Translate task
+	Transcribe int // Transcribe task
+}
+
+// Pool hands out speech recognition contexts to concurrent callers, up to a
+// maximum number of contexts in use at once. Create a new pool with the
+// function whisper.NewPool(string, uint, PoolPolicy),
+// whisper.NewPoolWithParams(string, ContextParams, uint, PoolPolicy),
+// whisper.NewSharedPool(string, ContextParams, uint, PoolPolicy) or
+// whisper.NewPoolWithCopies(string, ContextParams, uint, PoolPolicy)
+type Pool interface {
+	io.Closer
+
+	// Return a context, waiting for one to become available according to
+	// the queueing policy, or until the go context is done.
+	Get(gocontext.Context) (Context, error)
+
+	// Return a context obtained with Get to the pool.
+	Put(Context) error
+
+	// Return the maximum number of contexts in use at once.
+	Size() int
+
+	// Return the path of the model.
+	Path() string
+
+	// Return the metadata of the model.
+	Info() ModelInfo
+
+	// Load the model at a path and swap it in for new contexts. Contexts in
+	// use keep the previous model, which is released once they are all
+	// returned.
+	Reload(string) error
+}
+
+// ContextParams are the parameters used when loading a model. Selecting a GPU
+// device and flash attention are not supported by this version of whisper.cpp.
+// The Core ML encoder is always used when whisper.cpp is built with Core ML
+// support, and cannot be selected at runtime.
+type ContextParams struct {
+	UseGPU         bool   // Use the GPU, when whisper.cpp is built with GPU support
+	OpenVINODevice string // Run the encoder with OpenVINO on this device, such as "CPU" or "GPU", or empty to not use OpenVINO
+}
+
+// PoolPolicy determines what Pool.Get does when all contexts are in use
+type PoolPolicy int
+
+// Context is the speach recognition context. A context is not safe for
+// concurrent use, and should be used by one goroutine at a time. Contexts
+// created with Model.NewContext share the decoding state of the model, so
+// only one of them can process at once. Processing while the decoding state
+// is in use returns ErrContextInUse. Use Model.NewState or a Pool to
+// process concurrently. Changing the parameters of a context, reading its
+// segments, resetting it or closing it while it processes, on another
+// goroutine or from a callback, panics. Reading the segments of a context
+// created with Model.NewContext also panics while another such context
+// processes, as they share the results.
 type Context interface {
 	SetLanguage(string) error // Set the language to use for speech recognition, use "auto" for auto detect language.
 	SetTranslate(bool)        // Set translate flag
 	IsMultilingual() bool     // Return true if the model is multilingual.
 	Language() string         // Get language
+	DetectedLanguage() string // Get the language detected by the last call to Process
 
-	SetOffset(time.Duration)        // Set offset
-	SetDuration(time.Duration)      // Set duration
-	SetThreads(uint)                // Set number of threads to use
+	SetOffset(time.Duration)        // Set offset into the audio at which processing starts
+	SetDuration(time.Duration)      // Set duration of audio to process from the offset, or zero for the rest
+	SetThreads(uint)                // Set number of threads to use, or zero for one thread per CPU
 	SetSpeedup(bool)                // Set speedup flag
 	SetSplitOnWord(bool)            // Set split on word flag
 	SetTokenThreshold(float32)      // Set timestamp token probability threshold
 	SetTokenSumThreshold(float32)   // Set timestamp token sum probability threshold
 	SetMaxSegmentLength(uint)       // Set max segment length in characters
 	SetTokenTimestamps(bool)        // Set token timestamps flag
+	SetWordTimestamps(bool)         // Set word timestamps flag, which populates Segment.Words
 	SetMaxTokensPerSegment(uint)    // Set max tokens per segment (0 = no limit)
 	SetAudioCtx(uint)               // Set audio encoder context
+	SetDiarize(bool)                // Set tinydiarize speaker turn detection flag
 	SetInitialPrompt(prompt string) // Set initial prompt
 
+	SetTemperature(float32)         // Set initial decoding temperature
+	SetTemperatureFallback(float32) // Set temperature increment on decoding failure (0 = no fallback)
+	SetEntropyThreshold(float32)    // Set entropy threshold for decoding fallback
+	SetLogprobThreshold(float32)    // Set average log probability threshold for decoding fallback
+	SetNoSpeechThreshold(float32)   // Set no speech probability threshold
+	SetBeamSize(uint)               // Set beam size, and use beam search when greater than one
+	SetBestOf(uint)                 // Set number of candidates when sampling with non-zero temperature
+	SetSuppressBlank(bool)          // Set suppress blank flag, to suppress blank outputs at the start of a segment
+	SetSuppressNonSpeech(bool)      // Set suppress non-speech tokens flag, to suppress tokens such as "[Music]"
+	SetNoContext(bool)              // Set no context flag, to decode without the previous text as context
+
+	// Set hotwords, such as names and domain terms, which bias decoding
+	// through the initial prompt, and when boost is not zero, by adding it
+	// to the logits of the tokens of each hotword.
+	SetHotwords(words []string, boost float32) error
+
+	// Set the number of hypotheses of each segment returned in
+	// Segment.Alternatives, including the segment text. When greater than
+	// one, the audio of each segment is sampled again for the others, so
+	// processing takes longer.
+	SetAlternatives(uint)
+
+	// Set the callback which filters the logits of each token before it is
+	// sampled, or nil to remove it. It is called after hotwords are boosted.
+	SetLogitsFilter(LogitsFilterCallback)
+
+	// Return the text of each token in the vocabulary, indexed by token id,
+	// for use by a logits filter.
+	Vocab() []string
+
+	// Set the callback for tokens as they are decoded, or nil to remove it.
+	// With beam search, or when decoding falls back to a higher temperature,
+	// tokens are passed for a hypothesis which may not be kept, so the
+	// segments passed to the segment callback are the final text.
+	SetTokenCallback(TokenCallback)
+
+	// Set the function which is called as audio is processed, and stops
+	// processing when it returns true, or nil to remove it. Process then
+	// returns ErrAborted, and NextSegment returns the segments of the
+	// windows decoded before. The tokens of the window in progress have only
+	// been passed to the token callback, so a time-budgeted decode can
+	// stop after a deadline and use the tokens decoded so far.
+	SetAbortFunc(func() bool)
+
+	// Set the callback which is called before each window is encoded, or
+	// nil to remove it, to time each window or stop processing when the
+	// rest of the audio does not need to be decoded, such as when it is
+	// silent.
+	SetEncoderBeginCallback(EncoderBeginCallback)
+
 	// Process mono audio data and return any errors.
 	// If defined, newly generated segments are passed to the
 	// callback function during processing.
 	Process([]float32, SegmentCallback, ProgressCallback) error
 
+	// Process mono audio data as Process. Processing is aborted when the
+	// go context is cancelled, and the context error is returned.
+	ProcessContext(gocontext.Context, []float32, SegmentCallback, ProgressCallback) error
+
+	// Detect the spoken language of mono audio data, returning the most
+	// probable language and the probabilities of all languages in
+	// descending order. Pass the first few seconds of audio to probe the
+	// language before processing.
+	DetectLanguage([]float32) (string, []LanguageProb, error)
+
+	// Compute the log mel spectrogram of mono audio data, for example to
+	// cache it, or to change it before it is set with SetMel. Returns
+	// ErrMelUnavailable when whisper.cpp cannot return the spectrogram.
+	PCMToMel([]float32) (Mel, error)
+
+	// Set a log mel spectrogram, which calls to Process with no audio data
+	// process in place of audio, until the context is reset. Returns
+	// ErrInvalidMel when the number of mel bands is not that of the model.
+	// Alternatives are not decoded from a spectrogram.
+	SetMel(Mel) error
+
+	// Run the encoder on mono audio data, or on the spectrogram set with
+	// SetMel when there is no audio data, and return its output, for example
+	// to compare audio or to identify languages. The audio is encoded in
+	// windows of 30 seconds, and the output ends with the audio. Returns
+	// ErrEncoderOutputUnavailable when whisper.cpp cannot return the output.
+	Encode([]float32) (Embedding, error)
+
 	// After process is called, return segments until the end of the stream
 	// is reached, when io.EOF is returned.
 	NextSegment() (Segment, error)
 
+	// Return the segments of the last call to Process, without moving the
+	// position of NextSegment. With Go 1.23, whisper.AllSegments returns an
+	// iterator over the segments, for use with range.
+	Segments() []Segment
+
+	// Clear the segments of the last call to Process, and the text which it
+	// would pass as context to the next call, so that the context can be
+	// reused for an unrelated input, and the spectrogram set with SetMel.
+	// The parameters are kept. A new context starts reset.
+	Reset()
+
 	IsBEG(Token) bool          // Test for "begin" token
 	IsSOT(Token) bool          // Test for "start of transcription" token
 	IsEOT(Token) bool          // Test for "end of transcription" token
@@ -72,10 +312,52 @@ type Context interface {
 	// Timings
 	PrintTimings()
 	ResetTimings()
+	Timings() Timings // Return the timings of the last call to Process
 
 	SystemInfo() string
 }
 
+// StreamingContext accepts audio in arbitrarily-sized chunks, maintains a
+// rolling buffer and passes finalized segments to a callback. Create a new
+// streaming context with the function whisper.NewStreamingContext(Context, SegmentCallback)
+type StreamingContext interface {
+	// Return the speech recognition context, used to set parameters.
+	Context() Context
+
+	SetWindow(time.Duration)               // Set the amount of audio buffered before each pass
+	SetVAD(*vad.Detector, func(vad.Event)) // Set voice activity detection, and the event callback
+	SetMaxUtterance(time.Duration)         // Set the maximum utterance, to transcribe speech regions when they end
+
+	// Set the sliding window of step, length and keep, as with the stream
+	// example of whisper.cpp, in place of the window. Each step of new audio
+	// is decoded with the preceding audio and passed to the partial
+	// callback, and the segments are finalized once length has been decoded.
+	SetSlidingWindow(step, length, keep time.Duration)
+
+	// Set the callback for interim hypotheses. When the interval is not
+	// zero, buffered audio is also processed each time the interval of
+	// audio has been fed.
+	SetPartialCallback(PartialCallback, time.Duration)
+
+	// Set the callback for tokens as they are decoded, with times offset
+	// from the start of the stream. Audio which is decoded again by a later
+	// pass, such as with the sliding window, passes its tokens again.
+	SetTokenCallback(TokenCallback)
+
+	// Feed mono audio data into the stream. Segments are passed to the
+	// callback function as they are finalized.
+	Feed([]float32) error
+
+	// Process any buffered audio and emit the remaining segments. Call
+	// this at the end of the stream.
+	Flush() error
+
+	// Skip audio which is not fed, such as audio dropped when processing
+	// falls behind, so that the times of later segments include it. The
+	// buffered audio which has not been finalized is discarded.
+	Skip(time.Duration)
+}
+
 // Segment is the text result of a speech recognition.
 type Segment struct {
 	// Segment Number
@@ -87,8 +369,60 @@ type Segment struct {
 	// The text of the segment.
 	Text string
 
+	// The language of the segment. When the language is "auto", it is
+	// detected for each decode window of 30 seconds in which segments start,
+	// so that code-switched audio shows the language of each window. The
+	// segments passed to a segment callback have the language detected for
+	// the call.
+	Language string
+
 	// The tokens of the segment.
 	Tokens []Token
+
+	// The words of the segment, when word timestamps are enabled.
+	Words []Word
+
+	// True if the next segment is predicted to be spoken by a different
+	// speaker. Requires speaker turn detection and a tinydiarize model.
+	SpeakerTurnNext bool
+
+	// The N-best hypotheses of the segment, when alternatives are set. The
+	// first is the text of the segment, and the others are in descending
+	// order of confidence.
+	Alternatives []Alternative
+}
+
+// Timings are the time spent in each stage of a call to Process, and the
+// real-time factor of the call
+type Timings struct {
+	Audio  time.Duration // Duration of the audio processed
+	Mel    time.Duration // Computing the log mel spectrogram
+	Sample time.Duration // Sampling tokens
+	Encode time.Duration // Running the encoder
+	Decode time.Duration // Running the decoder, including the prompt
+	Total  time.Duration // Processing, from start to end
+	RTF    float64       // Total divided by the duration of the audio
+}
+
+// Mel is a log mel spectrogram, whose frames are MelFrame apart
+type Mel struct {
+	Mels   int       // Number of mel bands
+	Frames int       // Number of frames
+	Data   []float32 // The frames of each mel band in turn
+}
+
+// Embedding is the output of the encoder, whose frames are EmbeddingFrame
+// apart
+type Embedding struct {
+	Frames int       // Number of frames
+	Width  int       // Number of values of each frame, the width of the encoder
+	Data   []float32 // The values of each frame in turn
+}
+
+// Alternative is a hypothesis of the text of a segment
+type Alternative struct {
+	Text       string
+	Confidence float32 // Mean probability of the text tokens
 }
 
 // Token is a text or special token
@@ -96,5 +430,19 @@ type Token struct {
 	Id         int
 	Text       string
 	P          float32
+	Plog       float32 // Log probability of the token
 	Start, End time.Duration
 }
+
+// Word is a word within a segment, made up of one or more text tokens
+type Word struct {
+	Text       string
+	P          float32 // Mean probability of the tokens
+	Start, End time.Duration
+}
+
+// LanguageProb is the probability that audio is spoken in a language
+type LanguageProb struct {
+	Language string
+	P        float32
+}
@@ -0,0 +1,26 @@
+//go:build go1.23
+
+package whisper
+
+import (
+	"iter"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// AllSegments returns an iterator over the segments of the last call to
+// Process of a context, for use with range in place of NextSegment:
+//
+//	for segment := range whisper.AllSegments(context) {
+//		fmt.Println(segment.Text)
+//	}
+func AllSegments(context Context) iter.Seq[Segment] {
+	return func(yield func(Segment) bool) {
+		for _, segment := range context.Segments() {
+			if !yield(segment) {
+				return
+			}
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"sync"
+	"sync/atomic"
 
 	// Bindings
 	whisper "github.com/ggerganov/whisper.cpp/bindings/go"
@@ -13,8 +15,13 @@ import (
 // TYPES
 
 type model struct {
-	path string
-	ctx  *whisper.Context
+	sync.RWMutex // Held to read ctx, and to close the model
+	path         string
+	ctx          *whisper.Context
+	busy         atomic.Bool    // True while a context processes with the default state
+	closing      bool           // Set when Close is called, after which no context can process
+	active       sync.WaitGroup // Contexts which are processing, which Close waits for
+	windows      []window       // The decode windows of the results in the default state
 }
 
 // Make sure model adheres to the interface
@@ -23,12 +30,23 @@ var _ Model = (*model)(nil)
 ///////////////////////////////////////////////////////////////////////////////
 // LIFECYCLE
 
+// New loads the model at path with the default parameters
 func New(path string) (Model, error) {
+	return NewWithParams(path, DefaultContextParams())
+}
+
+// NewWithParams loads the model at path with the context parameters
+func NewWithParams(path string, params ContextParams) (Model, error) {
 	model := new(model)
+	cparams := whisper.Whisper_context_default_params()
+	cparams.SetUseGPU(params.UseGPU)
 	if _, err := os.Stat(path); err != nil {
 		return nil, err
-	} else if ctx := whisper.Whisper_init(path); ctx == nil {
+	} else if ctx := whisper.Whisper_init_with_params(path, cparams); ctx == nil {
 		return nil, ErrUnableToLoadModel
+	} else if params.OpenVINODevice != "" && ctx.Whisper_ctx_init_openvino_encoder("", params.OpenVINODevice, "") != 0 {
+		ctx.Whisper_free()
+		return nil, ErrOpenVINOUnavailable
 	} else {
 		model.ctx = ctx
 		model.path = path
@@ -38,7 +56,24 @@ func New(path string) (Model, error) {
 	return model, nil
 }
 
+// DefaultContextParams returns the default parameters for loading a model
+func DefaultContextParams() ContextParams {
+	cparams := whisper.Whisper_context_default_params()
+	return ContextParams{
+		UseGPU: cparams.UseGPU(),
+	}
+}
+
+// Close the model, once the contexts which are processing are done. The lock
+// is not held while waiting, so that their callbacks can call the model.
 func (model *model) Close() error {
+	model.Lock()
+	model.closing = true
+	model.Unlock()
+	model.active.Wait()
+
+	model.Lock()
+	defer model.Unlock()
 	if model.ctx != nil {
 		model.ctx.Whisper_free()
 	}
@@ -54,9 +89,14 @@ func (model *model) Close() error {
 // STRINGIFY
 
 func (model *model) String() string {
+	model.RLock()
+	defer model.RUnlock()
 	str := "<whisper.model"
 	if model.ctx != nil {
+		info := model.info()
 		str += fmt.Sprintf(" model=%q", model.path)
+		str += fmt.Sprintf(" type=%q", info.Type)
+		str += fmt.Sprintf(" quantization=%q", info.Quantization)
 	}
 	return str + ">"
 }
@@ -66,11 +106,18 @@ func (model *model) String() string {
 
 // Return true if model is multilingual (language and translation options are supported)
 func (model *model) IsMultilingual() bool {
-	return model.ctx.Whisper_is_multilingual() != 0
+	model.RLock()
+	defer model.RUnlock()
+	return model.isMultilingual()
 }
 
 // Return all recognized languages. Initially it is set to auto-detect
 func (model *model) Languages() []string {
+	model.RLock()
+	defer model.RUnlock()
+	if model.ctx == nil {
+		return nil
+	}
 	result := make([]string, 0, whisper.Whisper_lang_max_id())
 	for i := 0; i < whisper.Whisper_lang_max_id(); i++ {
 		str := whisper.Whisper_lang_str(i)
@@ -81,12 +128,145 @@ func (model *model) Languages() []string {
 	return result
 }
 
+// Return the metadata of the model
+func (model *model) Info() ModelInfo {
+	model.RLock()
+	defer model.RUnlock()
+	return model.info()
+}
+
+// Return the tokens of text. Each token is at least one byte of the text,
+// so there are at most as many tokens as bytes.
+func (model *model) Tokenize(text string) ([]Token, error) {
+	model.RLock()
+	defer model.RUnlock()
+	if model.ctx == nil {
+		return nil, ErrInternalAppError
+	}
+	tokens := make([]whisper.Token, len(text)+1)
+	n, err := model.ctx.Whisper_tokenize(text, tokens)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Token, n)
+	for i, id := range tokens[:n] {
+		result[i] = Token{Id: int(id), Text: model.ctx.Whisper_token_to_str(id)}
+	}
+
+	// Return success
+	return result, nil
+}
+
+// Return the text of a token id, or an empty string when it is not in the
+// vocabulary
+func (model *model) TokenToString(id int) string {
+	model.RLock()
+	defer model.RUnlock()
+	if model.ctx == nil || id < 0 || id >= model.ctx.Whisper_n_vocab() {
+		return ""
+	}
+	return model.ctx.Whisper_token_to_str(whisper.Token(id))
+}
+
+// Return the ids of the special tokens, which are all zero when the model
+// is closed
+func (model *model) SpecialTokens() SpecialTokens {
+	model.RLock()
+	defer model.RUnlock()
+	if model.ctx == nil {
+		return SpecialTokens{}
+	}
+	return SpecialTokens{
+		EOT:        int(model.ctx.Whisper_token_eot()),
+		SOT:        int(model.ctx.Whisper_token_sot()),
+		PREV:       int(model.ctx.Whisper_token_prev()),
+		SOLM:       int(model.ctx.Whisper_token_solm()),
+		NOT:        int(model.ctx.Whisper_token_not()),
+		BEG:        int(model.ctx.Whisper_token_beg()),
+		Translate:  int(model.ctx.Whisper_token_translate()),
+		Transcribe: int(model.ctx.Whisper_token_transcribe()),
+	}
+}
+
+// Return the id of the token of a language
+func (model *model) LanguageToken(lang string) (int, error) {
+	model.RLock()
+	defer model.RUnlock()
+	if model.ctx == nil {
+		return 0, ErrInternalAppError
+	}
+	id := model.ctx.Whisper_lang_id(lang)
+	if id < 0 {
+		return 0, ErrUnsupportedLanguage
+	}
+	return int(model.ctx.Whisper_token_lang(id)), nil
+}
+
+// Return a new context which decodes with the default state of the model.
+// Contexts can be created concurrently.
 func (model *model) NewContext() (Context, error) {
+	model.RLock()
+	defer model.RUnlock()
+	if model.ctx == nil {
+		return nil, ErrInternalAppError
+	}
+
+	// Return new context
+	return newContext(model, nil, model.defaultParams())
+}
+
+// Return a new context with its own decoding state, which processes
+// concurrently with other contexts of the model. Contexts can be created
+// concurrently.
+func (model *model) NewState() (StateContext, error) {
+	model.RLock()
+	defer model.RUnlock()
 	if model.ctx == nil {
 		return nil, ErrInternalAppError
 	}
 
-	// Create new context
+	// Create new state
+	state := model.ctx.Whisper_init_state()
+	if state == nil {
+		return nil, ErrUnableToCreateState
+	}
+
+	// Return new context
+	return newContext(model, state, model.defaultParams())
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Return the metadata of the model, with the lock held
+func (model *model) info() ModelInfo {
+	if model.ctx == nil {
+		return ModelInfo{}
+	}
+	return ModelInfo{
+		Type:         model.ctx.Whisper_model_type_readable(),
+		Quantization: quantization(model.ctx.Whisper_model_ftype()),
+		Multilingual: model.isMultilingual(),
+		Vocab:        model.ctx.Whisper_model_n_vocab(),
+		Mels:         model.ctx.Whisper_model_n_mels(),
+		AudioCtx:     model.ctx.Whisper_model_n_audio_ctx(),
+		AudioState:   model.ctx.Whisper_model_n_audio_state(),
+		AudioHead:    model.ctx.Whisper_model_n_audio_head(),
+		AudioLayer:   model.ctx.Whisper_model_n_audio_layer(),
+		TextCtx:      model.ctx.Whisper_model_n_text_ctx(),
+		TextState:    model.ctx.Whisper_model_n_text_state(),
+		TextHead:     model.ctx.Whisper_model_n_text_head(),
+		TextLayer:    model.ctx.Whisper_model_n_text_layer(),
+	}
+}
+
+// Return true if the model is multilingual, with the lock held
+func (model *model) isMultilingual() bool {
+	return model.ctx != nil && model.ctx.Whisper_is_multilingual() != 0
+}
+
+// Return the parameters of a new context
+func (model *model) defaultParams() whisper.Params {
 	params := model.ctx.Whisper_full_default_params(whisper.SAMPLING_GREEDY)
 	params.SetTranslate(false)
 	params.SetPrintSpecial(false)
@@ -95,7 +275,39 @@ func (model *model) NewContext() (Context, error) {
 	params.SetPrintTimestamps(false)
 	params.SetThreads(runtime.NumCPU())
 	params.SetNoContext(true)
+	return params
+}
 
-	// Return new context
-	return newContext(model, params)
+// Return the name of a ggml_ftype weight type
+func quantization(ftype int) string {
+	switch ftype {
+	case 0:
+		return "f32"
+	case 1:
+		return "f16"
+	case 2:
+		return "q4_0"
+	case 3:
+		return "q4_1"
+	case 4:
+		return "q4_1_some_f16"
+	case 7:
+		return "q8_0"
+	case 8:
+		return "q5_0"
+	case 9:
+		return "q5_1"
+	case 10:
+		return "q2_k"
+	case 11:
+		return "q3_k"
+	case 12:
+		return "q4_k"
+	case 13:
+		return "q5_k"
+	case 14:
+		return "q6_k"
+	default:
+		return "unknown"
+	}
 }
@@ -0,0 +1,401 @@
+package whisper
+
+import (
+	gocontext "context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	// Bindings
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type pool struct {
+	sync.Mutex
+	path   string
+	info   ModelInfo
+	params ContextParams
+	policy PoolPolicy
+	size   int
+	shared bool
+	idle   chan Model
+	inuse  map[Context]Model
+	done   chan struct{}
+	closed bool
+
+	// When the model is reloaded, models which are in use are stale and are
+	// replaced with the pending models as they are returned
+	gen     uint
+	gens    map[Model]uint
+	pending []Model
+}
+
+// A slot in a shared pool, which decodes with its own state against the
+// weights of a model shared with the other slots
+type sharedModel struct {
+	*model
+	state *whisper.State
+	refs  *atomic.Int32 // The number of slots which share the model
+}
+
+// Make sure pool adheres to the interface
+var _ Pool = (*pool)(nil)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// NewPool loads the model at path once and returns a pool which hands out up
+// to size contexts at once. Each slot in the pool decodes with its own state,
+// so that the slots decode concurrently while sharing the model weights.
+func NewPool(path string, size uint, policy PoolPolicy) (Pool, error) {
+	return NewPoolWithParams(path, DefaultContextParams(), size, policy)
+}
+
+// NewPoolWithParams returns a pool as with NewPool, loading the model with
+// the context parameters. The OpenVINO encoder is only initialized for the
+// default state of a model, so when an OpenVINO device is selected the pool
+// loads a copy of the model for each slot, as with NewPoolWithCopies.
+func NewPoolWithParams(path string, params ContextParams, size uint, policy PoolPolicy) (Pool, error) {
+	return newPool(path, params, size, policy, params.OpenVINODevice == "")
+}
+
+// NewSharedPool returns a pool as with NewPoolWithParams, but always shares
+// the model between the slots, so that the OpenVINO encoder is not used.
+func NewSharedPool(path string, params ContextParams, size uint, policy PoolPolicy) (Pool, error) {
+	return newPool(path, params, size, policy, true)
+}
+
+// NewPoolWithCopies returns a pool as with NewPoolWithParams, but each slot
+// holds its own copy of the model, which decodes with its default state: size
+// copies are loaded when the pool is created, and again on every Reload.
+func NewPoolWithCopies(path string, params ContextParams, size uint, policy PoolPolicy) (Pool, error) {
+	return newPool(path, params, size, policy, false)
+}
+
+func newPool(path string, params ContextParams, size uint, policy PoolPolicy, shared bool) (Pool, error) {
+	if size == 0 {
+		return nil, ErrInternalAppError
+	}
+	pool := new(pool)
+	pool.path = path
+	pool.params = params
+	pool.policy = policy
+	pool.size = int(size)
+	pool.shared = shared
+	pool.idle = make(chan Model, size)
+	pool.inuse = make(map[Context]Model, size)
+	pool.done = make(chan struct{})
+	pool.gens = make(map[Model]uint, size)
+
+	// Load the models
+	models, err := pool.load(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, model := range models {
+		pool.gens[model] = pool.gen
+		pool.idle <- model
+	}
+	pool.info = models[0].Info()
+
+	// Return success
+	return pool, nil
+}
+
+// Close the pool and release the models. Contexts which are in use are
+// released when they are returned with Put.
+func (pool *pool) Close() error {
+	pool.Lock()
+	defer pool.Unlock()
+	if pool.closed {
+		return nil
+	}
+	pool.closed = true
+	close(pool.done)
+
+	// Release pending and idle models
+	var result error
+	for _, model := range pool.pending {
+		if err := model.Close(); err != nil {
+			result = err
+		}
+	}
+	pool.pending = nil
+	for {
+		select {
+		case model := <-pool.idle:
+			if err := model.Close(); err != nil {
+				result = err
+			}
+		default:
+			return result
+		}
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (pool *pool) String() string {
+	pool.Lock()
+	defer pool.Unlock()
+	str := "<whisper.pool"
+	str += fmt.Sprintf(" model=%q", pool.path)
+	str += fmt.Sprintf(" size=%d", pool.size)
+	if pool.params.UseGPU {
+		str += " use_gpu"
+	}
+	if pool.shared {
+		str += " shared"
+	}
+	if pool.params.OpenVINODevice != "" {
+		str += fmt.Sprintf(" openvino=%q", pool.params.OpenVINODevice)
+	}
+	str += fmt.Sprintf(" inuse=%d", len(pool.inuse))
+	if len(pool.pending) > 0 {
+		str += fmt.Sprintf(" pending=%d", len(pool.pending))
+	}
+	if pool.closed {
+		str += " closed"
+	}
+	return str + ">"
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return the maximum number of contexts in use at once
+func (pool *pool) Size() int {
+	return pool.size
+}
+
+// Return the path of the model
+func (pool *pool) Path() string {
+	pool.Lock()
+	defer pool.Unlock()
+	return pool.path
+}
+
+// Return the metadata of the model
+func (pool *pool) Info() ModelInfo {
+	pool.Lock()
+	defer pool.Unlock()
+	return pool.info
+}
+
+// Return a new context from the pool
+func (pool *pool) Get(ctx gocontext.Context) (Context, error) {
+	var model Model
+
+	// Obtain an idle model
+	if pool.policy == PoolReject {
+		select {
+		case model = <-pool.idle:
+		case <-pool.done:
+			return nil, ErrPoolClosed
+		default:
+			return nil, ErrPoolExhausted
+		}
+	} else {
+		select {
+		case model = <-pool.idle:
+		case <-pool.done:
+			return nil, ErrPoolClosed
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	// Create a context for the model
+	pool.Lock()
+	defer pool.Unlock()
+	if pool.closed {
+		delete(pool.gens, model)
+		model.Close()
+		return nil, ErrPoolClosed
+	}
+	context, err := model.NewContext()
+	if err != nil {
+		pool.release(model)
+		return nil, err
+	}
+	pool.inuse[context] = model
+
+	// Return success
+	return context, nil
+}
+
+// Return a context to the pool
+func (pool *pool) Put(context Context) error {
+	pool.Lock()
+	defer pool.Unlock()
+	model, exists := pool.inuse[context]
+	if !exists {
+		return ErrInternalAppError
+	}
+	delete(pool.inuse, context)
+	if pool.closed {
+		delete(pool.gens, model)
+		return model.Close()
+	}
+	return pool.release(model)
+}
+
+// Reload loads the model at path for each slot in the pool, once for a
+// shared pool and otherwise once per slot, and swaps it in for new
+// contexts. Idle models are released at once, and models which are in use
+// are released when their contexts are returned with Put. The pool is
+// unchanged if the model cannot be loaded.
+func (pool *pool) Reload(path string) error {
+	// Load the models before taking the lock, so that contexts can be
+	// obtained and returned meanwhile
+	models, err := pool.load(path)
+	if err != nil {
+		return err
+	}
+
+	pool.Lock()
+	defer pool.Unlock()
+	if pool.closed {
+		for _, model := range models {
+			model.Close()
+		}
+		return ErrPoolClosed
+	}
+	pool.path = path
+	pool.info = models[0].Info()
+	pool.gen++
+	for _, model := range models {
+		pool.gens[model] = pool.gen
+	}
+
+	// Release models which were pending from an earlier reload
+	var result error
+	for _, model := range pool.pending {
+		delete(pool.gens, model)
+		if err := model.Close(); err != nil {
+			result = err
+		}
+	}
+
+	// Replace the idle models, and keep the remainder pending until the
+	// stale models are returned
+	var idle []Model
+drain:
+	for {
+		select {
+		case model := <-pool.idle:
+			idle = append(idle, model)
+		default:
+			break drain
+		}
+	}
+	for i, model := range idle {
+		delete(pool.gens, model)
+		if err := model.Close(); err != nil {
+			result = err
+		}
+		pool.idle <- models[i]
+	}
+	pool.pending = models[len(idle):]
+
+	// Return any error from releasing the stale models
+	return result
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Load the model at path for each slot in the pool. A shared pool loads the
+// model once, and creates a state for each slot.
+func (pool *pool) load(path string) ([]Model, error) {
+	models := make([]Model, 0, pool.size)
+	if !pool.shared {
+		for i := 0; i < pool.size; i++ {
+			model, err := NewWithParams(path, pool.params)
+			if err != nil {
+				for _, model := range models {
+					model.Close()
+				}
+				return nil, err
+			}
+			models = append(models, model)
+		}
+		return models, nil
+	}
+
+	// Load the shared model without the OpenVINO encoder, which is not used
+	// by the states
+	params := pool.params
+	params.OpenVINODevice = ""
+	shared, err := NewWithParams(path, params)
+	if err != nil {
+		return nil, err
+	}
+	refs := new(atomic.Int32)
+	refs.Store(1)
+	defer func() {
+		if refs.Add(-1) == 0 {
+			shared.Close()
+		}
+	}()
+	for i := 0; i < pool.size; i++ {
+		state := shared.(*model).ctx.Whisper_init_state()
+		if state == nil {
+			for _, model := range models {
+				model.Close()
+			}
+			return nil, ErrUnableToCreateState
+		}
+		refs.Add(1)
+		models = append(models, &sharedModel{shared.(*model), state, refs})
+	}
+
+	// Return success
+	return models, nil
+}
+
+// Return a model to the idle models, or if it is stale, release it and make
+// a pending model idle in its place. Called with the lock held.
+func (pool *pool) release(model Model) error {
+	if pool.gens[model] == pool.gen {
+		pool.idle <- model
+		return nil
+	}
+	delete(pool.gens, model)
+	err := model.Close()
+	if len(pool.pending) > 0 {
+		pool.idle <- pool.pending[0]
+		pool.pending = pool.pending[1:]
+	}
+	return err
+}
+
+// Return a new context which decodes with the state of the slot
+func (model *sharedModel) NewContext() (Context, error) {
+	model.RLock()
+	defer model.RUnlock()
+	if model.ctx == nil {
+		return nil, ErrInternalAppError
+	}
+
+	// Return new context
+	return newContext(model.model, model.state, model.defaultParams())
+}
+
+// Release the state of the slot, and the model once no slot shares it
+func (model *sharedModel) Close() error {
+	if model.state != nil {
+		model.state.Whisper_free_state()
+		model.state = nil
+		if model.refs.Add(-1) == 0 {
+			return model.model.Close()
+		}
+	}
+
+	// Return success
+	return nil
+}
@@ -0,0 +1,536 @@
+package whisper
+
+import (
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	// Packages
+	vad "github.com/ggerganov/whisper.cpp/bindings/go/pkg/vad"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type stream struct {
+	context  Context
+	callback SegmentCallback
+	window   int   // Number of samples processed per pass
+	n        int   // Number of segments emitted
+	start    int64 // Position of the first buffered sample in the stream
+	pos      int64 // Number of samples fed
+	buf      []float32
+
+	// Interim hypotheses
+	partial  PartialCallback
+	interval int // Number of samples between interim passes
+	last     int // Buffer length at the last pass
+
+	// Sliding window, in samples
+	step   int     // Number of new samples decoded per pass, or zero
+	length int     // Number of samples decoded per pass
+	keep   int     // Number of samples kept from the finalized window
+	old    int     // Number of buffered samples decoded by the last pass
+	steps  int     // Number of passes since the last finalized window
+	tail   []Token // Text tokens of the last finalized window
+
+	// Voice activity detection
+	vad       *vad.Detector
+	vadEvent  func(vad.Event)
+	speech    bool
+	utterance int // Maximum number of samples in an utterance, or zero
+}
+
+// Make sure stream adheres to the interface
+var _ StreamingContext = (*stream)(nil)
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+// DefaultStreamWindow is the amount of audio buffered before each pass of
+// a streaming context
+const DefaultStreamWindow = 10 * time.Second
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// NewStreamingContext returns a streaming context which feeds audio through
+// the speech recognition context, and passes finalized segments to the
+// callback function.
+func NewStreamingContext(context Context, callback SegmentCallback) (StreamingContext, error) {
+	if context == nil {
+		return nil, ErrInternalAppError
+	}
+	stream := new(stream)
+	stream.context = context
+	stream.callback = callback
+	stream.SetWindow(DefaultStreamWindow)
+
+	// Return success
+	return stream, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return the speech recognition context
+func (stream *stream) Context() Context {
+	return stream.context
+}
+
+// Set the amount of audio buffered before each pass
+func (stream *stream) SetWindow(v time.Duration) {
+	stream.window = int(v.Seconds() * SampleRate)
+}
+
+// Set the callback for interim hypotheses. When the interval is not zero,
+// the buffered audio is also processed each time the interval of audio has
+// been fed, so the callback is called before the window is full.
+func (stream *stream) SetPartialCallback(fn PartialCallback, interval time.Duration) {
+	stream.partial = fn
+	stream.interval = toSamples(interval)
+}
+
+// Set the callback for tokens as they are decoded. The segment numbers and
+// times are those of the stream.
+func (stream *stream) SetTokenCallback(fn TokenCallback) {
+	if fn == nil {
+		stream.context.SetTokenCallback(nil)
+		return
+	}
+	stream.context.SetTokenCallback(func(segment Segment, token Token) {
+		offset := stream.offset()
+		segment = stream.offsetSegment(segment)
+		segment.Num += stream.n
+		token.Start += offset
+		token.End += offset
+		fn(segment, token)
+	})
+}
+
+// Set the sliding window, as with the stream example of whisper.cpp. When
+// step is not zero, the audio is decoded each time step of new audio has been
+// fed, together with the preceding audio up to length. The hypothesis of each
+// pass is passed to the partial callback, and once length of audio has been
+// decoded the segments are finalized, keeping keep of audio for the next
+// window. A zero step restores the default window.
+func (stream *stream) SetSlidingWindow(step, length, keep time.Duration) {
+	stream.step = toSamples(step)
+	stream.length = max(toSamples(length), stream.step)
+	stream.keep = min(toSamples(keep), stream.step)
+	stream.old = 0
+	stream.steps = 0
+	stream.tail = nil
+}
+
+// Set the voice activity detector. When set, only speech regions are passed
+// to the speech recognition context, and the buffer is flushed at the end of
+// each speech region. The callback, if not nil, is called on each event.
+func (stream *stream) SetVAD(detector *vad.Detector, fn func(vad.Event)) {
+	stream.vad = detector
+	stream.vadEvent = fn
+	stream.speech = false
+}
+
+// Set the maximum length of an utterance. When not zero and voice activity
+// detection is set, each speech region is buffered until it ends, so that
+// complete utterances are transcribed, rather than processed each window.
+// A speech region longer than the maximum is transcribed in parts of the
+// maximum length. The sliding window takes precedence.
+func (stream *stream) SetMaxUtterance(v time.Duration) {
+	stream.utterance = toSamples(v)
+}
+
+// Feed mono audio data into the stream
+func (stream *stream) Feed(data []float32) error {
+	pos := stream.pos
+	stream.pos += int64(len(data))
+
+	// Without voice activity detection, buffer all the samples
+	if stream.vad == nil {
+		return stream.append(data)
+	}
+
+	// Otherwise, buffer only the speech regions
+	last := 0
+	for _, evt := range stream.vad.Process(data) {
+		i := clamp(int(evt.Offset-pos), 0, len(data))
+		if stream.speech {
+			if err := stream.append(data[last:i]); err != nil {
+				return err
+			}
+		}
+		last = i
+		switch evt.Type {
+		case vad.SpeechStart:
+			stream.speech = true
+			stream.start = pos + int64(i)
+		case vad.SpeechEnd:
+			stream.speech = false
+			if err := stream.Flush(); err != nil {
+				return err
+			}
+		}
+		if stream.vadEvent != nil {
+			stream.vadEvent(evt)
+		}
+	}
+	if stream.speech {
+		return stream.append(data[last:])
+	}
+
+	// Return success
+	return nil
+}
+
+// Process any buffered audio and emit the remaining segments
+func (stream *stream) Flush() error {
+	if stream.step > 0 {
+		if err := stream.slideFinal(); err != nil {
+			return err
+		}
+	} else if err := stream.process(true); err != nil {
+		return err
+	}
+	stream.start = stream.pos
+
+	// Return success
+	return nil
+}
+
+// Skip audio which is not fed, such as audio dropped when processing falls
+// behind, so that the times of later segments include it. The buffered audio
+// which has not been finalized is discarded, and a speech region in
+// progress ends.
+func (stream *stream) Skip(t time.Duration) {
+	n := int64(toSamples(t))
+	if stream.vad != nil {
+		if stream.speech && stream.vadEvent != nil {
+			stream.vadEvent(vad.Event{Type: vad.SpeechEnd, Offset: stream.pos, Time: time.Duration(stream.pos) * time.Second / SampleRate})
+		}
+		stream.vad.Skip(n)
+		stream.speech = false
+	}
+	stream.pos += n
+	stream.start = stream.pos
+	stream.buf = stream.buf[:0]
+	stream.last = 0
+	stream.old = 0
+	stream.steps = 0
+	stream.tail = nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Append samples to the buffer, processing whenever the window is full
+func (stream *stream) append(data []float32) error {
+	stream.buf = append(stream.buf, data...)
+	if stream.step > 0 {
+		for len(stream.buf)-stream.old >= stream.step {
+			if err := stream.slide(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if stream.vad != nil && stream.utterance > 0 {
+		return stream.appendUtterance()
+	}
+	for len(stream.buf) >= stream.window {
+		if err := stream.process(false); err != nil {
+			return err
+		}
+	}
+	if stream.partial != nil && stream.interval > 0 && len(stream.buf)-stream.last >= stream.interval {
+		return stream.processPartial()
+	}
+	return nil
+}
+
+// Transcribe the buffered utterance in parts of the maximum length,
+// buffering the remainder until the utterance ends
+func (stream *stream) appendUtterance() error {
+	for len(stream.buf) >= stream.utterance {
+		data := stream.buf[stream.utterance:]
+		stream.buf = stream.buf[:stream.utterance]
+		if err := stream.process(true); err != nil {
+			return err
+		}
+		stream.buf = append(stream.buf, data...)
+		stream.last = len(stream.buf)
+	}
+	if stream.partial != nil && stream.interval > 0 && len(stream.buf)-stream.last >= stream.interval {
+		return stream.processPartial()
+	}
+	return nil
+}
+
+// Process the buffered audio and pass all segments to the partial
+// callback, without finalizing them
+func (stream *stream) processPartial() error {
+	stream.last = len(stream.buf)
+	segments, err := stream.decode(stream.buf)
+	if err != nil {
+		return err
+	}
+	stream.emitPartial(segments)
+
+	// Return success
+	return nil
+}
+
+// Merge segments into a single interim hypothesis and pass it to the
+// partial callback
+func (stream *stream) emitPartial(segments []Segment) {
+	if stream.partial == nil || len(segments) == 0 {
+		return
+	}
+	result := Segment{Num: stream.n}
+	text := make([]string, 0, len(segments))
+	for i, segment := range segments {
+		segment = stream.offsetSegment(segment)
+		if i == 0 {
+			result.Start = segment.Start
+		}
+		result.End = segment.End
+		result.Tokens = append(result.Tokens, segment.Tokens...)
+		result.Words = append(result.Words, segment.Words...)
+		text = append(text, segment.Text)
+	}
+	result.Text = strings.Join(text, " ")
+	stream.partial(result)
+}
+
+// Process data and return the segments
+func (stream *stream) decode(data []float32) ([]Segment, error) {
+	if err := stream.context.Process(data, nil, nil); err != nil {
+		return nil, err
+	}
+	var segments []Segment
+	for {
+		segment, err := stream.context.NextSegment()
+		if err == io.EOF {
+			return segments, nil
+		} else if err != nil {
+			return nil, err
+		}
+		segments = append(segments, segment)
+	}
+}
+
+// Return the offset of the first buffered sample from the start of the stream
+func (stream *stream) offset() time.Duration {
+	return time.Duration(stream.start) * time.Second / SampleRate
+}
+
+// Offset segment times from the start of the buffer to the start of the stream
+func (stream *stream) offsetSegment(segment Segment) Segment {
+	offset := stream.offset()
+	segment.Start += offset
+	segment.End += offset
+	for i := range segment.Tokens {
+		segment.Tokens[i].Start += offset
+		segment.Tokens[i].End += offset
+	}
+	for i := range segment.Words {
+		segment.Words[i].Start += offset
+		segment.Words[i].End += offset
+	}
+	return segment
+}
+
+// Process the buffered audio. Unless this is the final pass, the last
+// segment may have been cut off at the end of the window, so it is not
+// emitted and its audio is retained for the next pass.
+func (stream *stream) process(final bool) error {
+	data := stream.buf
+	if !final && len(data) > stream.window {
+		data = data[:stream.window]
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	segments, err := stream.decode(data)
+	if err != nil {
+		return err
+	}
+
+	// Determine which segments are final, and the number of samples consumed
+	var pending []Segment
+	cut := len(data)
+	if !final && len(segments) > 1 {
+		cut = clamp(toSamples(segments[len(segments)-1].Start), 1, len(data))
+		segments, pending = segments[:len(segments)-1], segments[len(segments)-1:]
+	}
+
+	// Emit segments, offset from the start of the stream
+	stream.emit(segments)
+
+	// The segment which was held back is an interim hypothesis
+	stream.emitPartial(pending)
+
+	// Retain the samples which were not consumed
+	stream.discard(cut)
+	stream.last = len(stream.buf)
+
+	// Return success
+	return nil
+}
+
+// Decode the next step of audio with the preceding audio, up to the window
+// length. Every length of audio, the segments are finalized and the window
+// starts again with the kept audio.
+func (stream *stream) slide() error {
+	// Drop the audio which precedes the window
+	take := min(stream.old, max(0, stream.keep+stream.length-stream.step))
+	stream.discard(stream.old - take)
+	stream.old = take + stream.step
+
+	// Decode the window
+	segments, err := stream.decode(stream.buf[:stream.old])
+	if err != nil {
+		return err
+	}
+	stream.steps++
+	if stream.steps < max(1, stream.length/stream.step-1) {
+		stream.emitPartial(stream.dedupe(segments))
+		return nil
+	}
+
+	// Finalize the window, keeping the end of its audio for the next one
+	stream.emit(stream.dedupe(segments))
+	stream.tail = stream.textTokens(segments)
+	stream.discard(stream.old - stream.keep)
+	stream.old = stream.keep
+	stream.steps = 0
+
+	// Return success
+	return nil
+}
+
+// Decode and finalize the audio which has not been finalized, and empty the
+// buffer
+func (stream *stream) slideFinal() error {
+	if len(stream.buf) > stream.old || stream.steps > 0 {
+		take := min(stream.old, max(0, stream.keep+stream.length-(len(stream.buf)-stream.old)))
+		stream.discard(stream.old - take)
+		segments, err := stream.decode(stream.buf)
+		if err != nil {
+			return err
+		}
+		stream.emit(stream.dedupe(segments))
+	}
+	stream.buf = stream.buf[:0]
+	stream.old = 0
+	stream.steps = 0
+	stream.tail = nil
+
+	// Return success
+	return nil
+}
+
+// Remove the text at the start of the segments which repeats the end of the
+// last finalized window, as the windows overlap by the kept audio. The
+// longest run of tokens which ends the last window and starts the segments
+// is removed, and segments left without text are dropped.
+func (stream *stream) dedupe(segments []Segment) []Segment {
+	n := overlap(stream.tail, stream.textTokens(segments))
+	if n == 0 {
+		return segments
+	}
+	result := make([]Segment, 0, len(segments))
+	for _, segment := range segments {
+		if n > 0 {
+			segment, n = stream.trimTokens(segment, n)
+		}
+		if strings.TrimSpace(segment.Text) != "" {
+			result = append(result, segment)
+		}
+	}
+	return result
+}
+
+// Remove up to n text tokens from the start of a segment, and return the
+// segment and the number of tokens which remain to be removed
+func (stream *stream) trimTokens(segment Segment, n int) (Segment, int) {
+	var end time.Duration
+	tokens := make([]Token, 0, len(segment.Tokens))
+	text := make([]string, 0, len(segment.Tokens))
+	for _, token := range segment.Tokens {
+		if stream.context.IsText(token) {
+			if n > 0 {
+				end = token.End
+				n--
+				continue
+			}
+			text = append(text, token.Text)
+		}
+		tokens = append(tokens, token)
+	}
+	segment.Tokens = tokens
+	segment.Text = strings.Join(text, "")
+
+	// Drop the words of the removed tokens
+	for len(segment.Words) > 0 && segment.Words[0].End <= end {
+		segment.Words = segment.Words[1:]
+	}
+	return segment, n
+}
+
+// Return the text tokens of segments
+func (stream *stream) textTokens(segments []Segment) []Token {
+	var result []Token
+	for _, segment := range segments {
+		for _, token := range segment.Tokens {
+			if stream.context.IsText(token) {
+				result = append(result, token)
+			}
+		}
+	}
+	return result
+}
+
+// Pass segments to the callback, offset from the start of the stream
+func (stream *stream) emit(segments []Segment) {
+	for _, segment := range segments {
+		segment = stream.offsetSegment(segment)
+		segment.Num = stream.n
+		if stream.callback != nil {
+			stream.callback(segment)
+		}
+		stream.n++
+	}
+}
+
+// Discard samples from the start of the buffer
+func (stream *stream) discard(n int) {
+	stream.buf = append(stream.buf[:0], stream.buf[n:]...)
+	stream.start += int64(n)
+}
+
+// Return the length of the longest suffix of a which is a prefix of b
+func overlap(a, b []Token) int {
+	for n := min(len(a), len(b)); n > 0; n-- {
+		if slices.EqualFunc(a[len(a)-n:], b[:n], func(a, b Token) bool {
+			return a.Id == b.Id
+		}) {
+			return n
+		}
+	}
+	return 0
+}
+
+func toSamples(t time.Duration) int {
+	return int(t.Seconds() * SampleRate)
+}
+
+func clamp(v, min, max int) int {
+	if v < min {
+		return min
+	} else if v > max {
+		return max
+	}
+	return v
+}
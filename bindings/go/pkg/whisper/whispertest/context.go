@@ -0,0 +1,436 @@
+package whispertest
+
+import (
+	gocontext "context"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	// Packages
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Context is a fake context, which records the parameters it is set with
+// and the audio it processes, and returns the scripted segments of its
+// model from each call to Process. The exported fields can be inspected
+// once the context is no longer in use.
+type Context struct {
+	sync.Mutex
+	model *Model
+
+	// Parameters which have been set
+	Translate      bool
+	Offset         time.Duration
+	Duration       time.Duration
+	Threads        uint
+	Prompt         string
+	Hotwords       []string
+	WordTimestamps bool
+	BeamSize       uint
+	Temperature    float32
+	Alternatives   uint
+
+	// The audio passed to each call to Process
+	Audio [][]float32
+
+	// The log mel spectrogram set, which calls to Process with no audio
+	// process in place of audio
+	Mel *whisper.Mel
+
+	// True when the context has been closed
+	Closed bool
+
+	lang         string
+	detected     string
+	segments     []whisper.Segment
+	n            int
+	token        whisper.TokenCallback
+	abort        func() bool
+	encoderBegin whisper.EncoderBeginCallback
+	timings      whisper.Timings
+}
+
+// Make sure the fake adheres to the interface
+var _ whisper.StateContext = (*Context)(nil)
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+// Number of samples of audio for each frame of a log mel spectrogram
+const melSamples = int(whisper.MelFrame * whisper.SampleRate / time.Second)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Close the context
+func (context *Context) Close() error {
+	context.Lock()
+	defer context.Unlock()
+	context.Closed = true
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS - PARAMETERS
+
+// Set the language, which returns an error when the model is not
+// multilingual, as with a real context, or does not have the language
+func (context *Context) SetLanguage(lang string) error {
+	if !context.model.IsMultilingual() {
+		return whisper.ErrModelNotMultilingual
+	} else if lang != "auto" && !slices.Contains(context.model.Languages(), lang) {
+		return whisper.ErrUnsupportedLanguage
+	}
+	context.Lock()
+	defer context.Unlock()
+	context.lang = lang
+
+	// Return success
+	return nil
+}
+
+func (context *Context) SetTranslate(v bool) {
+	context.Lock()
+	defer context.Unlock()
+	context.Translate = v
+}
+
+func (context *Context) IsMultilingual() bool {
+	return context.model.IsMultilingual()
+}
+
+func (context *Context) Language() string {
+	context.Lock()
+	defer context.Unlock()
+	return context.lang
+}
+
+// Return the language of the first segment of the last call to Process,
+// when it is set, or otherwise the language which is set, or the first
+// language of the model for "auto"
+func (context *Context) DetectedLanguage() string {
+	context.Lock()
+	defer context.Unlock()
+	return context.detected
+}
+
+func (context *Context) SetOffset(v time.Duration) {
+	context.Lock()
+	defer context.Unlock()
+	context.Offset = v
+}
+
+func (context *Context) SetDuration(v time.Duration) {
+	context.Lock()
+	defer context.Unlock()
+	context.Duration = v
+}
+
+func (context *Context) SetThreads(v uint) {
+	context.Lock()
+	defer context.Unlock()
+	context.Threads = v
+}
+
+func (context *Context) SetInitialPrompt(v string) {
+	context.Lock()
+	defer context.Unlock()
+	context.Prompt = v
+}
+
+// Set the hotwords, which are recorded but do not change the segments
+func (context *Context) SetHotwords(words []string, boost float32) error {
+	context.Lock()
+	defer context.Unlock()
+	context.Hotwords = slices.Clone(words)
+	return nil
+}
+
+func (context *Context) SetWordTimestamps(v bool) {
+	context.Lock()
+	defer context.Unlock()
+	context.WordTimestamps = v
+}
+
+func (context *Context) SetBeamSize(v uint) {
+	context.Lock()
+	defer context.Unlock()
+	context.BeamSize = v
+}
+
+func (context *Context) SetTemperature(v float32) {
+	context.Lock()
+	defer context.Unlock()
+	context.Temperature = v
+}
+
+func (context *Context) SetAlternatives(v uint) {
+	context.Lock()
+	defer context.Unlock()
+	context.Alternatives = v
+}
+
+// Parameters which do not change the segments are ignored
+func (context *Context) SetSpeedup(bool)                              {}
+func (context *Context) SetSplitOnWord(bool)                          {}
+func (context *Context) SetTokenThreshold(float32)                    {}
+func (context *Context) SetTokenSumThreshold(float32)                 {}
+func (context *Context) SetMaxSegmentLength(uint)                     {}
+func (context *Context) SetTokenTimestamps(bool)                      {}
+func (context *Context) SetMaxTokensPerSegment(uint)                  {}
+func (context *Context) SetAudioCtx(uint)                             {}
+func (context *Context) SetDiarize(bool)                              {}
+func (context *Context) SetTemperatureFallback(float32)               {}
+func (context *Context) SetEntropyThreshold(float32)                  {}
+func (context *Context) SetLogprobThreshold(float32)                  {}
+func (context *Context) SetNoSpeechThreshold(float32)                 {}
+func (context *Context) SetBestOf(uint)                               {}
+func (context *Context) SetSuppressBlank(bool)                        {}
+func (context *Context) SetSuppressNonSpeech(bool)                    {}
+func (context *Context) SetNoContext(bool)                            {}
+func (context *Context) SetLogitsFilter(whisper.LogitsFilterCallback) {}
+
+// Return the text of each token id in the vocabulary of the model
+func (context *Context) Vocab() []string {
+	context.model.Lock()
+	defer context.model.Unlock()
+	return slices.Clone(context.model.vocabulary())
+}
+
+func (context *Context) SetTokenCallback(fn whisper.TokenCallback) {
+	context.Lock()
+	defer context.Unlock()
+	context.token = fn
+}
+
+func (context *Context) SetAbortFunc(fn func() bool) {
+	context.Lock()
+	defer context.Unlock()
+	context.abort = fn
+}
+
+func (context *Context) SetEncoderBeginCallback(fn whisper.EncoderBeginCallback) {
+	context.Lock()
+	defer context.Unlock()
+	context.encoderBegin = fn
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS - PROCESSING
+
+// Process audio, as ProcessContext without a go context
+func (context *Context) Process(data []float32, cb whisper.SegmentCallback, progress whisper.ProgressCallback) error {
+	return context.ProcessContext(gocontext.Background(), data, cb, progress)
+}
+
+// Process audio, or the spectrogram which is set when there is no audio,
+// returning the scripted segments numbered from zero. The
+// encoder begin callback is called once, with the offset, and the abort
+// function and the go context are checked before each segment, as real
+// processing checks them during each window. The segments passed to the
+// callback before processing is stopped are kept.
+func (context *Context) ProcessContext(ctx gocontext.Context, data []float32, cb whisper.SegmentCallback, progress whisper.ProgressCallback) error {
+	start := time.Now()
+	context.Lock()
+	samples := len(data)
+	if samples == 0 {
+		if context.Mel == nil {
+			context.Unlock()
+			return whisper.ErrProcessingFailed
+		}
+		samples = context.Mel.Frames * melSamples
+	}
+	context.Audio = append(context.Audio, slices.Clone(data))
+	context.segments, context.n = []whisper.Segment{}, 0
+	context.detected = context.lang
+	if context.detected == "auto" {
+		context.detected = context.model.language()
+	}
+	token, abort, encoderBegin, offset := context.token, context.abort, context.encoderBegin, context.Offset
+	context.Unlock()
+
+	// Obtain the scripted segments
+	segments := slices.Clone(context.model.Segments)
+	if context.model.Func != nil {
+		var err error
+		if segments, err = context.model.Func(data); err != nil {
+			return err
+		}
+	}
+	if len(segments) > 0 && segments[0].Language != "" {
+		context.Lock()
+		context.detected = segments[0].Language
+		context.Unlock()
+	}
+
+	// Emit the segments
+	if encoderBegin != nil && !encoderBegin(offset) {
+		return nil
+	}
+	for i, segment := range segments {
+		if err := ctx.Err(); err != nil {
+			return err
+		} else if abort != nil && abort() {
+			return whisper.ErrAborted
+		}
+		segment.Num = i
+		if segment.Language == "" {
+			segment.Language = context.DetectedLanguage()
+		}
+		if token != nil {
+			for _, t := range segment.Tokens {
+				token(segment, t)
+			}
+		}
+		context.Lock()
+		context.segments = append(context.segments, segment)
+		context.Unlock()
+		if cb != nil {
+			cb(segment)
+		}
+	}
+	if progress != nil {
+		progress(100)
+	}
+
+	// Set the timings
+	audio := time.Duration(samples) * time.Second / whisper.SampleRate
+	context.Lock()
+	defer context.Unlock()
+	context.timings = whisper.Timings{Audio: audio, Total: time.Since(start)}
+	if audio > 0 {
+		context.timings.RTF = context.timings.Total.Seconds() / audio.Seconds()
+	}
+
+	// Return success
+	return nil
+}
+
+// Return a log mel spectrogram of zeros, with a frame for each MelFrame of
+// audio and the number of mel bands of the model, or 80 when it is not set
+func (context *Context) PCMToMel(data []float32) (whisper.Mel, error) {
+	if len(data) == 0 {
+		return whisper.Mel{}, whisper.ErrProcessingFailed
+	}
+	mels, frames := context.model.mels(), max(len(data)/melSamples, 1)
+	return whisper.Mel{Mels: mels, Frames: frames, Data: make([]float32, mels*frames)}, nil
+}
+
+// Set the log mel spectrogram, which is recorded, or return an error when
+// the number of mel bands is not that of the model
+func (context *Context) SetMel(mel whisper.Mel) error {
+	if mel.Frames == 0 && len(mel.Data) == 0 {
+		context.Lock()
+		defer context.Unlock()
+		context.Mel = nil
+		return nil
+	}
+	if mel.Mels != context.model.mels() || mel.Frames <= 0 || len(mel.Data) != mel.Mels*mel.Frames {
+		return whisper.ErrInvalidMel
+	}
+	mel.Data = slices.Clone(mel.Data)
+	context.Lock()
+	defer context.Unlock()
+	context.Mel = &mel
+
+	// Return success
+	return nil
+}
+
+// Return an output of the encoder of zeros, with a frame for each
+// EmbeddingFrame of the audio, or of the spectrogram which is set when there
+// is no audio, and the width of the encoder of the model, or 384 when it is
+// not set
+func (context *Context) Encode(data []float32) (whisper.Embedding, error) {
+	frames := len(data) / (2 * melSamples)
+	if len(data) == 0 {
+		context.Lock()
+		mel := context.Mel
+		context.Unlock()
+		if mel == nil {
+			return whisper.Embedding{}, whisper.ErrProcessingFailed
+		}
+		frames = (mel.Frames + 1) / 2
+	}
+	width, frames := context.model.width(), max(frames, 1)
+	return whisper.Embedding{Frames: frames, Width: width, Data: make([]float32, frames*width)}, nil
+}
+
+// Return the detected language with a probability of one
+func (context *Context) DetectLanguage(data []float32) (string, []whisper.LanguageProb, error) {
+	lang := context.model.language()
+	return lang, []whisper.LanguageProb{{Language: lang, P: 1}}, nil
+}
+
+// Return the next segment of the last call to Process, or io.EOF after the
+// last segment
+func (context *Context) NextSegment() (whisper.Segment, error) {
+	context.Lock()
+	defer context.Unlock()
+	if context.n >= len(context.segments) {
+		return whisper.Segment{}, io.EOF
+	}
+	context.n++
+	return context.segments[context.n-1], nil
+}
+
+// Return the segments of the last call to Process
+func (context *Context) Segments() []whisper.Segment {
+	context.Lock()
+	defer context.Unlock()
+	return slices.Clone(context.segments)
+}
+
+// Clear the segments of the last call to Process, and the spectrogram which
+// is set
+func (context *Context) Reset() {
+	context.Lock()
+	defer context.Unlock()
+	context.segments, context.n = nil, 0
+	context.Mel = nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS - TOKENS
+
+// Special tokens are those with text in brackets, such as "[_BEG_]", as
+// returned by whisper.cpp, and the others are text tokens
+func (context *Context) IsBEG(t whisper.Token) bool  { return t.Text == "[_BEG_]" }
+func (context *Context) IsSOT(t whisper.Token) bool  { return t.Text == "[_SOT_]" }
+func (context *Context) IsEOT(t whisper.Token) bool  { return t.Text == "[_EOT_]" }
+func (context *Context) IsPREV(t whisper.Token) bool { return t.Text == "[_PREV_]" }
+func (context *Context) IsSOLM(t whisper.Token) bool { return t.Text == "[_SOLM_]" }
+func (context *Context) IsNOT(t whisper.Token) bool  { return t.Text == "[_NOT_]" }
+func (context *Context) IsText(t whisper.Token) bool { return !strings.HasPrefix(t.Text, "[_") }
+
+func (context *Context) IsLANG(t whisper.Token, lang string) bool {
+	return t.Text == "[_"+lang+"_]"
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS - TIMINGS
+
+func (context *Context) PrintTimings() {}
+
+func (context *Context) ResetTimings() {
+	context.Lock()
+	defer context.Unlock()
+	context.timings = whisper.Timings{}
+}
+
+// Return the timings of the last call to Process, in which only the audio,
+// the total time and the real-time factor are set
+func (context *Context) Timings() whisper.Timings {
+	context.Lock()
+	defer context.Unlock()
+	return context.timings
+}
+
+func (context *Context) SystemInfo() string {
+	return "whispertest"
+}
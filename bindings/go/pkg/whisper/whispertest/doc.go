@@ -0,0 +1,18 @@
+/*
+Package whispertest provides fake implementations of the whisper Model,
+Context and Pool interfaces, for unit testing code which handles
+transcriptions without a model file.
+
+A fake context returns scripted segments from each call to Process, passing
+them to the callbacks as a real context would, and records the parameters
+it is set with and the audio it processes:
+
+	model := whispertest.NewModel(whisper.Segment{Text: "hello", End: time.Second})
+	context, _ := model.NewContext()
+	context.Process(samples, nil, nil)
+	segment, _ := context.NextSegment()
+
+The fakes run no inference and make no calls into whisper.cpp, although the
+whisper package, which defines the interfaces, is still linked.
+*/
+package whispertest
@@ -0,0 +1,340 @@
+package whispertest
+
+import (
+	gocontext "context"
+	"slices"
+	"strings"
+	"sync"
+
+	// Packages
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Model is a fake model, which returns contexts that transcribe audio as
+// the scripted segments. Set the fields before creating contexts.
+type Model struct {
+	sync.Mutex
+
+	// Segments returned by each call to Process of the contexts
+	Segments []whisper.Segment
+
+	// When set, returns the segments and error of each call to Process in
+	// place of Segments, for example to script each window of a stream
+	Func func(data []float32) ([]whisper.Segment, error)
+
+	// Languages of the model. The model is multilingual when there are
+	// languages other than English.
+	Langs []string
+
+	// Metadata of the model
+	ModelInfo whisper.ModelInfo
+
+	// Contexts created, in order
+	Contexts []*Context
+
+	vocab  []string // Text of each token id, from the scripted tokens and Tokenize
+	closed bool
+}
+
+// Pool is a fake pool, which hands out contexts of a fake model
+type Pool struct {
+	sync.Mutex
+	model  *Model
+	path   string
+	size   int
+	inuse  map[whisper.Context]bool
+	closed bool
+}
+
+// Make sure the fakes adhere to the interfaces
+var _ whisper.Model = (*Model)(nil)
+var _ whisper.Pool = (*Pool)(nil)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// NewModel returns a fake English model, whose contexts return the segments
+// from each call to Process
+func NewModel(segments ...whisper.Segment) *Model {
+	return &Model{
+		Segments:  segments,
+		Langs:     []string{"en"},
+		ModelInfo: whisper.ModelInfo{Type: "tiny", Quantization: "f16"},
+	}
+}
+
+// NewPool returns a fake pool of a model, with up to size contexts in use at
+// once. Get returns whisper.ErrPoolExhausted when all are in use.
+func NewPool(model *Model, path string, size int) *Pool {
+	return &Pool{
+		model: model,
+		path:  path,
+		size:  max(size, 1),
+		inuse: make(map[whisper.Context]bool),
+	}
+}
+
+// Close the model
+func (model *Model) Close() error {
+	model.Lock()
+	defer model.Unlock()
+	model.closed = true
+	return nil
+}
+
+// Close the pool
+func (pool *Pool) Close() error {
+	pool.Lock()
+	defer pool.Unlock()
+	pool.closed = true
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS - MODEL
+
+// Return a new fake context
+func (model *Model) NewContext() (whisper.Context, error) {
+	if context, err := model.newContext(); err != nil {
+		return nil, err
+	} else {
+		return context, nil
+	}
+}
+
+// Return a new fake context, as NewContext
+func (model *Model) NewState() (whisper.StateContext, error) {
+	if context, err := model.newContext(); err != nil {
+		return nil, err
+	} else {
+		return context, nil
+	}
+}
+
+// Return true if the model has languages other than English
+func (model *Model) IsMultilingual() bool {
+	for _, lang := range model.Langs {
+		if lang != "en" {
+			return true
+		}
+	}
+	return false
+}
+
+// Return the languages of the model
+func (model *Model) Languages() []string {
+	return model.Langs
+}
+
+// Return the metadata of the model
+func (model *Model) Info() whisper.ModelInfo {
+	info := model.ModelInfo
+	info.Multilingual = model.IsMultilingual()
+	return info
+}
+
+// Return the tokens of text, as the words of the text with a leading
+// space, each with the id of the token of the scripted segments with the
+// same text, or a new id
+func (model *Model) Tokenize(text string) ([]whisper.Token, error) {
+	model.Lock()
+	defer model.Unlock()
+	var result []whisper.Token
+	for i, word := range strings.Fields(text) {
+		if i > 0 || strings.HasPrefix(text, " ") {
+			word = " " + word
+		}
+		result = append(result, whisper.Token{Id: model.token(word), Text: word})
+	}
+
+	// Return success
+	return result, nil
+}
+
+// Return the text of a token id, or an empty string when it is not a token
+// of the scripted segments or returned by Tokenize
+func (model *Model) TokenToString(id int) string {
+	model.Lock()
+	defer model.Unlock()
+	if vocab := model.vocabulary(); id >= 0 && id < len(vocab) {
+		return vocab[id]
+	}
+	return ""
+}
+
+// Return the ids of the special tokens, whose text is in brackets, such as
+// "[_EOT_]", as the fake contexts expect
+func (model *Model) SpecialTokens() whisper.SpecialTokens {
+	model.Lock()
+	defer model.Unlock()
+	return whisper.SpecialTokens{
+		EOT:        model.token("[_EOT_]"),
+		SOT:        model.token("[_SOT_]"),
+		PREV:       model.token("[_PREV_]"),
+		SOLM:       model.token("[_SOLM_]"),
+		NOT:        model.token("[_NOT_]"),
+		BEG:        model.token("[_BEG_]"),
+		Translate:  model.token("[_TRANSLATE_]"),
+		Transcribe: model.token("[_TRANSCRIBE_]"),
+	}
+}
+
+// Return the id of the token of a language of the model, whose text is the
+// language in brackets, such as "[_en_]"
+func (model *Model) LanguageToken(lang string) (int, error) {
+	if !slices.Contains(model.Languages(), lang) {
+		return 0, whisper.ErrUnsupportedLanguage
+	}
+	model.Lock()
+	defer model.Unlock()
+	return model.token("[_" + lang + "_]"), nil
+}
+
+// IsClosed returns true when the model has been closed
+func (model *Model) IsClosed() bool {
+	model.Lock()
+	defer model.Unlock()
+	return model.closed
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS - POOL
+
+// Return a context, or an error when the pool is closed, all the contexts
+// are in use, or the go context is done
+func (pool *Pool) Get(ctx gocontext.Context) (whisper.Context, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	pool.Lock()
+	defer pool.Unlock()
+	if pool.closed {
+		return nil, whisper.ErrPoolClosed
+	} else if len(pool.inuse) >= pool.size {
+		return nil, whisper.ErrPoolExhausted
+	}
+	context, err := pool.model.NewContext()
+	if err != nil {
+		return nil, err
+	}
+	pool.inuse[context] = true
+
+	// Return success
+	return context, nil
+}
+
+// Return a context obtained with Get to the pool
+func (pool *Pool) Put(context whisper.Context) error {
+	pool.Lock()
+	defer pool.Unlock()
+	if !pool.inuse[context] {
+		return whisper.ErrInternalAppError
+	}
+	delete(pool.inuse, context)
+	return nil
+}
+
+// Return the maximum number of contexts in use at once
+func (pool *Pool) Size() int {
+	return pool.size
+}
+
+// Return the path of the model
+func (pool *Pool) Path() string {
+	pool.Lock()
+	defer pool.Unlock()
+	return pool.path
+}
+
+// Return the metadata of the model
+func (pool *Pool) Info() whisper.ModelInfo {
+	return pool.model.Info()
+}
+
+// Set the path of the model, which is otherwise unchanged
+func (pool *Pool) Reload(path string) error {
+	pool.Lock()
+	defer pool.Unlock()
+	if pool.closed {
+		return whisper.ErrPoolClosed
+	}
+	pool.path = path
+	return nil
+}
+
+// InUse returns the number of contexts in use
+func (pool *Pool) InUse() int {
+	pool.Lock()
+	defer pool.Unlock()
+	return len(pool.inuse)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Return a new context, and add it to the contexts of the model
+func (model *Model) newContext() (*Context, error) {
+	model.Lock()
+	defer model.Unlock()
+	if model.closed {
+		return nil, whisper.ErrInternalAppError
+	}
+	context := &Context{model: model, lang: "en"}
+	model.Contexts = append(model.Contexts, context)
+	return context, nil
+}
+
+// Return the first language of the model, or English when it has none
+func (model *Model) language() string {
+	if len(model.Langs) > 0 {
+		return model.Langs[0]
+	}
+	return "en"
+}
+
+// Return the number of mel bands of the model, or 80 when it is not set
+func (model *Model) mels() int {
+	if model.ModelInfo.Mels > 0 {
+		return model.ModelInfo.Mels
+	}
+	return 80
+}
+
+// Return the width of the encoder of the model, or 384 when it is not set
+func (model *Model) width() int {
+	if model.ModelInfo.AudioState > 0 {
+		return model.ModelInfo.AudioState
+	}
+	return 384
+}
+
+// Return the text of each token id, adding the tokens of the scripted
+// segments to the vocabulary the first time
+func (model *Model) vocabulary() []string {
+	if model.vocab == nil {
+		model.vocab = []string{}
+		for _, segment := range model.Segments {
+			for _, token := range segment.Tokens {
+				if token.Id >= len(model.vocab) {
+					model.vocab = append(model.vocab, make([]string, token.Id-len(model.vocab)+1)...)
+				}
+				model.vocab[token.Id] = token.Text
+			}
+		}
+	}
+	return model.vocab
+}
+
+// Return the id of the token with text, adding it to the vocabulary when
+// there is none
+func (model *Model) token(text string) int {
+	id := slices.Index(model.vocabulary(), text)
+	if id < 0 {
+		id = len(model.vocab)
+		model.vocab = append(model.vocab, text)
+	}
+	return id
+}
@@ -0,0 +1,205 @@
+package whispertest_test
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	// Packages
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	whispertest "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper/whispertest"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func Test_WhisperTest_000(t *testing.T) {
+	assert := assert.New(t)
+	model := whispertest.NewModel(
+		whisper.Segment{Text: "hello", End: time.Second, Tokens: []whisper.Token{{Id: 1, Text: "hello", P: 0.9}}},
+		whisper.Segment{Text: "world", Start: time.Second, End: 2 * time.Second},
+	)
+	assert.False(model.IsMultilingual())
+	ctx, err := model.NewContext()
+	assert.NoError(err)
+
+	// Parameters are recorded, and the language is checked as with a model
+	ctx.SetTranslate(true)
+	ctx.SetInitialPrompt("greeting")
+	assert.ErrorIs(ctx.SetLanguage("de"), whisper.ErrModelNotMultilingual)
+
+	// The segments are returned, and passed to the callbacks
+	var segments []whisper.Segment
+	var tokens []whisper.Token
+	var progress int
+	ctx.SetTokenCallback(func(segment whisper.Segment, token whisper.Token) {
+		tokens = append(tokens, token)
+	})
+	data := make([]float32, whisper.SampleRate*2)
+	assert.NoError(ctx.Process(data, func(segment whisper.Segment) {
+		segments = append(segments, segment)
+	}, func(v int) {
+		progress = v
+	}))
+	assert.Len(segments, 2)
+	assert.Len(tokens, 1)
+	assert.Equal(100, progress)
+	for i := 0; ; i++ {
+		segment, err := ctx.NextSegment()
+		if err == io.EOF {
+			assert.Equal(2, i)
+			break
+		}
+		assert.NoError(err)
+		assert.Equal(i, segment.Num)
+		assert.Equal("en", segment.Language)
+	}
+	assert.Equal(segments, ctx.Segments())
+	assert.Equal("en", ctx.DetectedLanguage())
+	assert.Equal(2*time.Second, ctx.Timings().Audio)
+
+	fake := model.Contexts[0]
+	assert.True(fake.Translate)
+	assert.Equal("greeting", fake.Prompt)
+	if assert.Len(fake.Audio, 1) {
+		assert.Len(fake.Audio[0], len(data))
+	}
+}
+
+func Test_WhisperTest_001(t *testing.T) {
+	assert := assert.New(t)
+	model := whispertest.NewModel(whisper.Segment{Text: "one"}, whisper.Segment{Text: "two"})
+	model.Langs = []string{"en", "de"}
+	ctx, err := model.NewContext()
+	assert.NoError(err)
+	assert.NoError(ctx.SetLanguage("de"))
+	assert.ErrorIs(ctx.SetLanguage("xx"), whisper.ErrUnsupportedLanguage)
+
+	// A cancelled go context stops processing
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(ctx.ProcessContext(cancelled, make([]float32, whisper.SampleRate), nil, nil), context.Canceled)
+
+	// The abort function stops processing, keeping the segments before
+	var calls int
+	ctx.SetAbortFunc(func() bool {
+		calls++
+		return calls > 1
+	})
+	assert.ErrorIs(ctx.Process(make([]float32, whisper.SampleRate), nil, nil), whisper.ErrAborted)
+	segment, err := ctx.NextSegment()
+	assert.NoError(err)
+	assert.Equal("one", segment.Text)
+	assert.Equal("de", segment.Language)
+	_, err = ctx.NextSegment()
+	assert.ErrorIs(err, io.EOF)
+}
+
+func Test_WhisperTest_002(t *testing.T) {
+	assert := assert.New(t)
+
+	// Script a segment for each window of a stream
+	model := whispertest.NewModel()
+	model.Func = func(data []float32) ([]whisper.Segment, error) {
+		duration := time.Duration(len(data)) * time.Second / whisper.SampleRate
+		return []whisper.Segment{{Text: "window", End: duration}}, nil
+	}
+	ctx, err := model.NewContext()
+	assert.NoError(err)
+	var segments []whisper.Segment
+	stream, err := whisper.NewStreamingContext(ctx, func(segment whisper.Segment) {
+		segments = append(segments, segment)
+	})
+	assert.NoError(err)
+	stream.SetWindow(time.Second)
+	data := make([]float32, whisper.SampleRate/10)
+	for i := 0; i < 25; i++ {
+		assert.NoError(stream.Feed(data))
+	}
+	assert.NoError(stream.Flush())
+	assert.NotEmpty(segments)
+	for i, segment := range segments {
+		assert.Equal(i, segment.Num)
+		assert.Equal("window", segment.Text)
+	}
+}
+
+func Test_WhisperTest_003(t *testing.T) {
+	assert := assert.New(t)
+	pool := whispertest.NewPool(whispertest.NewModel(), "models/fake.bin", 1)
+	assert.Equal(1, pool.Size())
+	assert.Equal("models/fake.bin", pool.Path())
+
+	// Contexts are handed out up to the size of the pool
+	ctx, err := pool.Get(context.Background())
+	assert.NoError(err)
+	_, err = pool.Get(context.Background())
+	assert.ErrorIs(err, whisper.ErrPoolExhausted)
+	assert.Equal(1, pool.InUse())
+	assert.NoError(pool.Put(ctx))
+	assert.ErrorIs(pool.Put(ctx), whisper.ErrInternalAppError)
+
+	// Reload replaces the path, and a closed pool returns an error
+	assert.NoError(pool.Reload("models/other.bin"))
+	assert.Equal("models/other.bin", pool.Path())
+	assert.NoError(pool.Close())
+	_, err = pool.Get(context.Background())
+	assert.ErrorIs(err, whisper.ErrPoolClosed)
+}
+
+func Test_WhisperTest_004(t *testing.T) {
+	assert := assert.New(t)
+	model := whispertest.NewModel(whisper.Segment{Text: "hello", Tokens: []whisper.Token{{Id: 2, Text: " hello"}}})
+
+	// Words are tokens, with the ids of the scripted tokens
+	tokens, err := model.Tokenize(" hello world")
+	assert.NoError(err)
+	if assert.Len(tokens, 2) {
+		assert.Equal(whisper.Token{Id: 2, Text: " hello"}, tokens[0])
+		assert.Equal(" world", model.TokenToString(tokens[1].Id))
+	}
+	assert.Empty(model.TokenToString(100))
+}
+
+func Test_WhisperTest_005(t *testing.T) {
+	assert := assert.New(t)
+	model := whispertest.NewModel()
+	ctx, err := model.NewContext()
+	assert.NoError(err)
+
+	// The special tokens are those the fake context tests for
+	special := model.SpecialTokens()
+	assert.True(ctx.IsEOT(whisper.Token{Id: special.EOT, Text: model.TokenToString(special.EOT)}))
+	assert.True(ctx.IsBEG(whisper.Token{Id: special.BEG, Text: model.TokenToString(special.BEG)}))
+	assert.Equal(special, model.SpecialTokens())
+
+	id, err := model.LanguageToken("en")
+	assert.NoError(err)
+	assert.True(ctx.IsLANG(whisper.Token{Id: id, Text: model.TokenToString(id)}, "en"))
+	_, err = model.LanguageToken("de")
+	assert.ErrorIs(err, whisper.ErrUnsupportedLanguage)
+}
+
+func Test_WhisperTest_006(t *testing.T) {
+	assert := assert.New(t)
+	model := whispertest.NewModel(whisper.Segment{Text: "hello"})
+	ctx, err := model.NewContext()
+	assert.NoError(err)
+
+	// A spectrogram is processed in place of audio, until reset
+	mel, err := ctx.PCMToMel(make([]float32, whisper.SampleRate))
+	assert.NoError(err)
+	assert.Equal(whisper.Mel{Mels: 80, Frames: 100, Data: make([]float32, 8000)}, mel)
+	assert.ErrorIs(ctx.SetMel(whisper.Mel{Mels: 128, Frames: 1, Data: make([]float32, 128)}), whisper.ErrInvalidMel)
+	assert.NoError(ctx.SetMel(mel))
+	assert.NoError(ctx.Process(nil, nil, nil))
+	assert.Equal(time.Second, ctx.Timings().Audio)
+	assert.Equal(&mel, model.Contexts[0].Mel)
+
+	// The output of the encoder has a frame for each two of the spectrogram
+	embedding, err := ctx.Encode(nil)
+	assert.NoError(err)
+	assert.Equal(50, embedding.Frames)
+	assert.Len(embedding.Data, 50*embedding.Width)
+	ctx.Reset()
+	assert.ErrorIs(ctx.Process(nil, nil, nil), whisper.ErrProcessingFailed)
+}
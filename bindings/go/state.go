@@ -0,0 +1,217 @@
+package whisper
+
+import (
+	"unsafe"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// CGO
+
+/*
+#include <whisper.h>
+#include <stdlib.h>
+#include "compat.h"
+*/
+import "C"
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// State is a decoding state, which holds the mel spectrogram, the caches and
+// the results of whisper_full_with_state. Each state can process on its own
+// goroutine, concurrently with other states of the same context, so that the
+// model weights are loaded once.
+type State C.struct_whisper_state
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Allocates a new decoding state for the context. Returns NULL on failure.
+func (ctx *Context) Whisper_init_state() *State {
+	if state := C.whisper_init_state((*C.struct_whisper_context)(ctx)); state != nil {
+		return (*State)(state)
+	} else {
+		return nil
+	}
+}
+
+// Frees all memory allocated by the state.
+func (state *State) Whisper_free_state() {
+	C.whisper_free_state((*C.struct_whisper_state)(state))
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Convert RAW PCM audio to log mel spectrogram, which is stored in the state.
+func (ctx *Context) Whisper_pcm_to_mel_with_state(state *State, data []float32, threads int) error {
+	if C.whisper_pcm_to_mel_with_state((*C.struct_whisper_context)(ctx), (*C.struct_whisper_state)(state), (*C.float)(&data[0]), C.int(len(data)), C.int(threads)) == 0 {
+		return nil
+	} else {
+		return ErrConversionFailed
+	}
+}
+
+// Set a custom log mel spectrogram in the state, as n_mel rows of frames, as
+// Whisper_set_mel
+func (ctx *Context) Whisper_set_mel_with_state(state *State, data []float32, n_mel int) error {
+	if n_mel <= 0 || len(data) == 0 || len(data)%n_mel != 0 {
+		return ErrConversionFailed
+	}
+	if C.whisper_set_mel_with_state((*C.struct_whisper_context)(ctx), (*C.struct_whisper_state)(state), (*C.float)(&data[0]), C.int(len(data)/n_mel), C.int(n_mel)) == 0 {
+		return nil
+	} else {
+		return ErrConversionFailed
+	}
+}
+
+// Run the encoder on the log mel spectrogram stored in the state, from the
+// frame at offset.
+func (ctx *Context) Whisper_encode_with_state(state *State, offset, threads int) error {
+	if C.whisper_encode_with_state((*C.struct_whisper_context)(ctx), (*C.struct_whisper_state)(state), C.int(offset), C.int(threads)) == 0 {
+		return nil
+	} else {
+		return ErrConversionFailed
+	}
+}
+
+// Use mel data in the state at offset_ms to try and auto-detect the spoken
+// language. Returns the probabilities of all languages.
+func (ctx *Context) Whisper_lang_auto_detect_with_state(state *State, offset_ms, n_threads int) ([]float32, error) {
+	probs := make([]float32, Whisper_lang_max_id()+1)
+	if n := int(C.whisper_lang_auto_detect_with_state((*C.struct_whisper_context)(ctx), (*C.struct_whisper_state)(state), C.int(offset_ms), C.int(n_threads), (*C.float)(&probs[0]))); n < 0 {
+		return nil, ErrAutoDetectFailed
+	} else {
+		return probs, nil
+	}
+}
+
+// Run the entire model as Whisper_full_with_abort, but with the state
+// instead of the default state of the context, so that the results are
+// stored in the state.
+func (ctx *Context) Whisper_full_with_state(
+	state *State,
+	params Params,
+	samples []float32,
+	encoderBeginCallback func() bool,
+	newSegmentCallback func(int),
+	progressCallback func(int),
+	abortCallback func() bool,
+) error {
+	// The callbacks are keyed by the state, as several states of the context
+	// can process at once
+	key := unsafe.Pointer(state)
+	params.new_segment_callback_user_data = key
+	params.encoder_begin_callback_user_data = key
+	params.progress_callback_user_data = key
+	params.abort_callback_user_data = key
+	params.logits_filter_callback_user_data = key
+	registerEncoderBeginCallback(key, encoderBeginCallback)
+	registerNewSegmentCallback(key, newSegmentCallback)
+	registerProgressCallback(key, progressCallback)
+	registerAbortCallback(key, abortCallback)
+	defer registerEncoderBeginCallback(key, nil)
+	defer registerNewSegmentCallback(key, nil)
+	defer registerProgressCallback(key, nil)
+	defer registerAbortCallback(key, nil)
+	if C.whisper_full_with_state((*C.struct_whisper_context)(ctx), (*C.struct_whisper_state)(state), (C.struct_whisper_full_params)(params), samplesPtr(samples), C.int(len(samples))) == 0 {
+		return nil
+	} else {
+		return ErrConversionFailed
+	}
+}
+
+// Set the logits filter for processing with the state, as with
+// Context.Whisper_set_logits_filter
+func (state *State) Whisper_set_logits_filter(fn func([]TokenData, []float32)) {
+	registerLogitsFilterCallback(unsafe.Pointer(state), fn)
+}
+
+// Return a copy of the log mel spectrogram stored in the state, as
+// Context.Whisper_get_mel
+func (state *State) Whisper_get_mel() ([]float32, int) {
+	var n_len, n_len_org, n_mel C.int
+	data := C.whisper_get_mel_from_state((*C.struct_whisper_state)(state), &n_len, &n_len_org, &n_mel)
+	return copyMel(data, int(n_len), int(n_len_org), int(n_mel)), int(n_mel)
+}
+
+// Return the number of frames of audio in the log mel spectrogram stored in
+// the state
+func (state *State) Whisper_n_len() int {
+	return int(C.whisper_n_len_from_state((*C.struct_whisper_state)(state)))
+}
+
+// Return a copy of the output of the encoder stored in the state, as
+// Context.Whisper_get_encoder_output
+func (state *State) Whisper_get_encoder_output() []float32 {
+	n := int(C.whisper_get_encoder_output_from_state((*C.struct_whisper_state)(state), nil, 0))
+	if n <= 0 {
+		return nil
+	}
+	data := make([]float32, n)
+	C.whisper_get_encoder_output_from_state((*C.struct_whisper_state)(state), (*C.float)(&data[0]), C.int(n))
+	return data
+}
+
+// Return the time spent in each stage by the state since it was created,
+// which is zero when whisper.cpp does not provide the timings
+func (state *State) Whisper_get_timings() Timings {
+	return Timings(C.whisper_get_timings_from_state((*C.struct_whisper_state)(state)))
+}
+
+// Return the id of the language detected by the last call to
+// Whisper_full_with_state
+func (state *State) Whisper_full_lang_id() int {
+	return int(C.whisper_full_lang_id_from_state((*C.struct_whisper_state)(state)))
+}
+
+// Number of generated text segments.
+func (state *State) Whisper_full_n_segments() int {
+	return int(C.whisper_full_n_segments_from_state((*C.struct_whisper_state)(state)))
+}
+
+// Get the start and end time of the specified segment.
+func (state *State) Whisper_full_get_segment_t0(segment int) int64 {
+	return int64(C.whisper_full_get_segment_t0_from_state((*C.struct_whisper_state)(state), C.int(segment)))
+}
+
+// Get the start and end time of the specified segment.
+func (state *State) Whisper_full_get_segment_t1(segment int) int64 {
+	return int64(C.whisper_full_get_segment_t1_from_state((*C.struct_whisper_state)(state), C.int(segment)))
+}
+
+// Get whether the next segment is predicted as a speaker turn (tinydiarize).
+func (state *State) Whisper_full_get_segment_speaker_turn_next(segment int) bool {
+	return bool(C.whisper_full_get_segment_speaker_turn_next_from_state((*C.struct_whisper_state)(state), C.int(segment)))
+}
+
+// Get the text of the specified segment.
+func (state *State) Whisper_full_get_segment_text(segment int) string {
+	return C.GoString(C.whisper_full_get_segment_text_from_state((*C.struct_whisper_state)(state), C.int(segment)))
+}
+
+// Get number of tokens in the specified segment.
+func (state *State) Whisper_full_n_tokens(segment int) int {
+	return int(C.whisper_full_n_tokens_from_state((*C.struct_whisper_state)(state), C.int(segment)))
+}
+
+// Get the token text of the specified token index in the specified segment.
+// The vocabulary is in the context.
+func (ctx *Context) Whisper_full_get_token_text_from_state(state *State, segment int, token int) string {
+	return C.GoString(C.whisper_full_get_token_text_from_state((*C.struct_whisper_context)(ctx), (*C.struct_whisper_state)(state), C.int(segment), C.int(token)))
+}
+
+// Get the token of the specified token index in the specified segment.
+func (state *State) Whisper_full_get_token_id(segment int, token int) Token {
+	return Token(C.whisper_full_get_token_id_from_state((*C.struct_whisper_state)(state), C.int(segment), C.int(token)))
+}
+
+// Get token data for the specified token in the specified segment.
+func (state *State) Whisper_full_get_token_data(segment int, token int) TokenData {
+	return TokenData(C.whisper_full_get_token_data_from_state((*C.struct_whisper_state)(state), C.int(segment), C.int(token)))
+}
+
+// Get the probability of the specified token in the specified segment.
+func (state *State) Whisper_full_get_token_p(segment int, token int) float32 {
+	return float32(C.whisper_full_get_token_p_from_state((*C.struct_whisper_state)(state), C.int(segment), C.int(token)))
+}
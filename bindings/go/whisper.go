@@ -2,6 +2,7 @@ package whisper
 
 import (
 	"errors"
+	"sync"
 	"unsafe"
 )
 
@@ -13,10 +14,13 @@ import (
 #cgo darwin LDFLAGS: -framework Accelerate
 #include <whisper.h>
 #include <stdlib.h>
+#include "compat.h"
 
 extern void callNewSegment(void* user_data, int new);
 extern void callProgress(void* user_data, int progress);
 extern bool callEncoderBegin(void* user_data);
+extern bool callAbort(void* user_data);
+extern void callLogitsFilter(void* user_data, struct whisper_token_data* tokens, int n_tokens, float* logits, int n_vocab);
 
 // Text segment callback
 // Called on every newly generated text segment
@@ -46,6 +50,25 @@ static bool whisper_encoder_begin_cb(struct whisper_context* ctx, struct whisper
     return false;
 }
 
+// Abort callback
+// If not NULL, called before ggml computation
+// If it returns true, the computation is aborted
+static bool whisper_abort_cb(void* user_data) {
+    if(user_data != NULL) {
+        return callAbort(user_data);
+    }
+    return false;
+}
+
+// Logits filter callback
+// Called after applying temperature to the logits of the next token, which
+// can be modified before sampling
+static void whisper_logits_filter_cb(struct whisper_context* ctx, struct whisper_state* state, const struct whisper_token_data* tokens, int n_tokens, float* logits, void* user_data) {
+    if(user_data != NULL && ctx != NULL) {
+        callLogitsFilter(user_data, (struct whisper_token_data*)(tokens), n_tokens, logits, whisper_n_vocab(ctx));
+    }
+}
+
 // Get default parameters and set callbacks
 static struct whisper_full_params whisper_full_default_params_cb(struct whisper_context* ctx, enum whisper_sampling_strategy strategy) {
 	struct whisper_full_params params = whisper_full_default_params(strategy);
@@ -55,6 +78,10 @@ static struct whisper_full_params whisper_full_default_params_cb(struct whisper_
 	params.encoder_begin_callback_user_data = (void*)(ctx);
 	params.progress_callback = whisper_progress_cb;
 	params.progress_callback_user_data = (void*)(ctx);
+	params.abort_callback = whisper_abort_cb;
+	params.abort_callback_user_data = (void*)(ctx);
+	params.logits_filter_callback = whisper_logits_filter_cb;
+	params.logits_filter_callback_user_data = (void*)(ctx);
 	return params;
 }
 */
@@ -69,6 +96,8 @@ type (
 	TokenData        C.struct_whisper_token_data
 	SamplingStrategy C.enum_whisper_sampling_strategy
 	Params           C.struct_whisper_full_params
+	ContextParams    C.struct_whisper_context_params
+	Timings          C.struct_whisper_timings
 )
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -87,6 +116,11 @@ const (
 	ChunkSize  = C.WHISPER_CHUNK_SIZE
 )
 
+const (
+	HasGetMel        = C.WHISPER_GO_HAS_GET_MEL != 0        // True when the log mel spectrogram can be read
+	HasEncoderOutput = C.WHISPER_GO_HAS_ENCODER_OUTPUT != 0 // True when the output of the encoder can be read
+)
+
 var (
 	ErrTokenizerFailed  = errors.New("whisper_tokenize failed")
 	ErrAutoDetectFailed = errors.New("whisper_lang_auto_detect failed")
@@ -100,15 +134,46 @@ var (
 // Allocates all memory needed for the model and loads the model from the given file.
 // Returns NULL on failure.
 func Whisper_init(path string) *Context {
+	return Whisper_init_with_params(path, Whisper_context_default_params())
+}
+
+// Return the default context parameters
+func Whisper_context_default_params() ContextParams {
+	return ContextParams(C.whisper_context_default_params())
+}
+
+// Allocates all memory needed for the model and loads the model from the given file,
+// using the context parameters. Returns NULL on failure.
+func Whisper_init_with_params(path string, params ContextParams) *Context {
 	cPath := C.CString(path)
 	defer C.free(unsafe.Pointer(cPath))
-	if ctx := C.whisper_init_from_file_with_params(cPath, C.whisper_context_default_params()); ctx != nil {
+	if ctx := C.whisper_init_from_file_with_params(cPath, (C.struct_whisper_context_params)(params)); ctx != nil {
 		return (*Context)(ctx)
 	} else {
 		return nil
 	}
 }
 
+// Initialize the OpenVINO encoder on a device, such as "CPU" or "GPU". When
+// the path is empty, the encoder is loaded from ggml-<model>-encoder-openvino.xml
+// next to the model, and when the cache directory is empty, compiled
+// encoders are cached next to the model. Returns 0 on success, or 1 on
+// failure or when whisper.cpp is built without OpenVINO.
+func (ctx *Context) Whisper_ctx_init_openvino_encoder(path, device, cache_dir string) int {
+	var cPath, cCacheDir *C.char
+	if path != "" {
+		cPath = C.CString(path)
+		defer C.free(unsafe.Pointer(cPath))
+	}
+	if cache_dir != "" {
+		cCacheDir = C.CString(cache_dir)
+		defer C.free(unsafe.Pointer(cCacheDir))
+	}
+	cDevice := C.CString(device)
+	defer C.free(unsafe.Pointer(cDevice))
+	return int(C.whisper_ctx_init_openvino_encoder((*C.struct_whisper_context)(ctx), cPath, cDevice, cCacheDir))
+}
+
 // Frees all memory allocated by the model.
 func (ctx *Context) Whisper_free() {
 	C.whisper_free((*C.struct_whisper_context)(ctx))
@@ -126,15 +191,27 @@ func (ctx *Context) Whisper_pcm_to_mel(data []float32, threads int) error {
 
 // This can be used to set a custom log mel spectrogram inside the provided whisper context.
 // Use this instead of whisper_pcm_to_mel() if you want to provide your own log mel spectrogram.
-// n_mel must be 80
+// The data is n_mel rows of frames, and n_mel must be the number of mel bands of the model.
 func (ctx *Context) Whisper_set_mel(data []float32, n_mel int) error {
-	if C.whisper_set_mel((*C.struct_whisper_context)(ctx), (*C.float)(&data[0]), C.int(len(data)), C.int(n_mel)) == 0 {
+	if n_mel <= 0 || len(data) == 0 || len(data)%n_mel != 0 {
+		return ErrConversionFailed
+	}
+	if C.whisper_set_mel((*C.struct_whisper_context)(ctx), (*C.float)(&data[0]), C.int(len(data)/n_mel), C.int(n_mel)) == 0 {
 		return nil
 	} else {
 		return ErrConversionFailed
 	}
 }
 
+// Return a copy of the log mel spectrogram stored inside the provided whisper context, as n_mel
+// rows of the frames of the audio, without the padding, and n_mel. Returns nil when there is
+// no spectrogram.
+func (ctx *Context) Whisper_get_mel() ([]float32, int) {
+	var n_len, n_len_org, n_mel C.int
+	data := C.whisper_get_mel((*C.struct_whisper_context)(ctx), &n_len, &n_len_org, &n_mel)
+	return copyMel(data, int(n_len), int(n_len_org), int(n_mel)), int(n_mel)
+}
+
 // Run the Whisper encoder on the log mel spectrogram stored inside the provided whisper context.
 // Make sure to call whisper_pcm_to_mel() or whisper_set_mel() first.
 // offset can be used to specify the offset of the first frame in the spectrogram.
@@ -146,6 +223,18 @@ func (ctx *Context) Whisper_encode(offset, threads int) error {
 	}
 }
 
+// Return a copy of the output of the encoder, computed by the last call to Whisper_encode(),
+// as frames of n_audio_state values. Returns nil when the encoder has not run.
+func (ctx *Context) Whisper_get_encoder_output() []float32 {
+	n := int(C.whisper_get_encoder_output((*C.struct_whisper_context)(ctx), nil, 0))
+	if n <= 0 {
+		return nil
+	}
+	data := make([]float32, n)
+	C.whisper_get_encoder_output((*C.struct_whisper_context)(ctx), (*C.float)(&data[0]), C.int(n))
+	return data
+}
+
 // Run the Whisper decoder to obtain the logits and probabilities for the next token.
 // Make sure to call whisper_encode() first.
 // tokens + n_tokens is the provided context for the decoder.
@@ -223,6 +312,60 @@ func (ctx *Context) Whisper_is_multilingual() int {
 	return int(C.whisper_is_multilingual((*C.struct_whisper_context)(ctx)))
 }
 
+func (ctx *Context) Whisper_model_n_vocab() int {
+	return int(C.whisper_model_n_vocab((*C.struct_whisper_context)(ctx)))
+}
+
+func (ctx *Context) Whisper_model_n_audio_ctx() int {
+	return int(C.whisper_model_n_audio_ctx((*C.struct_whisper_context)(ctx)))
+}
+
+func (ctx *Context) Whisper_model_n_audio_state() int {
+	return int(C.whisper_model_n_audio_state((*C.struct_whisper_context)(ctx)))
+}
+
+func (ctx *Context) Whisper_model_n_audio_head() int {
+	return int(C.whisper_model_n_audio_head((*C.struct_whisper_context)(ctx)))
+}
+
+func (ctx *Context) Whisper_model_n_audio_layer() int {
+	return int(C.whisper_model_n_audio_layer((*C.struct_whisper_context)(ctx)))
+}
+
+func (ctx *Context) Whisper_model_n_text_ctx() int {
+	return int(C.whisper_model_n_text_ctx((*C.struct_whisper_context)(ctx)))
+}
+
+func (ctx *Context) Whisper_model_n_text_state() int {
+	return int(C.whisper_model_n_text_state((*C.struct_whisper_context)(ctx)))
+}
+
+func (ctx *Context) Whisper_model_n_text_head() int {
+	return int(C.whisper_model_n_text_head((*C.struct_whisper_context)(ctx)))
+}
+
+func (ctx *Context) Whisper_model_n_text_layer() int {
+	return int(C.whisper_model_n_text_layer((*C.struct_whisper_context)(ctx)))
+}
+
+func (ctx *Context) Whisper_model_n_mels() int {
+	return int(C.whisper_model_n_mels((*C.struct_whisper_context)(ctx)))
+}
+
+// Return the weight type of the model, as a ggml_ftype value
+func (ctx *Context) Whisper_model_ftype() int {
+	return int(C.whisper_model_ftype((*C.struct_whisper_context)(ctx)))
+}
+
+func (ctx *Context) Whisper_model_type() int {
+	return int(C.whisper_model_type((*C.struct_whisper_context)(ctx)))
+}
+
+// Return the model size as a string, such as "tiny" or "large"
+func (ctx *Context) Whisper_model_type_readable() string {
+	return C.GoString(C.whisper_model_type_readable((*C.struct_whisper_context)(ctx)))
+}
+
 // The probabilities for the next token
 //func (ctx *Whisper_context) Whisper_get_probs() []float32 {
 //	return (*[1 << 30]float32)(unsafe.Pointer(C.whisper_get_probs((*C.struct_whisper_context)(ctx))))[:ctx.Whisper_n_vocab()]
@@ -288,6 +431,13 @@ func (ctx *Context) Whisper_reset_timings() {
 	C.whisper_reset_timings((*C.struct_whisper_context)(ctx))
 }
 
+// Return the time spent in each stage by the default state since the
+// timings were reset, which is zero when whisper.cpp does not provide the
+// timings
+func (ctx *Context) Whisper_get_timings() Timings {
+	return Timings(C.whisper_get_timings((*C.struct_whisper_context)(ctx)))
+}
+
 // Print system information
 func Whisper_print_system_info() string {
 	return C.GoString(C.whisper_print_system_info())
@@ -308,27 +458,52 @@ func (ctx *Context) Whisper_full(
 	newSegmentCallback func(int),
 	progressCallback func(int),
 ) error {
-	registerEncoderBeginCallback(ctx, encoderBeginCallback)
-	registerNewSegmentCallback(ctx, newSegmentCallback)
-	registerProgressCallback(ctx, progressCallback)
-	defer registerEncoderBeginCallback(ctx, nil)
-	defer registerNewSegmentCallback(ctx, nil)
-	defer registerProgressCallback(ctx, nil)
-	if C.whisper_full((*C.struct_whisper_context)(ctx), (C.struct_whisper_full_params)(params), (*C.float)(&samples[0]), C.int(len(samples))) == 0 {
+	return ctx.Whisper_full_with_abort(params, samples, encoderBeginCallback, newSegmentCallback, progressCallback, nil)
+}
+
+// Run the entire model as Whisper_full. The abort callback is called before
+// each ggml computation, and if it returns true, processing is aborted and
+// an error is returned.
+func (ctx *Context) Whisper_full_with_abort(
+	params Params,
+	samples []float32,
+	encoderBeginCallback func() bool,
+	newSegmentCallback func(int),
+	progressCallback func(int),
+	abortCallback func() bool,
+) error {
+	registerEncoderBeginCallback(unsafe.Pointer(ctx), encoderBeginCallback)
+	registerNewSegmentCallback(unsafe.Pointer(ctx), newSegmentCallback)
+	registerProgressCallback(unsafe.Pointer(ctx), progressCallback)
+	registerAbortCallback(unsafe.Pointer(ctx), abortCallback)
+	defer registerEncoderBeginCallback(unsafe.Pointer(ctx), nil)
+	defer registerNewSegmentCallback(unsafe.Pointer(ctx), nil)
+	defer registerProgressCallback(unsafe.Pointer(ctx), nil)
+	defer registerAbortCallback(unsafe.Pointer(ctx), nil)
+	if C.whisper_full((*C.struct_whisper_context)(ctx), (C.struct_whisper_full_params)(params), samplesPtr(samples), C.int(len(samples))) == 0 {
 		return nil
 	} else {
 		return ErrConversionFailed
 	}
 }
 
+// Set the logits filter, which is called with the tokens decoded so far in
+// the segment and the logits of the next token, after temperature is
+// applied, and can modify the logits before sampling. Both slices are of
+// the memory of whisper.cpp, and are only valid during the call, so the
+// filter must copy them to keep them. Pass nil to remove the filter.
+func (ctx *Context) Whisper_set_logits_filter(fn func([]TokenData, []float32)) {
+	registerLogitsFilterCallback(unsafe.Pointer(ctx), fn)
+}
+
 // Split the input audio in chunks and process each chunk separately using whisper_full()
 // It seems this approach can offer some speedup in some cases.
 // However, the transcription accuracy can be worse at the beginning and end of each chunk.
 func (ctx *Context) Whisper_full_parallel(params Params, samples []float32, processors int, encoderBeginCallback func() bool, newSegmentCallback func(int)) error {
-	registerEncoderBeginCallback(ctx, encoderBeginCallback)
-	registerNewSegmentCallback(ctx, newSegmentCallback)
-	defer registerEncoderBeginCallback(ctx, nil)
-	defer registerNewSegmentCallback(ctx, nil)
+	registerEncoderBeginCallback(unsafe.Pointer(ctx), encoderBeginCallback)
+	registerNewSegmentCallback(unsafe.Pointer(ctx), newSegmentCallback)
+	defer registerEncoderBeginCallback(unsafe.Pointer(ctx), nil)
+	defer registerNewSegmentCallback(unsafe.Pointer(ctx), nil)
 
 	if C.whisper_full_parallel((*C.struct_whisper_context)(ctx), (C.struct_whisper_full_params)(params), (*C.float)(&samples[0]), C.int(len(samples)), C.int(processors)) == 0 {
 		return nil
@@ -365,6 +540,11 @@ func (ctx *Context) Whisper_full_get_segment_t1(segment int) int64 {
 	return int64(C.whisper_full_get_segment_t1((*C.struct_whisper_context)(ctx), C.int(segment)))
 }
 
+// Get whether the next segment is predicted as a speaker turn (tinydiarize).
+func (ctx *Context) Whisper_full_get_segment_speaker_turn_next(segment int) bool {
+	return bool(C.whisper_full_get_segment_speaker_turn_next((*C.struct_whisper_context)(ctx), C.int(segment)))
+}
+
 // Get the text of the specified segment.
 func (ctx *Context) Whisper_full_get_segment_text(segment int) string {
 	return C.GoString(C.whisper_full_get_segment_text((*C.struct_whisper_context)(ctx), C.int(segment)))
@@ -396,56 +576,122 @@ func (ctx *Context) Whisper_full_get_token_p(segment int, token int) float32 {
 	return float32(C.whisper_full_get_token_p((*C.struct_whisper_context)(ctx), C.int(segment), C.int(token)))
 }
 
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Return a pointer to the samples, or NULL when there are none, in which
+// case whisper_full processes the log mel spectrogram which has been set
+func samplesPtr(samples []float32) *C.float {
+	if len(samples) == 0 {
+		return nil
+	}
+	return (*C.float)(&samples[0])
+}
+
+// Copy the first n_len_org frames of each of the n_mel rows of n_len frames
+func copyMel(data *C.float, n_len, n_len_org, n_mel int) []float32 {
+	if data == nil || n_len_org <= 0 || n_mel <= 0 {
+		return nil
+	}
+	src := unsafe.Slice((*float32)(unsafe.Pointer(data)), n_len*n_mel)
+	dst := make([]float32, 0, n_len_org*n_mel)
+	for j := 0; j < n_mel; j++ {
+		dst = append(dst, src[j*n_len:j*n_len+n_len_org]...)
+	}
+	return dst
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 // CALLBACKS
 
 var (
+	cbLock         sync.RWMutex
 	cbNewSegment   = make(map[unsafe.Pointer]func(int))
 	cbProgress     = make(map[unsafe.Pointer]func(int))
 	cbEncoderBegin = make(map[unsafe.Pointer]func() bool)
+	cbAbort        = make(map[unsafe.Pointer]func() bool)
+	cbLogitsFilter = make(map[unsafe.Pointer]func([]TokenData, []float32))
 )
 
-func registerNewSegmentCallback(ctx *Context, fn func(int)) {
+// Callbacks are registered with a key, which is passed to the callbacks as
+// user data: the context, or the state when processing with a state
+
+func registerNewSegmentCallback(key unsafe.Pointer, fn func(int)) {
+	cbLock.Lock()
+	defer cbLock.Unlock()
+	if fn == nil {
+		delete(cbNewSegment, key)
+	} else {
+		cbNewSegment[key] = fn
+	}
+}
+
+func registerProgressCallback(key unsafe.Pointer, fn func(int)) {
+	cbLock.Lock()
+	defer cbLock.Unlock()
+	if fn == nil {
+		delete(cbProgress, key)
+	} else {
+		cbProgress[key] = fn
+	}
+}
+
+func registerEncoderBeginCallback(key unsafe.Pointer, fn func() bool) {
+	cbLock.Lock()
+	defer cbLock.Unlock()
 	if fn == nil {
-		delete(cbNewSegment, unsafe.Pointer(ctx))
+		delete(cbEncoderBegin, key)
 	} else {
-		cbNewSegment[unsafe.Pointer(ctx)] = fn
+		cbEncoderBegin[key] = fn
 	}
 }
 
-func registerProgressCallback(ctx *Context, fn func(int)) {
+func registerAbortCallback(key unsafe.Pointer, fn func() bool) {
+	cbLock.Lock()
+	defer cbLock.Unlock()
 	if fn == nil {
-		delete(cbProgress, unsafe.Pointer(ctx))
+		delete(cbAbort, key)
 	} else {
-		cbProgress[unsafe.Pointer(ctx)] = fn
+		cbAbort[key] = fn
 	}
 }
 
-func registerEncoderBeginCallback(ctx *Context, fn func() bool) {
+func registerLogitsFilterCallback(key unsafe.Pointer, fn func([]TokenData, []float32)) {
+	cbLock.Lock()
+	defer cbLock.Unlock()
 	if fn == nil {
-		delete(cbEncoderBegin, unsafe.Pointer(ctx))
+		delete(cbLogitsFilter, key)
 	} else {
-		cbEncoderBegin[unsafe.Pointer(ctx)] = fn
+		cbLogitsFilter[key] = fn
 	}
 }
 
 //export callNewSegment
 func callNewSegment(user_data unsafe.Pointer, new C.int) {
-	if fn, ok := cbNewSegment[user_data]; ok {
+	cbLock.RLock()
+	fn, ok := cbNewSegment[user_data]
+	cbLock.RUnlock()
+	if ok {
 		fn(int(new))
 	}
 }
 
 //export callProgress
 func callProgress(user_data unsafe.Pointer, progress C.int) {
-	if fn, ok := cbProgress[user_data]; ok {
+	cbLock.RLock()
+	fn, ok := cbProgress[user_data]
+	cbLock.RUnlock()
+	if ok {
 		fn(int(progress))
 	}
 }
 
 //export callEncoderBegin
 func callEncoderBegin(user_data unsafe.Pointer) C.bool {
-	if fn, ok := cbEncoderBegin[user_data]; ok {
+	cbLock.RLock()
+	fn, ok := cbEncoderBegin[user_data]
+	cbLock.RUnlock()
+	if ok {
 		if fn() {
 			return C.bool(true)
 		} else {
@@ -455,6 +701,38 @@ func callEncoderBegin(user_data unsafe.Pointer) C.bool {
 	return true
 }
 
+//export callAbort
+func callAbort(user_data unsafe.Pointer) C.bool {
+	cbLock.RLock()
+	fn, ok := cbAbort[user_data]
+	cbLock.RUnlock()
+	if ok {
+		if fn() {
+			return C.bool(true)
+		} else {
+			return C.bool(false)
+		}
+	}
+	return false
+}
+
+// The tokens and logits are passed without a copy, and whisper.cpp reuses
+// their memory once the filter returns
+//
+//export callLogitsFilter
+func callLogitsFilter(user_data unsafe.Pointer, tokens *C.struct_whisper_token_data, n_tokens C.int, logits *C.float, n_vocab C.int) {
+	cbLock.RLock()
+	fn, ok := cbLogitsFilter[user_data]
+	cbLock.RUnlock()
+	if ok {
+		var data []TokenData
+		if n_tokens > 0 {
+			data = unsafe.Slice((*TokenData)(tokens), int(n_tokens))
+		}
+		fn(data, unsafe.Slice((*float32)(logits), int(n_vocab)))
+	}
+}
+
 func (t TokenData) T0() int64 {
 	return int64(t.t0)
 }
@@ -463,6 +741,44 @@ func (t TokenData) T1() int64 {
 	return int64(t.t1)
 }
 
+func (t TokenData) Plog() float32 {
+	return float32(t.plog)
+}
+
+func (t TokenData) P() float32 {
+	return float32(t.p)
+}
+
 func (t TokenData) Id() Token {
 	return Token(t.id)
 }
+
+// Time spent computing the log mel spectrogram, in microseconds
+func (t Timings) Mel() int64 {
+	return int64(t.mel_us)
+}
+
+// Time spent sampling tokens, in microseconds
+func (t Timings) Sample() int64 {
+	return int64(t.sample_us)
+}
+
+// Time spent in the encoder, in microseconds
+func (t Timings) Encode() int64 {
+	return int64(t.encode_us)
+}
+
+// Time spent in the decoder, in microseconds, one token at a time
+func (t Timings) Decode() int64 {
+	return int64(t.decode_us)
+}
+
+// Time spent in the decoder, in microseconds, for batches of tokens
+func (t Timings) Batchd() int64 {
+	return int64(t.batchd_us)
+}
+
+// Time spent in the decoder, in microseconds, for the prompt
+func (t Timings) Prompt() int64 {
+	return int64(t.prompt_us)
+}
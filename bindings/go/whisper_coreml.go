@@ -0,0 +1,12 @@
+//go:build coreml
+
+package whisper
+
+// Link with the Core ML frameworks, for a libwhisper.a built with
+// WHISPER_COREML=1. The encoder runs with Core ML when the compiled
+// ggml-<model>-encoder.mlmodelc is next to the model file.
+
+/*
+#cgo darwin LDFLAGS: -framework Foundation -framework CoreML
+*/
+import "C"
@@ -0,0 +1,12 @@
+//go:build openvino
+
+package whisper
+
+// Link with the OpenVINO runtime, for a libwhisper.a built with
+// WHISPER_OPENVINO=1. The encoder runs with OpenVINO once it is initialized
+// with Whisper_ctx_init_openvino_encoder.
+
+/*
+#cgo LDFLAGS: -lopenvino
+*/
+import "C"
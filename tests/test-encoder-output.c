@@ -0,0 +1,58 @@
+// Check that whisper_get_encoder_output() copies the output of the last call
+// to whisper_encode(), and reports when the encoder has not run
+//
+// Usage: test-encoder-output <model>
+
+#include "whisper.h"
+
+#include <stdio.h>
+#include <stdlib.h>
+
+#define CHECK(cond) do { if (!(cond)) { fprintf(stderr, "%s:%d: check failed: %s\n", __FILE__, __LINE__, #cond); exit(1); } } while (0)
+
+int main(int argc, char ** argv) {
+    if (argc < 2) {
+        fprintf(stderr, "usage: %s <model>\n", argv[0]);
+        return 2;
+    }
+
+    struct whisper_context * ctx = whisper_init_from_file_with_params(argv[1], whisper_context_default_params());
+    CHECK(ctx != NULL);
+
+    // The encoder has not run, although the state was measured when it was
+    // created
+    CHECK(whisper_get_encoder_output(ctx, NULL, 0) == -1);
+
+    // The output is a frame of n_audio_state values for each of the n_audio_ctx
+    // positions of the encoder
+    const int n_samples = 2*WHISPER_SAMPLE_RATE;
+    float * samples = calloc(n_samples, sizeof(float));
+    CHECK(samples != NULL);
+    CHECK(whisper_pcm_to_mel(ctx, samples, n_samples, 1) == 0);
+    CHECK(whisper_encode(ctx, 0, 1) == 0);
+    const int n = whisper_get_encoder_output(ctx, NULL, 0);
+    CHECK(n == whisper_model_n_audio_ctx(ctx)*whisper_model_n_audio_state(ctx));
+
+    // The output is only copied when it fits
+    float * data = calloc(n, sizeof(float));
+    CHECK(data != NULL);
+    data[0] = 12345.0f;
+    CHECK(whisper_get_encoder_output(ctx, data, n - 1) == n);
+    CHECK(data[0] == 12345.0f);
+    CHECK(whisper_get_encoder_output(ctx, data, n) == n);
+
+    // A state holds its own output
+    struct whisper_state * state = whisper_init_state(ctx);
+    CHECK(state != NULL);
+    CHECK(whisper_get_encoder_output_from_state(state, NULL, 0) == -1);
+    CHECK(whisper_pcm_to_mel_with_state(ctx, state, samples, n_samples, 1) == 0);
+    CHECK(whisper_encode_with_state(ctx, state, 0, 1) == 0);
+    CHECK(whisper_get_encoder_output_from_state(state, data, n) == n);
+
+    whisper_free_state(state);
+    whisper_free(ctx);
+    free(samples);
+    free(data);
+
+    return 0;
+}
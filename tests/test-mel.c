@@ -0,0 +1,69 @@
+// Check that whisper_get_mel() returns the log mel spectrogram computed by
+// whisper_pcm_to_mel() or set with whisper_set_mel()
+//
+// Usage: test-mel <model>
+
+#include "whisper.h"
+
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+
+#define CHECK(cond) do { if (!(cond)) { fprintf(stderr, "%s:%d: check failed: %s\n", __FILE__, __LINE__, #cond); exit(1); } } while (0)
+
+int main(int argc, char ** argv) {
+    if (argc < 2) {
+        fprintf(stderr, "usage: %s <model>\n", argv[0]);
+        return 2;
+    }
+
+    struct whisper_context * ctx = whisper_init_from_file_with_params(argv[1], whisper_context_default_params());
+    CHECK(ctx != NULL);
+
+    int n_len = -1, n_len_org = -1, n_mel = -1;
+
+    // There is no spectrogram before one is computed or set
+    CHECK(whisper_get_mel(ctx, &n_len, &n_len_org, &n_mel) == NULL);
+    CHECK(n_len == 0 && n_len_org == 0 && n_mel == 0);
+
+    // The frames of the audio are followed by padding
+    const int n_samples = WHISPER_SAMPLE_RATE;
+    float * samples = calloc(n_samples, sizeof(float));
+    CHECK(samples != NULL);
+    CHECK(whisper_pcm_to_mel(ctx, samples, n_samples, 1) == 0);
+    const float * mel = whisper_get_mel(ctx, &n_len, &n_len_org, &n_mel);
+    CHECK(mel != NULL);
+    CHECK(n_mel == whisper_model_n_mels(ctx));
+    CHECK(n_len_org == whisper_n_len(ctx));
+    CHECK(n_len_org > 0);
+    CHECK(n_len >= n_len_org);
+
+    // A spectrogram which is set is returned without padding
+    float * data = calloc(10*n_mel, sizeof(float));
+    CHECK(data != NULL);
+    for (int i = 0; i < 10*n_mel; i++) {
+        data[i] = (float) i;
+    }
+    CHECK(whisper_set_mel(ctx, data, 10, n_mel) == 0);
+    mel = whisper_get_mel(ctx, &n_len, &n_len_org, &n_mel);
+    CHECK(mel != NULL);
+    CHECK(n_len == 10 && n_len_org == 10);
+    CHECK(memcmp(mel, data, 10*n_mel*sizeof(float)) == 0);
+
+    // A state holds its own spectrogram
+    struct whisper_state * state = whisper_init_state(ctx);
+    CHECK(state != NULL);
+    CHECK(whisper_get_mel_from_state(state, &n_len, &n_len_org, &n_mel) == NULL);
+    CHECK(whisper_pcm_to_mel_with_state(ctx, state, samples, n_samples, 1) == 0);
+    CHECK(whisper_get_mel_from_state(state, &n_len, &n_len_org, &n_mel) != NULL);
+    CHECK(n_len_org == whisper_n_len_from_state(state));
+    CHECK(whisper_get_mel(ctx, &n_len, &n_len_org, &n_mel) != NULL);
+    CHECK(n_len_org == 10);
+
+    whisper_free_state(state);
+    whisper_free(ctx);
+    free(samples);
+    free(data);
+
+    return 0;
+}
@@ -0,0 +1,58 @@
+// Check that whisper_get_timings() reports the time spent in each stage by
+// whisper_full(), and that whisper_reset_timings() clears it
+//
+// Usage: test-timings <model>
+
+#include "whisper.h"
+
+#include <stdio.h>
+#include <stdlib.h>
+
+#define CHECK(cond) do { if (!(cond)) { fprintf(stderr, "%s:%d: check failed: %s\n", __FILE__, __LINE__, #cond); exit(1); } } while (0)
+
+int main(int argc, char ** argv) {
+    if (argc < 2) {
+        fprintf(stderr, "usage: %s <model>\n", argv[0]);
+        return 2;
+    }
+
+    struct whisper_context * ctx = whisper_init_from_file_with_params(argv[1], whisper_context_default_params());
+    CHECK(ctx != NULL);
+
+    // Two seconds of silence, as whisper_full() skips less than a second
+    const int n_samples = 2*WHISPER_SAMPLE_RATE;
+    float * samples = calloc(n_samples, sizeof(float));
+    CHECK(samples != NULL);
+
+    struct whisper_full_params params = whisper_full_default_params(WHISPER_SAMPLING_GREEDY);
+    params.print_progress = false;
+
+    // The default state
+    whisper_reset_timings(ctx);
+    struct whisper_timings timings = whisper_get_timings(ctx);
+    CHECK(timings.mel_us == 0 && timings.encode_us == 0);
+    CHECK(whisper_full(ctx, params, samples, n_samples) == 0);
+    timings = whisper_get_timings(ctx);
+    CHECK(timings.mel_us > 0);
+    CHECK(timings.encode_us > 0);
+    whisper_reset_timings(ctx);
+    timings = whisper_get_timings(ctx);
+    CHECK(timings.mel_us == 0 && timings.encode_us == 0);
+
+    // A state of its own
+    struct whisper_state * state = whisper_init_state(ctx);
+    CHECK(state != NULL);
+    timings = whisper_get_timings_from_state(state);
+    CHECK(timings.mel_us == 0 && timings.encode_us == 0);
+    CHECK(whisper_full_with_state(ctx, state, params, samples, n_samples) == 0);
+    timings = whisper_get_timings_from_state(state);
+    CHECK(timings.mel_us > 0);
+    CHECK(timings.encode_us > 0);
+    CHECK(whisper_get_timings(ctx).encode_us == 0);
+
+    whisper_free_state(state);
+    whisper_free(ctx);
+    free(samples);
+
+    return 0;
+}
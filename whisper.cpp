@@ -809,6 +809,9 @@ struct whisper_state {
     struct ggml_tensor * embd_conv = nullptr;
     struct ggml_tensor * embd_enc  = nullptr;
 
+    // true when embd_enc holds the output of an encoder run, rather than of a measure
+    bool has_embd_enc = false;
+
     // helpers for GPU offloading
     std::vector<float> inp_mel;
     std::vector<float> inp_mask;
@@ -2183,6 +2186,7 @@ static bool whisper_encode_internal(
 
     wstate.t_encode_us += ggml_time_us() - t_start_us;
     wstate.n_encode++;
+    wstate.has_embd_enc = true;
 
     return !(abort_callback && abort_callback(abort_callback_data));
 }
@@ -3510,6 +3514,31 @@ int whisper_set_mel(
     return whisper_set_mel_with_state(ctx, ctx->state, data, n_len, n_mel);
 }
 
+const float * whisper_get_mel(
+        struct whisper_context * ctx,
+        int * n_len,
+        int * n_len_org,
+        int * n_mel) {
+    return whisper_get_mel_from_state(ctx->state, n_len, n_len_org, n_mel);
+}
+
+const float * whisper_get_mel_from_state(
+        struct whisper_state * state,
+        int * n_len,
+        int * n_len_org,
+        int * n_mel) {
+    if (state == nullptr || state->mel.data.empty()) {
+        *n_len = *n_len_org = *n_mel = 0;
+        return nullptr;
+    }
+
+    *n_len     = state->mel.n_len;
+    *n_len_org = state->mel.n_len_org;
+    *n_mel     = state->mel.n_mel;
+
+    return state->mel.data.data();
+}
+
 int whisper_encode_with_state(struct whisper_context * ctx, struct whisper_state * state, int offset, int n_threads) {
     if (!whisper_encode_internal(*ctx, *state, offset, n_threads, nullptr, nullptr)) {
         WHISPER_LOG_ERROR("%s: failed to eval\n", __func__);
@@ -3528,6 +3557,23 @@ int whisper_encode(struct whisper_context * ctx, int offset, int n_threads) {
     return 0;
 }
 
+int whisper_get_encoder_output(struct whisper_context * ctx, float * data, int n_data) {
+    return whisper_get_encoder_output_from_state(ctx->state, data, n_data);
+}
+
+int whisper_get_encoder_output_from_state(struct whisper_state * state, float * data, int n_data) {
+    if (state == nullptr || !state->has_embd_enc || state->embd_enc == nullptr) {
+        return -1;
+    }
+
+    const int n = ggml_nelements(state->embd_enc);
+    if (data != nullptr && n_data >= n) {
+        ggml_backend_tensor_get(state->embd_enc, data, 0, n*sizeof(float));
+    }
+
+    return n;
+}
+
 int whisper_decode_with_state(struct whisper_context * ctx, struct whisper_state * state, const whisper_token * tokens, int n_tokens, int n_past, int n_threads) {
     whisper_batch_prep_legacy(state->batch, tokens, n_tokens, n_past, 0);
 
@@ -3858,6 +3904,24 @@ void whisper_print_timings(struct whisper_context * ctx) {
     WHISPER_LOG_INFO("%s:    total time = %8.2f ms\n", __func__, (t_end_us - ctx->t_start_us)/1000.0f);
 }
 
+struct whisper_timings whisper_get_timings(struct whisper_context * ctx) {
+    if (ctx->state == nullptr) {
+        return whisper_timings {};
+    }
+    return whisper_get_timings_from_state(ctx->state);
+}
+
+struct whisper_timings whisper_get_timings_from_state(struct whisper_state * state) {
+    whisper_timings timings;
+    timings.mel_us    = state->t_mel_us;
+    timings.sample_us = state->t_sample_us;
+    timings.encode_us = state->t_encode_us;
+    timings.decode_us = state->t_decode_us;
+    timings.batchd_us = state->t_batchd_us;
+    timings.prompt_us = state->t_prompt_us;
+    return timings;
+}
+
 void whisper_reset_timings(struct whisper_context * ctx) {
     ctx->t_start_us = ggml_time_us();
     if (ctx->state != nullptr) {
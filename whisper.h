@@ -256,6 +256,23 @@ extern "C" {
                                int   n_len,
                                int   n_mel);
 
+    // Return the log mel spectrogram stored inside the default state of the provided whisper context,
+    // as n_mel rows of n_len frames. The first n_len_org frames of each row are of the audio, and the
+    // rest are padding. Returns NULL when there is no spectrogram.
+    // WHISPER_HAS_GET_MEL is defined when whisper_get_mel() is available
+#define WHISPER_HAS_GET_MEL
+    WHISPER_API const float * whisper_get_mel(
+            struct whisper_context * ctx,
+                               int * n_len,
+                               int * n_len_org,
+                               int * n_mel);
+
+    WHISPER_API const float * whisper_get_mel_from_state(
+              struct whisper_state * state,
+                               int * n_len,
+                               int * n_len_org,
+                               int * n_mel);
+
     // Run the Whisper encoder on the log mel spectrogram stored inside the default state in the provided whisper context.
     // Make sure to call whisper_pcm_to_mel() or whisper_set_mel() first.
     // offset can be used to specify the offset of the first frame in the spectrogram.
@@ -271,6 +288,22 @@ extern "C" {
                                int   offset,
                                int   n_threads);
 
+    // Copy the output of the encoder, computed by the last call to whisper_encode() with the default state
+    // of the provided whisper context, into data, as frames of n_audio_state values.
+    // Returns the number of values of the output, which are copied when n_data is at least that,
+    // or -1 when the encoder has not run
+    // WHISPER_HAS_ENCODER_OUTPUT is defined when whisper_get_encoder_output() is available
+#define WHISPER_HAS_ENCODER_OUTPUT
+    WHISPER_API int whisper_get_encoder_output(
+            struct whisper_context * ctx,
+                             float * data,
+                               int   n_data);
+
+    WHISPER_API int whisper_get_encoder_output_from_state(
+              struct whisper_state * state,
+                             float * data,
+                               int   n_data);
+
     // Run the Whisper decoder to obtain the logits and probabilities for the next token.
     // Make sure to call whisper_encode() first.
     // tokens + n_tokens is the provided context for the decoder.
@@ -387,6 +420,21 @@ extern "C" {
     WHISPER_API void whisper_print_timings(struct whisper_context * ctx);
     WHISPER_API void whisper_reset_timings(struct whisper_context * ctx);
 
+    // Time spent in each stage since the timings were reset, in microseconds.
+    // WHISPER_HAS_TIMINGS is defined when whisper_get_timings() is available
+#define WHISPER_HAS_TIMINGS
+    struct whisper_timings {
+        int64_t mel_us;
+        int64_t sample_us;
+        int64_t encode_us;
+        int64_t decode_us;
+        int64_t batchd_us;
+        int64_t prompt_us;
+    };
+
+    WHISPER_API struct whisper_timings whisper_get_timings(struct whisper_context * ctx);
+    WHISPER_API struct whisper_timings whisper_get_timings_from_state(struct whisper_state * state);
+
     // Print system information
     WHISPER_API const char * whisper_print_system_info(void);
 